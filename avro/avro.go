@@ -0,0 +1,59 @@
+// Package avro provides an implementation of the Avro binary encoding for
+// objconv.
+//
+// Avro data carries no type information, emitters and parsers are bound to a
+// schema which drives the encoding and decoding of values, and against which
+// the values are validated. Because they can't be constructed without a
+// schema the codecs of this package aren't registered in the objconv registry.
+//
+// Emitters and parsers created by NewEmitter and NewParser produce and consume
+// sequences of Avro datums with no framing. NewFileEmitter and NewFileParser
+// deal with Avro object container files, which embed the schema of the records
+// they contain.
+package avro
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Names of the compression codecs supported for the blocks of object container
+// files.
+const (
+	NullCodec    = "null"
+	DeflateCodec = "deflate"
+)
+
+func appendLong(b []byte, v int64) []byte {
+	return binary.AppendUvarint(b, uint64(v<<1)^uint64(v>>63))
+}
+
+func appendString(b []byte, s string) []byte {
+	return append(appendLong(b, int64(len(s))), s...)
+}
+
+func appendBytes(b []byte, s []byte) []byte {
+	return append(appendLong(b, int64(len(s))), s...)
+}
+
+// insertLong inserts the encoding of v at offset i of b.
+func insertLong(b []byte, i int, v int64) []byte {
+	var a [binary.MaxVarintLen64]byte
+	h := appendLong(a[:0], v)
+	n := len(b)
+	b = append(b, h...)
+	copy(b[i+len(h):], b[i:n])
+	copy(b[i:], h)
+	return b
+}
+
+var errLongOverflow = errors.New("objconv/avro: variable length integer overflows 64 bits")
+
+func appendFloat(b []byte, f float32) []byte {
+	return binary.LittleEndian.AppendUint32(b, math.Float32bits(f))
+}
+
+func appendDouble(b []byte, f float64) []byte {
+	return binary.LittleEndian.AppendUint64(b, math.Float64bits(f))
+}
@@ -0,0 +1,424 @@
+package avro
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/segmentio/objconv"
+)
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		s string
+		v interface{}
+		b []byte
+	}{
+		{`"null"`, nil, []byte{}},
+		{`"boolean"`, true, []byte{1}},
+		{`"int"`, -64, []byte{0x7F}},
+		{`"long"`, 64, []byte{0x80, 0x01}},
+		{`"double"`, 1, []byte{0, 0, 0, 0, 0, 0, 0xF0, 0x3F}},
+		{`"float"`, 0.5, []byte{0, 0, 0, 0x3F}},
+		{`"string"`, "foo", []byte{6, 'f', 'o', 'o'}},
+		{`"bytes"`, []byte{1, 2}, []byte{4, 1, 2}},
+		{`{"type":"fixed","name":"f","size":2}`, []byte{1, 2}, []byte{1, 2}},
+		{`{"type":"enum","name":"e","symbols":["A","B"]}`, "B", []byte{2}},
+		{`{"type":"array","items":"long"}`, []int{3, 27}, []byte{4, 6, 54, 0}},
+		{`{"type":"array","items":"long"}`, []int{}, []byte{0}},
+		{`{"type":"array","items":"long"}`, []int(nil), []byte{0}},
+		{`{"type":"map","values":"int"}`, map[string]int{"a": 1}, []byte{2, 2, 'a', 2, 0}},
+		{`["null","string"]`, nil, []byte{0}},
+		{`["null","string"]`, "a", []byte{2, 2, 'a'}},
+		{`["double","long"]`, 1, []byte{2, 2}},
+		{`{"type":"long","logicalType":"timestamp-millis"}`, time.Unix(1, 5e6), []byte{0xDA, 0x0F}},
+		{`{"type":"int","logicalType":"date"}`, time.Date(1969, 12, 31, 12, 0, 0, 0, time.UTC), []byte{1}},
+		{
+			`{"type":"record","name":"test","fields":[{"name":"a","type":"long"},{"name":"b","type":"string"}]}`,
+			map[string]interface{}{"b": "foo", "a": 27},
+			[]byte{0x36, 0x06, 'f', 'o', 'o'},
+		},
+		{
+			`{"type":"record","name":"test","fields":[{"name":"a","type":"long","default":1},{"name":"b","type":["null","string"],"default":null}]}`,
+			map[string]interface{}{},
+			[]byte{2, 0},
+		},
+	}
+
+	for _, test := range tests {
+		b, err := Marshal(test.v, MustParseSchema(test.s))
+		if err != nil {
+			t.Errorf("%s: %#v: %s", test.s, test.v, err)
+			continue
+		}
+		if !bytes.Equal(b, test.b) {
+			t.Errorf("%s: %#v: % x != % x", test.s, test.v, test.b, b)
+		}
+	}
+}
+
+func TestMarshalError(t *testing.T) {
+	tests := []struct {
+		s string
+		v interface{}
+	}{
+		{`"null"`, 1},
+		{`"int"`, int64(1) << 40},
+		{`"long"`, uint64(1) << 63},
+		{`"string"`, 1},
+		{`"long"`, time.Now()},
+		{`{"type":"fixed","name":"f","size":2}`, []byte{1}},
+		{`{"type":"enum","name":"e","symbols":["A","B"]}`, "C"},
+		{`{"type":"array","items":"long"}`, []string{"a"}},
+		{`{"type":"record","name":"r","fields":[{"name":"a","type":"long"}]}`, map[string]int{}},
+		{`{"type":"record","name":"r","fields":[{"name":"a","type":"long"}]}`, map[string]int{"a": 1, "b": 2}},
+	}
+
+	for _, test := range tests {
+		if _, err := Marshal(test.v, MustParseSchema(test.s)); err == nil {
+			t.Errorf("%s: %#v: expected an error", test.s, test.v)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	tests := []struct {
+		s string
+		b []byte
+		v interface{}
+	}{
+		{`"int"`, []byte{0x7F}, int64(-64)},
+		{`"float"`, []byte{0, 0, 0, 0x3F}, 0.5},
+		{`{"type":"enum","name":"e","symbols":["A","B"]}`, []byte{2}, "B"},
+		{`{"type":"fixed","name":"f","size":2}`, []byte{1, 2}, []byte{1, 2}},
+		{`["null","string"]`, []byte{2, 2, 'a'}, "a"},
+		{
+			// Blocks with negative counts are followed by their size.
+			`{"type":"array","items":"long"}`,
+			[]byte{3, 4, 6, 54, 2, 2, 0},
+			[]interface{}{int64(3), int64(27), int64(1)},
+		},
+		{
+			`{"type":"map","values":"int"}`,
+			[]byte{2, 2, 'a', 2, 2, 2, 'b', 4, 0},
+			map[interface{}]interface{}{"a": int64(1), "b": int64(2)},
+		},
+		{
+			`{"type":"record","name":"test","fields":[{"name":"a","type":"long"},{"name":"b","type":"string"}]}`,
+			[]byte{0x36, 0x06, 'f', 'o', 'o'},
+			map[interface{}]interface{}{"a": int64(27), "b": "foo"},
+		},
+		{
+			`{"type":"long","logicalType":"timestamp-micros"}`,
+			[]byte{0x80, 0x89, 0x7A},
+			time.Unix(1, 0).UTC(),
+		},
+	}
+
+	for _, test := range tests {
+		var v interface{}
+
+		if err := Unmarshal(test.b, MustParseSchema(test.s), &v); err != nil {
+			t.Errorf("%s: % x: %s", test.s, test.b, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(v, test.v) {
+			t.Errorf("%s: % x: %#v != %#v", test.s, test.b, test.v, v)
+		}
+	}
+}
+
+func TestUnmarshalError(t *testing.T) {
+	tests := []struct {
+		s string
+		b []byte
+	}{
+		{`"long"`, []byte{}},
+		{`"long"`, []byte{0x80}},
+		{`"int"`, []byte{0x80, 0x80, 0x80, 0x80, 0x10}},
+		{`"boolean"`, []byte{2}},
+		{`"string"`, []byte{6, 'a'}},
+		{`"string"`, []byte{1}},
+		{`["null","string"]`, []byte{4}},
+		{`{"type":"enum","name":"e","symbols":["A","B"]}`, []byte{4}},
+	}
+
+	for _, test := range tests {
+		var v interface{}
+
+		if err := Unmarshal(test.b, MustParseSchema(test.s), &v); err == nil {
+			t.Errorf("%s: % x: expected an error", test.s, test.b)
+		}
+	}
+}
+
+func TestParseSchemaError(t *testing.T) {
+	for _, s := range []string{
+		``,
+		`"unknown"`,
+		`1`,
+		`{"type":"record","fields":[]}`,
+		`{"type":"record","name":"r"}`,
+		`{"type":"record","name":"r","fields":[{"name":"a","type":"long"},{"name":"a","type":"long"}]}`,
+		`{"type":"record","name":"r","fields":[{"name":"a","type":"long","default":"x"}]}`,
+		`{"type":"fixed","name":"f"}`,
+		`["null","null"]`,
+		`["null",["long"]]`,
+		`[{"type":"enum","name":"e","symbols":[]},{"type":"enum","name":"e","symbols":[]}]`,
+	} {
+		if _, err := ParseSchema(s); err == nil {
+			t.Errorf("%s: expected an error", s)
+		}
+	}
+}
+
+const userSchema = `{
+  "type": "record",
+  "name": "User",
+  "namespace": "com.example",
+  "fields": [
+    {"name": "id", "type": "long"},
+    {"name": "name", "type": "string"},
+    {"name": "email", "type": ["null", "string"], "default": null},
+    {"name": "kind", "type": {"type": "enum", "name": "Kind", "symbols": ["ADMIN", "USER"]}, "default": "USER"},
+    {"name": "tags", "type": {"type": "array", "items": "string"}, "default": []},
+    {"name": "scores", "type": {"type": "map", "values": "double"}, "default": {}},
+    {"name": "created", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "friends", "type": {"type": "array", "items": "User"}, "default": []}
+  ]
+}`
+
+type user struct {
+	ID      int64              `objconv:"id"`
+	Name    string             `objconv:"name"`
+	Email   *string            `objconv:"email"`
+	Kind    string             `objconv:"kind"`
+	Tags    []string           `objconv:"tags"`
+	Scores  map[string]float64 `objconv:"scores"`
+	Created time.Time          `objconv:"created"`
+	Friends []user             `objconv:"friends"`
+}
+
+func makeUsers(n int) []user {
+	email := "bob@example.com"
+	users := make([]user, n)
+
+	for i := range users {
+		users[i] = user{
+			ID:      int64(i),
+			Name:    fmt.Sprint("user-", i),
+			Kind:    "USER",
+			Tags:    []string{},
+			Scores:  map[string]float64{},
+			Created: time.Unix(int64(1500000000+i), 123e6).UTC(),
+			Friends: []user{},
+		}
+
+		if i%2 == 0 {
+			users[i].Email = &email
+			users[i].Kind = "ADMIN"
+			users[i].Tags = []string{"a", "b"}
+			users[i].Scores = map[string]float64{"x": 0.5}
+			users[i].Friends = []user{{
+				ID:      -1,
+				Name:    "friend",
+				Kind:    "USER",
+				Tags:    []string{},
+				Scores:  map[string]float64{},
+				Created: time.Unix(0, 0).UTC(),
+				Friends: []user{},
+			}}
+		}
+	}
+
+	return users
+}
+
+func TestRoundTrip(t *testing.T) {
+	schema := MustParseSchema(userSchema)
+
+	if schema.Name() != "com.example.User" || schema.Type() != "record" {
+		t.Fatalf("bad schema: %s %s", schema.Type(), schema.Name())
+	}
+
+	for _, u1 := range makeUsers(2) {
+		b, err := Marshal(u1, schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var u2 user
+
+		if err := Unmarshal(b, schema, &u2); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(u1, u2) {
+			t.Errorf("%#v != %#v", u1, u2)
+		}
+	}
+}
+
+func TestRoundTripDefaults(t *testing.T) {
+	schema := MustParseSchema(userSchema)
+
+	b, err := Marshal(map[string]interface{}{
+		"created": time.Unix(1, 0),
+		"name":    "alice",
+		"id":      1,
+	}, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var u user
+
+	if err := Unmarshal(b, schema, &u); err != nil {
+		t.Fatal(err)
+	}
+
+	if u.Name != "alice" || u.Email != nil || u.Kind != "USER" || len(u.Tags) != 0 || len(u.Friends) != 0 {
+		t.Errorf("bad user: %#v", u)
+	}
+}
+
+func TestStream(t *testing.T) {
+	schema := MustParseSchema(`"string"`)
+	b := &bytes.Buffer{}
+	e := NewStreamEncoder(b, schema)
+
+	for _, v := range []interface{}{"a", errors.New("b"), time.Second} {
+		if err := e.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewStreamDecoder(b, schema)
+	var vs []string
+
+	for {
+		var v string
+		if d.Decode(&v) != nil {
+			break
+		}
+		vs = append(vs, v)
+	}
+
+	if err := d.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(vs, []string{"a", "b", "1s"}) {
+		t.Errorf("bad values: %#v", vs)
+	}
+}
+
+func TestFile(t *testing.T) {
+	for _, codec := range []string{NullCodec, DeflateCodec} {
+		t.Run(codec, func(t *testing.T) {
+			schema := MustParseSchema(userSchema)
+			users := makeUsers(2000) // enough records for multiple blocks
+
+			b := &bytes.Buffer{}
+			emitter, err := NewFileEmitter(b, schema, codec)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			e := objconv.NewStreamEncoder(emitter)
+
+			for _, u := range users {
+				if err := e.Encode(u); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := e.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if n := bytes.Count(b.Bytes(), emitter.file.sync[:]); n < 3 {
+				t.Errorf("the records were not split into blocks: %d sync markers", n)
+			}
+
+			p := NewFileParser(b)
+
+			if s := p.Schema(); s == nil || s.Name() != "com.example.User" {
+				t.Fatal("bad schema loaded from the file header")
+			}
+
+			d := objconv.NewStreamDecoder(p)
+			var found []user
+
+			for {
+				var u user
+				if d.Decode(&u) != nil {
+					break
+				}
+				found = append(found, u)
+			}
+
+			if err := d.Err(); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(users, found) {
+				t.Errorf("decoded %d users that differ from the %d encoded", len(found), len(users))
+			}
+		})
+	}
+}
+
+func TestFileEmpty(t *testing.T) {
+	b := &bytes.Buffer{}
+	e, _ := NewFileEmitter(b, MustParseSchema(`"long"`), "")
+
+	if err := objconv.NewEncoder(e).Encode([]int{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var v []int
+
+	if err := objconv.NewDecoder(NewFileParser(b)).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(v) != 0 {
+		t.Errorf("bad values: %#v", v)
+	}
+}
+
+func TestFileError(t *testing.T) {
+	if _, err := NewFileEmitter(nil, MustParseSchema(`"long"`), "snappy"); err == nil {
+		t.Error("expected an error for an unsupported codec")
+	}
+
+	b := &bytes.Buffer{}
+	e, _ := NewFileEmitter(b, MustParseSchema(`"long"`), NullCodec)
+
+	if err := objconv.NewEncoder(e).Encode([]int{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Corrupt the sync marker that ends the block.
+	c := b.Bytes()
+	c[len(c)-1]++
+
+	var v []int
+
+	if err := objconv.NewDecoder(NewFileParser(bytes.NewReader(c))).Decode(&v); err == nil {
+		t.Error("expected an error for an invalid sync marker")
+	}
+
+	if err := objconv.NewDecoder(NewFileParser(bytes.NewReader([]byte("Obj")))).Decode(&v); err == nil {
+		t.Error("expected an error for a truncated header")
+	}
+}
@@ -0,0 +1,26 @@
+package avro
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/segmentio/objconv"
+)
+
+// NewDecoder returns a new Avro decoder that parses datums of the given schema
+// from r.
+func NewDecoder(r io.Reader, schema *Schema) *objconv.Decoder {
+	return objconv.NewDecoder(NewParser(r, schema))
+}
+
+// NewStreamDecoder returns a new Avro stream decoder that parses datums of the
+// given schema from r.
+func NewStreamDecoder(r io.Reader, schema *Schema) *objconv.StreamDecoder {
+	return objconv.NewStreamDecoder(NewParser(r, schema))
+}
+
+// Unmarshal decodes the Avro representation of v, written with schema, from
+// b.
+func Unmarshal(b []byte, schema *Schema, v interface{}) error {
+	return NewDecoder(bytes.NewReader(b), schema).Decode(v)
+}
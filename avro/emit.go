@@ -0,0 +1,511 @@
+package avro
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/objconv"
+)
+
+// Emitter implements an Avro emitter that satisfies the objconv.Emitter
+// interface.
+//
+// The emitter validates the values it receives against its schema, here are
+// a few details on how values are mapped to the schema:
+//
+//   - integers and floats are converted to the numeric type of the schema,
+//     as long as they fit in its range
+//   - strings are accepted for enums if they match one of the symbols
+//   - time values are encoded as longs if the schema has a timestamp-millis
+//     or timestamp-micros logical type, as ints if it has a date logical type,
+//     or as RFC3339 strings
+//   - durations and errors are encoded as strings
+//   - nil values are encoded as empty arrays or maps when the schema doesn't
+//     accept null
+//   - the fields of records may be emitted in any order, missing fields are
+//     set to their default values
+//
+// Values of unions are encoded with the first branch that accepts them, with
+// a preference for branches of the most precise type, so 42 matches "long"
+// before "double" regardless of their order in the union.
+type Emitter struct {
+	w      io.Writer
+	schema *Schema
+	b      []byte
+	// This stack tracks the records, arrays and maps being emitted.
+	stack []frame
+	// Set when the emitter writes object container files.
+	file *fileWriter
+}
+
+type frame struct {
+	s     *Schema
+	start int  // offset of the content of the container in b
+	n     int  // number of items in arrays and maps
+	key   bool // next value is a map key or record field name
+	field int  // index of the current record field
+	// Encoded values of the record fields, they are buffered because fields
+	// may be emitted in an order that differs from the schema.
+	values [][]byte
+	set    []bool
+}
+
+// NewEmitter returns a new emitter which writes datums of the given schema to
+// w, with no framing.
+func NewEmitter(w io.Writer, schema *Schema) *Emitter {
+	return &Emitter{w: w, schema: schema}
+}
+
+func (e *Emitter) Reset(w io.Writer) {
+	e.w = w
+	e.b = e.b[:0]
+	e.stack = e.stack[:0]
+
+	if e.file != nil {
+		e.file.reset()
+	}
+}
+
+// SequenceEmitter returns true, unless the emitter writes object container
+// files which represent top-level arrays of records.
+func (e *Emitter) SequenceEmitter() bool {
+	return e.file == nil
+}
+
+func (e *Emitter) EmitNil() error {
+	if e.isKey() {
+		return errKey
+	}
+
+	s, err := e.begin(objconv.Nil, nil)
+	if err != nil {
+		return err
+	}
+
+	if s.typ != "null" { // empty array or map
+		e.b = append(e.b, 0)
+	}
+
+	return e.end()
+}
+
+func (e *Emitter) EmitBool(v bool) error {
+	if e.isKey() {
+		return e.emitKey(strconv.FormatBool(v))
+	}
+
+	if _, err := e.begin(objconv.Bool, nil); err != nil {
+		return err
+	}
+
+	if v {
+		e.b = append(e.b, 1)
+	} else {
+		e.b = append(e.b, 0)
+	}
+
+	return e.end()
+}
+
+func (e *Emitter) EmitInt(v int64, _ int) error {
+	if e.isKey() {
+		return e.emitKey(strconv.FormatInt(v, 10))
+	}
+
+	s, err := e.begin(objconv.Int, func(s *Schema) bool {
+		return s.typ != "int" || (v >= math.MinInt32 && v <= math.MaxInt32)
+	})
+	if err != nil {
+		return err
+	}
+
+	e.appendNumber(s, v, float64(v))
+	return e.end()
+}
+
+func (e *Emitter) EmitUint(v uint64, _ int) error {
+	if e.isKey() {
+		return e.emitKey(strconv.FormatUint(v, 10))
+	}
+
+	s, err := e.begin(objconv.Uint, func(s *Schema) bool {
+		switch s.typ {
+		case "int":
+			return v <= math.MaxInt32
+		case "long":
+			return v <= math.MaxInt64
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	e.appendNumber(s, int64(v), float64(v))
+	return e.end()
+}
+
+func (e *Emitter) EmitFloat(v float64, bitSize int) error {
+	if e.isKey() {
+		return e.emitKey(strconv.FormatFloat(v, 'g', -1, bitSize))
+	}
+
+	s, err := e.begin(objconv.Float, nil)
+	if err != nil {
+		return err
+	}
+
+	e.appendNumber(s, 0, v)
+	return e.end()
+}
+
+func (e *Emitter) appendNumber(s *Schema, i int64, f float64) {
+	switch s.typ {
+	case "int", "long":
+		e.b = appendLong(e.b, i)
+	case "float":
+		e.b = appendFloat(e.b, float32(f))
+	default:
+		e.b = appendDouble(e.b, f)
+	}
+}
+
+func (e *Emitter) EmitString(v string) error {
+	if e.isKey() {
+		return e.emitKey(v)
+	}
+
+	s, err := e.begin(objconv.String, func(s *Schema) bool {
+		return s.typ != "enum" || s.symbol(v) >= 0
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.typ == "enum" {
+		e.b = appendLong(e.b, int64(s.symbol(v)))
+	} else {
+		e.b = appendString(e.b, v)
+	}
+
+	return e.end()
+}
+
+func (e *Emitter) EmitBytes(v []byte) error {
+	if e.isKey() {
+		return errKey
+	}
+
+	s, err := e.begin(objconv.Bytes, func(s *Schema) bool {
+		return s.typ != "fixed" || s.size == len(v)
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.typ == "fixed" {
+		e.b = append(e.b, v...)
+	} else {
+		e.b = appendBytes(e.b, v)
+	}
+
+	return e.end()
+}
+
+func (e *Emitter) EmitTime(v time.Time) error {
+	if e.isKey() {
+		return e.emitKey(v.Format(time.RFC3339Nano))
+	}
+
+	s, err := e.begin(objconv.Time, func(s *Schema) bool {
+		return s.typ == "string" || s.logical != ""
+	})
+	if err != nil {
+		return err
+	}
+
+	switch s.logical {
+	case "timestamp-millis":
+		e.b = appendLong(e.b, v.Unix()*1e3+int64(v.Nanosecond())/1e6)
+	case "timestamp-micros":
+		e.b = appendLong(e.b, v.Unix()*1e6+int64(v.Nanosecond())/1e3)
+	case "date":
+		e.b = appendLong(e.b, floorDiv(v.Unix(), 86400))
+	default:
+		e.b = appendString(e.b, v.Format(time.RFC3339Nano))
+	}
+
+	return e.end()
+}
+
+func (e *Emitter) EmitDuration(v time.Duration) error {
+	return e.EmitString(v.String())
+}
+
+func (e *Emitter) EmitError(v error) error {
+	return e.EmitString(v.Error())
+}
+
+func (e *Emitter) EmitArrayBegin(_ int) error {
+	if e.isKey() {
+		return errKey
+	}
+
+	if e.file != nil && len(e.stack) == 0 && !e.file.open {
+		e.file.open = true
+		return nil
+	}
+
+	s, err := e.begin(objconv.Array, nil)
+	if err != nil {
+		return err
+	}
+
+	e.push(s)
+	return nil
+}
+
+func (e *Emitter) EmitArrayEnd() error {
+	if e.file != nil && len(e.stack) == 0 {
+		e.file.open = false
+		return e.flush()
+	}
+
+	if f := e.pop(); f.n != 0 {
+		e.b = insertLong(e.b, f.start, int64(f.n))
+	}
+
+	e.b = append(e.b, 0)
+	return e.end()
+}
+
+func (e *Emitter) EmitArrayNext() error {
+	return nil
+}
+
+func (e *Emitter) EmitMapBegin(_ int) error {
+	if e.isKey() {
+		return errKey
+	}
+
+	s, err := e.begin(objconv.Map, nil)
+	if err != nil {
+		return err
+	}
+
+	e.push(s).key = true
+	return nil
+}
+
+func (e *Emitter) EmitMapEnd() error {
+	f := &e.stack[len(e.stack)-1]
+	e.commit(f)
+
+	if f.s.typ == "record" {
+		for i, field := range f.s.fields {
+			switch {
+			case f.set[i]:
+				e.b = append(e.b, f.values[i]...)
+			case field.hasDef:
+				e.b = append(e.b, field.def...)
+			default:
+				return fmt.Errorf("objconv/avro: missing value for field %q of %s", field.name, f.s)
+			}
+		}
+	} else {
+		if f.n != 0 {
+			e.b = insertLong(e.b, f.start, int64(f.n))
+		}
+		e.b = append(e.b, 0)
+	}
+
+	e.pop()
+	return e.end()
+}
+
+func (e *Emitter) EmitMapValue() error {
+	e.stack[len(e.stack)-1].key = false
+	return nil
+}
+
+func (e *Emitter) EmitMapNext() error {
+	f := &e.stack[len(e.stack)-1]
+	e.commit(f)
+	f.key = true
+	return nil
+}
+
+// isKey returns true if the next value emitted is a map key or record field
+// name.
+func (e *Emitter) isKey() bool {
+	n := len(e.stack)
+	return n != 0 && e.stack[n-1].key
+}
+
+func (e *Emitter) emitKey(k string) error {
+	f := &e.stack[len(e.stack)-1]
+
+	if f.s.typ != "record" {
+		f.n++
+		e.b = appendString(e.b, k)
+		return nil
+	}
+
+	i, ok := f.s.index[k]
+	if !ok {
+		return fmt.Errorf("objconv/avro: record %s has no field named %q", f.s, k)
+	}
+
+	if f.set[i] {
+		return fmt.Errorf("objconv/avro: field %q of record %s was emitted more than once", k, f.s)
+	}
+
+	f.field = i
+	return nil
+}
+
+// commit moves the value of the current record field from the output buffer
+// to the field buffers of the record.
+func (e *Emitter) commit(f *frame) {
+	if f.s.typ == "record" && !f.key {
+		f.values[f.field] = append(f.values[f.field][:0], e.b[f.start:]...)
+		f.set[f.field] = true
+		e.b = e.b[:f.start]
+	}
+}
+
+// begin resolves the schema of the next value, which must accept values of
+// type t, and writes the union branch index if the schema is a union. The ok
+// function performs extra checks on schemas of acceptable types, it may be
+// nil.
+func (e *Emitter) begin(t objconv.Type, ok func(*Schema) bool) (*Schema, error) {
+	var s *Schema
+
+	if n := len(e.stack); n == 0 {
+		s = e.schema
+	} else {
+		switch f := &e.stack[n-1]; f.s.typ {
+		case "array":
+			f.n++
+			s = f.s.items
+		case "map":
+			s = f.s.values
+		default:
+			s = f.s.fields[f.field].typ
+		}
+	}
+
+	for _, typ := range accepts[t] {
+		if s.typ == "union" {
+			for i, b := range s.union {
+				if b.typ == typ && (ok == nil || ok(b)) {
+					e.b = appendLong(e.b, int64(i))
+					return b, nil
+				}
+			}
+		} else if s.typ == typ && (ok == nil || ok(s)) {
+			return s, nil
+		}
+	}
+
+	return nil, fmt.Errorf("objconv/avro: cannot encode %s value as %s", t, s)
+}
+
+// end flushes the output buffer when a top-level value was completed.
+func (e *Emitter) end() (err error) {
+	if len(e.stack) != 0 {
+		return
+	}
+
+	if e.file != nil {
+		e.file.count++
+
+		if !e.file.open || len(e.b) >= blockSize {
+			err = e.flush()
+		}
+
+		return
+	}
+
+	_, err = e.w.Write(e.b)
+	e.b = e.b[:0]
+	return
+}
+
+// flush writes the records buffered by an emitter of object container files
+// as a new block.
+func (e *Emitter) flush() (err error) {
+	err = e.file.writeBlock(e.w, e.schema, e.b)
+	e.b = e.b[:0]
+	return
+}
+
+func (e *Emitter) push(s *Schema) *frame {
+	n := len(e.stack)
+
+	if n == cap(e.stack) {
+		e.stack = append(e.stack, frame{})
+	} else {
+		e.stack = e.stack[:n+1]
+	}
+
+	f := &e.stack[n]
+	f.s = s
+	f.start = len(e.b)
+	f.n = 0
+	f.key = false
+	f.field = 0
+
+	if m := len(s.fields); m != 0 {
+		if cap(f.values) < m {
+			f.values = make([][]byte, m)
+			f.set = make([]bool, m)
+		}
+		f.values = f.values[:m]
+		f.set = f.set[:m]
+
+		for i := range f.set {
+			f.set[i] = false
+		}
+	}
+
+	return f
+}
+
+func (e *Emitter) pop() frame {
+	i := len(e.stack) - 1
+	f := e.stack[i]
+	e.stack = e.stack[:i]
+	return f
+}
+
+// accepts lists the schema types that values of each objconv type may be
+// encoded as, in order of preference.
+var accepts = [...][]string{
+	objconv.Nil:      {"null", "array", "map"},
+	objconv.Bool:     {"boolean"},
+	objconv.Int:      {"long", "int", "double", "float"},
+	objconv.Uint:     {"long", "int", "double", "float"},
+	objconv.Float:    {"double", "float"},
+	objconv.String:   {"string", "enum", "bytes"},
+	objconv.Bytes:    {"bytes", "fixed", "string"},
+	objconv.Time:     {"long", "int", "string"},
+	objconv.Duration: {"string"},
+	objconv.Error:    {"string"},
+	objconv.Array:    {"array"},
+	objconv.Map:      {"record", "map"},
+}
+
+var errKey = errors.New("objconv/avro: map keys must be strings or scalar values")
+
+func floorDiv(a int64, b int64) int64 {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
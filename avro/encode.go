@@ -0,0 +1,32 @@
+package avro
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/segmentio/objconv"
+)
+
+// NewEncoder returns a new Avro encoder that writes datums of the given schema
+// to w.
+func NewEncoder(w io.Writer, schema *Schema) *objconv.Encoder {
+	return objconv.NewEncoder(NewEmitter(w, schema))
+}
+
+// NewStreamEncoder returns a new Avro stream encoder that writes datums of the
+// given schema to w.
+func NewStreamEncoder(w io.Writer, schema *Schema) *objconv.StreamEncoder {
+	return objconv.NewStreamEncoder(NewEmitter(w, schema))
+}
+
+// Marshal writes the Avro representation of v, validated against schema, to
+// a byte slice returned in b.
+func Marshal(v interface{}, schema *Schema) (b []byte, err error) {
+	buf := &bytes.Buffer{}
+
+	if err = NewEncoder(buf, schema).Encode(v); err == nil {
+		b = buf.Bytes()
+	}
+
+	return
+}
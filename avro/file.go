@@ -0,0 +1,278 @@
+package avro
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+const (
+	// Size of the buffered records at which emitters of object container
+	// files write a new block.
+	blockSize = 64 * 1024
+
+	syncSize = 16
+)
+
+// magic is the header of Avro object container files.
+var magic = [...]byte{'O', 'b', 'j', 1}
+
+// NewFileEmitter returns a new emitter which writes an Avro object container
+// file to w, storing records of the given schema in blocks compressed with
+// codec (NullCodec or DeflateCodec).
+//
+// Files are top-level arrays of records, the emitter is expected to be used
+// with a StreamEncoder, or an Encoder given a slice of records. Records are
+// buffered and written in blocks, the last block is written when the array
+// ends (when the StreamEncoder is closed).
+func NewFileEmitter(w io.Writer, schema *Schema, codec string) (*Emitter, error) {
+	switch codec {
+	case "":
+		codec = NullCodec
+	case NullCodec, DeflateCodec:
+	default:
+		return nil, fmt.Errorf("objconv/avro: unsupported codec %q", codec)
+	}
+
+	f := &fileWriter{codec: codec}
+	f.reset()
+	return &Emitter{w: w, schema: schema, file: f}, nil
+}
+
+type fileWriter struct {
+	codec  string
+	sync   [syncSize]byte
+	header bool  // set when the header was written
+	open   bool  // set while the top-level array is being emitted
+	count  int64 // number of records buffered
+	z      bytes.Buffer
+	zw     *flate.Writer
+}
+
+func (f *fileWriter) reset() {
+	f.header = false
+	f.open = false
+	f.count = 0
+
+	if _, err := rand.Read(f.sync[:]); err != nil {
+		panic(err)
+	}
+}
+
+// writeBlock writes the block of records in b, after the file header if it
+// wasn't written yet.
+func (f *fileWriter) writeBlock(w io.Writer, schema *Schema, b []byte) (err error) {
+	var h []byte
+
+	if !f.header {
+		h = append(h, magic[:]...)
+		h = appendLong(h, 2)
+		h = appendString(h, "avro.schema")
+		h = appendString(h, schema.src)
+		h = appendString(h, "avro.codec")
+		h = appendString(h, f.codec)
+		h = append(h, 0)
+		h = append(h, f.sync[:]...)
+		f.header = true
+	}
+
+	if f.count != 0 {
+		if f.codec == DeflateCodec {
+			if b, err = f.compress(b); err != nil {
+				return
+			}
+		}
+
+		h = appendLong(h, f.count)
+		h = appendLong(h, int64(len(b)))
+		h = append(h, b...)
+		h = append(h, f.sync[:]...)
+		f.count = 0
+	}
+
+	if len(h) != 0 {
+		_, err = w.Write(h)
+	}
+
+	return
+}
+
+func (f *fileWriter) compress(b []byte) ([]byte, error) {
+	f.z.Reset()
+
+	if f.zw == nil {
+		f.zw, _ = flate.NewWriter(&f.z, flate.DefaultCompression)
+	} else {
+		f.zw.Reset(&f.z)
+	}
+
+	if _, err := f.zw.Write(b); err != nil {
+		return nil, err
+	}
+
+	if err := f.zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return f.z.Bytes(), nil
+}
+
+// NewFileParser returns a new parser which reads an Avro object container
+// file from r. The parser produces a top-level array of records, decoded with
+// the schema stored in the file header.
+func NewFileParser(r io.Reader) *Parser {
+	return &Parser{r: bufio.NewReader(r), file: &fileReader{}}
+}
+
+type fileReader struct {
+	codec string
+	sync  [syncSize]byte
+	state int   // fileHeader, fileStart, fileOpen or fileDone
+	count int64 // number of records remaining in the current block
+	block bytes.Reader
+	zr    io.ReadCloser
+	b     []byte
+}
+
+const (
+	fileHeader = iota // the header wasn't read yet
+	fileStart         // the header was read, the array of records wasn't opened yet
+	fileOpen
+	fileDone
+)
+
+// readHeader reads the header of the file from r, returning its schema.
+func (f *fileReader) readHeader(r *bufio.Reader) (*Schema, error) {
+	var m [len(magic)]byte
+
+	if _, err := io.ReadFull(r, m[:]); err != nil {
+		return nil, noEOF(err)
+	}
+
+	if m != magic {
+		return nil, errors.New("objconv/avro: invalid object container file header")
+	}
+
+	var schema string
+	f.codec = NullCodec
+
+	for {
+		n, err := readLong(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if n == 0 {
+			break
+		}
+
+		if n < 0 { // the block size follows negative counts
+			if _, err = readLong(r); err != nil {
+				return nil, err
+			}
+			n = -n
+		}
+
+		for ; n != 0; n-- {
+			k, err := readBytes(r, nil)
+			if err != nil {
+				return nil, err
+			}
+
+			v, err := readBytes(r, nil)
+			if err != nil {
+				return nil, err
+			}
+
+			switch string(k) {
+			case "avro.schema":
+				schema = string(v)
+			case "avro.codec":
+				f.codec = string(v)
+			}
+		}
+	}
+
+	switch f.codec {
+	case NullCodec, DeflateCodec:
+	default:
+		return nil, fmt.Errorf("objconv/avro: unsupported codec %q", f.codec)
+	}
+
+	if _, err := io.ReadFull(r, f.sync[:]); err != nil {
+		return nil, noEOF(err)
+	}
+
+	return ParseSchema(schema)
+}
+
+// readBlock loads the next block of records from r, it returns io.EOF when
+// the end of the file was reached.
+func (f *fileReader) readBlock(r *bufio.Reader) error {
+	for f.count == 0 {
+		if _, err := r.Peek(1); err != nil {
+			return err
+		}
+
+		count, err := readLong(r)
+		if err != nil {
+			return err
+		}
+
+		size, err := readLong(r)
+		if err != nil {
+			return err
+		}
+
+		if count < 0 || size < 0 {
+			return errors.New("objconv/avro: invalid block in object container file")
+		}
+
+		if f.b, err = readFull(r, f.b, size); err != nil {
+			return err
+		}
+
+		var sync [syncSize]byte
+
+		if _, err = io.ReadFull(r, sync[:]); err != nil {
+			return noEOF(err)
+		}
+
+		if sync != f.sync {
+			return errors.New("objconv/avro: invalid sync marker in object container file")
+		}
+
+		b := f.b
+
+		if f.codec == DeflateCodec {
+			if b, err = f.decompress(b); err != nil {
+				return err
+			}
+		}
+
+		f.block.Reset(b)
+		f.count = count
+	}
+
+	return nil
+}
+
+func (f *fileReader) decompress(b []byte) ([]byte, error) {
+	if f.zr == nil {
+		f.zr = flate.NewReader(bytes.NewReader(b))
+	} else {
+		f.zr.(flate.Resetter).Reset(bytes.NewReader(b), nil)
+	}
+
+	b, err := ioutil.ReadAll(f.zr)
+	if err != nil {
+		return nil, fmt.Errorf("objconv/avro: invalid deflate block: %s", err)
+	}
+
+	return b, nil
+}
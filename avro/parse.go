@@ -0,0 +1,558 @@
+package avro
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/segmentio/objconv"
+)
+
+// Parser implements an Avro parser that satisfies the objconv.Parser
+// interface.
+//
+// Values are mapped to objconv types based on the schema:
+//
+//   - ints and longs are parsed as integers
+//   - floats and doubles are parsed as floats
+//   - strings and enums are parsed as strings
+//   - bytes and fixed are parsed as bytes
+//   - longs with a timestamp-millis or timestamp-micros logical type, and ints
+//     with a date logical type are parsed as time values (in UTC)
+//   - records and maps are parsed as maps, arrays as arrays
+type Parser struct {
+	r      *bufio.Reader
+	in     reader // either r or the current block of an object container file
+	schema *Schema
+	cur    *Schema // resolved schema of the next value
+	s      []byte  // string buffer
+	// This stack tracks the records, arrays and maps being parsed.
+	stack []parseFrame
+	// Set when the parser reads object container files.
+	file *fileReader
+}
+
+type reader interface {
+	io.Reader
+	io.ByteReader
+}
+
+type parseFrame struct {
+	s     *Schema
+	n     int64 // number of items remaining in the current block
+	key   bool  // next value is a map key or record field name
+	field int   // index of the current record field
+}
+
+// NewParser returns a new parser which reads datums of the given schema from
+// r, with no framing.
+func NewParser(r io.Reader, schema *Schema) *Parser {
+	b := bufio.NewReader(r)
+	return &Parser{r: b, in: b, schema: schema}
+}
+
+func (p *Parser) Reset(r io.Reader) {
+	p.r.Reset(r)
+	p.in = p.r
+	p.cur = nil
+	p.stack = p.stack[:0]
+
+	if p.file != nil {
+		p.schema = nil
+		p.file.state = fileHeader
+		p.file.count = 0
+	}
+}
+
+func (p *Parser) Buffered() io.Reader {
+	b, _ := p.r.Peek(p.r.Buffered())
+	return bytes.NewReader(b)
+}
+
+// Schema returns the schema of the values produced by the parser. For object
+// container files the schema is loaded from the file header, the method
+// returns nil if the header couldn't be read.
+func (p *Parser) Schema() *Schema {
+	if p.file != nil && p.file.state == fileHeader {
+		p.readHeader() // the error is reported by the next call to ParseType
+	}
+	return p.schema
+}
+
+// SequenceParser returns true, unless the parser reads object container files
+// which represent top-level arrays of records.
+func (p *Parser) SequenceParser() bool {
+	return p.file == nil
+}
+
+func (p *Parser) ParseType() (objconv.Type, error) {
+	if p.file != nil && len(p.stack) == 0 {
+		switch p.file.state {
+		case fileHeader:
+			if err := p.readHeader(); err != nil {
+				return objconv.Unknown, err
+			}
+			return objconv.Array, nil
+		case fileStart:
+			return objconv.Array, nil
+		case fileDone:
+			return objconv.Unknown, io.EOF
+		}
+	}
+
+	s, err := p.resolve()
+	if err != nil {
+		return objconv.Unknown, err
+	}
+
+	switch s.typ {
+	case "null":
+		return objconv.Nil, nil
+	case "boolean":
+		return objconv.Bool, nil
+	case "int", "long":
+		if s.logical != "" {
+			return objconv.Time, nil
+		}
+		return objconv.Int, nil
+	case "float", "double":
+		return objconv.Float, nil
+	case "string", "enum":
+		return objconv.String, nil
+	case "bytes", "fixed":
+		return objconv.Bytes, nil
+	case "array":
+		return objconv.Array, nil
+	default:
+		return objconv.Map, nil
+	}
+}
+
+func (p *Parser) ParseNil() (err error) {
+	if _, err = p.resolve(); err == nil {
+		p.done()
+	}
+	return
+}
+
+func (p *Parser) ParseBool() (v bool, err error) {
+	var c byte
+
+	if c, err = p.in.ReadByte(); err != nil {
+		err = noEOF(err)
+		return
+	}
+
+	if c > 1 {
+		err = fmt.Errorf("objconv/avro: invalid boolean value %d", c)
+		return
+	}
+
+	v = c == 1
+	p.done()
+	return
+}
+
+func (p *Parser) ParseInt() (v int64, err error) {
+	if v, err = readLong(p.in); err != nil {
+		return
+	}
+
+	if p.cur.typ == "int" && (v < math.MinInt32 || v > math.MaxInt32) {
+		err = fmt.Errorf("objconv/avro: int value %d overflows 32 bits", v)
+		return
+	}
+
+	p.done()
+	return
+}
+
+func (p *Parser) ParseUint() (v uint64, err error) {
+	panic("objconv/avro: ParseUint should never be called because Avro has no unsigned integer type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseFloat() (v float64, err error) {
+	var b []byte
+
+	if p.cur.typ == "float" {
+		if b, err = p.read(4); err == nil {
+			v = float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		}
+	} else {
+		if b, err = p.read(8); err == nil {
+			v = math.Float64frombits(binary.LittleEndian.Uint64(b))
+		}
+	}
+
+	if err == nil {
+		p.done()
+	}
+
+	return
+}
+
+func (p *Parser) ParseString() (v []byte, err error) {
+	switch n := len(p.stack); {
+	case n != 0 && p.stack[n-1].key && p.stack[n-1].s.typ == "record":
+		f := &p.stack[n-1]
+		v = append(p.s[:0], f.s.fields[f.field].name...)
+		p.s = v
+
+	case p.cur.typ == "enum":
+		var i int64
+
+		if i, err = readLong(p.in); err != nil {
+			return
+		}
+
+		if i < 0 || i >= int64(len(p.cur.symbols)) {
+			err = fmt.Errorf("objconv/avro: invalid symbol index %d of enum %s", i, p.cur)
+			return
+		}
+
+		v = append(p.s[:0], p.cur.symbols[i]...)
+		p.s = v
+
+	default:
+		if v, err = p.readBytes(); err != nil {
+			return
+		}
+	}
+
+	p.done()
+	return
+}
+
+func (p *Parser) ParseBytes() (v []byte, err error) {
+	if p.cur.typ == "fixed" {
+		v, err = p.read(int64(p.cur.size))
+	} else {
+		v, err = p.readBytes()
+	}
+
+	if err == nil {
+		p.done()
+	}
+
+	return
+}
+
+func (p *Parser) ParseTime() (v time.Time, err error) {
+	var n int64
+
+	if n, err = readLong(p.in); err != nil {
+		return
+	}
+
+	switch p.cur.logical {
+	case "timestamp-millis":
+		v = time.Unix(floorDiv(n, 1e3), (n-floorDiv(n, 1e3)*1e3)*1e6)
+	case "timestamp-micros":
+		v = time.Unix(floorDiv(n, 1e6), (n-floorDiv(n, 1e6)*1e6)*1e3)
+	default:
+		v = time.Unix(n*86400, 0)
+	}
+
+	v = v.UTC()
+	p.done()
+	return
+}
+
+func (p *Parser) ParseDuration() (v time.Duration, err error) {
+	panic("objconv/avro: ParseDuration should never be called because Avro has no duration type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseError() (v error, err error) {
+	panic("objconv/avro: ParseError should never be called because Avro has no error type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseArrayBegin() (n int, err error) {
+	if p.file != nil && len(p.stack) == 0 && p.file.state == fileHeader {
+		if err = p.readHeader(); err != nil {
+			return
+		}
+	}
+
+	if p.file != nil && len(p.stack) == 0 && p.file.state == fileStart {
+		p.file.state = fileOpen
+
+		if err = p.readBlock(); err == io.EOF {
+			err = nil
+			p.file.state = fileDone
+		} else if err == nil {
+			n = -1
+		}
+
+		return
+	}
+
+	var c int64
+
+	if c, err = p.readBlockCount(); err != nil {
+		return
+	}
+
+	p.push(p.cur).n = c
+	p.cur = nil
+
+	if c != 0 {
+		n = -1
+	}
+
+	return
+}
+
+func (p *Parser) ParseArrayEnd(n int) (err error) {
+	if p.file != nil && len(p.stack) == 0 {
+		p.file.state = fileDone
+		return
+	}
+	p.stack = p.stack[:len(p.stack)-1]
+	p.done()
+	return
+}
+
+func (p *Parser) ParseArrayNext(n int) (err error) {
+	if p.file != nil && len(p.stack) == 0 {
+		if err = p.readBlock(); err == io.EOF {
+			err = objconv.End
+		}
+		return
+	}
+
+	if f := &p.stack[len(p.stack)-1]; f.n == 0 {
+		if f.n, err = p.readBlockCount(); err == nil && f.n == 0 {
+			err = objconv.End
+		}
+	}
+
+	return
+}
+
+func (p *Parser) ParseMapBegin() (n int, err error) {
+	s := p.cur
+
+	if s.typ == "record" {
+		f := p.push(s)
+		f.key = true
+		p.cur = nil
+		n = len(s.fields)
+		return
+	}
+
+	var c int64
+
+	if c, err = p.readBlockCount(); err != nil {
+		return
+	}
+
+	f := p.push(s)
+	f.n = c
+	f.key = true
+	p.cur = nil
+
+	if c != 0 {
+		n = -1
+	}
+
+	return
+}
+
+func (p *Parser) ParseMapEnd(n int) (err error) {
+	p.stack = p.stack[:len(p.stack)-1]
+	p.done()
+	return
+}
+
+func (p *Parser) ParseMapValue(n int) (err error) {
+	return
+}
+
+func (p *Parser) ParseMapNext(n int) (err error) {
+	if f := &p.stack[len(p.stack)-1]; f.s.typ == "map" && f.n == 0 {
+		if f.n, err = p.readBlockCount(); err == nil && f.n == 0 {
+			err = objconv.End
+		}
+	}
+	return
+}
+
+// resolve returns the schema of the next value, reading the branch index if
+// the schema is a union.
+func (p *Parser) resolve() (*Schema, error) {
+	if p.cur != nil {
+		return p.cur, nil
+	}
+
+	var s *Schema
+
+	if n := len(p.stack); n == 0 {
+		// Sequences of datums end when there are no more bytes to read.
+		if p.file == nil {
+			if _, err := p.r.Peek(1); err != nil {
+				return nil, err
+			}
+		}
+		s = p.schema
+	} else {
+		switch f := &p.stack[n-1]; {
+		case f.key:
+			s = stringSchema
+		case f.s.typ == "array":
+			s = f.s.items
+		case f.s.typ == "map":
+			s = f.s.values
+		default:
+			s = f.s.fields[f.field].typ
+		}
+	}
+
+	if s.typ == "union" {
+		i, err := readLong(p.in)
+		if err != nil {
+			return nil, err
+		}
+
+		if i < 0 || i >= int64(len(s.union)) {
+			return nil, fmt.Errorf("objconv/avro: invalid branch index %d of union", i)
+		}
+
+		s = s.union[i]
+	}
+
+	p.cur = s
+	return s, nil
+}
+
+// done must be called when a value was entirely parsed, it moves the parser to
+// the next value.
+func (p *Parser) done() {
+	p.cur = nil
+
+	if n := len(p.stack); n == 0 {
+		if p.file != nil {
+			p.file.count--
+		}
+	} else {
+		switch f := &p.stack[n-1]; {
+		case f.key:
+			f.key = false
+		case f.s.typ == "record":
+			f.key = true
+			f.field++
+		case f.s.typ == "map":
+			f.key = true
+			f.n--
+		default:
+			f.n--
+		}
+	}
+}
+
+func (p *Parser) push(s *Schema) *parseFrame {
+	p.stack = append(p.stack, parseFrame{s: s})
+	return &p.stack[len(p.stack)-1]
+}
+
+func (p *Parser) readHeader() (err error) {
+	if p.schema, err = p.file.readHeader(p.r); err != nil {
+		return
+	}
+	p.file.state = fileStart
+	return
+}
+
+// readBlock loads the next block of records from an object container file.
+func (p *Parser) readBlock() (err error) {
+	if err = p.file.readBlock(p.r); err == nil {
+		p.in = &p.file.block
+	}
+	return
+}
+
+// readBlockCount reads the number of items in the next block of an array or
+// map.
+func (p *Parser) readBlockCount() (n int64, err error) {
+	if n, err = readLong(p.in); err == nil && n < 0 {
+		// The block size follows negative counts, it allows skipping blocks
+		// but isn't needed here.
+		if _, err = readLong(p.in); err == nil {
+			n = -n
+		}
+	}
+	return
+}
+
+func (p *Parser) readBytes() ([]byte, error) {
+	n, err := readLong(p.in)
+	if err != nil {
+		return nil, err
+	}
+	return p.read(n)
+}
+
+func (p *Parser) read(n int64) (b []byte, err error) {
+	p.s, err = readFull(p.in, p.s, n)
+	return p.s, err
+}
+
+var stringSchema = &Schema{typ: "string"}
+
+func readLong(r io.ByteReader) (int64, error) {
+	u, err := binary.ReadUvarint(r)
+	if err != nil {
+		if err != io.EOF && err != io.ErrUnexpectedEOF {
+			err = errLongOverflow
+		}
+		return 0, noEOF(err)
+	}
+	return int64(u>>1) ^ -int64(u&1), nil
+}
+
+// readBytes reads a length-prefixed sequence of bytes from r.
+func readBytes(r reader, b []byte) ([]byte, error) {
+	n, err := readLong(r)
+	if err != nil {
+		return nil, err
+	}
+	return readFull(r, b, n)
+}
+
+// readFull reads n bytes from r into b, which is grown if needed.
+func readFull(r io.Reader, b []byte, n int64) ([]byte, error) {
+	if n < 0 || n > math.MaxInt32 {
+		return nil, fmt.Errorf("objconv/avro: invalid length %d", n)
+	}
+
+	if int64(cap(b)) < n {
+		// Grow the buffer progressively so invalid lengths don't trigger
+		// large allocations.
+		var buf bytes.Buffer
+		buf.Grow(int(min(n, 1<<20)))
+
+		if _, err := io.CopyN(&buf, r, n); err != nil {
+			return nil, noEOF(err)
+		}
+
+		return buf.Bytes(), nil
+	}
+
+	b = b[:n]
+
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, noEOF(err)
+	}
+
+	return b, nil
+}
+
+func noEOF(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
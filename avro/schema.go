@@ -0,0 +1,457 @@
+package avro
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/segmentio/objconv/json"
+)
+
+// Schema represents a parsed Avro schema.
+//
+// Schemas are immutable once parsed and are safe to use concurrently by
+// multiple emitters and parsers.
+type Schema struct {
+	typ     string
+	name    string // full name of named types
+	logical string // logical type, only for the types that are supported
+	fields  []field
+	index   map[string]int // record field indexes by name
+	symbols []string       // enum symbols
+	items   *Schema        // array items
+	values  *Schema        // map values
+	size    int            // fixed size
+	union   []*Schema      // union branches
+	src     string         // JSON representation of the root schema
+}
+
+type field struct {
+	name   string
+	typ    *Schema
+	def    []byte // binary encoding of the default value
+	hasDef bool
+}
+
+// ParseSchema parses the Avro schema represented in JSON by s.
+//
+// The logical types timestamp-millis and timestamp-micros (annotating longs)
+// and date (annotating ints) are supported and map to time values, other
+// logical types are ignored and their underlying type is used instead.
+func ParseSchema(s string) (*Schema, error) {
+	var v interface{}
+
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return nil, fmt.Errorf("objconv/avro: invalid schema: %s", err)
+	}
+
+	p := schemaParser{names: make(map[string]*Schema)}
+
+	schema, err := p.parse(v, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// Defaults are resolved once the whole schema was parsed, because they may
+	// refer to types that were not complete when the field was parsed.
+	for _, f := range p.defaults {
+		if f.def, err = appendDefault(nil, f.typ, f.value); err != nil {
+			return nil, fmt.Errorf("objconv/avro: invalid default value of field %q: %s", f.name, err)
+		}
+		f.hasDef = true
+	}
+
+	schema.src = s
+	return schema, nil
+}
+
+// MustParseSchema is like ParseSchema but panics if s is not a valid schema.
+func MustParseSchema(s string) *Schema {
+	schema, err := ParseSchema(s)
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}
+
+// Type returns the type of the schema, which is "union" for unions.
+func (s *Schema) Type() string {
+	return s.typ
+}
+
+// Name returns the full name of named schemas (records, enums and fixed), or
+// an empty string for other types.
+func (s *Schema) Name() string {
+	return s.name
+}
+
+// String returns a description of the schema for error messages.
+func (s *Schema) String() string {
+	if s.name != "" {
+		return s.name
+	}
+	return s.typ
+}
+
+type schemaParser struct {
+	names    map[string]*Schema
+	defaults []pendingDefault
+}
+
+type pendingDefault struct {
+	*field
+	value interface{}
+}
+
+func (p *schemaParser) parse(v interface{}, namespace string) (*Schema, error) {
+	switch x := v.(type) {
+	case string:
+		return p.parseName(x, namespace)
+
+	case []interface{}:
+		return p.parseUnion(x, namespace)
+
+	case map[interface{}]interface{}:
+		return p.parseObject(x, namespace)
+	}
+
+	return nil, fmt.Errorf("objconv/avro: invalid schema: %v", v)
+}
+
+func (p *schemaParser) parseName(name string, namespace string) (*Schema, error) {
+	switch name {
+	case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+		return &Schema{typ: name}, nil
+	}
+
+	if s, ok := p.names[fullName(name, namespace)]; ok {
+		return s, nil
+	}
+
+	if s, ok := p.names[name]; ok {
+		return s, nil
+	}
+
+	return nil, fmt.Errorf("objconv/avro: unknown type %q", name)
+}
+
+func (p *schemaParser) parseUnion(branches []interface{}, namespace string) (*Schema, error) {
+	s := &Schema{typ: "union", union: make([]*Schema, 0, len(branches))}
+	seen := make(map[string]bool)
+
+	for _, b := range branches {
+		t, err := p.parse(b, namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		if t.typ == "union" {
+			return nil, errors.New("objconv/avro: unions may not immediately contain other unions")
+		}
+
+		if seen[t.String()] {
+			return nil, fmt.Errorf("objconv/avro: union contains %s more than once", t)
+		}
+
+		seen[t.String()] = true
+		s.union = append(s.union, t)
+	}
+
+	return s, nil
+}
+
+func (p *schemaParser) parseObject(obj map[interface{}]interface{}, namespace string) (s *Schema, err error) {
+	typ, _ := obj["type"].(string)
+
+	switch typ {
+	case "record", "error", "enum", "fixed":
+		s = &Schema{typ: typ}
+
+		if typ == "error" {
+			s.typ = "record"
+		}
+
+		name, _ := obj["name"].(string)
+		if name == "" {
+			return nil, fmt.Errorf("objconv/avro: %s schema has no name", typ)
+		}
+
+		if ns, ok := obj["namespace"].(string); ok && !strings.Contains(name, ".") {
+			namespace = ns
+		}
+
+		if s.name = fullName(name, namespace); p.names[s.name] != nil {
+			return nil, fmt.Errorf("objconv/avro: type %q is defined more than once", s.name)
+		}
+
+		// Named types are registered before being parsed so they can be
+		// referenced recursively.
+		p.names[s.name] = s
+
+		if i := strings.LastIndexByte(s.name, '.'); i >= 0 {
+			namespace = s.name[:i]
+		} else {
+			namespace = ""
+		}
+
+	case "array", "map":
+		s = &Schema{typ: typ}
+
+	default:
+		if s, err = p.parse(obj["type"], namespace); err != nil {
+			return
+		}
+
+		// Only copy primitive types, named types must keep their identity.
+		if s.name == "" && s.typ != "union" {
+			c := *s
+			s = &c
+		}
+	}
+
+	switch s.typ {
+	case "record":
+		err = p.parseFields(s, obj, namespace)
+
+	case "enum":
+		list, _ := obj["symbols"].([]interface{})
+		for _, v := range list {
+			sym, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("objconv/avro: invalid symbol of enum %s: %v", s.name, v)
+			}
+			s.symbols = append(s.symbols, sym)
+		}
+
+	case "fixed":
+		size, ok := obj["size"].(int64)
+		if !ok || size < 0 || size > math.MaxInt32 {
+			return nil, fmt.Errorf("objconv/avro: invalid size of fixed %s: %v", s.name, obj["size"])
+		}
+		s.size = int(size)
+
+	case "array":
+		s.items, err = p.parse(obj["items"], namespace)
+
+	case "map":
+		s.values, err = p.parse(obj["values"], namespace)
+
+	case "int", "long":
+		switch logical, _ := obj["logicalType"].(string); {
+		case s.typ == "long" && (logical == "timestamp-millis" || logical == "timestamp-micros"):
+			s.logical = logical
+		case s.typ == "int" && logical == "date":
+			s.logical = logical
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (p *schemaParser) parseFields(s *Schema, obj map[interface{}]interface{}, namespace string) error {
+	list, ok := obj["fields"].([]interface{})
+	if !ok {
+		return fmt.Errorf("objconv/avro: record %s has no fields", s.name)
+	}
+
+	s.fields = make([]field, len(list))
+	s.index = make(map[string]int, len(list))
+
+	for i, v := range list {
+		f, _ := v.(map[interface{}]interface{})
+		name, _ := f["name"].(string)
+
+		if name == "" {
+			return fmt.Errorf("objconv/avro: field of record %s has no name", s.name)
+		}
+
+		if _, dup := s.index[name]; dup {
+			return fmt.Errorf("objconv/avro: record %s has more than one field named %q", s.name, name)
+		}
+
+		t, err := p.parse(f["type"], namespace)
+		if err != nil {
+			return err
+		}
+
+		s.fields[i] = field{name: name, typ: t}
+		s.index[name] = i
+
+		if def, ok := f["default"]; ok {
+			p.defaults = append(p.defaults, pendingDefault{&s.fields[i], def})
+		}
+	}
+
+	return nil
+}
+
+func fullName(name string, namespace string) string {
+	if namespace == "" || strings.Contains(name, ".") {
+		return name
+	}
+	return namespace + "." + name
+}
+
+// appendDefault appends the binary encoding of the default value v, which
+// comes from the JSON representation of a schema, to b.
+func appendDefault(b []byte, s *Schema, v interface{}) ([]byte, error) {
+	switch s.typ {
+	case "null":
+		if v == nil {
+			return b, nil
+		}
+
+	case "boolean":
+		if x, ok := v.(bool); ok {
+			if x {
+				return append(b, 1), nil
+			}
+			return append(b, 0), nil
+		}
+
+	case "int", "long":
+		switch x := v.(type) {
+		case int64:
+			if s.typ == "long" || (x >= math.MinInt32 && x <= math.MaxInt32) {
+				return appendLong(b, x), nil
+			}
+		}
+
+	case "float", "double":
+		var f float64
+
+		switch x := v.(type) {
+		case int64:
+			f = float64(x)
+		case uint64:
+			f = float64(x)
+		case float64:
+			f = x
+		default:
+			return nil, fmt.Errorf("%v is not a valid %s", v, s)
+		}
+
+		if s.typ == "float" {
+			return appendFloat(b, float32(f)), nil
+		}
+		return appendDouble(b, f), nil
+
+	case "bytes", "fixed":
+		// Default values of bytes are strings where each code point is a byte.
+		if x, ok := v.(string); ok {
+			var a []byte
+
+			for _, r := range x {
+				if r > 0xFF {
+					return nil, fmt.Errorf("%q is not a valid %s", x, s)
+				}
+				a = append(a, byte(r))
+			}
+
+			if s.typ == "bytes" {
+				return appendBytes(b, a), nil
+			}
+
+			if len(a) == s.size {
+				return append(b, a...), nil
+			}
+		}
+
+	case "string":
+		if x, ok := v.(string); ok {
+			return appendString(b, x), nil
+		}
+
+	case "enum":
+		if x, ok := v.(string); ok {
+			if i := s.symbol(x); i >= 0 {
+				return appendLong(b, int64(i)), nil
+			}
+		}
+
+	case "array":
+		if x, ok := v.([]interface{}); ok {
+			var err error
+
+			if len(x) != 0 {
+				b = appendLong(b, int64(len(x)))
+
+				for _, item := range x {
+					if b, err = appendDefault(b, s.items, item); err != nil {
+						return nil, err
+					}
+				}
+			}
+
+			return append(b, 0), nil
+		}
+
+	case "map":
+		if x, ok := v.(map[interface{}]interface{}); ok {
+			keys := make([]string, 0, len(x))
+
+			for k := range x {
+				keys = append(keys, k.(string))
+			}
+
+			sort.Strings(keys)
+
+			if len(keys) != 0 {
+				var err error
+				b = appendLong(b, int64(len(keys)))
+
+				for _, k := range keys {
+					b = appendString(b, k)
+
+					if b, err = appendDefault(b, s.values, x[k]); err != nil {
+						return nil, err
+					}
+				}
+			}
+
+			return append(b, 0), nil
+		}
+
+	case "record":
+		if x, ok := v.(map[interface{}]interface{}); ok {
+			var err error
+
+			for _, f := range s.fields {
+				if fv, ok := x[f.name]; ok {
+					b, err = appendDefault(b, f.typ, fv)
+				} else if f.hasDef {
+					b = append(b, f.def...)
+				} else {
+					err = fmt.Errorf("missing value for field %q of %s", f.name, s)
+				}
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			return b, nil
+		}
+
+	case "union":
+		// Default values of unions are for the first branch.
+		return appendDefault(append(b, 0), s.union[0], v)
+	}
+
+	return nil, fmt.Errorf("%v is not a valid %s", v, s)
+}
+
+// symbol returns the index of the enum symbol sym, or -1 if it doesn't exist.
+func (s *Schema) symbol(sym string) int {
+	for i, x := range s.symbols {
+		if x == sym {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,235 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		v interface{}
+		s string
+	}{
+		{0, "i0e"},
+		{-42, "i-42e"},
+		{uint64(math.MaxUint64), "i18446744073709551615e"},
+		{true, "i1e"},
+		{false, "i0e"},
+		{0.5, "3:0.5"},
+		{"", "0:"},
+		{"spam", "4:spam"},
+		{[]byte{0xff}, "1:\xff"},
+		{time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC), "20:2017-01-02T03:04:05Z"},
+		{time.Second, "2:1s"},
+		{errors.New("oops"), "4:oops"},
+		{[]interface{}{}, "le"},
+		{[]interface{}{"spam", 42, []int{1}}, "l4:spami42eli1eee"},
+		{map[string]int{}, "de"},
+		{
+			struct {
+				Zeta  int
+				Alpha string
+				Beta  *int
+				Mid   map[string]int
+			}{1, "a", nil, map[string]int{"b": 2, "a": 1}},
+			"d5:Alpha1:a3:Midd1:ai1e1:bi2ee4:Zetai1ee",
+		},
+		{map[int]string{10: "a", 9: "b"}, "d2:101:a1:91:be"},
+	}
+
+	for _, test := range tests {
+		b, err := Marshal(test.v)
+		if err != nil {
+			t.Errorf("%#v: %s", test.v, err)
+			continue
+		}
+		if string(b) != test.s {
+			t.Errorf("%#v: %q != %q", test.v, test.s, b)
+		}
+	}
+}
+
+func TestMarshalError(t *testing.T) {
+	tests := []interface{}{
+		nil,
+		[]interface{}{nil},
+		map[interface{}]int{"1": 1, 1: 2},
+	}
+
+	for _, test := range tests {
+		if _, err := Marshal(test); err == nil {
+			t.Errorf("%#v: expected an error", test)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	tests := []struct {
+		s string
+		v interface{}
+	}{
+		{"i0e", int64(0)},
+		{"i-3e", int64(-3)},
+		{"i18446744073709551615e", uint64(math.MaxUint64)},
+		{"4:spam", "spam"},
+		{"2:\xff\xfe", []byte{0xff, 0xfe}},
+		{"le", []interface{}{}},
+		{"l4:spami42ee", []interface{}{"spam", int64(42)}},
+		{"d3:cow3:moo4:spaml1:a1:bee", map[interface{}]interface{}{
+			"cow":  "moo",
+			"spam": []interface{}{"a", "b"},
+		}},
+	}
+
+	for _, test := range tests {
+		var v interface{}
+
+		if err := Unmarshal([]byte(test.s), &v); err != nil {
+			t.Errorf("%q: %s", test.s, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(v, test.v) {
+			t.Errorf("%q: %#v != %#v", test.s, test.v, v)
+		}
+	}
+}
+
+func TestUnmarshalBool(t *testing.T) {
+	for s, exp := range map[string]bool{"i0e": false, "i1e": true, "i-2e": true} {
+		var v bool
+
+		if err := Unmarshal([]byte(s), &v); err != nil {
+			t.Errorf("%q: %s", s, err)
+		} else if v != exp {
+			t.Errorf("%q: %t != %t", s, exp, v)
+		}
+	}
+
+	var v bool
+
+	if err := Unmarshal([]byte("4:true"), &v); err == nil {
+		t.Error("expected an error decoding a string into a bool")
+	}
+}
+
+func TestUnmarshalError(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"i-0e",
+		"i03e",
+		"ie",
+		"i1",
+		"03:abc",
+		"5:abc",
+		"l",
+		"x",
+		"i99999999999999999999e",
+	} {
+		var v interface{}
+
+		if err := Unmarshal([]byte(s), &v); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+type torrent struct {
+	Announce     string    `objconv:"announce"`
+	CreationDate int64     `objconv:"creation date"`
+	Info         info      `objconv:"info"`
+	Private      bool      `objconv:"private"`
+	Ratio        float64   `objconv:"ratio"`
+	Updated      time.Time `objconv:"updated"`
+}
+
+type info struct {
+	Name        string `objconv:"name"`
+	PieceLength int    `objconv:"piece length"`
+	Pieces      []byte `objconv:"pieces"`
+	Files       []file `objconv:"files,omitempty"`
+}
+
+type file struct {
+	Length int      `objconv:"length"`
+	Path   []string `objconv:"path"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	t1 := torrent{
+		Announce:     "http://tracker.example.com/announce",
+		CreationDate: 1500000000,
+		Info: info{
+			Name:        "example",
+			PieceLength: 262144,
+			Pieces:      bytes.Repeat([]byte{0xde, 0xad, 0xbe, 0xef, 0x00}, 100),
+			Files: []file{
+				{Length: 42, Path: []string{"dir", "a.txt"}},
+			},
+		},
+		Private: true,
+		Ratio:   0.75,
+		Updated: time.Date(2017, 1, 2, 3, 4, 5, 6, time.UTC),
+	}
+
+	b, err := Marshal(t1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(b, []byte("d8:announce35:http://tracker.example.com/announce13:creation datei1500000000e4:infod5:filesl")) {
+		t.Errorf("keys were not sorted: %q", b)
+	}
+
+	var t2 torrent
+
+	if err := Unmarshal(b, &t2); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(t1, t2) {
+		t.Errorf("%#v != %#v", t1, t2)
+	}
+}
+
+func TestStream(t *testing.T) {
+	b := &bytes.Buffer{}
+	e := NewStreamEncoder(b)
+
+	for _, v := range []interface{}{1, "a", map[string]int{"b": 2, "a": 1}} {
+		if err := e.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := b.String(); s != "li1e1:ad1:ai1e1:bi2eee" {
+		t.Fatal("bad stream:", s)
+	}
+
+	d := NewStreamDecoder(b)
+	var vs []interface{}
+
+	for {
+		var v interface{}
+		if d.Decode(&v) != nil {
+			break
+		}
+		vs = append(vs, v)
+	}
+
+	if err := d.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(vs) != 3 {
+		t.Error("bad values:", vs)
+	}
+}
@@ -0,0 +1,284 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Emitter implements a bencode emitter that satisfies the objconv.Emitter
+// interface.
+//
+// Bencode only has integers, byte strings, lists and dictionaries, values of
+// other types are converted with these rules:
+//
+//   - booleans are written as the integers 1 and 0
+//   - floats, times, durations and errors are written as strings (floats use
+//     the shortest representation that round-trips, times use RFC 3339)
+//   - nil values of dictionaries are omitted, nil values are not supported
+//     anywhere else
+//
+// Keys of dictionaries are always sorted by their raw bytes, as required by
+// the format, keys that aren't strings are converted with the same rules.
+type Emitter struct {
+	w io.Writer
+	b []byte // output buffer
+
+	// This stack tracks the state of the lists and dictionaries being emitted.
+	stack []*frame
+
+	// Number of dictionaries in the stack, the output is buffered until they
+	// are all complete since their entries may have to be reordered.
+	dicts int
+}
+
+type frame struct {
+	dict    bool
+	key     bool    // true if the next value of the dictionary is a key
+	omit    bool    // true if the value of the current entry is nil
+	off     int     // offset of the first entry of the dictionary in b
+	entries []entry // entries of the dictionary
+}
+
+// entry represents the position of a dictionary entry in the output buffer.
+type entry struct {
+	start int // offset of the key
+	kbeg  int // offset of the key bytes, after the length prefix
+	kend  int // offset of the value
+	end   int // offset after the value
+}
+
+func NewEmitter(w io.Writer) *Emitter {
+	return &Emitter{w: w}
+}
+
+func (e *Emitter) Reset(w io.Writer) {
+	e.w = w
+	e.b = e.b[:0]
+	e.stack = e.stack[:0]
+	e.dicts = 0
+}
+
+func (e *Emitter) EmitNil() error {
+	if f := e.top(); f != nil && f.dict && !f.key {
+		f.omit = true
+		return nil
+	}
+	return errors.New("objconv/bencode: nil values can only be omitted from dictionaries because bencode has no null type")
+}
+
+func (e *Emitter) EmitBool(v bool) error {
+	if v {
+		return e.EmitInt(1, 0)
+	}
+	return e.EmitInt(0, 0)
+}
+
+func (e *Emitter) EmitInt(v int64, _ int) error {
+	if e.isKey() {
+		return e.emitKey(strconv.AppendInt(nil, v, 10))
+	}
+	e.b = append(e.b, 'i')
+	e.b = strconv.AppendInt(e.b, v, 10)
+	e.b = append(e.b, 'e')
+	return e.end()
+}
+
+func (e *Emitter) EmitUint(v uint64, _ int) error {
+	if e.isKey() {
+		return e.emitKey(strconv.AppendUint(nil, v, 10))
+	}
+	e.b = append(e.b, 'i')
+	e.b = strconv.AppendUint(e.b, v, 10)
+	e.b = append(e.b, 'e')
+	return e.end()
+}
+
+func (e *Emitter) EmitFloat(v float64, bitSize int) error {
+	return e.EmitString(strconv.FormatFloat(v, 'g', -1, bitSize))
+}
+
+func (e *Emitter) EmitString(v string) error {
+	if e.isKey() {
+		return e.emitKey([]byte(v))
+	}
+	e.b = strconv.AppendInt(e.b, int64(len(v)), 10)
+	e.b = append(e.b, ':')
+	e.b = append(e.b, v...)
+	return e.end()
+}
+
+func (e *Emitter) EmitBytes(v []byte) error {
+	if e.isKey() {
+		return e.emitKey(v)
+	}
+	e.b = strconv.AppendInt(e.b, int64(len(v)), 10)
+	e.b = append(e.b, ':')
+	e.b = append(e.b, v...)
+	return e.end()
+}
+
+func (e *Emitter) EmitTime(v time.Time) error {
+	return e.EmitString(v.Format(time.RFC3339Nano))
+}
+
+func (e *Emitter) EmitDuration(v time.Duration) error {
+	return e.EmitString(v.String())
+}
+
+func (e *Emitter) EmitError(v error) error {
+	return e.EmitString(v.Error())
+}
+
+func (e *Emitter) EmitArrayBegin(_ int) (err error) {
+	if e.isKey() {
+		return errKey
+	}
+	e.stack = append(e.stack, &frame{})
+	e.b = append(e.b, 'l')
+	return
+}
+
+func (e *Emitter) EmitArrayEnd() (err error) {
+	e.pop()
+	e.b = append(e.b, 'e')
+	return e.end()
+}
+
+func (e *Emitter) EmitArrayNext() (err error) {
+	return
+}
+
+func (e *Emitter) EmitMapBegin(_ int) (err error) {
+	if e.isKey() {
+		return errKey
+	}
+	e.b = append(e.b, 'd')
+	e.stack = append(e.stack, &frame{dict: true, key: true, off: len(e.b)})
+	e.dicts++
+	return
+}
+
+func (e *Emitter) EmitMapEnd() (err error) {
+	f := e.top()
+	e.endEntry(f)
+
+	if err = e.sortEntries(f); err != nil {
+		return
+	}
+
+	e.pop()
+	e.dicts--
+	e.b = append(e.b, 'e')
+	return e.end()
+}
+
+func (e *Emitter) EmitMapValue() (err error) {
+	f := e.top()
+	f.key = false
+	return
+}
+
+func (e *Emitter) EmitMapNext() (err error) {
+	f := e.top()
+	e.endEntry(f)
+	f.key = true
+	return
+}
+
+func (e *Emitter) top() *frame {
+	if n := len(e.stack); n != 0 {
+		return e.stack[n-1]
+	}
+	return nil
+}
+
+func (e *Emitter) pop() {
+	e.stack = e.stack[:len(e.stack)-1]
+}
+
+// isKey returns true if the next value emitted is the key of a dictionary.
+func (e *Emitter) isKey() bool {
+	f := e.top()
+	return f != nil && f.dict && f.key
+}
+
+func (e *Emitter) emitKey(k []byte) error {
+	f := e.top()
+	start := len(e.b)
+	e.b = strconv.AppendInt(e.b, int64(len(k)), 10)
+	e.b = append(e.b, ':')
+	kbeg := len(e.b)
+	e.b = append(e.b, k...)
+	f.entries = append(f.entries, entry{start: start, kbeg: kbeg, kend: len(e.b)})
+	return nil
+}
+
+// endEntry records the end of the current entry of f, or removes it if its
+// value was nil.
+func (e *Emitter) endEntry(f *frame) {
+	n := len(f.entries)
+
+	if n == 0 || f.entries[n-1].end != 0 {
+		return // no entries, or the entry was already ended
+	}
+
+	if f.omit {
+		e.b = e.b[:f.entries[n-1].start]
+		f.entries = f.entries[:n-1]
+		f.omit = false
+		return
+	}
+
+	f.entries[n-1].end = len(e.b)
+}
+
+// sortEntries reorders the entries of the dictionary f by their keys.
+func (e *Emitter) sortEntries(f *frame) error {
+	key := func(i int) []byte {
+		return e.b[f.entries[i].kbeg:f.entries[i].kend]
+	}
+
+	less := func(i int, j int) bool {
+		return bytes.Compare(key(i), key(j)) < 0
+	}
+
+	sorted := sort.SliceIsSorted(f.entries, less)
+
+	if !sorted {
+		sort.Slice(f.entries, less)
+	}
+
+	for i := 1; i < len(f.entries); i++ {
+		if bytes.Equal(key(i-1), key(i)) {
+			return errors.New("objconv/bencode: duplicate key " + strconv.Quote(string(key(i))))
+		}
+	}
+
+	if !sorted {
+		b := make([]byte, 0, len(e.b)-f.off)
+
+		for _, x := range f.entries {
+			b = append(b, e.b[x.start:x.end]...)
+		}
+
+		e.b = append(e.b[:f.off], b...)
+	}
+
+	return nil
+}
+
+// end must be called after writing a value, it flushes the output buffer when
+// no dictionaries are being emitted.
+func (e *Emitter) end() (err error) {
+	if e.dicts == 0 && len(e.b) != 0 {
+		_, err = e.w.Write(e.b)
+		e.b = e.b[:0]
+	}
+	return
+}
+
+var errKey = errors.New("objconv/bencode: dictionary keys must be strings or scalar values")
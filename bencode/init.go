@@ -0,0 +1,51 @@
+package bencode
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/segmentio/objconv"
+)
+
+// Codec for the bencode format.
+var Codec = objconv.Codec{
+	NewEmitter: func(w io.Writer) objconv.Emitter { return NewEmitter(w) },
+	NewParser:  func(r io.Reader) objconv.Parser { return NewParser(r) },
+	Extensions: []string{".bencode", ".torrent"},
+	Sniff:      sniff,
+}
+
+func init() {
+	for _, name := range [...]string{
+		"application/x-bencode",
+		"application/x-bittorrent",
+		"bencode",
+	} {
+		objconv.Register(name, Codec)
+	}
+}
+
+// sniff recognizes bencoded dictionaries, and to a lesser degree lists and
+// integers.
+func sniff(b []byte) float64 {
+	if len(b) < 2 {
+		return 0
+	}
+
+	switch c, d := b[0], b[1]; {
+	case c == 'd' && d >= '0' && d <= '9':
+		if i := bytes.IndexByte(b, ':'); i > 1 && isDigits(b[1:i]) {
+			return 0.7
+		}
+	case c == 'd' && d == 'e':
+		return 0.4
+	case c == 'l' && (d == 'd' || d == 'l' || d == 'i' || d == 'e' || (d >= '0' && d <= '9')):
+		return 0.3
+	case c == 'i' && (d == '-' || (d >= '0' && d <= '9')):
+		if i := bytes.IndexByte(b, 'e'); i > 1 && isDigits(bytes.TrimPrefix(b[1:i], []byte("-"))) {
+			return 0.3
+		}
+	}
+
+	return 0
+}
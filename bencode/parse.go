@@ -0,0 +1,374 @@
+package bencode
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/segmentio/objconv"
+)
+
+// Parser implements a bencode parser that satisfies the objconv.Parser
+// interface.
+//
+// Byte strings are parsed as strings when they contain valid UTF-8, and as
+// bytes otherwise. Integers are parsed as signed integers, or unsigned
+// integers when they overflow the range of int64.
+type Parser struct {
+	r io.Reader // reader to load bytes from
+	i int       // offset of the first unread byte in b
+	j int       // offset + 1 of the last unread byte in b
+	n int64     // number of bytes consumed before b[0]
+	s []byte    // string buffer
+	b [240]byte // read buffer
+
+	// Integers and strings have to be read to know their type, the value is
+	// retained here by ParseType until it is consumed.
+	v  interface{}
+	ok bool
+}
+
+func NewParser(r io.Reader) *Parser {
+	return &Parser{r: r}
+}
+
+func (p *Parser) Reset(r io.Reader) {
+	p.r = r
+	p.i = 0
+	p.j = 0
+	p.n = 0
+	p.v, p.ok = nil, false
+}
+
+func (p *Parser) Buffered() io.Reader {
+	return bytes.NewReader(p.b[p.i:p.j])
+}
+
+// Offset returns the number of bytes of the input consumed by p.
+func (p *Parser) Offset() int64 {
+	return p.n + int64(p.i)
+}
+
+func (p *Parser) ParseType() (typ objconv.Type, err error) {
+	if !p.ok {
+		var b []byte
+
+		if b, err = p.peek(1); err != nil {
+			return
+		}
+
+		switch c := b[0]; {
+		case c == 'i':
+			p.v, err = p.readInt()
+
+		case c >= '0' && c <= '9':
+			p.v, err = p.readString()
+
+		case c == 'l':
+			return objconv.Array, nil
+
+		case c == 'd':
+			return objconv.Map, nil
+
+		default:
+			err = fmt.Errorf("objconv/bencode: unexpected character %q", c)
+		}
+
+		if err != nil {
+			return
+		}
+
+		p.ok = true
+	}
+
+	switch v := p.v.(type) {
+	case int64:
+		typ = objconv.Int
+	case uint64:
+		typ = objconv.Uint
+	default:
+		if utf8.Valid(v.([]byte)) {
+			typ = objconv.String
+		} else {
+			typ = objconv.Bytes
+		}
+	}
+
+	return
+}
+
+func (p *Parser) ParseNil() (err error) {
+	panic("objconv/bencode: ParseNil should never be called because bencode has no nil type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseBool() (v bool, err error) {
+	panic("objconv/bencode: ParseBool should never be called because bencode has no boolean type, this is likely a bug in the decoder code")
+}
+
+// DecodeBool decodes booleans from integers, which is how they are represented
+// in bencode, non-zero values are true.
+func (p *Parser) DecodeBool(t objconv.Type) (v bool, err error) {
+	switch t {
+	case objconv.Int:
+		var i int64
+		i, err = p.ParseInt()
+		v = i != 0
+
+	case objconv.Uint:
+		var u uint64
+		u, err = p.ParseUint()
+		v = u != 0
+
+	default:
+		err = fmt.Errorf("objconv/bencode: cannot decode %s into bool", t)
+	}
+	return
+}
+
+func (p *Parser) ParseInt() (v int64, err error) {
+	v = p.take().(int64)
+	return
+}
+
+func (p *Parser) ParseUint() (v uint64, err error) {
+	v = p.take().(uint64)
+	return
+}
+
+func (p *Parser) ParseFloat() (v float64, err error) {
+	panic("objconv/bencode: ParseFloat should never be called because bencode has no float type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseString() (v []byte, err error) {
+	v = p.take().([]byte)
+	return
+}
+
+func (p *Parser) ParseBytes() (v []byte, err error) {
+	v = p.take().([]byte)
+	return
+}
+
+func (p *Parser) ParseTime() (v time.Time, err error) {
+	panic("objconv/bencode: ParseTime should never be called because bencode has no time type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseDuration() (v time.Duration, err error) {
+	panic("objconv/bencode: ParseDuration should never be called because bencode has no duration type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseError() (v error, err error) {
+	panic("objconv/bencode: ParseError should never be called because bencode has no error type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseArrayBegin() (n int, err error) {
+	p.i++ // 'l'
+	return -1, nil
+}
+
+func (p *Parser) ParseArrayEnd(n int) (err error) {
+	return p.parseEnd()
+}
+
+func (p *Parser) ParseArrayNext(n int) (err error) {
+	return p.parseNext()
+}
+
+func (p *Parser) ParseMapBegin() (n int, err error) {
+	p.i++ // 'd'
+	return -1, nil
+}
+
+func (p *Parser) ParseMapEnd(n int) (err error) {
+	return p.parseEnd()
+}
+
+func (p *Parser) ParseMapValue(n int) (err error) {
+	return
+}
+
+func (p *Parser) ParseMapNext(n int) (err error) {
+	return p.parseNext()
+}
+
+func (p *Parser) parseNext() (err error) {
+	var b []byte
+	if b, err = p.peek(1); err == nil && b[0] == 'e' {
+		err = objconv.End
+	}
+	return
+}
+
+func (p *Parser) parseEnd() (err error) {
+	var b []byte
+	if b, err = p.peek(1); err != nil {
+		return
+	}
+	if b[0] != 'e' {
+		return fmt.Errorf("objconv/bencode: expected 'e' at the end of a list or dictionary but found %q", b[0])
+	}
+	p.i++
+	return
+}
+
+func (p *Parser) take() (v interface{}) {
+	v, p.v, p.ok = p.v, nil, false
+	return
+}
+
+// readInt reads an integer of the form i<digits>e.
+func (p *Parser) readInt() (v interface{}, err error) {
+	p.i++ // 'i'
+
+	var s []byte
+
+	if s, err = p.readUntil('e'); err != nil {
+		return
+	}
+
+	digits := s
+	if len(digits) != 0 && digits[0] == '-' {
+		digits = digits[1:]
+	}
+
+	if !isDigits(digits) || (digits[0] == '0' && len(s) != 1) {
+		// Leading zeros and negative zero are not allowed.
+		return nil, fmt.Errorf("objconv/bencode: invalid integer %q", s)
+	}
+
+	if i, e := strconv.ParseInt(string(s), 10, 64); e == nil {
+		return i, nil
+	}
+
+	if u, e := strconv.ParseUint(string(s), 10, 64); e == nil {
+		return u, nil
+	}
+
+	return nil, fmt.Errorf("objconv/bencode: integer %s overflows the range of 64 bits integers", s)
+}
+
+// readString reads a byte string of the form <length>:<bytes>.
+func (p *Parser) readString() (v []byte, err error) {
+	var s []byte
+
+	if s, err = p.readUntil(':'); err != nil {
+		return
+	}
+
+	if !isDigits(s) || (s[0] == '0' && len(s) != 1) {
+		return nil, fmt.Errorf("objconv/bencode: invalid string length %q", s)
+	}
+
+	n, e := strconv.ParseInt(string(s), 10, 0)
+	if e != nil {
+		return nil, fmt.Errorf("objconv/bencode: invalid string length %q", s)
+	}
+
+	return p.read(int(n))
+}
+
+// readUntil reads bytes up to the delimiter c, which is consumed but not
+// returned. Integers and string lengths are short, the method fails if the
+// delimiter is not found within the read buffer.
+func (p *Parser) readUntil(c byte) (b []byte, err error) {
+	for n := 1; n <= len(p.b); n++ {
+		if b, err = p.peek(n); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return
+		}
+		if b[n-1] == c {
+			p.i += n
+			return b[:n-1], nil
+		}
+	}
+	return nil, fmt.Errorf("objconv/bencode: missing %q delimiter", c)
+}
+
+func (p *Parser) read(n int) (b []byte, err error) {
+	if n <= (p.j - p.i) { // check if the string is already buffered
+		b = p.b[p.i : p.i+n]
+		p.i += n
+		return
+	}
+
+	if n <= len(p.b) { // check if the string can be loaded in the read buffer
+		if b, err = p.peek(n); err != nil {
+			return
+		}
+		p.i += n
+		return
+	}
+
+	if cap(p.s) < n {
+		p.s = make([]byte, n, align(n, 1024))
+	} else {
+		p.s = p.s[:n]
+	}
+
+	copy(p.s, p.b[p.i:p.j])
+	p.n += int64(p.i + len(p.s))
+	n = p.j - p.i
+	p.i = 0
+	p.j = 0
+
+	if _, err = io.ReadFull(p.r, p.s[n:]); err != nil {
+		return
+	}
+
+	b = p.s
+	return
+}
+
+func (p *Parser) peek(n int) (b []byte, err error) {
+	for (p.i + n) > p.j {
+		if err = p.fill(); err != nil {
+			return
+		}
+	}
+	b = p.b[p.i : p.i+n]
+	return
+}
+
+func (p *Parser) fill() (err error) {
+	n := p.j - p.i
+	copy(p.b[:], p.b[p.i:p.j])
+	p.n += int64(p.i)
+	p.i = 0
+	p.j = n
+
+	if n, err = p.r.Read(p.b[n:]); n > 0 {
+		err = nil
+		p.j += n
+	} else if err != nil {
+		return
+	} else {
+		err = io.ErrNoProgress
+		return
+	}
+
+	return
+}
+
+func isDigits(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func align(n int, a int) int {
+	if (n % a) == 0 {
+		return n
+	}
+	return ((n / a) + 1) * a
+}
@@ -0,0 +1,106 @@
+package bson
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+const ( // element types
+	TypeDouble        = 0x01
+	TypeString        = 0x02
+	TypeDocument      = 0x03
+	TypeArray         = 0x04
+	TypeBinary        = 0x05
+	TypeUndefined     = 0x06
+	TypeObjectID      = 0x07
+	TypeBool          = 0x08
+	TypeDateTime      = 0x09
+	TypeNull          = 0x0A
+	TypeRegex         = 0x0B
+	TypeDBPointer     = 0x0C
+	TypeJavaScript    = 0x0D
+	TypeSymbol        = 0x0E
+	TypeCodeWithScope = 0x0F
+	TypeInt32         = 0x10
+	TypeTimestamp     = 0x11
+	TypeInt64         = 0x12
+	TypeDecimal128    = 0x13
+	TypeMinKey        = 0xFF
+	TypeMaxKey        = 0x7F
+)
+
+const ( // binary subtypes
+	BinaryGeneric = 0x00
+	BinaryOld     = 0x02
+)
+
+// ValueKey is the key of the single element of the documents used to wrap
+// values that are not documents at the top level of a BSON stream.
+//
+// BSON only supports documents at the top level, so other values (arrays,
+// strings, numbers...) are written as a document made of one element with this
+// key. Because MongoDB reserves keys starting with a '$' sign, a top-level
+// document whose only key is ValueKey is always interpreted as a wrapper when
+// decoding.
+const ValueKey = "$value"
+
+func putInt32(b []byte, v int32) {
+	binary.LittleEndian.PutUint32(b, uint32(v))
+}
+
+func putInt64(b []byte, v int64) {
+	binary.LittleEndian.PutUint64(b, uint64(v))
+}
+
+func getInt32(b []byte) int32 {
+	return int32(binary.LittleEndian.Uint32(b))
+}
+
+func getInt64(b []byte) int64 {
+	return int64(binary.LittleEndian.Uint64(b))
+}
+
+func getUint64(b []byte) uint64 {
+	return binary.LittleEndian.Uint64(b)
+}
+
+func getFloat64(b []byte) float64 {
+	return math.Float64frombits(binary.LittleEndian.Uint64(b))
+}
+
+func appendInt32(b []byte, v int32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendInt64(b []byte, v int64) []byte {
+	return append(b,
+		byte(v), byte(v>>8), byte(v>>16), byte(v>>24),
+		byte(v>>32), byte(v>>40), byte(v>>48), byte(v>>56),
+	)
+}
+
+func appendFloat64(b []byte, v float64) []byte {
+	return appendInt64(b, int64(math.Float64bits(v)))
+}
+
+// unixMilli returns the number of milliseconds elapsed since the unix epoch,
+// and whether t could be represented exactly with millisecond precision.
+func unixMilli(t time.Time) (ms int64, exact bool) {
+	ns := int64(t.Nanosecond())
+	ms = t.Unix()*1000 + ns/1000000
+	exact = (ns % 1000000) == 0
+	return
+}
+
+func timeFromUnixMilli(ms int64) time.Time {
+	s := ms / 1000
+	m := ms % 1000
+
+	if m < 0 {
+		s--
+		m += 1000
+	}
+
+	return time.Unix(s, m*1000000).UTC()
+}
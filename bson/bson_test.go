@@ -0,0 +1,319 @@
+package bson
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/segmentio/objconv/objtests"
+)
+
+func TestCodec(t *testing.T) {
+	objtests.TestCodec(t, Codec)
+}
+
+func BenchmarkCodec(b *testing.B) {
+	objtests.BenchmarkCodec(b, Codec)
+}
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		v interface{}
+		s string
+	}{
+		{
+			// Example from bsonspec.org
+			v: map[string]string{"hello": "world"},
+			s: "16000000" + "02" + "68656c6c6f00" + "06000000776f726c6400" + "00",
+		},
+		{
+			v: map[string]interface{}{"a": []interface{}{int64(1), 0.5}},
+			s: "1f000000" + "04" + "6100" +
+				"17000000" + "10" + "3000" + "01000000" + "01" + "3100" + "000000000000e03f" + "00" +
+				"00",
+		},
+		{
+			v: int64(1) << 40,
+			s: "15000000" + "12" + "2476616c756500" + "0000000000010000" + "00",
+		},
+		{
+			v: time.Unix(1, 5e6),
+			s: "15000000" + "09" + "2476616c756500" + "ed03000000000000" + "00",
+		},
+		{
+			v: struct {
+				ID ObjectID   `objconv:"_id"`
+				D  Decimal128 `objconv:"d"`
+			}{
+				ID: ObjectID{0x5f, 0x5f, 0x5f, 0x5f, 1, 2, 3, 4, 5, 6, 7, 8},
+				D:  Decimal128{High: 0x3040000000000000, Low: 1},
+			},
+			s: "29000000" +
+				"07" + "5f696400" + "5f5f5f5f0102030405060708" +
+				"13" + "6400" + "0100000000000000" + "0000000000004030" +
+				"00",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.s, func(t *testing.T) {
+			b, err := Marshal(test.v)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if s := hex.EncodeToString(b); s != test.s {
+				t.Error(s)
+			}
+
+			v := reflect.New(reflect.TypeOf(test.v))
+
+			if err := Unmarshal(b, v.Interface()); err != nil {
+				t.Fatal(err)
+			}
+
+			if x := v.Elem().Interface(); !reflect.DeepEqual(x, test.v) {
+				if tm, ok := test.v.(time.Time); !ok || !tm.Equal(x.(time.Time)) {
+					t.Errorf("%#v", x)
+				}
+			}
+		})
+	}
+}
+
+func TestUnmarshalTypes(t *testing.T) {
+	doc := []byte{
+		0, 0, 0, 0, // length, set below
+		0x0B, 'r', 0, 'a', '+', 0, 'i', 0,
+		0x05, 'o', 0, 6, 0, 0, 0, BinaryOld, 2, 0, 0, 0, 'h', 'i',
+		0x11, 't', 0, 1, 0, 0, 0, 2, 0, 0, 0,
+		0x0A, 'n', 0,
+		0x08, 'b', 0, 1,
+		0,
+	}
+	putInt32(doc, int32(len(doc)))
+
+	var v map[string]interface{}
+
+	if err := Unmarshal(doc, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(v, map[string]interface{}{
+		"r": "/a+/i",
+		"o": []byte("hi"),
+		"t": uint64(2)<<32 | 1,
+		"n": nil,
+		"b": true,
+	}) {
+		t.Errorf("%#v", v)
+	}
+}
+
+func TestUnmarshalValueKey(t *testing.T) {
+	tests := []struct {
+		doc []byte
+		v   interface{}
+	}{
+		{
+			// A document made of a single $value element is a wrapper.
+			doc: []byte{
+				0, 0, 0, 0,
+				0x02, '$', 'v', 'a', 'l', 'u', 'e', 0, 2, 0, 0, 0, 'a', 0,
+				0,
+			},
+			v: "a",
+		},
+		{
+			// Documents with other elements are regular maps.
+			doc: []byte{
+				0, 0, 0, 0,
+				0x02, '$', 'v', 'a', 'l', 'u', 'e', 0, 2, 0, 0, 0, 'a', 0,
+				0x08, 'b', 0, 1,
+				0,
+			},
+			v: map[interface{}]interface{}{"$value": "a", "b": true},
+		},
+	}
+
+	for _, test := range tests {
+		putInt32(test.doc, int32(len(test.doc)))
+
+		var v interface{}
+
+		if err := Unmarshal(test.doc, &v); err != nil {
+			t.Error(err)
+		} else if !reflect.DeepEqual(v, test.v) {
+			t.Errorf("%#v != %#v", test.v, v)
+		}
+	}
+}
+
+func TestInterfaceRoundTrip(t *testing.T) {
+	id := ObjectID{0x5f, 0x5e, 0x10, 0x0a, 1, 2, 3, 4, 5, 6, 7, 8}
+	dec := Decimal128{High: 0x303E000000000000, Low: 1}
+
+	b1, err := Marshal(struct {
+		ID  ObjectID   `objconv:"_id"`
+		Dec Decimal128 `objconv:"dec"`
+	}{ID: id, Dec: dec})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Decoding into an empty interface must preserve the BSON types, so the
+	// document is encoded back to the same bytes.
+	var v interface{}
+
+	if err := Unmarshal(b1, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp := map[interface{}]interface{}{"_id": id, "dec": dec}; !reflect.DeepEqual(v, exp) {
+		t.Errorf("%#v != %#v", exp, v)
+	}
+
+	b2 := &bytes.Buffer{}
+	e := NewEncoder(b2)
+	e.SortMapKeys = true // "_id" < "dec", same as the struct fields
+
+	if err := e.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(b1, b2.Bytes()) {
+		t.Errorf("the document changed after a round trip:\n%s\n%s", hex.Dump(b1), hex.Dump(b2.Bytes()))
+	}
+}
+
+func TestObjectID(t *testing.T) {
+	id, err := ParseObjectID("5f5e100a0102030405060708")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := id.String(); s != "5f5e100a0102030405060708" {
+		t.Error(s)
+	}
+
+	if tm := id.Time(); !tm.Equal(time.Unix(0x5f5e100a, 0)) {
+		t.Error(tm)
+	}
+
+	if _, err := ParseObjectID("5f5e100a"); err == nil {
+		t.Error("parsing an invalid ObjectId should have failed")
+	}
+
+	// ObjectIds are decoded from strings when they come from other formats.
+	var x ObjectID
+	b, _ := Marshal(map[string]string{"_id": id.String()})
+	v := struct {
+		ID *ObjectID `objconv:"_id"`
+	}{ID: &x}
+
+	if err := Unmarshal(b, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if x != id {
+		t.Error(x)
+	}
+}
+
+func TestDecimal128(t *testing.T) {
+	// Test vectors from the BSON decimal128 specification.
+	tests := []struct {
+		s   string
+		d   Decimal128
+		out string
+	}{
+		{s: "0", d: Decimal128{0x3040000000000000, 0}},
+		{s: "-0", d: Decimal128{0xB040000000000000, 0}},
+		{s: "1", d: Decimal128{0x3040000000000000, 1}},
+		{s: "-1", d: Decimal128{0xB040000000000000, 1}},
+		{s: "0.1", d: Decimal128{0x303E000000000000, 1}},
+		{s: "0.001234", d: Decimal128{0x3034000000000000, 1234}},
+		{s: "1.234E-7", d: Decimal128{0x302C000000000000, 1234}},
+		{s: "9.999999999999999999999999999999999E+6144", d: Decimal128{0x5FFFED09BEAD87C0, 0x378D8E63FFFFFFFF}},
+		{s: "1E+3", d: Decimal128{0x3046000000000000, 1}},
+		{s: "12345689012345789012345", d: Decimal128{0x304000000000029D, 0x42DA3A76F9E0D979}},
+		{s: "Infinity", d: Decimal128{0x7800000000000000, 0}},
+		{s: "-Infinity", d: Decimal128{0xF800000000000000, 0}},
+		{s: "NaN", d: Decimal128{0x7C00000000000000, 0}},
+		{s: "1.5e3", d: Decimal128{0x3044000000000000, 15}, out: "1.5E+3"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.s, func(t *testing.T) {
+			out := test.out
+			if out == "" {
+				out = test.s
+			}
+
+			d, err := ParseDecimal128(test.s)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if d != test.d {
+				t.Errorf("%#x %#x", d.High, d.Low)
+			}
+
+			if s := d.String(); s != out {
+				t.Error(s)
+			}
+		})
+	}
+
+	if _, err := ParseDecimal128("1" + string(bytes.Repeat([]byte("1"), 34))); err == nil {
+		t.Error("parsing a decimal with too many digits should have failed")
+	}
+}
+
+func TestStreamDecoder(t *testing.T) {
+	b := &bytes.Buffer{}
+	e := NewStreamEncoder(b)
+
+	for _, v := range []interface{}{map[string]int{"a": 1}, "hello", []int{1, 2}} {
+		if err := e.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The stream is a plain sequence of documents.
+	n := 0
+	for r := bytes.NewReader(b.Bytes()); r.Len() != 0; n++ {
+		var h [4]byte
+		r.Read(h[:])
+		r.Seek(int64(getInt32(h[:]))-4, io.SeekCurrent)
+	}
+
+	if n != 3 {
+		t.Error("bad number of documents:", n)
+	}
+
+	truncated := b.Bytes()[:b.Len()-3]
+	d := NewStreamDecoder(bytes.NewReader(truncated))
+
+	var v interface{}
+
+	for i := 0; i != 2; i++ {
+		if err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := d.Decode(&v); err != io.ErrUnexpectedEOF {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,259 @@
+package bson
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/segmentio/objconv"
+)
+
+// Decimal128 represents the BSON 128 bits decimal type, which stores values in
+// the IEEE 754-2008 decimal128 format using the binary integer decimal
+// encoding.
+//
+// The parser exposes decimals as strings, or as Decimal128 values when they are
+// decoded into empty interfaces, the Decimal128 type can be used to decode them
+// without loss of precision. When encoded with a BSON emitter the
+// value is written as a native decimal, other emitters receive its string
+// representation.
+type Decimal128 struct {
+	High uint64
+	Low  uint64
+}
+
+const (
+	decimal128Bias        = 6176
+	decimal128MinExponent = -6176
+	decimal128MaxExponent = 6111
+	decimal128MaxDigits   = 34
+)
+
+var (
+	decimal128MaxSignificand = new(big.Int).Sub(new(big.Int).Exp(big.NewInt(10), big.NewInt(decimal128MaxDigits), nil), big.NewInt(1))
+	decimal128LowMask        = new(big.Int).SetUint64(^uint64(0))
+)
+
+// ParseDecimal128 parses the string representation of a decimal value.
+//
+// The function returns an error if s cannot be represented exactly by a 128
+// bits decimal.
+func ParseDecimal128(s string) (d Decimal128, err error) {
+	str := s
+	neg := false
+
+	switch {
+	case strings.HasPrefix(s, "-"):
+		neg, s = true, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	switch strings.ToLower(s) {
+	case "nan":
+		d.High = 0x7C00000000000000
+		return
+	case "inf", "infinity":
+		d.High = 0x7800000000000000
+		if neg {
+			d.High |= 1 << 63
+		}
+		return
+	}
+
+	digits := make([]byte, 0, len(s))
+	exp := 0
+	dot := false
+	i := 0
+
+	for ; i != len(s); i++ {
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			digits = append(digits, c)
+			if dot {
+				exp--
+			}
+			continue
+		case c == '.' && !dot:
+			dot = true
+			continue
+		}
+		break
+	}
+
+	if len(digits) == 0 {
+		return d, fmt.Errorf("objconv/bson: invalid decimal %q", str)
+	}
+
+	if i != len(s) {
+		if s[i] != 'e' && s[i] != 'E' {
+			return d, fmt.Errorf("objconv/bson: invalid decimal %q", str)
+		}
+
+		e, perr := strconv.Atoi(s[i+1:])
+
+		if perr != nil {
+			return d, fmt.Errorf("objconv/bson: invalid decimal %q", str)
+		}
+
+		exp += e
+	}
+
+	digits = []byte(strings.TrimLeft(string(digits), "0"))
+
+	if len(digits) == 0 {
+		digits = append(digits, '0')
+	}
+
+	// Drop trailing zeros when the significand has too many digits or the
+	// exponent is too small, then pad with zeros if the exponent is too large.
+	for len(digits) > 1 && digits[len(digits)-1] == '0' && (len(digits) > decimal128MaxDigits || exp < decimal128MinExponent) {
+		digits = digits[:len(digits)-1]
+		exp++
+	}
+
+	for exp > decimal128MaxExponent && len(digits) < decimal128MaxDigits && string(digits) != "0" {
+		digits = append(digits, '0')
+		exp--
+	}
+
+	if string(digits) == "0" {
+		if exp < decimal128MinExponent {
+			exp = decimal128MinExponent
+		}
+		if exp > decimal128MaxExponent {
+			exp = decimal128MaxExponent
+		}
+	}
+
+	if len(digits) > decimal128MaxDigits || exp < decimal128MinExponent || exp > decimal128MaxExponent {
+		return d, fmt.Errorf("objconv/bson: decimal %q cannot be represented exactly by a 128 bits decimal", str)
+	}
+
+	sig, _ := new(big.Int).SetString(string(digits), 10)
+
+	d.Low = new(big.Int).And(sig, decimal128LowMask).Uint64()
+	d.High = new(big.Int).Rsh(sig, 64).Uint64() | uint64(exp+decimal128Bias)<<49
+
+	if neg {
+		d.High |= 1 << 63
+	}
+
+	return
+}
+
+// String returns the string representation of d, following the format
+// defined by the BSON decimal128 specification.
+func (d Decimal128) String() string {
+	var b []byte
+
+	if (d.High >> 63) != 0 {
+		b = append(b, '-')
+	}
+
+	var exp int
+	var sig *big.Int
+
+	switch comb := (d.High >> 58) & 0x1F; {
+	case comb == 0x1F:
+		return "NaN"
+
+	case comb == 0x1E:
+		return string(append(b, "Infinity"...))
+
+	case (d.High>>61)&3 == 3:
+		// The significand would always exceed the maximum value in this form,
+		// which the specification requires to be interpreted as zero.
+		exp = int((d.High>>47)&0x3FFF) - decimal128Bias
+		sig = new(big.Int)
+
+	default:
+		exp = int((d.High>>49)&0x3FFF) - decimal128Bias
+		sig = new(big.Int).SetUint64(d.High & (1<<49 - 1))
+		sig.Lsh(sig, 64)
+		sig.Or(sig, new(big.Int).SetUint64(d.Low))
+
+		if sig.Cmp(decimal128MaxSignificand) > 0 {
+			sig.SetInt64(0)
+		}
+	}
+
+	digits := sig.String()
+	adjusted := exp + len(digits) - 1
+
+	switch {
+	case exp == 0:
+		b = append(b, digits...)
+
+	case exp < 0 && adjusted >= -6:
+		if n := -exp; n >= len(digits) {
+			b = append(b, '0', '.')
+			b = append(b, strings.Repeat("0", n-len(digits))...)
+			b = append(b, digits...)
+		} else {
+			b = append(b, digits[:len(digits)-n]...)
+			b = append(b, '.')
+			b = append(b, digits[len(digits)-n:]...)
+		}
+
+	default:
+		b = append(b, digits[0])
+
+		if len(digits) > 1 {
+			b = append(b, '.')
+			b = append(b, digits[1:]...)
+		}
+
+		b = append(b, 'E')
+
+		if adjusted >= 0 {
+			b = append(b, '+')
+		}
+
+		b = strconv.AppendInt(b, int64(adjusted), 10)
+	}
+
+	return string(b)
+}
+
+// EncodeValue satisfies the objconv.ValueEncoder interface.
+func (d Decimal128) EncodeValue(e objconv.Encoder) error {
+	if emitter, ok := e.Emitter.(decimal128Emitter); ok {
+		return emitter.EmitDecimal128(d)
+	}
+	return e.Emitter.EmitString(d.String())
+}
+
+// DecodeValue satisfies the objconv.ValueDecoder interface.
+//
+// The method accepts BSON decimals, strings, and numeric values.
+func (d *Decimal128) DecodeValue(dec objconv.Decoder) (err error) {
+	var v interface{}
+
+	if err = dec.Decode(&v); err != nil {
+		return
+	}
+
+	switch x := v.(type) {
+	case Decimal128:
+		*d = x
+	case string:
+		*d, err = ParseDecimal128(x)
+	case int64:
+		*d, err = ParseDecimal128(strconv.FormatInt(x, 10))
+	case uint64:
+		*d, err = ParseDecimal128(strconv.FormatUint(x, 10))
+	case float64:
+		*d, err = ParseDecimal128(strconv.FormatFloat(x, 'g', -1, 64))
+	default:
+		err = errors.New("objconv/bson: decimals must be decoded from strings or numbers")
+	}
+
+	return
+}
+
+type decimal128Emitter interface {
+	EmitDecimal128(Decimal128) error
+}
@@ -0,0 +1,340 @@
+package bson
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/objconv/objutil"
+)
+
+// Emitter implements a BSON emitter that satisfies the objconv.Emitter
+// interface.
+//
+// BSON documents are prefixed with their length, so the emitter buffers each
+// top-level document in memory and writes it to the output stream once it is
+// complete. Values that aren't maps are wrapped in a document made of a single
+// element keyed by ValueKey.
+type Emitter struct {
+	w io.Writer
+	b []byte // buffer of the top-level document being emitted
+
+	// key of the next element, set when a map key is emitted
+	k []byte
+
+	// stack of the documents being emitted
+	s []frame
+
+	// starting buffer for the stack, avoids dynamic memory allocations for
+	// most of the values
+	sback [16]frame
+}
+
+type frame struct {
+	off   int  // offset of the document length in the buffer
+	n     int  // index of the next array element
+	array bool // true if the document is a BSON array
+	wrap  bool // true if the document wraps a value that isn't a map
+	key   bool // true if a map key is expected
+}
+
+func NewEmitter(w io.Writer) *Emitter {
+	e := &Emitter{w: w}
+	e.s = e.sback[:0]
+	return e
+}
+
+func (e *Emitter) Reset(w io.Writer) {
+	e.w = w
+	e.b = e.b[:0]
+	e.k = e.k[:0]
+	e.s = e.sback[:0]
+}
+
+// SequenceEmitter returns true, BSON streams are sequences of documents
+// written one after the other.
+func (e *Emitter) SequenceEmitter() bool {
+	return true
+}
+
+func (e *Emitter) EmitNil() (err error) {
+	if e.key() {
+		return errUnsupportedKey("nil")
+	}
+	if err = e.header(TypeNull); err == nil {
+		err = e.done()
+	}
+	return
+}
+
+func (e *Emitter) EmitBool(v bool) (err error) {
+	if e.key() {
+		return e.emitKey(strconv.FormatBool(v))
+	}
+	if err = e.header(TypeBool); err == nil {
+		if v {
+			e.b = append(e.b, 1)
+		} else {
+			e.b = append(e.b, 0)
+		}
+		err = e.done()
+	}
+	return
+}
+
+func (e *Emitter) EmitInt(v int64, _ int) (err error) {
+	if e.key() {
+		return e.emitKey(strconv.FormatInt(v, 10))
+	}
+
+	if v >= objutil.Int32Min && v <= objutil.Int32Max {
+		if err = e.header(TypeInt32); err == nil {
+			e.b = appendInt32(e.b, int32(v))
+		}
+	} else {
+		if err = e.header(TypeInt64); err == nil {
+			e.b = appendInt64(e.b, v)
+		}
+	}
+
+	if err == nil {
+		err = e.done()
+	}
+	return
+}
+
+func (e *Emitter) EmitUint(v uint64, _ int) (err error) {
+	if e.key() {
+		return e.emitKey(strconv.FormatUint(v, 10))
+	}
+
+	if v > objutil.Int64Max {
+		return fmt.Errorf("objconv/bson: %d overflows the range of BSON integers", v)
+	}
+
+	return e.EmitInt(int64(v), 64)
+}
+
+func (e *Emitter) EmitFloat(v float64, bitSize int) (err error) {
+	if e.key() {
+		return e.emitKey(strconv.FormatFloat(v, 'g', -1, bitSize))
+	}
+	if err = e.header(TypeDouble); err == nil {
+		e.b = appendFloat64(e.b, v)
+		err = e.done()
+	}
+	return
+}
+
+func (e *Emitter) EmitString(v string) (err error) {
+	if e.key() {
+		return e.emitKey(v)
+	}
+	if err = e.header(TypeString); err == nil {
+		e.b = appendInt32(e.b, int32(len(v)+1))
+		e.b = append(e.b, v...)
+		e.b = append(e.b, 0)
+		err = e.done()
+	}
+	return
+}
+
+func (e *Emitter) EmitBytes(v []byte) (err error) {
+	if e.key() {
+		return e.emitKey(string(v))
+	}
+	if err = e.header(TypeBinary); err == nil {
+		e.b = appendInt32(e.b, int32(len(v)))
+		e.b = append(e.b, BinaryGeneric)
+		e.b = append(e.b, v...)
+		err = e.done()
+	}
+	return
+}
+
+// EmitTime writes v as a BSON datetime if it can be represented with
+// millisecond precision, otherwise it falls back to writing a RFC3339 string
+// to avoid losing information.
+func (e *Emitter) EmitTime(v time.Time) (err error) {
+	ms, exact := unixMilli(v)
+
+	if e.key() || !exact {
+		return e.EmitString(v.Format(time.RFC3339Nano))
+	}
+
+	if err = e.header(TypeDateTime); err == nil {
+		e.b = appendInt64(e.b, ms)
+		err = e.done()
+	}
+	return
+}
+
+func (e *Emitter) EmitDuration(v time.Duration) (err error) {
+	return e.EmitString(string(objutil.AppendDuration(nil, v)))
+}
+
+func (e *Emitter) EmitError(v error) (err error) {
+	return e.EmitString(v.Error())
+}
+
+// EmitObjectID writes v as a BSON ObjectId.
+func (e *Emitter) EmitObjectID(v ObjectID) (err error) {
+	if e.key() {
+		return e.emitKey(v.String())
+	}
+	if err = e.header(TypeObjectID); err == nil {
+		e.b = append(e.b, v[:]...)
+		err = e.done()
+	}
+	return
+}
+
+// EmitDecimal128 writes v as a BSON 128 bits decimal.
+func (e *Emitter) EmitDecimal128(v Decimal128) (err error) {
+	if e.key() {
+		return e.emitKey(v.String())
+	}
+	if err = e.header(TypeDecimal128); err == nil {
+		e.b = appendInt64(e.b, int64(v.Low))
+		e.b = appendInt64(e.b, int64(v.High))
+		err = e.done()
+	}
+	return
+}
+
+func (e *Emitter) EmitArrayBegin(_ int) (err error) {
+	if e.key() {
+		return errUnsupportedKey("array")
+	}
+	if err = e.header(TypeArray); err == nil {
+		e.push(frame{array: true})
+	}
+	return
+}
+
+func (e *Emitter) EmitArrayEnd() (err error) {
+	return e.pop()
+}
+
+func (e *Emitter) EmitArrayNext() (err error) {
+	e.s[len(e.s)-1].n++
+	return
+}
+
+func (e *Emitter) EmitMapBegin(_ int) (err error) {
+	if e.key() {
+		return errUnsupportedKey("map")
+	}
+
+	// Maps are the only values that don't need to be wrapped when they appear
+	// at the top level of the stream.
+	if len(e.s) != 0 {
+		err = e.header(TypeDocument)
+	}
+
+	if err == nil {
+		e.push(frame{key: true})
+	}
+	return
+}
+
+func (e *Emitter) EmitMapEnd() (err error) {
+	return e.pop()
+}
+
+func (e *Emitter) EmitMapValue() (err error) {
+	e.s[len(e.s)-1].key = false
+	return
+}
+
+func (e *Emitter) EmitMapNext() (err error) {
+	e.s[len(e.s)-1].key = true
+	return
+}
+
+// key returns true if the emitter expects the next value to be a map key.
+func (e *Emitter) key() bool {
+	i := len(e.s) - 1
+	return i >= 0 && e.s[i].key
+}
+
+func (e *Emitter) emitKey(k string) error {
+	for i := 0; i != len(k); i++ {
+		if k[i] == 0 {
+			return errors.New("objconv/bson: map keys cannot contain null bytes")
+		}
+	}
+	e.k = append(e.k[:0], k...)
+	return nil
+}
+
+// header writes the type and key of the next element of the current document.
+// If the value is written at the top level a wrapper document is started
+// first.
+func (e *Emitter) header(t byte) error {
+	if len(e.s) == 0 {
+		e.push(frame{wrap: true})
+	}
+
+	f := &e.s[len(e.s)-1]
+	e.b = append(e.b, t)
+
+	switch {
+	case f.wrap:
+		e.b = append(e.b, ValueKey...)
+	case f.array:
+		e.b = strconv.AppendInt(e.b, int64(f.n), 10)
+	default:
+		e.b = append(e.b, e.k...)
+	}
+
+	e.b = append(e.b, 0)
+	return nil
+}
+
+// done must be called after writing a scalar value, it terminates the wrapper
+// document if the value was written at the top level.
+func (e *Emitter) done() error {
+	if i := len(e.s) - 1; i >= 0 && e.s[i].wrap {
+		return e.pop()
+	}
+	return nil
+}
+
+func (e *Emitter) push(f frame) {
+	f.off = len(e.b)
+	e.b = append(e.b, 0, 0, 0, 0)
+	e.s = append(e.s, f)
+}
+
+func (e *Emitter) pop() (err error) {
+	i := len(e.s) - 1
+	f := e.s[i]
+	e.s = e.s[:i]
+	e.b = append(e.b, 0)
+
+	n := len(e.b) - f.off
+
+	if n > math.MaxInt32 {
+		e.b = e.b[:0]
+		e.s = e.s[:0]
+		return fmt.Errorf("objconv/bson: document of %d bytes exceeds the maximum size of BSON documents", n)
+	}
+
+	putInt32(e.b[f.off:], int32(n))
+
+	if i == 0 {
+		_, err = e.w.Write(e.b)
+		e.b = e.b[:0]
+		return
+	}
+
+	return e.done()
+}
+
+func errUnsupportedKey(typ string) error {
+	return fmt.Errorf("objconv/bson: %s values cannot be used as map keys", typ)
+}
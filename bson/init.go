@@ -0,0 +1,54 @@
+package bson
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/segmentio/objconv"
+)
+
+// Codec for the BSON format.
+var Codec = objconv.Codec{
+	NewEmitter: func(w io.Writer) objconv.Emitter { return NewEmitter(w) },
+	NewParser:  func(r io.Reader) objconv.Parser { return NewParser(r) },
+	Extensions: []string{".bson"},
+	Sniff:      sniff,
+}
+
+func init() {
+	for _, name := range [...]string{
+		"application/bson",
+		"bson",
+	} {
+		objconv.Register(name, Codec)
+	}
+}
+
+// sniff recognizes BSON documents by the consistency of their length prefix
+// and first element.
+func sniff(b []byte) float64 {
+	if len(b) < 5 {
+		return 0
+	}
+
+	n := binary.LittleEndian.Uint32(b)
+
+	switch t := b[4]; {
+	case n == 5:
+		if t == 0 {
+			return 0.5
+		}
+		return 0
+	case n < 5 || n > 16*1024*1024:
+		return 0
+	case (t >= TypeDouble && t <= TypeDecimal128) || t == TypeMinKey || t == TypeMaxKey:
+		// The element type is followed by its name as a C string.
+		if i := bytes.IndexByte(b[5:], 0); i > 0 {
+			return 0.6
+		}
+		return 0.3
+	}
+
+	return 0
+}
@@ -0,0 +1,83 @@
+package bson
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/objconv"
+)
+
+// ObjectID represents the BSON ObjectId type, a 12 bytes identifier used by
+// MongoDB as default value for the _id field of documents.
+//
+// The parser exposes ObjectIds as 12 bytes values, or as ObjectID values when
+// they are decoded into empty interfaces. When encoded with a BSON emitter the
+// value is written as a native ObjectId, other emitters receive its
+// hexadecimal representation.
+type ObjectID [12]byte
+
+// ParseObjectID parses the hexadecimal representation of an ObjectId.
+func ParseObjectID(s string) (id ObjectID, err error) {
+	if len(s) != 24 {
+		err = fmt.Errorf("objconv/bson: invalid ObjectId %q", s)
+		return
+	}
+	if _, err = hex.Decode(id[:], []byte(s)); err != nil {
+		err = fmt.Errorf("objconv/bson: invalid ObjectId %q", s)
+	}
+	return
+}
+
+// Time returns the creation time embedded in the first 4 bytes of id.
+func (id ObjectID) Time() time.Time {
+	return time.Unix(int64(uint32(id[0])<<24|uint32(id[1])<<16|uint32(id[2])<<8|uint32(id[3])), 0).UTC()
+}
+
+// String returns the hexadecimal representation of id.
+func (id ObjectID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// EncodeValue satisfies the objconv.ValueEncoder interface.
+func (id ObjectID) EncodeValue(e objconv.Encoder) error {
+	if emitter, ok := e.Emitter.(objectIDEmitter); ok {
+		return emitter.EmitObjectID(id)
+	}
+	return e.Emitter.EmitString(id.String())
+}
+
+// DecodeValue satisfies the objconv.ValueDecoder interface.
+//
+// The method accepts BSON ObjectIds, 12 bytes values, and strings holding the
+// hexadecimal representation of an ObjectId.
+func (id *ObjectID) DecodeValue(d objconv.Decoder) (err error) {
+	var v interface{}
+
+	if err = d.Decode(&v); err != nil {
+		return
+	}
+
+	switch x := v.(type) {
+	case nil:
+		*id = ObjectID{}
+	case ObjectID:
+		*id = x
+	case []byte:
+		if len(x) != len(id) {
+			return fmt.Errorf("objconv/bson: cannot decode %d bytes into an ObjectId", len(x))
+		}
+		copy(id[:], x)
+	case string:
+		*id, err = ParseObjectID(x)
+	default:
+		err = errors.New("objconv/bson: ObjectIds must be decoded from bytes or strings")
+	}
+
+	return
+}
+
+type objectIDEmitter interface {
+	EmitObjectID(ObjectID) error
+}
@@ -0,0 +1,551 @@
+package bson
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/segmentio/objconv"
+)
+
+// Parser implements a BSON parser that satisfies the objconv.Parser interface.
+//
+// The parser loads whole top-level documents in memory before parsing their
+// elements. Documents made of a single element keyed by ValueKey are unwrapped
+// and produce the value of this element.
+type Parser struct {
+	r io.Reader // reader to load documents from
+	b []byte    // buffer of the top-level document being parsed
+	i int       // offset of the next byte to parse in b
+	n int64     // number of bytes of the documents parsed before b
+	d int       // depth of nested documents
+	t byte      // type of the next element
+	k []byte    // key of the next element, set when a map key is expected
+
+	loaded bool // true if a top-level document was loaded in b
+	wrap   bool // true if the top-level document is a wrapper
+	key    bool // true if the next value is a map key
+}
+
+func NewParser(r io.Reader) *Parser {
+	return &Parser{r: r}
+}
+
+func (p *Parser) Reset(r io.Reader) {
+	p.r = r
+	p.b = p.b[:0]
+	p.i = 0
+	p.n = 0
+	p.d = 0
+	p.t = 0
+	p.k = nil
+	p.loaded = false
+	p.wrap = false
+	p.key = false
+}
+
+// Buffered returns a reader exposing the bytes buffered by the parser that
+// weren't consumed yet. Because documents are loaded one at a time it is
+// always empty after a top-level value was fully parsed.
+func (p *Parser) Buffered() io.Reader {
+	if !p.loaded {
+		return bytes.NewReader(nil)
+	}
+	return bytes.NewReader(p.b[p.i:])
+}
+
+// Offset returns the number of bytes of the input consumed by p.
+func (p *Parser) Offset() int64 {
+	if !p.loaded {
+		return p.n
+	}
+	return p.n + int64(p.i)
+}
+
+// SequenceParser returns true, BSON streams are sequences of documents written
+// one after the other.
+func (p *Parser) SequenceParser() bool {
+	return true
+}
+
+func (p *Parser) ParseType() (objconv.Type, error) {
+	if p.key {
+		return objconv.String, nil
+	}
+
+	if !p.loaded {
+		if err := p.load(); err != nil {
+			return objconv.Unknown, err
+		}
+	}
+
+	switch p.t {
+	case TypeDouble:
+		return objconv.Float, nil
+
+	case TypeString, TypeJavaScript, TypeSymbol, TypeRegex, TypeDecimal128:
+		return objconv.String, nil
+
+	case TypeDocument:
+		return objconv.Map, nil
+
+	case TypeArray:
+		return objconv.Array, nil
+
+	case TypeBinary, TypeObjectID:
+		return objconv.Bytes, nil
+
+	case TypeUndefined, TypeNull, TypeMinKey, TypeMaxKey:
+		return objconv.Nil, nil
+
+	case TypeBool:
+		return objconv.Bool, nil
+
+	case TypeDateTime:
+		return objconv.Time, nil
+
+	case TypeInt32, TypeInt64:
+		return objconv.Int, nil
+
+	case TypeTimestamp:
+		return objconv.Uint, nil
+
+	default:
+		return objconv.Unknown, fmt.Errorf("objconv/bson: unsupported element type 0x%02X", p.t)
+	}
+}
+
+// DecodeInterface decodes ObjectIds and decimals as ObjectID and Decimal128
+// values when the destination is an empty interface, so they can be encoded
+// back to their native BSON types.
+func (p *Parser) DecodeInterface(t objconv.Type) (v interface{}, ok bool, err error) {
+	if p.key {
+		return
+	}
+
+	var b []byte
+
+	switch p.t {
+	case TypeObjectID:
+		if b, err = p.read(12); err == nil {
+			var id ObjectID
+			copy(id[:], b)
+			v, ok, err = id, true, p.done()
+		}
+
+	case TypeDecimal128:
+		if b, err = p.read(16); err == nil {
+			v, ok, err = Decimal128{High: getUint64(b[8:]), Low: getUint64(b)}, true, p.done()
+		}
+	}
+
+	return
+}
+
+func (p *Parser) ParseNil() (err error) {
+	return p.done()
+}
+
+func (p *Parser) ParseBool() (v bool, err error) {
+	var b []byte
+
+	if b, err = p.read(1); err != nil {
+		return
+	}
+
+	switch b[0] {
+	case 0:
+	case 1:
+		v = true
+	default:
+		err = fmt.Errorf("objconv/bson: invalid boolean value 0x%02X", b[0])
+		return
+	}
+
+	err = p.done()
+	return
+}
+
+func (p *Parser) ParseInt() (v int64, err error) {
+	var b []byte
+
+	switch p.t {
+	case TypeInt32:
+		if b, err = p.read(4); err == nil {
+			v = int64(getInt32(b))
+		}
+	default:
+		if b, err = p.read(8); err == nil {
+			v = getInt64(b)
+		}
+	}
+
+	if err == nil {
+		err = p.done()
+	}
+	return
+}
+
+func (p *Parser) ParseUint() (v uint64, err error) {
+	var b []byte
+
+	if b, err = p.read(8); err == nil {
+		v = getUint64(b)
+		err = p.done()
+	}
+
+	return
+}
+
+func (p *Parser) ParseFloat() (v float64, err error) {
+	var b []byte
+
+	if b, err = p.read(8); err == nil {
+		v = getFloat64(b)
+		err = p.done()
+	}
+
+	return
+}
+
+func (p *Parser) ParseString() (v []byte, err error) {
+	if p.key {
+		v, p.key = p.k, false
+		return
+	}
+
+	switch p.t {
+	case TypeRegex:
+		var pattern, options []byte
+
+		if pattern, err = p.readCString(); err != nil {
+			return
+		}
+
+		if options, err = p.readCString(); err != nil {
+			return
+		}
+
+		v = make([]byte, 0, len(pattern)+len(options)+2)
+		v = append(v, '/')
+		v = append(v, pattern...)
+		v = append(v, '/')
+		v = append(v, options...)
+
+	case TypeDecimal128:
+		var b []byte
+
+		if b, err = p.read(16); err != nil {
+			return
+		}
+
+		v = []byte(Decimal128{High: getUint64(b[8:]), Low: getUint64(b)}.String())
+
+	default:
+		var b []byte
+		var n int32
+
+		if b, err = p.read(4); err != nil {
+			return
+		}
+
+		if n = getInt32(b); n < 1 {
+			err = fmt.Errorf("objconv/bson: invalid string length %d", n)
+			return
+		}
+
+		if b, err = p.read(int(n)); err != nil {
+			return
+		}
+
+		if b[n-1] != 0 {
+			err = errors.New("objconv/bson: strings must be terminated by a null byte")
+			return
+		}
+
+		v = b[:n-1]
+	}
+
+	err = p.done()
+	return
+}
+
+func (p *Parser) ParseBytes() (v []byte, err error) {
+	if p.t == TypeObjectID {
+		if v, err = p.read(12); err == nil {
+			err = p.done()
+		}
+		return
+	}
+
+	var b []byte
+	var n int32
+
+	if b, err = p.read(5); err != nil {
+		return
+	}
+
+	if n = getInt32(b); n < 0 {
+		err = fmt.Errorf("objconv/bson: invalid binary length %d", n)
+		return
+	}
+
+	if v, err = p.read(int(n)); err != nil {
+		return
+	}
+
+	// The old binary subtype has its length repeated in the first 4 bytes of
+	// the data.
+	if b[4] == BinaryOld && len(v) >= 4 {
+		v = v[4:]
+	}
+
+	err = p.done()
+	return
+}
+
+func (p *Parser) ParseTime() (v time.Time, err error) {
+	var b []byte
+
+	if b, err = p.read(8); err == nil {
+		v = timeFromUnixMilli(getInt64(b))
+		err = p.done()
+	}
+
+	return
+}
+
+func (p *Parser) ParseDuration() (v time.Duration, err error) {
+	panic("objconv/bson: ParseDuration should never be called because BSON has no duration type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseError() (v error, err error) {
+	panic("objconv/bson: ParseError should never be called because BSON has no error type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseArrayBegin() (n int, err error) {
+	if _, err = p.read(4); err == nil {
+		p.d++
+		n = -1
+	}
+	return
+}
+
+func (p *Parser) ParseArrayEnd(n int) (err error) {
+	return p.end()
+}
+
+func (p *Parser) ParseArrayNext(n int) (err error) {
+	_, err = p.next()
+	return
+}
+
+func (p *Parser) ParseMapBegin() (n int, err error) {
+	return p.ParseArrayBegin()
+}
+
+func (p *Parser) ParseMapEnd(n int) (err error) {
+	return p.end()
+}
+
+func (p *Parser) ParseMapValue(n int) (err error) {
+	return
+}
+
+func (p *Parser) ParseMapNext(n int) (err error) {
+	if p.k, err = p.next(); err == nil {
+		p.key = true
+	}
+	return
+}
+
+// load reads the next top-level document from the input stream.
+func (p *Parser) load() (err error) {
+	var b [4]byte
+	var n int
+
+	if n, err = io.ReadFull(p.r, b[:]); err != nil {
+		if err == io.ErrUnexpectedEOF && n == 0 {
+			err = io.EOF
+		}
+		return
+	}
+
+	size := int(getInt32(b[:]))
+
+	if size < 5 {
+		return fmt.Errorf("objconv/bson: invalid document length %d", size)
+	}
+
+	if cap(p.b) < size {
+		p.b = make([]byte, size)
+	} else {
+		p.b = p.b[:size]
+	}
+
+	copy(p.b, b[:])
+
+	if _, err = io.ReadFull(p.r, p.b[4:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+
+	if p.b[size-1] != 0 {
+		return errors.New("objconv/bson: documents must be terminated by a null byte")
+	}
+
+	p.i = 4
+	p.loaded = true
+	p.wrap = false
+
+	// The document is a wrapper only when the ValueKey element is followed by
+	// the terminator, documents with more elements are regular maps.
+	if t, k, err := p.header(); err == nil && string(k) == ValueKey {
+		if n := valueSize(t, p.b[p.i:]); n >= 0 && p.i+n == size-1 {
+			p.t = t
+			p.wrap = true
+			return nil
+		}
+	}
+
+	p.i = 0
+	p.t = TypeDocument
+	return nil
+}
+
+// next reads the header of the next element of the current document, it
+// returns objconv.End when the terminator of the document is reached.
+func (p *Parser) next() (k []byte, err error) {
+	if p.i < len(p.b) && p.b[p.i] == 0 {
+		err = objconv.End
+		return
+	}
+	p.t, k, err = p.header()
+	return
+}
+
+func (p *Parser) header() (t byte, k []byte, err error) {
+	var b []byte
+
+	if b, err = p.read(1); err != nil {
+		return
+	}
+
+	t = b[0]
+	k, err = p.readCString()
+	return
+}
+
+// end consumes the terminator of the current document.
+func (p *Parser) end() (err error) {
+	var b []byte
+
+	if b, err = p.read(1); err != nil {
+		return
+	}
+
+	if b[0] != 0 {
+		return errors.New("objconv/bson: expected the terminator of a document")
+	}
+
+	p.d--
+	return p.done()
+}
+
+// done must be called after parsing a value, it completes the parsing of the
+// top-level document when the value was the last one it contained.
+func (p *Parser) done() error {
+	if p.d != 0 {
+		return nil
+	}
+
+	if p.wrap {
+		if p.i != len(p.b)-1 {
+			return fmt.Errorf("objconv/bson: unexpected elements after the %q element of a wrapper document", ValueKey)
+		}
+		p.i++
+	}
+
+	p.n += int64(len(p.b))
+	p.loaded = false
+	p.wrap = false
+	return nil
+}
+
+func (p *Parser) read(n int) (b []byte, err error) {
+	if n > len(p.b)-p.i {
+		err = errors.New("objconv/bson: element exceeds the bounds of its document")
+		return
+	}
+	b = p.b[p.i : p.i+n]
+	p.i += n
+	return
+}
+
+// valueSize returns the size of the value of type t at the beginning of b, or
+// -1 if it can't be determined.
+func valueSize(t byte, b []byte) int {
+	switch t {
+	case TypeUndefined, TypeNull, TypeMinKey, TypeMaxKey:
+		return 0
+	case TypeBool:
+		return 1
+	case TypeInt32:
+		return 4
+	case TypeDouble, TypeDateTime, TypeTimestamp, TypeInt64:
+		return 8
+	case TypeObjectID:
+		return 12
+	case TypeDecimal128:
+		return 16
+	case TypeRegex:
+		i := bytes.IndexByte(b, 0)
+		if i < 0 {
+			return -1
+		}
+		j := bytes.IndexByte(b[i+1:], 0)
+		if j < 0 {
+			return -1
+		}
+		return i + j + 2
+	}
+
+	if len(b) < 4 {
+		return -1
+	}
+
+	n := int(getInt32(b))
+
+	switch t {
+	case TypeString, TypeJavaScript, TypeSymbol:
+		n += 4
+	case TypeBinary:
+		n += 5
+	case TypeDocument, TypeArray, TypeCodeWithScope:
+	default:
+		return -1
+	}
+
+	if n < 0 || n > len(b) {
+		return -1
+	}
+
+	return n
+}
+
+func (p *Parser) readCString() (b []byte, err error) {
+	i := bytes.IndexByte(p.b[p.i:], 0)
+
+	if i < 0 {
+		err = errors.New("objconv/bson: unterminated string")
+		return
+	}
+
+	b = p.b[p.i : p.i+i]
+	p.i += i + 1
+	return
+}
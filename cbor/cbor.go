@@ -47,6 +47,12 @@ const (
 	noTag    = uint64(objutil.Uint64Max)
 )
 
+const (
+	// f16NaN is the canonical representation of NaN values as half-precision
+	// floating point numbers.
+	f16NaN uint16 = 0x7E00
+)
+
 func majorByte(maj byte, val byte) byte {
 	return (maj << 5) | val
 }
@@ -117,3 +123,47 @@ func f16tof32bits(yy uint16) (d uint32) {
 	m = m << 13
 	return (s << 31) | (e << 23) | m
 }
+
+// f32tof16bits converts the bits of a single-precision floating point number to
+// their half-precision representation. The exact return value is true only if
+// the conversion didn't lose any precision.
+func f32tof16bits(f uint32) (h uint16, exact bool) {
+	s := uint16((f >> 16) & 0x8000)
+	e := int32((f >> 23) & 0xff)
+	m := f & 0x007fffff
+
+	switch {
+	case e == 0xff:
+		if m != 0 { // NaN, payloads are not preserved
+			return s | f16NaN, false
+		}
+		return s | 0x7c00, true // Inf
+
+	case e == 0: // +/- 0 or denormalized, too small for half-precision
+		return s, m == 0
+	}
+
+	e -= 127 - 15
+
+	switch {
+	case e >= 0x1f: // overflow
+		return 0, false
+
+	case e <= 0: // denormalized half-precision number
+		if e < -10 {
+			return 0, false
+		}
+		m |= 0x00800000
+		shift := uint32(14 - e)
+		if (m & ((1 << shift) - 1)) != 0 {
+			return 0, false
+		}
+		return s | uint16(m>>shift), true
+
+	default:
+		if (m & 0x1fff) != 0 {
+			return 0, false
+		}
+		return s | uint16(e<<10) | uint16(m>>13), true
+	}
+}
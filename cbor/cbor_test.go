@@ -1,9 +1,16 @@
 package cbor
 
 import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"math"
+	"reflect"
 	"testing"
 
+	"github.com/segmentio/objconv"
 	"github.com/segmentio/objconv/objtests"
+	"github.com/segmentio/objconv/objutil"
 )
 
 func TestCodec(t *testing.T) {
@@ -25,3 +32,338 @@ func TestMajorType(t *testing.T) {
 		t.Error("bad info value:", b)
 	}
 }
+
+func TestCanonicalCodec(t *testing.T) {
+	objtests.TestCodec(t, CanonicalCodec)
+}
+
+func BenchmarkCanonicalCodec(b *testing.B) {
+	objtests.BenchmarkCodec(b, CanonicalCodec)
+}
+
+func TestF32toF16Bits(t *testing.T) {
+	for i := 0; i <= objutil.Uint16Max; i++ {
+		h := uint16(i)
+		f := f16tof32bits(h)
+
+		if math.IsNaN(float64(math.Float32frombits(f))) {
+			continue
+		}
+
+		if x, exact := f32tof16bits(f); !exact || x != h {
+			t.Errorf("0x%04X: bad conversion: 0x%04X (exact = %t)", h, x, exact)
+		}
+	}
+}
+
+func TestMarshalCanonical(t *testing.T) {
+	tests := []struct {
+		v interface{}
+		s string
+	}{
+		{v: 0.0, s: "f90000"},
+		{v: -0.0, s: "f90000"},
+		{v: 1.5, s: "f93e00"},
+		{v: 65504.0, s: "f97bff"},
+		{v: 100000.0, s: "fa47c35000"},
+		{v: 1.1, s: "fb3ff199999999999a"},
+		{v: 5.960464477539063e-8, s: "f90001"},
+		{v: float32(3.4028234663852886e+38), s: "fa7f7fffff"},
+		{v: math.Inf(+1), s: "f97c00"},
+		{v: math.Inf(-1), s: "f9fc00"},
+		{v: math.NaN(), s: "f97e00"},
+		{v: 1000000, s: "1a000f4240"},
+		{
+			v: map[interface{}]interface{}{
+				false:      0,
+				"aa":       1,
+				-1:         2,
+				[1]int{-1}: 3,
+				"z":        4,
+				100:        5,
+				[0]int{}:   6,
+				10:         7,
+			},
+			s: "a80a071864052002617a04626161018006812003f400",
+		},
+		{
+			v: struct{ B, A, AA int }{1, 2, 3},
+			s: "a361410261420162414103",
+		},
+		{
+			v: []interface{}{map[string]int{"b": 1, "a": 2}},
+			s: "81a2616102616201",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.s, func(t *testing.T) {
+			b, err := MarshalCanonical(test.v)
+
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if s := hex.EncodeToString(b); s != test.s {
+				t.Errorf("%#v: %s", test.v, s)
+			}
+		})
+	}
+}
+
+func TestCanonicalStreamEncoder(t *testing.T) {
+	b := &bytes.Buffer{}
+	e := NewCanonicalStreamEncoder(b)
+
+	for _, v := range []interface{}{1, map[string]int{"b": 1, "a": 2}, 0.5} {
+		if err := e.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := hex.EncodeToString(b.Bytes()); s != "8301a2616102616201f93800" {
+		t.Error(s)
+	}
+}
+
+var float16Codec = objconv.Codec{
+	NewEmitter: func(w io.Writer) objconv.Emitter {
+		e := NewEmitter(w)
+		e.Float16 = true
+		return e
+	},
+	NewParser: func(r io.Reader) objconv.Parser { return NewParser(r) },
+}
+
+func TestFloat16Codec(t *testing.T) {
+	objtests.TestCodec(t, float16Codec)
+}
+
+func TestFloat16(t *testing.T) {
+	tests := []struct {
+		v interface{}
+		s string
+	}{
+		{v: 0.5, s: "f93800"},
+		{v: float32(-2), s: "f9c000"},
+		{v: 65504.0, s: "f97bff"},
+		{v: 65505.0, s: "fb40effc2000000000"},
+		{v: float32(65505), s: "fa477fe100"},
+		{v: 1.1, s: "fb3ff199999999999a"},
+		{v: math.Inf(-1), s: "f9fc00"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.s, func(t *testing.T) {
+			b := &bytes.Buffer{}
+
+			if err := float16Codec.NewEncoder(b).Encode(test.v); err != nil {
+				t.Error(err)
+				return
+			}
+
+			if s := hex.EncodeToString(b.Bytes()); s != test.s {
+				t.Errorf("%#v: %s", test.v, s)
+			}
+		})
+	}
+}
+
+func TestIndefiniteLength(t *testing.T) {
+	tests := []struct {
+		s string
+		v interface{}
+	}{
+		{
+			s: "9f9f01ff02ff",
+			v: []interface{}{[]interface{}{uint64(1)}, uint64(2)},
+		},
+		{
+			s: "9f9fff9fffff",
+			v: []interface{}{[]interface{}{}, []interface{}{}},
+		},
+		{
+			s: "bf61619f01ff616202ff",
+			v: map[interface{}]interface{}{
+				"a": []interface{}{uint64(1)},
+				"b": uint64(2),
+			},
+		},
+		{
+			s: "827f6161626263ffbfff",
+			v: []interface{}{"abc", map[interface{}]interface{}{}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.s, func(t *testing.T) {
+			var v interface{}
+			b, _ := hex.DecodeString(test.s)
+
+			if err := Unmarshal(b, &v); err != nil {
+				t.Error(err)
+				return
+			}
+
+			if !reflect.DeepEqual(v, test.v) {
+				t.Errorf("%#v", v)
+			}
+		})
+	}
+}
+
+func TestStreamEncoderIndefiniteLength(t *testing.T) {
+	b := &bytes.Buffer{}
+	e := NewStreamEncoder(b)
+
+	for i, s := range []string{"9f01", "9f0102", "9f010203"} {
+		if err := e.Encode(i + 1); err != nil {
+			t.Fatal(err)
+		}
+
+		// Values must be written as they are received by the stream encoder.
+		if x := hex.EncodeToString(b.Bytes()); x != s {
+			t.Fatal(x)
+		}
+	}
+
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := hex.EncodeToString(b.Bytes()); s != "9f010203ff" {
+		t.Error(s)
+	}
+}
+
+func TestSeqCodec(t *testing.T) {
+	objtests.TestCodec(t, SeqCodec)
+}
+
+func BenchmarkSeqCodec(b *testing.B) {
+	objtests.BenchmarkCodec(b, SeqCodec)
+}
+
+func TestSeqStreamEncoder(t *testing.T) {
+	b := &bytes.Buffer{}
+	e := NewSeqStreamEncoder(b)
+
+	for _, v := range []interface{}{1, []int{2, 3}, "A"} {
+		if err := e.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := hex.EncodeToString(b.Bytes()); s != "018202036141" {
+		t.Error(s)
+	}
+}
+
+func TestSeqStreamDecoder(t *testing.T) {
+	tests := []struct {
+		s   string
+		v   []interface{}
+		err error
+	}{
+		{
+			s: "",
+		},
+		{
+			s: "018202036141",
+			v: []interface{}{uint64(1), []interface{}{uint64(2), uint64(3)}, "A"},
+		},
+		{
+			s:   "018202031901",
+			v:   []interface{}{uint64(1), []interface{}{uint64(2), uint64(3)}},
+			err: io.ErrUnexpectedEOF,
+		},
+		{
+			s:   "01a2616101",
+			v:   []interface{}{uint64(1)},
+			err: io.ErrUnexpectedEOF,
+		},
+		{
+			s:   "016341",
+			v:   []interface{}{uint64(1)},
+			err: io.ErrUnexpectedEOF,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.s, func(t *testing.T) {
+			var values []interface{}
+			b, _ := hex.DecodeString(test.s)
+			d := NewSeqStreamDecoder(bytes.NewReader(b))
+
+			for {
+				var v interface{}
+				if d.Decode(&v) != nil {
+					break
+				}
+				values = append(values, v)
+			}
+
+			if !reflect.DeepEqual(values, test.v) {
+				t.Errorf("%#v", values)
+			}
+
+			if err := d.Err(); err != test.err {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		s string
+		c float64
+	}{
+		{"", 0},
+		{"\xd9\xd9\xf7\xa1aa\x01", 1},
+		{"\xa1aa\x01", 0.6},
+		{"\xa1\x01\x01", 0.3},
+		{"\x82\x01\x02", 0.1},
+		{`{"a":1}`, 0},
+	}
+
+	for _, test := range tests {
+		if c := sniff([]byte(test.s)); c != test.c {
+			t.Errorf("%q: expected confidence %g but got %g", test.s, test.c, c)
+		}
+	}
+}
+
+func TestCodecOptions(t *testing.T) {
+	codec, err := Codec.With(map[string]string{"canonical": "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := codec.NewEmitter(io.Discard).(*CanonicalEmitter); !ok {
+		t.Error("the codec does not produce canonical emitters")
+	}
+
+	if codec, err = Codec.With(map[string]string{"float16": "true"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if e, ok := codec.NewEmitter(io.Discard).(*Emitter); !ok || !e.Float16 {
+		t.Error("the codec does not produce emitters writing half-precision floats")
+	}
+
+	for _, name := range []string{"canonical", "float16"} {
+		if _, err := Codec.With(map[string]string{name: "nope"}); err == nil {
+			t.Errorf("%s: expected an error for an invalid value", name)
+		}
+	}
+}
@@ -18,6 +18,12 @@ func NewStreamDecoder(r io.Reader) *objconv.StreamDecoder {
 	return objconv.NewStreamDecoder(NewParser(r))
 }
 
+// NewSeqStreamDecoder returns a new CBOR sequence stream decoder that parses
+// values from r.
+func NewSeqStreamDecoder(r io.Reader) *objconv.StreamDecoder {
+	return objconv.NewStreamDecoder(NewSeqParser(r))
+}
+
 // Unmarshal decodes a MessagePack representation of v from b.
 func Unmarshal(b []byte, v interface{}) error {
 	u := unmarshalerPool.Get().(*unmarshaler)
@@ -1,9 +1,13 @@
 package cbor
 
 import (
+	"bytes"
+	"errors"
 	"io"
 	"math"
 	"reflect"
+	"sort"
+	"sync"
 	"time"
 	"unsafe"
 
@@ -13,6 +17,11 @@ import (
 // Emitter implements a MessagePack emitter that satisfies the objconv.Emitter
 // interface.
 type Emitter struct {
+	// Float16 may be set to true to have the emitter write floating point
+	// numbers as half-precision values when it can be done without losing
+	// precision.
+	Float16 bool
+
 	w io.Writer
 	b [16]byte
 
@@ -61,20 +70,18 @@ func (e *Emitter) EmitUint(v uint64, _ int) (err error) {
 }
 
 func (e *Emitter) EmitFloat(v float64, bitSize int) (err error) {
-	n := 0
+	if e.Float16 {
+		if f := float32(v); float64(f) == v {
+			if h, ok := f32tof16bits(math.Float32bits(f)); ok {
+				return e.emitFloat16(h)
+			}
+		}
+	}
 
 	if bitSize == 32 {
-		n = 5
-		e.b[0] = majorByte(majorType7, svFloat32)
-		putUint32(e.b[1:], math.Float32bits(float32(v)))
-	} else {
-		n = 9
-		e.b[0] = majorByte(majorType7, svFloat64)
-		putUint64(e.b[1:], math.Float64bits(v))
+		return e.emitFloat32(float32(v))
 	}
-
-	_, err = e.w.Write(e.b[:n])
-	return
+	return e.emitFloat64(v)
 }
 
 func (e *Emitter) EmitString(v string) (err error) {
@@ -219,3 +226,237 @@ func (e *Emitter) emitUint(m byte, v uint64) (err error) {
 	_, err = e.w.Write(e.b[:n])
 	return
 }
+
+func (e *Emitter) emitFloat16(v uint16) (err error) {
+	e.b[0] = majorByte(majorType7, svFloat16)
+	putUint16(e.b[1:], v)
+	_, err = e.w.Write(e.b[:3])
+	return
+}
+
+func (e *Emitter) emitFloat32(v float32) (err error) {
+	e.b[0] = majorByte(majorType7, svFloat32)
+	putUint32(e.b[1:], math.Float32bits(v))
+	_, err = e.w.Write(e.b[:5])
+	return
+}
+
+func (e *Emitter) emitFloat64(v float64) (err error) {
+	e.b[0] = majorByte(majorType7, svFloat64)
+	putUint64(e.b[1:], math.Float64bits(v))
+	_, err = e.w.Write(e.b[:9])
+	return
+}
+
+// emitFloatShortest writes v using the smallest floating point width that can
+// represent it without losing precision. NaN values are always written in the
+// canonical half-precision form 0xF97E00.
+func (e *Emitter) emitFloatShortest(v float64) (err error) {
+	if math.IsNaN(v) {
+		return e.emitFloat16(f16NaN)
+	}
+
+	if f := float32(v); float64(f) == v {
+		if h, ok := f32tof16bits(math.Float32bits(f)); ok {
+			return e.emitFloat16(h)
+		}
+		return e.emitFloat32(f)
+	}
+
+	return e.emitFloat64(v)
+}
+
+// CanonicalEmitter implements a CBOR emitter that produces the deterministic
+// encoding described in section 4.2 of RFC 8949.
+//
+// Integers and lengths always use their shortest form, floating point numbers
+// are written using the smallest width that represents them without loss of
+// precision, arrays and maps of unknown length are buffered so they can be
+// written with a definite length, and map keys are sorted by the bytewise
+// lexicographic order of their encoded form (which applies to struct fields as
+// well).
+type CanonicalEmitter struct {
+	Emitter
+
+	// This stack is used to keep track of the arrays and maps that are being
+	// buffered. Arrays of known length are written directly and have a nil
+	// entry in the stack.
+	stack []*canonicalContext
+	sback [16]*canonicalContext
+}
+
+type canonicalContext struct {
+	b bytes.Buffer // buffer where the elements are cached
+	w io.Writer    // the previous writer where b will be flushed
+	n int          // the number of elements written to the array
+	k int          // offset of the next map key in b
+	e []mapEntry   // offsets of the map entries in b
+}
+
+// mapEntry carries the offsets of a key/value pair written to the buffer of a
+// canonicalContext, the key is b[k:v] and the value is b[v:end].
+type mapEntry struct {
+	k   int
+	v   int
+	end int
+}
+
+func NewCanonicalEmitter(w io.Writer) *CanonicalEmitter {
+	e := &CanonicalEmitter{
+		Emitter: *NewEmitter(w),
+	}
+	e.Emitter.stack = e.Emitter.sback[:0]
+	e.stack = e.sback[:0]
+	return e
+}
+
+func (e *CanonicalEmitter) Reset(w io.Writer) {
+	for _, c := range e.stack {
+		if c != nil {
+			canonicalContextPool.Put(c)
+		}
+	}
+	e.Emitter.Reset(w)
+	e.stack = e.stack[:0]
+}
+
+func (e *CanonicalEmitter) EmitFloat(v float64, _ int) (err error) {
+	return e.emitFloatShortest(v)
+}
+
+func (e *CanonicalEmitter) EmitArrayBegin(n int) (err error) {
+	var c *canonicalContext
+
+	if n < 0 {
+		c = e.push()
+	} else {
+		err = e.emitUint(majorType4, uint64(n))
+	}
+
+	e.stack = append(e.stack, c)
+	return
+}
+
+func (e *CanonicalEmitter) EmitArrayEnd() (err error) {
+	c := e.pop()
+
+	if c != nil {
+		if c.b.Len() != 0 {
+			c.n++
+		}
+
+		if err = e.emitUint(majorType4, uint64(c.n)); err == nil {
+			_, err = c.b.WriteTo(e.w)
+		}
+
+		canonicalContextPool.Put(c)
+	}
+
+	return
+}
+
+func (e *CanonicalEmitter) EmitArrayNext() (err error) {
+	if c := e.stack[len(e.stack)-1]; c != nil {
+		c.n++
+	}
+	return
+}
+
+func (e *CanonicalEmitter) EmitMapBegin(n int) (err error) {
+	e.stack = append(e.stack, e.push())
+	return
+}
+
+func (e *CanonicalEmitter) EmitMapEnd() (err error) {
+	c := e.pop()
+	n := len(c.e)
+
+	if n != 0 {
+		c.e[n-1].end = c.b.Len()
+	}
+
+	b := c.b.Bytes()
+	sort.Slice(c.e, func(i int, j int) bool {
+		return bytes.Compare(b[c.e[i].k:c.e[i].v], b[c.e[j].k:c.e[j].v]) < 0
+	})
+
+	for i := 1; i < n; i++ {
+		if bytes.Equal(b[c.e[i-1].k:c.e[i-1].v], b[c.e[i].k:c.e[i].v]) {
+			err = errors.New("objconv/cbor: duplicate keys are not allowed in maps of the canonical encoding")
+			break
+		}
+	}
+
+	if err == nil {
+		if err = e.emitUint(majorType5, uint64(n)); err == nil {
+			for _, x := range c.e {
+				if _, err = e.w.Write(b[x.k:x.end]); err != nil {
+					break
+				}
+			}
+		}
+	}
+
+	canonicalContextPool.Put(c)
+	return
+}
+
+func (e *CanonicalEmitter) EmitMapValue() (err error) {
+	c := e.stack[len(e.stack)-1]
+	c.e = append(c.e, mapEntry{k: c.k, v: c.b.Len()})
+	return
+}
+
+func (e *CanonicalEmitter) EmitMapNext() (err error) {
+	c := e.stack[len(e.stack)-1]
+	c.k = c.b.Len()
+	c.e[len(c.e)-1].end = c.k
+	return
+}
+
+func (e *CanonicalEmitter) push() *canonicalContext {
+	c := canonicalContextPool.Get().(*canonicalContext)
+	c.b.Truncate(0)
+	c.n = 0
+	c.k = 0
+	c.e = c.e[:0]
+	c.w = e.w
+	e.w = &c.b
+	return c
+}
+
+func (e *CanonicalEmitter) pop() *canonicalContext {
+	i := len(e.stack) - 1
+	c := e.stack[i]
+	e.stack = e.stack[:i]
+
+	if c != nil {
+		e.w = c.w
+		c.w = nil
+	}
+
+	return c
+}
+
+var canonicalContextPool = sync.Pool{
+	New: func() interface{} { return &canonicalContext{} },
+}
+
+// SeqEmitter implements an emitter for CBOR sequences as defined in RFC 8742,
+// where streams of values are written back-to-back instead of being wrapped in
+// an indefinite-length array.
+type SeqEmitter struct {
+	Emitter
+}
+
+func NewSeqEmitter(w io.Writer) *SeqEmitter {
+	e := &SeqEmitter{
+		Emitter: *NewEmitter(w),
+	}
+	e.stack = e.sback[:0]
+	return e
+}
+
+func (e *SeqEmitter) SequenceEmitter() bool {
+	return true
+}
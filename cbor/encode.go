@@ -18,6 +18,24 @@ func NewStreamEncoder(w io.Writer) *objconv.StreamEncoder {
 	return objconv.NewStreamEncoder(NewEmitter(w))
 }
 
+// NewSeqStreamEncoder returns a new CBOR sequence stream encoder that writes
+// to w.
+func NewSeqStreamEncoder(w io.Writer) *objconv.StreamEncoder {
+	return objconv.NewStreamEncoder(NewSeqEmitter(w))
+}
+
+// NewCanonicalEncoder returns a new CBOR encoder that writes the canonical
+// representation of values to w.
+func NewCanonicalEncoder(w io.Writer) *objconv.Encoder {
+	return objconv.NewEncoder(NewCanonicalEmitter(w))
+}
+
+// NewCanonicalStreamEncoder returns a new CBOR stream encoder that writes the
+// canonical representation of values to w.
+func NewCanonicalStreamEncoder(w io.Writer) *objconv.StreamEncoder {
+	return objconv.NewStreamEncoder(NewCanonicalEmitter(w))
+}
+
 // Marshal writes the MessagePack representation of v to a byte slice returned in b.
 func Marshal(v interface{}) (b []byte, err error) {
 	m := marshalerPool.Get().(*marshaler)
@@ -46,3 +64,18 @@ func newMarshaler() *marshaler {
 	m.w = &m.b
 	return m
 }
+
+// MarshalCanonical writes the canonical CBOR representation of v to a byte
+// slice returned in b.
+//
+// The output is deterministic, which makes it suitable for producing payloads
+// that get signed or hashed.
+func MarshalCanonical(v interface{}) (b []byte, err error) {
+	w := &bytes.Buffer{}
+
+	if err = NewCanonicalEncoder(w).Encode(v); err == nil {
+		b = w.Bytes()
+	}
+
+	return
+}
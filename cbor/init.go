@@ -1,7 +1,10 @@
 package cbor
 
 import (
+	"bytes"
+	"fmt"
 	"io"
+	"strconv"
 
 	"github.com/segmentio/objconv"
 )
@@ -10,6 +13,23 @@ import (
 var Codec = objconv.Codec{
 	NewEmitter: func(w io.Writer) objconv.Emitter { return NewEmitter(w) },
 	NewParser:  func(r io.Reader) objconv.Parser { return NewParser(r) },
+	Extensions: []string{".cbor"},
+	Sniff:      sniff,
+	Options:    codecOptions,
+	Configure:  configure,
+}
+
+// CanonicalCodec for the CBOR format, the emitters it creates produce the
+// deterministic encoding defined in RFC 8949.
+var CanonicalCodec = objconv.Codec{
+	NewEmitter: func(w io.Writer) objconv.Emitter { return NewCanonicalEmitter(w) },
+	NewParser:  func(r io.Reader) objconv.Parser { return NewParser(r) },
+}
+
+// SeqCodec for the CBOR sequence format (RFC 8742).
+var SeqCodec = objconv.Codec{
+	NewEmitter: func(w io.Writer) objconv.Emitter { return NewSeqEmitter(w) },
+	NewParser:  func(r io.Reader) objconv.Parser { return NewSeqParser(r) },
 }
 
 func init() {
@@ -19,4 +39,81 @@ func init() {
 	} {
 		objconv.Register(name, Codec)
 	}
+
+	for _, name := range [...]string{
+		"application/cbor-seq",
+		"cbor-seq",
+	} {
+		objconv.Register(name, SeqCodec)
+	}
+}
+
+var codecOptions = []objconv.Option{
+	{Name: "canonical", Doc: `"true" to produce the deterministic encoding defined in RFC 8949`},
+	{Name: "float16", Doc: `"true" to write floating point numbers as half-precision values when it doesn't lose precision`},
+}
+
+// configure returns a copy of c which creates emitters configured with the
+// options of the CBOR codec.
+func configure(c objconv.Codec, options map[string]string) (objconv.Codec, error) {
+	canonical, ok1, err := boolOption(options, "canonical")
+	if err != nil {
+		return c, err
+	}
+
+	float16, ok2, err := boolOption(options, "float16")
+	if err != nil {
+		return c, err
+	}
+
+	switch {
+	case canonical:
+		// The canonical encoding always uses the smallest width of floating
+		// point numbers, float16 doesn't apply.
+		c.NewEmitter = func(w io.Writer) objconv.Emitter { return NewCanonicalEmitter(w) }
+	case ok1 || ok2:
+		c.NewEmitter = func(w io.Writer) objconv.Emitter {
+			e := NewEmitter(w)
+			e.Float16 = float16
+			return e
+		}
+	}
+
+	return c, nil
+}
+
+func boolOption(options map[string]string, name string) (v bool, ok bool, err error) {
+	var s string
+
+	if s, ok = options[name]; ok {
+		if v, err = strconv.ParseBool(s); err != nil {
+			err = fmt.Errorf("objconv/cbor: invalid value %q for the %s option", s, name)
+		}
+	}
+
+	return
+}
+
+// sniff recognizes the self-described CBOR tag, and to a lesser degree CBOR
+// maps whose first key is a text string.
+func sniff(b []byte) float64 {
+	switch {
+	case bytes.HasPrefix(b, []byte{0xD9, 0xD9, 0xF7}):
+		return 1
+	case len(b) == 0:
+		return 0
+	}
+
+	switch c := b[0]; {
+	case c >= 0xA0 && c <= 0xBB, c == 0xBF:
+		if len(b) > 1 && b[1] >= 0x60 && b[1] <= 0x7F {
+			return 0.6
+		}
+		return 0.3
+
+	case c >= 0x80 && c <= 0x9B, c == 0x9F:
+		return 0.1
+	}
+
+	return 0
 }
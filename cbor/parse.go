@@ -15,6 +15,7 @@ type Parser struct {
 	r io.Reader // reader to load bytes from
 	i int       // offset of the first unread byte in b
 	j int       // offset + 1 of the last unread byte in b
+	n int64     // number of bytes consumed before b[0]
 	s []byte    // string buffer
 	b [240]byte // read buffer
 
@@ -39,6 +40,7 @@ func (p *Parser) Reset(r io.Reader) {
 	p.r = r
 	p.i = 0
 	p.j = 0
+	p.n = 0
 	p.tag = noTag
 	p.stack = p.stack[:0]
 }
@@ -47,6 +49,11 @@ func (p *Parser) Buffered() io.Reader {
 	return bytes.NewReader(p.b[p.i:p.j])
 }
 
+// Offset returns the number of bytes of the input consumed by p.
+func (p *Parser) Offset() int64 {
+	return p.n + int64(p.i)
+}
+
 func (p *Parser) ParseType() (typ objconv.Type, err error) {
 	if p.tag != noTag {
 		typ = p.typ
@@ -314,7 +321,15 @@ func (p *Parser) ParseArrayBegin() (n int, err error) {
 }
 
 func (p *Parser) ParseArrayEnd(n int) (err error) {
-	p.stack = p.stack[:len(p.stack)-1]
+	i := len(p.stack) - 1
+
+	if p.stack[i] < 0 {
+		if err = p.parseBreak(); err != nil {
+			return
+		}
+	}
+
+	p.stack = p.stack[:i]
 	return
 }
 
@@ -357,7 +372,15 @@ func (p *Parser) ParseMapBegin() (n int, err error) {
 }
 
 func (p *Parser) ParseMapEnd(n int) (err error) {
-	p.stack = p.stack[:len(p.stack)-1]
+	i := len(p.stack) - 1
+
+	if p.stack[i] < 0 {
+		if err = p.parseBreak(); err != nil {
+			return
+		}
+	}
+
+	p.stack = p.stack[:i]
 	return
 }
 
@@ -470,6 +493,22 @@ func (p *Parser) parseBytes(m byte) (v []byte, err error) {
 	return
 }
 
+func (p *Parser) parseBreak() (err error) {
+	var s []byte
+
+	if s, err = p.peek(1); err != nil {
+		return
+	}
+
+	if s[0] != 0xFF {
+		err = fmt.Errorf("objconv/cbor: expected break code at the end of an indefinite-length item but found 0x%02X", s[0])
+		return
+	}
+
+	p.i++
+	return
+}
+
 func (p *Parser) parseType7() (b byte, err error) {
 	var s []byte
 
@@ -514,6 +553,7 @@ func (p *Parser) load(n int) (b []byte, err error) {
 		copy(p.s[i:], p.b[p.i:p.i+n1])
 
 		if p.i += n1; p.i == p.j {
+			p.n += int64(p.j)
 			p.i = 0
 			p.j = 0
 		}
@@ -522,6 +562,8 @@ func (p *Parser) load(n int) (b []byte, err error) {
 	}
 
 	if i != j {
+		p.n += int64(j - i)
+
 		if _, err = io.ReadFull(p.r, p.s[i:]); err != nil {
 			return
 		}
@@ -544,6 +586,7 @@ func (p *Parser) peek(n int) (b []byte, err error) {
 func (p *Parser) fill() (err error) {
 	n := p.j - p.i
 	copy(p.b[:], p.b[p.i:p.j])
+	p.n += int64(p.i)
 	p.i = 0
 	p.j = n
 
@@ -559,3 +602,22 @@ func (p *Parser) fill() (err error) {
 
 	return
 }
+
+// SeqParser implements a parser for CBOR sequences as defined in RFC 8742,
+// stream decoders using this parser read values until reaching the end of the
+// input.
+type SeqParser struct {
+	Parser
+}
+
+func NewSeqParser(r io.Reader) *SeqParser {
+	p := &SeqParser{
+		Parser: *NewParser(r),
+	}
+	p.stack = p.sback[:0]
+	return p
+}
+
+func (p *SeqParser) SequenceParser() bool {
+	return true
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/segmentio/objconv"
+)
+
+// diffMain runs the diff subcommand with args and returns the exit code of
+// the program: 0 if the documents are equal, 1 if they differ, 2 on errors.
+func diffMain(args []string) int {
+	var input string
+	var output string
+	var patch bool
+	var pretty bool
+
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	fs.StringVar(&input, "i", "", "The format of the inputs, inferred from the input files when omitted")
+	fs.BoolVar(&patch, "patch", false, "Prints the differences as a JSON Patch (RFC 6902)")
+	fs.StringVar(&output, "o", "json", "The format of the patch")
+	fs.BoolVar(&pretty, "p", false, "Prints the patch in pretty format when available")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), `Usage:
+  objconv diff [options] a b
+
+Compares the documents a and b, which may be in different formats, and lists
+the paths that were added (+), removed (-) or changed (~). "-" designates the
+standard input.
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	a, err := loadDocument(fs.Arg(0), input)
+	if err == nil {
+		var b interface{}
+		if b, err = loadDocument(fs.Arg(1), input); err == nil {
+			err = printDiff(os.Stdout, a, b, patch, output, pretty)
+		}
+	}
+
+	switch {
+	case err == errDifferent:
+		return exitFailure
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return exitUsage
+	}
+
+	return 0
+}
+
+var errDifferent = errors.New("the documents are different")
+
+// printDiff writes the differences between a and b to w, it returns
+// errDifferent if there were any.
+func printDiff(w io.Writer, a interface{}, b interface{}, patch bool, format string, pretty bool) error {
+	changes, err := objconv.Diff(a, b)
+	if err != nil {
+		return err
+	}
+
+	if patch {
+		var codec objconv.Codec
+		if codec, err = objconv.Resolve(format); err != nil {
+			return err
+		}
+
+		bw := bufio.NewWriter(w)
+		m := codec.NewEmitter(bw)
+
+		if pretty {
+			if p, ok := m.(objconv.PrettyEmitter); ok {
+				m = p.PrettyEmitter()
+			}
+		}
+
+		if changes == nil {
+			changes = []objconv.Change{}
+		}
+
+		if err = objconv.NewEncoder(m).Encode(changes); err == nil {
+			if err = closeEmitter(m); err == nil {
+				if t, ok := m.(interface{ TextEmitter() bool }); ok && t.TextEmitter() && !codec.Compressed {
+					bw.WriteByte('\n')
+				}
+				err = bw.Flush()
+			}
+		}
+	} else {
+		for _, c := range changes {
+			if _, err = fmt.Fprintln(w, c); err != nil {
+				break
+			}
+		}
+	}
+
+	if err == nil && len(changes) != 0 {
+		err = errDifferent
+	}
+
+	return err
+}
+
+// loadDocument decodes the file at path, documents made of multiple top-level
+// values are loaded as arrays.
+func loadDocument(path string, format string) (doc interface{}, err error) {
+	var r = bufio.NewReaderSize(os.Stdin, sniffSize)
+	var name = "<stdin>"
+
+	if path != "-" {
+		var f *os.File
+		if f, err = os.Open(path); err != nil {
+			return
+		}
+		defer f.Close()
+		r.Reset(f)
+		name = path
+	}
+
+	codec, _, err := inputCodec(format, path, r)
+	if err != nil {
+		return
+	}
+
+	src := newSource(name, r, codec)
+	values := []interface{}{}
+
+	for {
+		var v interface{}
+
+		// The end of the input is only expected between values.
+		if _, err = src.p.ParseType(); errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err == nil {
+			err = objconv.NewDecoder(src.p).Decode(&v)
+		}
+
+		if err != nil {
+			return nil, src.syntaxError(err)
+		}
+
+		values = append(values, v)
+	}
+
+	if len(values) == 1 {
+		return values[0], nil
+	}
+
+	return values, nil
+}
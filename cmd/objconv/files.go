@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/segmentio/objconv"
+)
+
+// usageError is returned when the arguments of the program are invalid.
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string { return e.err.Error() }
+
+// convFile converts the content of the input file to the output file, the
+// standard input and output are used when the names are empty or "-".
+func convFile(output string, input string, opts options) (err error) {
+	var r = bufio.NewReaderSize(os.Stdin, sniffSize)
+	var w = bufio.NewWriter(os.Stdout)
+	var name = "<stdin>"
+	var ic objconv.Codec
+	var oc objconv.Codec
+
+	if err = checkSameFile(output, input); err != nil {
+		return
+	}
+
+	if input != "" && input != "-" {
+		var f *os.File
+		if f, err = os.Open(input); err != nil {
+			return
+		}
+		defer f.Close()
+		r.Reset(f)
+		name = input
+	}
+
+	if ic, _, err = inputCodec(opts.input, input, r); err != nil {
+		return
+	}
+
+	if output == "-" {
+		output = ""
+	}
+
+	if oc, err = outputCodec(&opts, output, func() objconv.Codec { return lookupFormat("json") }); err != nil {
+		return
+	}
+
+	if output == "" {
+		if err = conv(w, oc, r, ic, name, opts); err != nil {
+			return
+		}
+		return w.Flush()
+	}
+
+	// The output is written only after the conversion succeeded, so a failed
+	// conversion doesn't leave a truncated file behind.
+	perm := os.FileMode(0644)
+
+	if info, err := os.Stat(output); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err = conv(buf, oc, r, ic, name, opts); err != nil {
+		return
+	}
+
+	return writeFile(output, buf.Bytes(), perm)
+}
+
+// checkSameFile returns an error if the input and output designate the same
+// file, which would be overwritten before it was read.
+func checkSameFile(output string, input string) error {
+	if output == "" || output == "-" || input == "" || input == "-" {
+		return nil
+	}
+
+	if filepath.Clean(output) == filepath.Clean(input) {
+		return usageError{fmt.Errorf("the input and output are the same file: %s", input)}
+	}
+
+	i, err := os.Stat(input)
+	if err != nil {
+		return nil
+	}
+
+	o, err := os.Stat(output)
+	if err != nil {
+		return nil
+	}
+
+	if os.SameFile(i, o) {
+		return usageError{fmt.Errorf("the input and output are the same file: %s and %s", input, output)}
+	}
+
+	return nil
+}
+
+// convFiles converts the files in place, each file is rewritten with the
+// output format. When the output format was set explicitly and the extension
+// of a file belongs to the input format, the file is renamed with the
+// extension of the output format.
+//
+// Errors are reported for each file, the conversion continues with the next
+// files.
+func convFiles(paths []string, opts options) error {
+	failed := 0
+
+	for _, path := range paths {
+		if err := convInPlace(path, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			failed++
+		}
+	}
+
+	if failed != 0 {
+		return fmt.Errorf("failed to convert %d of %d files", failed, len(paths))
+	}
+
+	return nil
+}
+
+func convInPlace(path string, opts options) (err error) {
+	var b []byte
+	var info os.FileInfo
+	var ic objconv.Codec
+	var oc objconv.Codec
+	var ext string
+
+	if info, err = os.Stat(path); err != nil {
+		return
+	}
+
+	if b, err = os.ReadFile(path); err != nil {
+		return
+	}
+
+	r := bufio.NewReaderSize(bytes.NewReader(b), sniffSize)
+
+	if ic, ext, err = inputCodec(opts.input, path, r); err != nil {
+		return
+	}
+
+	if oc, err = outputCodec(&opts, "", func() objconv.Codec { return ic }); err != nil {
+		return
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err = conv(buf, oc, r, ic, path, opts); err != nil {
+		return
+	}
+
+	target := path
+
+	if opts.output != "" && ext != "" && len(oc.Extensions) != 0 && !hasExtension(oc.Extensions, ext) {
+		target = strings.TrimSuffix(path, ext) + oc.Extensions[0]
+	}
+
+	if err = writeFile(target, buf.Bytes(), info.Mode().Perm()); err != nil {
+		return
+	}
+
+	if target != path {
+		err = os.Remove(path)
+	}
+
+	return
+}
+
+// writeFile atomically replaces the content of the file at path with b.
+func writeFile(path string, b []byte, perm os.FileMode) (err error) {
+	var f *os.File
+
+	if f, err = os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*"); err != nil {
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}()
+
+	if _, err = f.Write(b); err != nil {
+		f.Close()
+		return
+	}
+
+	if err = f.Chmod(perm); err != nil {
+		f.Close()
+		return
+	}
+
+	if err = f.Close(); err != nil {
+		return
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// sniffSize is the number of bytes used to detect the format of inputs.
+const sniffSize = 4096
+
+// inputCodec returns the codec used to read the input at path. The codec is
+// resolved from format if it's not empty, then from the extension of the
+// path, and finally from the content of r. The extension matched with the
+// codec is returned as well.
+func inputCodec(format string, path string, r *bufio.Reader) (codec objconv.Codec, ext string, err error) {
+	if format != "" {
+		if codec, err = objconv.Resolve(format); err != nil {
+			err = usageError{fmt.Errorf("bad input format: %v", err)}
+		}
+		return
+	}
+
+	var ok bool
+
+	if codec, ext, ok = lookupPath(path); ok {
+		return
+	}
+
+	b, _ := r.Peek(sniffSize)
+
+	if c, confidence := objconv.Sniff(b); confidence > 0 {
+		codec = c
+		return
+	}
+
+	codec = lookupFormat("json")
+	return
+}
+
+// outputCodec returns the codec used to write the output at path, which is
+// resolved from the output format of opts, then from the extension of the
+// path, falling back to the codec returned by def.
+//
+// The indentation of opts is applied to the codec when it supports the
+// "indent" option, otherwise a non-zero indentation enables the pretty format
+// in opts.
+func outputCodec(opts *options, path string, def func() objconv.Codec) (codec objconv.Codec, err error) {
+	var ok bool
+
+	switch {
+	case opts.output != "":
+		if codec, err = objconv.Resolve(opts.output); err != nil {
+			err = usageError{fmt.Errorf("bad output format: %v", err)}
+			return
+		}
+	default:
+		if codec, _, ok = lookupPath(path); !ok {
+			codec = def()
+		}
+	}
+
+	if opts.indent != "" {
+		if !hasOption(codec, "indent") {
+			if opts.indent != "0" {
+				opts.pretty = true
+			}
+		} else if codec, err = codec.With(map[string]string{"indent": opts.indent}); err != nil {
+			err = usageError{err}
+		}
+	}
+
+	return
+}
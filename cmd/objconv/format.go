@@ -0,0 +1,53 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/segmentio/objconv"
+)
+
+// lookupPath returns the codec matching the extension of path. Extensions may
+// be made of multiple components (like ".json.gz"), the longest extension
+// matching a codec is used and returned.
+func lookupPath(path string) (codec objconv.Codec, ext string, ok bool) {
+	base := filepath.Base(path)
+
+	for i := 1; i < len(base); i++ {
+		if base[i] == '.' {
+			if codec, ok = objconv.LookupExtension(base[i:]); ok {
+				ext = base[i:]
+				return
+			}
+		}
+	}
+
+	return
+}
+
+// lookupFormat returns the codec registered under name, which must exist.
+func lookupFormat(name string) objconv.Codec {
+	codec, ok := objconv.Lookup(name)
+	if !ok {
+		panic("objconv: no codec registered for " + name)
+	}
+	return codec
+}
+
+func hasOption(codec objconv.Codec, name string) bool {
+	for _, opt := range codec.Options {
+		if opt.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasExtension(list []string, ext string) bool {
+	for _, x := range list {
+		if strings.EqualFold(x, ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,20 +1,29 @@
 package main
 
 import (
-	"bufio"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"reflect"
 	"sort"
-	"strings"
 
 	"github.com/segmentio/objconv"
+	_ "github.com/segmentio/objconv/bencode"
+	_ "github.com/segmentio/objconv/bson"
 	_ "github.com/segmentio/objconv/cbor"
+	_ "github.com/segmentio/objconv/csv"
+	_ "github.com/segmentio/objconv/ion"
 	_ "github.com/segmentio/objconv/json"
 	_ "github.com/segmentio/objconv/msgpack"
+	"github.com/segmentio/objconv/protobuf"
+	"github.com/segmentio/objconv/query"
 	_ "github.com/segmentio/objconv/resp"
+	_ "github.com/segmentio/objconv/smile"
+	_ "github.com/segmentio/objconv/toml"
+	_ "github.com/segmentio/objconv/ubjson"
+	_ "github.com/segmentio/objconv/xml"
 	_ "github.com/segmentio/objconv/yaml"
 )
 
@@ -40,6 +49,13 @@ func (doc document) EncodeValue(e objconv.Encoder) error {
 	})
 }
 
+// Len, Key and Value implement query.Map so queries preserve the order of keys.
+func (doc document) Len() int { return len(doc) }
+
+func (doc document) Key(i int) interface{} { return doc[i].K }
+
+func (doc document) Value(i int) interface{} { return doc[i].V }
+
 func (doc *document) DecodeValue(d objconv.Decoder) error {
 	return d.DecodeMap(func(k objconv.Decoder, v objconv.Decoder) (err error) {
 		var item item
@@ -54,74 +70,230 @@ func (doc *document) DecodeValue(d objconv.Decoder) error {
 	})
 }
 
+// sortKeys sorts the keys of the documents in v, recursively.
+func sortKeys(v interface{}) {
+	switch x := v.(type) {
+	case document:
+		sort.SliceStable(x, func(i int, j int) bool { return keyLess(x[i].K, x[j].K) })
+		for _, item := range x {
+			sortKeys(item.V)
+		}
+	case []interface{}:
+		for _, elem := range x {
+			sortKeys(elem)
+		}
+	}
+}
+
+// keyLess compares map keys, strings are compared directly and other types by
+// their string representation.
+func keyLess(a interface{}, b interface{}) bool {
+	if s1, ok := a.(string); ok {
+		if s2, ok := b.(string); ok {
+			return s1 < s2
+		}
+	}
+	return fmt.Sprint(a) < fmt.Sprint(b)
+}
+
+// Exit codes of the program.
+const (
+	exitFailure = 1 // conversion errors, including malformed input
+	exitUsage   = 2 // invalid arguments
+)
+
+// options holds the flags controlling how values are converted.
+type options struct {
+	input    string
+	output   string
+	pretty   bool
+	indent   string
+	sortKeys bool
+	query    *query.Query
+}
+
 func main() {
-	var r = bufio.NewReader(os.Stdin)
-	var w = bufio.NewWriter(os.Stdout)
-	var input string
-	var output string
+	var opts options
 	var list bool
-	var pretty bool
+	var inPlace bool
+	var descriptor string
+	var message string
+	var expr string
 
-	flag.StringVar(&input, "i", "json", "The format of the input stream")
-	flag.StringVar(&output, "o", "json", "The format of the output stream")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "validate":
+			os.Exit(validateMain(os.Args[2:]))
+		case "diff":
+			os.Exit(diffMain(os.Args[2:]))
+		}
+	}
+
+	flag.Usage = usage
+	flag.StringVar(&opts.input, "i", "", "The format of the input stream, inferred from the input file when omitted")
+	flag.StringVar(&opts.output, "o", "", "The format of the output stream, inferred from the output file when omitted")
 	flag.BoolVar(&list, "l", false, "Prints a list of all the formats available")
-	flag.BoolVar(&pretty, "p", false, "Prints in pretty format when available")
+	flag.BoolVar(&opts.pretty, "p", false, "Prints in pretty format when available")
+	flag.StringVar(&opts.indent, "indent", "", `The indentation of the output, a number of spaces or "tab", 0 for compact output`)
+	flag.BoolVar(&opts.sortKeys, "sort-keys", false, "Sorts the keys of maps in the output")
+	flag.StringVar(&expr, "q", "", "A query selecting the values to output, for example '.items[] | select(.id > 10)'")
+	flag.BoolVar(&inPlace, "w", false, "Converts the files given as arguments in place")
+	flag.StringVar(&descriptor, "descriptor", "", "The FileDescriptorSet file used by the protobuf format")
+	flag.StringVar(&message, "message", "", "The fully qualified name of the message type used by the protobuf format")
 	flag.Parse()
 
+	if descriptor != "" {
+		if err := registerProtobuf(descriptor, message); err != nil {
+			exit(exitUsage, err)
+		}
+	}
+
 	if list {
 		codecs(os.Stdout)
 		return
 	}
 
-	if err := conv(w, output, r, input, pretty); err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+	if expr != "" {
+		q, err := query.Parse(expr)
+		if err != nil {
+			exit(exitUsage, err)
+		}
+		opts.query = q
+	}
+
+	var err error
+	var args = flag.Args()
+
+	switch {
+	case inPlace:
+		if len(args) == 0 {
+			exit(exitUsage, errors.New("no files to convert in place"))
+		}
+		err = convFiles(args, opts)
+
+	case len(args) > 2:
+		exit(exitUsage, errors.New("too many arguments, use -w to convert multiple files"))
+
+	default:
+		var input, output string
+		if len(args) > 0 {
+			input = args[0]
+		}
+		if len(args) > 1 {
+			output = args[1]
+		}
+		err = convFile(output, input, opts)
+	}
+
+	if err != nil {
+		code := exitFailure
+		if _, ok := err.(usageError); ok {
+			code = exitUsage
+		}
+		exit(code, err)
 	}
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `Usage:
+  objconv [options] [input [output]]
+  objconv [options] -w files...
+  objconv validate -schema file [options] [files...]
+  objconv diff [options] a b
+
+The input and output default to stdin and stdout, or when "-" is given. Formats
+are inferred from the file extensions or the content of the input, and default
+to JSON.
+
+Options:
+`)
+	flag.PrintDefaults()
+}
 
-	w.Flush()
+func exit(code int, err error) {
+	fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	os.Exit(code)
+}
+
+// registerProtobuf registers the protobuf format for messages of the given
+// type, loaded from the descriptor set file at path.
+func registerProtobuf(path string, name string) error {
+	set, err := protobuf.LoadDescriptorSet(path)
+	if err != nil {
+		return err
+	}
+
+	msg := set.Message(name)
+	if msg == nil {
+		return fmt.Errorf("message type not found in %s: %q", path, name)
+	}
+
+	objconv.Register("protobuf", protobuf.NewCodec(msg))
+	return nil
 }
 
 func codecs(w io.Writer) {
 	var names []string
-	for name := range objconv.Codecs() {
+	var all = objconv.Codecs()
+	for name := range all {
 		names = append(names, name)
 	}
 	sort.Strings(names)
 	for _, name := range names {
 		fmt.Fprintf(w, "- %s\n", name)
+		for _, opt := range all[name].Options {
+			fmt.Fprintf(w, "    %s: %s\n", opt.Name, opt.Doc)
+		}
 	}
 	return
 }
 
-func conv(w io.Writer, output string, r io.Reader, input string, pretty bool) (err error) {
-	var ic objconv.Codec
-	var oc objconv.Codec
-	var ok bool
+// conv reads values from r and writes them to w. The name of the input is
+// used to report the position of errors.
+func conv(w io.Writer, oc objconv.Codec, r io.Reader, ic objconv.Codec, name string, opts options) (err error) {
+	var src = newSource(name, r, ic)
+	var lw = &lastByteWriter{w: w}
+	var m = oc.NewEmitter(lw)
 
-	if ic, ok = objconv.Lookup(input); !ok {
-		err = fmt.Errorf("unknown input format: %s", input)
-		return
+	if opts.pretty {
+		if p, ok := m.(objconv.PrettyEmitter); ok {
+			m = p.PrettyEmitter()
+		}
 	}
 
-	if oc, ok = objconv.Lookup(output); !ok {
-		err = fmt.Errorf("unknown output format: %s", output)
-		return
+	if opts.query != nil {
+		err = convQuery(m, src, opts)
+	} else {
+		err = convStream(m, src, opts)
 	}
 
-	var d = objconv.NewStreamDecoder(ic.NewParser(r))
-	var e *objconv.StreamEncoder
-	var v interface{}
-	var m = oc.NewEmitter(w)
+	if err != nil {
+		return
+	}
 
-	if pretty {
-		if p, ok := m.(objconv.PrettyEmitter); ok {
-			m = p.PrettyEmitter()
+	// Text outputs are terminated by a newline character to make them easier
+	// to read in terminals, compressed outputs are binary.
+	if t, ok := m.(interface{ TextEmitter() bool }); ok && t.TextEmitter() && !oc.Compressed {
+		if lw.n != 0 && lw.last != '\n' {
+			_, err = w.Write([]byte{'\n'})
 		}
 	}
 
+	return
+}
+
+// convStream converts the stream of values read from p, the elements of
+// top-level arrays are converted one by one.
+func convStream(m objconv.Emitter, src *source, opts options) (err error) {
+	var d = objconv.NewStreamDecoder(src.p)
+	var e *objconv.StreamEncoder
+	var v interface{}
+
 	if e, err = d.Encoder(m); err != nil {
 		if err == io.EOF { // empty input
-			err = nil
+			err = closeEmitter(m)
+		} else {
+			err = src.syntaxError(err)
 		}
 		return
 	}
@@ -131,22 +303,80 @@ func conv(w io.Writer, output string, r io.Reader, input string, pretty bool) (e
 	d.MapType = reflect.TypeOf(document(nil))
 
 	for d.Decode(&v) == nil {
+		if opts.sortKeys {
+			sortKeys(v)
+		}
 		if err = e.Encode(v); err != nil {
 			return
 		}
 		v = nil
 	}
 
-	if err = e.Close(); err != nil {
-		return
+	if err = d.Err(); err != nil {
+		return src.syntaxError(err)
 	}
 
-	// Not ideal but does the job, if the output is JSON we add a newline
-	// character at the end to make it easier to read in terminals.
-	if strings.Contains(output, "json") {
-		fmt.Fprintln(w)
+	return e.Close()
+}
+
+// convQuery applies the query of opts to each top-level value read from p. The
+// results are always output as a stream, which is an array in most formats,
+// so the shape of the output doesn't depend on the number of results.
+func convQuery(m objconv.Emitter, src *source, opts options) (err error) {
+	var e = objconv.NewStreamEncoder(m)
+
+	for {
+		var v interface{}
+		var d = objconv.NewDecoder(src.p)
+
+		d.MapType = reflect.TypeOf(document(nil))
+
+		// The end of the input is only expected between values.
+		if _, err = src.p.ParseType(); err == io.EOF {
+			break
+		}
+
+		if err == nil {
+			err = d.Decode(&v)
+		}
+
+		if err != nil {
+			return src.syntaxError(err)
+		}
+
+		if opts.sortKeys {
+			sortKeys(v)
+		}
+
+		if err = opts.query.Each(v, e.Encode); err != nil {
+			return fmt.Errorf("%s: %v", src.name, err)
+		}
+	}
+
+	return e.Close()
+}
+
+// closeEmitter closes m if it implements io.Closer, compressed outputs need to
+// be terminated for example.
+func closeEmitter(m objconv.Emitter) error {
+	if c, ok := m.(io.Closer); ok {
+		return c.Close()
 	}
+	return nil
+}
 
-	err = d.Err()
+// lastByteWriter remembers the last byte written to w.
+type lastByteWriter struct {
+	w    io.Writer
+	n    int64
+	last byte
+}
+
+func (w *lastByteWriter) Write(b []byte) (n int, err error) {
+	n, err = w.w.Write(b)
+	if n > 0 {
+		w.n += int64(n)
+		w.last = b[n-1]
+	}
 	return
 }
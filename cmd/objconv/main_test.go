@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/segmentio/objconv"
+)
+
+func TestConvCompressed(t *testing.T) {
+	for _, format := range []string{"json+gzip", "yaml+gzip", "tsv+gzip", "msgpack+gzip"} {
+		t.Run(format, func(t *testing.T) {
+			opts := options{output: format}
+			input := strings.NewReader(`{"a":1}`)
+
+			oc, err := outputCodec(&opts, "", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b := &bytes.Buffer{}
+
+			if err := conv(b, oc, input, lookupFormat("json"), "<stdin>", opts); err != nil {
+				t.Fatal(err)
+			}
+
+			// Nothing may be written after the end of the compressed stream.
+			z, err := gzip.NewReader(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			z.Multistream(false)
+
+			if _, err := io.ReadAll(z); err != nil {
+				t.Fatal(err)
+			}
+
+			if b.Len() != 0 {
+				t.Errorf("%d bytes written after the compressed stream: %q", b.Len(), b.Bytes())
+			}
+		})
+	}
+}
+
+func TestConvOffset(t *testing.T) {
+	for _, format := range []string{"msgpack", "cbor", "bson", "ubjson", "smile", "bencode"} {
+		t.Run(format, func(t *testing.T) {
+			ic := lookupFormat(format)
+			b := &bytes.Buffer{}
+
+			if err := objconv.NewEncoder(ic.NewEmitter(b)).Encode(map[string]string{"hello": "world"}); err != nil {
+				t.Fatal(err)
+			}
+
+			// Syntax errors of binary formats are reported at the offset
+			// where the parser stopped.
+			input := bytes.NewReader(b.Bytes()[:b.Len()-1])
+			err := conv(io.Discard, lookupFormat("json"), input, ic, "<stdin>", options{})
+
+			if err == nil || !strings.HasPrefix(err.Error(), "<stdin>: offset ") {
+				t.Errorf("expected an error with an offset, got %v", err)
+			}
+		})
+	}
+}
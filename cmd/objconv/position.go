@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/segmentio/objconv"
+)
+
+// lineReader records the offsets of the newline characters read from r, which
+// are used to convert offsets of the input to line and column numbers.
+type lineReader struct {
+	r     io.Reader
+	off   int64
+	lines []int64
+}
+
+func (r *lineReader) Read(b []byte) (n int, err error) {
+	n, err = r.r.Read(b)
+
+	for i, c := range b[:n] {
+		if c == '\n' {
+			r.lines = append(r.lines, r.off+int64(i))
+		}
+	}
+
+	r.off += int64(n)
+	return
+}
+
+// position returns the line and column of the byte at offset, both starting at
+// one.
+func (r *lineReader) position(offset int64) (line int, column int) {
+	line = sort.Search(len(r.lines), func(i int) bool { return r.lines[i] >= offset })
+	start := int64(0)
+
+	if line != 0 {
+		start = r.lines[line-1] + 1
+	}
+
+	return line + 1, int(offset-start) + 1
+}
+
+// source represents an input being parsed, it converts the offsets reported
+// by the parser to positions in the input.
+type source struct {
+	name  string
+	r     *lineReader
+	p     objconv.Parser
+	lines bool // true if offsets can be converted to lines and columns
+}
+
+// newSource returns the source parsing r with codec. Lines and columns are
+// reported for text formats when the offsets of the parser are offsets of the
+// bytes read from r, which isn't the case of compressed inputs.
+func newSource(name string, r io.Reader, codec objconv.Codec) *source {
+	s := &source{name: name, r: &lineReader{r: r}}
+	s.p = codec.NewParser(s.r)
+
+	if t, ok := s.p.(interface{ TextParser() bool }); ok && t.TextParser() {
+		s.lines = !codec.Compressed
+	}
+
+	return s
+}
+
+// offset returns the offset of the parser, or -1 if it's unknown.
+func (s *source) offset() int64 {
+	if p, ok := s.p.(objconv.OffsetParser); ok {
+		return p.Offset()
+	}
+	return -1
+}
+
+// position returns the position of the byte at offset, prefixed with the name
+// of the source.
+func (s *source) position(offset int64) string {
+	switch {
+	case offset < 0:
+		return s.name
+	case s.lines:
+		line, column := s.r.position(offset)
+		return fmt.Sprintf("%s:%d:%d", s.name, line, column)
+	default:
+		return fmt.Sprintf("%s: offset %d", s.name, offset)
+	}
+}
+
+// syntaxError returns err prefixed with the position where the parser
+// stopped.
+func (s *source) syntaxError(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("%s: %v", s.position(s.offset()), err)
+}
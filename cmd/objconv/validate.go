@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/segmentio/objconv/schema"
+)
+
+// validateMain runs the validate subcommand with args and returns the exit
+// code of the program.
+func validateMain(args []string) int {
+	var schemaPath string
+	var input string
+
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	fs.StringVar(&schemaPath, "schema", "", "The JSON Schema file to validate the inputs with, in any format")
+	fs.StringVar(&input, "i", "", "The format of the inputs, inferred from the input files when omitted")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), `Usage:
+  objconv validate -schema file [options] [files...]
+
+Validates the files against the schema, or the standard input when no files
+are given or for "-". Violations are reported with the path of the invalid
+values and their position when the format provides it.
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if schemaPath == "" {
+		fmt.Fprintln(os.Stderr, "error: missing schema file")
+		return exitUsage
+	}
+
+	s, err := loadSchema(schemaPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %v\n", schemaPath, err)
+		return exitUsage
+	}
+
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+
+	code := 0
+
+	for _, path := range paths {
+		n, err := validateFile(os.Stdout, s, path, input)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			if _, ok := err.(usageError); ok {
+				return exitUsage
+			}
+		}
+
+		if err != nil || n != 0 {
+			code = exitFailure
+		}
+	}
+
+	return code
+}
+
+// loadSchema loads the schema at path, its format is inferred from its
+// extension or content.
+func loadSchema(path string) (*schema.Schema, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReaderSize(f, sniffSize)
+
+	codec, _, err := inputCodec("", path, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return schema.Load(codec.NewParser(r))
+}
+
+// validateFile validates the values of the file at path with s, writing
+// violations to w. The number of violations is returned.
+func validateFile(w io.Writer, s *schema.Schema, path string, format string) (count int, err error) {
+	var r = bufio.NewReaderSize(os.Stdin, sniffSize)
+	var name = "<stdin>"
+
+	if path != "-" {
+		var f *os.File
+		if f, err = os.Open(path); err != nil {
+			return
+		}
+		defer f.Close()
+		r.Reset(f)
+		name = path
+	}
+
+	codec, _, err := inputCodec(format, path, r)
+	if err != nil {
+		return
+	}
+
+	src := newSource(name, r, codec)
+
+	for {
+		var violations []schema.Violation
+
+		// The end of the input is only expected between values.
+		if _, err = src.p.ParseType(); errors.Is(err, io.EOF) {
+			return count, nil
+		}
+
+		if err == nil {
+			violations, err = s.ValidateParser(src.p)
+		}
+
+		if err != nil {
+			return count, src.syntaxError(err)
+		}
+
+		for _, v := range violations {
+			fmt.Fprintf(w, "%s: %s\n", src.position(v.Offset), v)
+		}
+
+		count += len(violations)
+	}
+}
@@ -1,7 +1,12 @@
 package objconv
 
 import (
+	"bytes"
+	"fmt"
 	"io"
+	"mime"
+	"sort"
+	"strings"
 	"sync"
 )
 
@@ -9,6 +14,67 @@ import (
 type Codec struct {
 	NewEmitter func(io.Writer) Emitter
 	NewParser  func(io.Reader) Parser
+
+	// Extensions is the list of file extensions used by the format, with a
+	// leading dot (for example ".json").
+	Extensions []string
+
+	// Sniff returns the confidence, between 0 and 1, that b holds the leading
+	// bytes of a value encoded in the format of the codec. The field may be nil
+	// if the format cannot be detected from its content.
+	Sniff func(b []byte) float64
+
+	// Options is the list of options that the codec can be configured with.
+	Options []Option
+
+	// Configure returns a copy of codec configured with options, it is called
+	// by the With method which only passes options listed in Options. The
+	// field may be nil if the codec has no options.
+	Configure func(codec Codec, options map[string]string) (Codec, error)
+
+	// Compressed is true if the codec was combined with a compressor by
+	// Compress, its emitters and parsers produce and consume binary streams
+	// whatever the format of the codec.
+	Compressed bool
+}
+
+// Option describes an option supported by a codec.
+type Option struct {
+	// Name is the name of the option, as used in mimetype parameters.
+	Name string
+
+	// Doc is a short description of the option and the values it accepts.
+	Doc string
+}
+
+// With returns a copy of c configured with options, which are usually the
+// parameters of a mimetype (for example indent=2 in "application/json;
+// indent=2"). An error is returned if c doesn't support one of the options or
+// if one of their values is invalid.
+func (c Codec) With(options map[string]string) (Codec, error) {
+	if len(options) == 0 {
+		return c, nil
+	}
+
+	for name := range options {
+		if !c.hasOption(name) {
+			return c, fmt.Errorf("objconv: unsupported codec option %q", name)
+		}
+	}
+
+	return c.Configure(c, options)
+}
+
+func (c Codec) hasOption(name string) bool {
+	if c.Configure == nil {
+		return false
+	}
+	for _, opt := range c.Options {
+		if opt.Name == name {
+			return true
+		}
+	}
+	return false
 }
 
 // NewEncoder returns a new encoder that outputs to w.
@@ -33,10 +99,15 @@ func (c Codec) NewStreamDecoder(r io.Reader) *StreamDecoder {
 
 // A Registry associates mime types to codecs.
 //
+// Registries also hold compressors, which are combined with the codecs when
+// they are looked up with a mime type that has the name of a compressor as
+// suffix, for example "application/json+gzip".
+//
 // It is safe to use a registry concurrently from multiple goroutines.
 type Registry struct {
-	mutex  sync.RWMutex
-	codecs map[string]Codec
+	mutex       sync.RWMutex
+	codecs      map[string]Codec
+	compressors map[string]Compressor
 }
 
 // Register adds a codec for a mimetype to r.
@@ -59,15 +130,227 @@ func (reg *Registry) Unregister(mimetype string) {
 	delete(reg.codecs, mimetype)
 }
 
+// RegisterCompressor adds a compressor to r.
+func (reg *Registry) RegisterCompressor(name string, compressor Compressor) {
+	defer reg.mutex.Unlock()
+	reg.mutex.Lock()
+
+	if reg.compressors == nil {
+		reg.compressors = make(map[string]Compressor)
+	}
+
+	reg.compressors[name] = compressor
+}
+
+// UnregisterCompressor removes a compressor from r.
+func (reg *Registry) UnregisterCompressor(name string) {
+	defer reg.mutex.Unlock()
+	reg.mutex.Lock()
+
+	delete(reg.compressors, name)
+}
+
 // Lookup returns the codec associated with mimetype, ok is set to true or false
 // based on whether a codec was found.
+//
+// When the mimetype ends with "+" and the name of a compressor, the codec of
+// the prefix is combined with the compressor.
+//
+// The mimetype may have parameters, which are passed as options to the codec
+// (for example "application/json; indent=2"). ok is false if the parameters
+// are not supported by the codec, Resolve can be used to get the reason.
 func (reg *Registry) Lookup(mimetype string) (codec Codec, ok bool) {
 	reg.mutex.RLock()
-	codec, ok = reg.codecs[mimetype]
+	codec, ok = reg.lookup(mimetype)
 	reg.mutex.RUnlock()
+
+	if !ok && strings.IndexByte(mimetype, ';') >= 0 {
+		var err error
+		codec, err = reg.Resolve(mimetype)
+		ok = err == nil
+	}
+
 	return
 }
 
+// Resolve returns the codec associated with mimetype, configured with the
+// parameters of the mimetype. Unlike Lookup it returns an error explaining why
+// no codec could be returned.
+func (reg *Registry) Resolve(mimetype string) (codec Codec, err error) {
+	var name string
+	var params map[string]string
+	var ok bool
+
+	if name, params, err = mime.ParseMediaType(mimetype); err != nil {
+		err = fmt.Errorf("objconv: invalid mimetype %q: %s", mimetype, err)
+		return
+	}
+
+	reg.mutex.RLock()
+	codec, ok = reg.lookup(name)
+	reg.mutex.RUnlock()
+
+	if !ok {
+		err = fmt.Errorf("objconv: no codec registered for %q", name)
+		return
+	}
+
+	if codec, err = codec.With(params); err != nil {
+		err = fmt.Errorf("%s (mimetype %q)", err, mimetype)
+	}
+
+	return
+}
+
+// LookupExtension returns the codec for files with the extension ext, ok is set
+// to true or false based on whether a codec was found. The extension is case
+// insensitive and the leading dot may be omitted.
+//
+// Extensions made of the extension of a codec followed by the extension of a
+// compressor (like ".json.gz") return the combination of both.
+//
+// When the extension is declared by codecs registered under multiple
+// mimetypes, the codec of the first mimetype in lexicographical order is
+// returned.
+func (reg *Registry) LookupExtension(ext string) (codec Codec, ok bool) {
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+
+	reg.mutex.RLock()
+
+	if codec, ok = reg.lookupExtension(ext); !ok {
+		if i := strings.LastIndexByte(ext, '.'); i > 0 {
+			for _, name := range reg.compressorNames() {
+				c := reg.compressors[name]
+
+				if hasExtension(c.Extensions, ext[i:]) {
+					if codec, ok = reg.lookupExtension(ext[:i]); ok {
+						codec = Compress(codec, c)
+						break
+					}
+				}
+			}
+		}
+	}
+
+	reg.mutex.RUnlock()
+	return
+}
+
+// Sniff returns the codec that most likely decodes the content starting with
+// b, and the confidence of the detection between 0 and 1. The confidence is
+// zero if no codecs recognized the content.
+//
+// Only codecs and compressors with a Sniff function are considered. When b
+// looks compressed, the codecs are matched against the decompressed content
+// and the codec returned is combined with the compressor. When several codecs
+// are equally confident the codec of the first mimetype in lexicographical
+// order is returned.
+func (reg *Registry) Sniff(b []byte) (codec Codec, confidence float64) {
+	reg.mutex.RLock()
+
+	codec, confidence = reg.sniff(b)
+
+	for _, name := range reg.compressorNames() {
+		c := reg.compressors[name]
+
+		if c.Sniff == nil {
+			continue
+		}
+
+		if x := c.Sniff(b); x > confidence {
+			if d := decompressPrefix(c, b); len(d) != 0 {
+				if cc, y := reg.sniff(d); x*y > confidence {
+					codec, confidence = Compress(cc, c), x*y
+				}
+			}
+		}
+	}
+
+	reg.mutex.RUnlock()
+	return
+}
+
+func (reg *Registry) sniff(b []byte) (codec Codec, confidence float64) {
+	for _, mimetype := range reg.mimetypes() {
+		if c := reg.codecs[mimetype]; c.Sniff != nil {
+			if x := c.Sniff(b); x > confidence {
+				codec, confidence = c, x
+			}
+		}
+	}
+	return
+}
+
+// lookup returns the codec registered for mimetype, or the combination of a
+// codec and compressor, the caller must hold the mutex.
+func (reg *Registry) lookup(mimetype string) (codec Codec, ok bool) {
+	if codec, ok = reg.codecs[mimetype]; !ok {
+		if i := strings.LastIndexByte(mimetype, '+'); i > 0 {
+			if c, found := reg.compressors[mimetype[i+1:]]; found {
+				if codec, ok = reg.codecs[mimetype[:i]]; ok {
+					codec = Compress(codec, c)
+				}
+			}
+		}
+	}
+	return
+}
+
+// lookupExtension returns the codec declaring the extension ext, the caller
+// must hold the mutex.
+func (reg *Registry) lookupExtension(ext string) (codec Codec, ok bool) {
+	for _, mimetype := range reg.mimetypes() {
+		if c := reg.codecs[mimetype]; hasExtension(c.Extensions, ext) {
+			return c, true
+		}
+	}
+	return
+}
+
+func hasExtension(list []string, ext string) bool {
+	for _, x := range list {
+		if strings.EqualFold(x, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// decompressPrefix returns the decompressed content of b, which may be only
+// the prefix of a compressed stream.
+func decompressPrefix(c Compressor, b []byte) []byte {
+	r, err := c.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil
+	}
+	d, _ := io.ReadAll(io.LimitReader(r, 4096))
+	return d
+}
+
+// mimetypes returns the sorted list of mimetypes registered in reg, the caller
+// must hold the mutex.
+func (reg *Registry) mimetypes() []string {
+	list := make([]string, 0, len(reg.codecs))
+	for mimetype := range reg.codecs {
+		list = append(list, mimetype)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// compressorNames returns the sorted list of compressors registered in reg,
+// the caller must hold the mutex.
+func (reg *Registry) compressorNames() []string {
+	list := make([]string, 0, len(reg.compressors))
+	for name := range reg.compressors {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
 // Codecs returns a map of all codecs registered in reg.
 func (reg *Registry) Codecs() (codecs map[string]Codec) {
 	codecs = make(map[string]Codec)
@@ -79,14 +362,26 @@ func (reg *Registry) Codecs() (codecs map[string]Codec) {
 	return
 }
 
-// The global registry to which packages add their codecs.
-var registry Registry
+// The global registry to which packages add their codecs, it has compressors
+// for the gzip, zlib and deflate formats.
+var registry = Registry{
+	compressors: map[string]Compressor{
+		"gzip":    GzipCompressor,
+		"zlib":    ZlibCompressor,
+		"deflate": DeflateCompressor,
+	},
+}
 
 // Register adds a codec for a mimetype to the global registry.
 func Register(mimetype string, codec Codec) {
 	registry.Register(mimetype, codec)
 }
 
+// RegisterCompressor adds a compressor to the global registry.
+func RegisterCompressor(name string, compressor Compressor) {
+	registry.RegisterCompressor(name, compressor)
+}
+
 // Unregister removes the codec for a mimetype from the global registry.
 func Unregister(mimetype string) {
 	registry.Unregister(mimetype)
@@ -98,6 +393,25 @@ func Lookup(mimetype string) (Codec, bool) {
 	return registry.Lookup(mimetype)
 }
 
+// Resolve returns the codec of the global registry associated with mimetype,
+// configured with the parameters of the mimetype.
+func Resolve(mimetype string) (Codec, error) {
+	return registry.Resolve(mimetype)
+}
+
+// LookupExtension returns the codec of the global registry for files with the
+// extension ext, ok is set to true or false based on whether a codec was found.
+func LookupExtension(ext string) (Codec, bool) {
+	return registry.LookupExtension(ext)
+}
+
+// Sniff returns the codec of the global registry that most likely decodes the
+// content starting with b, and the confidence of the detection between 0 and
+// 1.
+func Sniff(b []byte) (Codec, float64) {
+	return registry.Sniff(b)
+}
+
 // Codecs returns a map of all codecs registered in the global registry.
 func Codecs() map[string]Codec {
 	return registry.Codecs()
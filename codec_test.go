@@ -0,0 +1,147 @@
+package objconv
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func testRegistry() *Registry {
+	reg := &Registry{}
+	reg.Register("application/a", Codec{
+		Extensions: []string{".a", ".AA"},
+		Sniff: func(b []byte) float64 {
+			if bytes.HasPrefix(b, []byte("a")) {
+				return 0.5
+			}
+			return 0
+		},
+	})
+	reg.Register("application/b", Codec{
+		Extensions: []string{".b"},
+		Sniff: func(b []byte) float64 {
+			switch {
+			case bytes.HasPrefix(b, []byte("ab")):
+				return 0.5
+			case bytes.HasPrefix(b, []byte("b")):
+				return 1
+			}
+			return 0
+		},
+	})
+	reg.Register("application/c", Codec{})
+	return reg
+}
+
+func TestRegistryLookupExtension(t *testing.T) {
+	tests := []struct {
+		ext     string
+		sniffed string
+		found   bool
+	}{
+		{ext: ".a", sniffed: "a", found: true},
+		{ext: "a", sniffed: "a", found: true},
+		{ext: ".aa", sniffed: "a", found: true},
+		{ext: ".B", sniffed: "b", found: true},
+		{ext: ".c", found: false},
+		{ext: "", found: false},
+	}
+
+	reg := testRegistry()
+
+	for _, test := range tests {
+		codec, ok := reg.LookupExtension(test.ext)
+
+		if ok != test.found {
+			t.Errorf("%q: expected found=%t but got %t", test.ext, test.found, ok)
+			continue
+		}
+
+		// The fake codecs are identified by the content they recognize.
+		if ok && codec.Sniff([]byte(test.sniffed)) == 0 {
+			t.Errorf("%q: the wrong codec was returned", test.ext)
+		}
+	}
+}
+
+func TestRegistrySniff(t *testing.T) {
+	tests := []struct {
+		b          string
+		sniffed    string
+		confidence float64
+	}{
+		{b: "a", sniffed: "a", confidence: 0.5},
+		{b: "b", sniffed: "b", confidence: 1},
+		{b: "ab", sniffed: "a", confidence: 0.5}, // ties go to application/a
+		{b: "c", confidence: 0},
+		{b: "", confidence: 0},
+	}
+
+	reg := testRegistry()
+
+	for _, test := range tests {
+		codec, confidence := reg.Sniff([]byte(test.b))
+
+		if confidence != test.confidence {
+			t.Errorf("%q: expected confidence %g but got %g", test.b, test.confidence, confidence)
+			continue
+		}
+
+		if confidence != 0 && codec.Sniff([]byte(test.sniffed)) == 0 {
+			t.Errorf("%q: the wrong codec was returned", test.b)
+		}
+	}
+}
+
+func testOptionsRegistry() *Registry {
+	reg := &Registry{}
+	reg.Register("application/a", Codec{
+		Options: []Option{{Name: "x", Doc: "any value"}},
+		Configure: func(c Codec, options map[string]string) (Codec, error) {
+			if options["x"] == "bad" {
+				return c, errors.New("bad value")
+			}
+			c.Extensions = []string{options["x"]}
+			return c, nil
+		},
+	})
+	reg.Register("application/b", Codec{})
+	return reg
+}
+
+func TestRegistryResolve(t *testing.T) {
+	tests := []struct {
+		mimetype string
+		ext      string
+		err      bool
+	}{
+		{mimetype: "application/a"},
+		{mimetype: "application/a; x=1", ext: "1"},
+		{mimetype: `Application/A; X="hello world"`, ext: "hello world"},
+		{mimetype: "application/a; x=bad", err: true},
+		{mimetype: "application/a; y=1", err: true},
+		{mimetype: "application/b"},
+		{mimetype: "application/b; x=1", err: true},
+		{mimetype: "application/c", err: true},
+		{mimetype: "application/a; x", err: true},
+	}
+
+	reg := testOptionsRegistry()
+
+	for _, test := range tests {
+		codec, err := reg.Resolve(test.mimetype)
+
+		if (err != nil) != test.err {
+			t.Errorf("%q: unexpected error: %v", test.mimetype, err)
+			continue
+		}
+
+		if _, ok := reg.Lookup(test.mimetype); ok == test.err {
+			t.Errorf("%q: Lookup and Resolve disagree", test.mimetype)
+		}
+
+		if err == nil && test.ext != "" && (len(codec.Extensions) != 1 || codec.Extensions[0] != test.ext) {
+			t.Errorf("%q: the codec was not configured: %q", test.mimetype, codec.Extensions)
+		}
+	}
+}
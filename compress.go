@@ -0,0 +1,293 @@
+package objconv
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"time"
+)
+
+// A Compressor is a factory for writers and readers that compress and
+// decompress byte streams. Compressors are combined with codecs to encode and
+// decode compressed streams of values.
+type Compressor struct {
+	NewWriter func(io.Writer) io.WriteCloser
+	NewReader func(io.Reader) (io.Reader, error)
+
+	// Extensions is the list of file extensions used by the compression
+	// format, with a leading dot (for example ".gz").
+	Extensions []string
+
+	// Sniff returns the confidence, between 0 and 1, that b holds the leading
+	// bytes of a stream compressed with the compressor. The field may be nil
+	// if the format cannot be detected from its content.
+	Sniff func(b []byte) float64
+}
+
+// Compress returns a codec which encodes and decodes values with codec in
+// streams compressed with c.
+//
+// The emitters of the returned codec implement io.Closer, the compressed
+// stream is complete only after they were closed. Stream encoders close their
+// emitter when they are closed. When used with an Encoder, the emitter closes
+// itself after the first top-level value was written, and returns an error if
+// more values are written, a StreamEncoder must be used to encode multiple
+// values.
+func Compress(codec Codec, c Compressor) Codec {
+	compressed := Codec{
+		NewEmitter: func(w io.Writer) Emitter {
+			z := c.NewWriter(w)
+			return &compressEmitter{Emitter: codec.NewEmitter(z), w: z}
+		},
+		NewParser: func(r io.Reader) Parser {
+			return codec.NewParser(&decompressReader{r: r, newReader: c.NewReader})
+		},
+		Options:    codec.Options,
+		Compressed: true,
+	}
+
+	for _, x := range codec.Extensions {
+		for _, y := range c.Extensions {
+			compressed.Extensions = append(compressed.Extensions, x+y)
+		}
+	}
+
+	if codec.Configure != nil {
+		compressed.Configure = func(_ Codec, options map[string]string) (Codec, error) {
+			configured, err := codec.With(options)
+			if err != nil {
+				return configured, err
+			}
+			return Compress(configured, c), nil
+		}
+	}
+
+	return compressed
+}
+
+// compressEmitter wraps the emitter of a codec writing to a compressor, it
+// forwards the optional interfaces of the emitter.
+//
+// Unless it is used by a stream encoder, the emitter closes itself after the
+// first top-level value, since compressed streams must be terminated and an
+// Encoder has no way of knowing when the last value was written.
+type compressEmitter struct {
+	Emitter
+	w      io.WriteCloser
+	depth  int  // nesting level of arrays and maps
+	stream bool // set when used by a stream encoder
+	closed bool
+}
+
+var errCompressClosed = errors.New("objconv: value written to a closed compressed emitter, a StreamEncoder must be used to encode multiple values")
+
+func (e *compressEmitter) EmitNil() error {
+	if e.closed {
+		return errCompressClosed
+	}
+	return e.value(e.Emitter.EmitNil())
+}
+
+func (e *compressEmitter) EmitBool(v bool) error {
+	if e.closed {
+		return errCompressClosed
+	}
+	return e.value(e.Emitter.EmitBool(v))
+}
+
+func (e *compressEmitter) EmitInt(v int64, bitSize int) error {
+	if e.closed {
+		return errCompressClosed
+	}
+	return e.value(e.Emitter.EmitInt(v, bitSize))
+}
+
+func (e *compressEmitter) EmitUint(v uint64, bitSize int) error {
+	if e.closed {
+		return errCompressClosed
+	}
+	return e.value(e.Emitter.EmitUint(v, bitSize))
+}
+
+func (e *compressEmitter) EmitFloat(v float64, bitSize int) error {
+	if e.closed {
+		return errCompressClosed
+	}
+	return e.value(e.Emitter.EmitFloat(v, bitSize))
+}
+
+func (e *compressEmitter) EmitString(v string) error {
+	if e.closed {
+		return errCompressClosed
+	}
+	return e.value(e.Emitter.EmitString(v))
+}
+
+func (e *compressEmitter) EmitBytes(v []byte) error {
+	if e.closed {
+		return errCompressClosed
+	}
+	return e.value(e.Emitter.EmitBytes(v))
+}
+
+func (e *compressEmitter) EmitTime(v time.Time) error {
+	if e.closed {
+		return errCompressClosed
+	}
+	return e.value(e.Emitter.EmitTime(v))
+}
+
+func (e *compressEmitter) EmitDuration(v time.Duration) error {
+	if e.closed {
+		return errCompressClosed
+	}
+	return e.value(e.Emitter.EmitDuration(v))
+}
+
+func (e *compressEmitter) EmitError(v error) error {
+	if e.closed {
+		return errCompressClosed
+	}
+	return e.value(e.Emitter.EmitError(v))
+}
+
+func (e *compressEmitter) EmitArrayBegin(n int) (err error) {
+	if e.closed {
+		return errCompressClosed
+	}
+	if err = e.Emitter.EmitArrayBegin(n); err == nil {
+		e.depth++
+	}
+	return
+}
+
+func (e *compressEmitter) EmitArrayEnd() error {
+	e.depth--
+	return e.value(e.Emitter.EmitArrayEnd())
+}
+
+func (e *compressEmitter) EmitMapBegin(n int) (err error) {
+	if e.closed {
+		return errCompressClosed
+	}
+	if err = e.Emitter.EmitMapBegin(n); err == nil {
+		e.depth++
+	}
+	return
+}
+
+func (e *compressEmitter) EmitMapEnd() error {
+	e.depth--
+	return e.value(e.Emitter.EmitMapEnd())
+}
+
+// value is called after a value was emitted, the emitter is closed when it was
+// a complete top-level value written by an Encoder.
+func (e *compressEmitter) value(err error) error {
+	if err == nil && e.depth == 0 && !e.stream {
+		err = e.Close()
+	}
+	return err
+}
+
+// openStream is called by stream encoders, which close the emitter when the
+// stream ends.
+func (e *compressEmitter) openStream() {
+	e.stream = true
+}
+
+func (e *compressEmitter) Close() (err error) {
+	if e.closed {
+		return
+	}
+	e.closed = true
+	if c, ok := e.Emitter.(io.Closer); ok {
+		err = c.Close()
+	}
+	if cerr := e.w.Close(); err == nil {
+		err = cerr
+	}
+	return
+}
+
+func (e *compressEmitter) TextEmitter() bool {
+	return isTextEmitter(e.Emitter)
+}
+
+func (e *compressEmitter) SequenceEmitter() bool {
+	return isSequenceEmitter(e.Emitter)
+}
+
+func (e *compressEmitter) SortedMapEmitter() bool {
+	return isSortedMapEmitter(e.Emitter)
+}
+
+func (e *compressEmitter) AttrEmitter() bool {
+	return isAttrEmitter(e.Emitter)
+}
+
+func (e *compressEmitter) PrettyEmitter() Emitter {
+	if p, ok := e.Emitter.(PrettyEmitter); ok {
+		return &compressEmitter{Emitter: p.PrettyEmitter(), w: e.w, depth: e.depth, stream: e.stream, closed: e.closed}
+	}
+	return e
+}
+
+// decompressReader creates the decompressor on the first call to Read since
+// decompressors may read the stream header when they are created, which may
+// fail or block.
+type decompressReader struct {
+	r         io.Reader
+	z         io.Reader
+	err       error
+	newReader func(io.Reader) (io.Reader, error)
+}
+
+func (d *decompressReader) Read(b []byte) (int, error) {
+	if d.z == nil && d.err == nil {
+		d.z, d.err = d.newReader(d.r)
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	return d.z.Read(b)
+}
+
+// GzipCompressor for the gzip format (RFC 1952).
+var GzipCompressor = Compressor{
+	NewWriter:  func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+	NewReader:  func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+	Extensions: []string{".gz"},
+	Sniff: func(b []byte) float64 {
+		if len(b) >= 3 && b[0] == 0x1F && b[1] == 0x8B && b[2] == 8 {
+			return 1
+		}
+		return 0
+	},
+}
+
+// ZlibCompressor for the zlib format (RFC 1950).
+var ZlibCompressor = Compressor{
+	NewWriter:  func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+	NewReader:  func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) },
+	Extensions: []string{".zz"},
+	Sniff: func(b []byte) float64 {
+		// The header is a deflate method and window size, with a checksum.
+		if len(b) >= 2 && b[0] == 0x78 && (uint(b[0])<<8|uint(b[1]))%31 == 0 {
+			return 0.8
+		}
+		return 0
+	},
+}
+
+// DeflateCompressor for the raw deflate format (RFC 1951).
+var DeflateCompressor = Compressor{
+	NewWriter: func(w io.Writer) io.WriteCloser {
+		z, _ := flate.NewWriter(w, flate.DefaultCompression)
+		return z
+	},
+	NewReader:  func(r io.Reader) (io.Reader, error) { return flate.NewReader(r), nil },
+	Extensions: []string{".deflate"},
+}
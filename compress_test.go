@@ -0,0 +1,113 @@
+package objconv
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+)
+
+// stringEmitter writes the strings it receives to w, other values are
+// discarded.
+type stringEmitter struct {
+	ValueEmitter
+	w io.Writer
+}
+
+func (e *stringEmitter) EmitString(v string) error {
+	_, err := io.WriteString(e.w, v)
+	return err
+}
+
+func testCompressRegistry() *Registry {
+	reg := testRegistry()
+	reg.RegisterCompressor("gzip", GzipCompressor)
+	reg.Register("text/plain", Codec{
+		NewEmitter: func(w io.Writer) Emitter { return &stringEmitter{w: w} },
+		Extensions: []string{".txt"},
+	})
+	return reg
+}
+
+func TestRegistryLookupCompressed(t *testing.T) {
+	reg := testCompressRegistry()
+
+	if _, ok := reg.Lookup("application/a+gzip"); !ok {
+		t.Error("application/a+gzip: codec not found")
+	}
+
+	for _, name := range []string{"application/a+zlib", "application/d+gzip", "+gzip"} {
+		if _, ok := reg.Lookup(name); ok {
+			t.Errorf("%s: unexpected codec found", name)
+		}
+	}
+
+	codec, ok := reg.LookupExtension(".a.GZ")
+	if !ok {
+		t.Fatal(".a.GZ: codec not found")
+	}
+	if !hasExtension(codec.Extensions, ".aa.gz") {
+		t.Errorf(".a.GZ: bad extensions: %q", codec.Extensions)
+	}
+
+	for _, ext := range []string{".gz", ".a.zz", ".c.gz"} {
+		if _, ok := reg.LookupExtension(ext); ok {
+			t.Errorf("%s: unexpected codec found", ext)
+		}
+	}
+}
+
+func TestRegistrySniffCompressed(t *testing.T) {
+	reg := testCompressRegistry()
+
+	b := &bytes.Buffer{}
+	z := gzip.NewWriter(b)
+	z.Write([]byte("b"))
+	z.Close()
+
+	codec, confidence := reg.Sniff(b.Bytes())
+
+	if confidence != 1 {
+		t.Errorf("bad confidence: %g", confidence)
+	}
+	if !hasExtension(codec.Extensions, ".b.gz") {
+		t.Errorf("bad codec extensions: %q", codec.Extensions)
+	}
+
+	// Truncated streams are still detected.
+	if _, confidence := reg.Sniff(b.Bytes()[:b.Len()-4]); confidence != 1 {
+		t.Errorf("bad confidence of truncated stream: %g", confidence)
+	}
+}
+
+func TestStreamEncoderCloseCompressed(t *testing.T) {
+	reg := testCompressRegistry()
+	codec, _ := reg.Lookup("text/plain+gzip")
+
+	b := &bytes.Buffer{}
+	e := codec.NewStreamEncoder(b)
+
+	for _, s := range []string{"Hello", " ", "World!"} {
+		if err := e.Encode(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	z, err := gzip.NewReader(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := io.ReadAll(z)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(s) != "Hello World!" {
+		t.Errorf("bad decompressed content: %q", s)
+	}
+}
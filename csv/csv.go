@@ -0,0 +1,44 @@
+package csv
+
+import "unicode/utf8"
+
+const (
+	// DefaultComma is the delimiter used when none is configured on a parser
+	// or emitter.
+	DefaultComma = ','
+
+	// DefaultQuote is the quote character used when none is configured on a
+	// parser or emitter.
+	DefaultQuote = '"'
+)
+
+func runeString(r rune, def rune) string {
+	if r == 0 {
+		r = def
+	}
+	var b [utf8.UTFMax]byte
+	return string(b[:utf8.EncodeRune(b[:], r)])
+}
+
+// row is the in-memory representation of maps, it retains the order in which
+// keys were defined.
+type row struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newRow() *row {
+	return &row{values: make(map[string]interface{})}
+}
+
+func (r *row) get(k string) (v interface{}, ok bool) {
+	v, ok = r.values[k]
+	return
+}
+
+func (r *row) set(k string, v interface{}) {
+	if _, exists := r.values[k]; !exists {
+		r.keys = append(r.keys, k)
+	}
+	r.values[k] = v
+}
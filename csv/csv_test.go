@@ -0,0 +1,371 @@
+package csv
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/objconv"
+)
+
+type person struct {
+	Name    string    `objconv:"name"`
+	Age     int       `objconv:"age"`
+	Admin   bool      `objconv:"admin"`
+	Score   float64   `objconv:"score"`
+	Zip     string    `objconv:"zip"`
+	Created time.Time `objconv:"created"`
+}
+
+var people = []person{
+	{"Alice", 42, true, 1.5, "01234", time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC)},
+	{"Bob, Jr.", 7, false, 0, "", time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC)},
+	{"\"Eve\"\nthe spy", -1, false, 1e21, "42", time.Date(2017, 1, 2, 3, 4, 5, 6, time.UTC)},
+}
+
+const peopleCSV = `name,age,admin,score,zip,created
+Alice,42,true,1.5,01234,2017-01-02T03:04:05Z
+"Bob, Jr.",7,false,0,"",2017-01-02T03:04:05Z
+"""Eve""
+the spy",-1,false,1e+21,"42",2017-01-02T03:04:05.000000006Z
+`
+
+func TestStreamEncoder(t *testing.T) {
+	b := &bytes.Buffer{}
+	e := NewStreamEncoder(b)
+
+	for _, p := range people {
+		if err := e.Encode(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := b.String(); s != peopleCSV {
+		t.Error(s)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	b, err := Marshal(people)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := string(b); s != peopleCSV {
+		t.Error(s)
+	}
+}
+
+func TestMarshalMapHeader(t *testing.T) {
+	rows := []map[string]interface{}{
+		{"d": 4, "b": 2, "e": 5, "a": 1, "c": 3},
+		{"c": 8, "e": 10, "a": 6, "d": 9, "b": 7},
+	}
+
+	// The columns of headers built from Go maps are sorted, they must not
+	// depend on the iteration order of the maps.
+	for i := 0; i != 20; i++ {
+		b, err := Marshal(rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s := string(b); s != "a,b,c,d,e\n1,2,3,4,5\n6,7,8,9,10\n" {
+			t.Fatalf("bad output: %q", s)
+		}
+	}
+}
+
+func TestStreamDecoder(t *testing.T) {
+	d := NewStreamDecoder(strings.NewReader(peopleCSV))
+
+	for i := 0; ; i++ {
+		var p person
+
+		if err := d.Decode(&p); err != nil {
+			if err != objconv.End {
+				t.Fatal(err)
+			}
+			if i != len(people) {
+				t.Error("bad number of rows:", i)
+			}
+			break
+		}
+
+		if !reflect.DeepEqual(p, people[i]) {
+			t.Errorf("%#v", p)
+		}
+	}
+
+	if err := d.Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStreamDecoderMaps(t *testing.T) {
+	d := NewStreamDecoder(strings.NewReader("a,b,c\r\n1,x,\r\n\r\n2.5,\"\",true"))
+
+	var rows []map[string]interface{}
+
+	for {
+		var m map[string]interface{}
+		if d.Decode(&m) != nil {
+			break
+		}
+		rows = append(rows, m)
+	}
+
+	if err := d.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(rows, []map[string]interface{}{
+		{"a": int64(1), "b": "x", "c": nil},
+		{"a": 2.5, "b": "", "c": true},
+	}) {
+		t.Errorf("%#v", rows)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(*Emitter, *Parser)
+		rows  []interface{}
+		s     string
+		out   []interface{} // decoded rows, same as rows when nil
+	}{
+		{
+			name: "tab",
+			setup: func(e *Emitter, p *Parser) {
+				e.Comma, p.Comma = '\t', '\t'
+			},
+			rows: []interface{}{
+				map[string]interface{}{"k": "a,b"},
+				map[string]interface{}{"k": "c\td"},
+			},
+			s: "k\na,b\n\"c\td\"\n",
+		},
+		{
+			name: "quote",
+			setup: func(e *Emitter, p *Parser) {
+				e.Quote, p.Quote = '\'', '\''
+			},
+			rows: []interface{}{
+				map[string]interface{}{"k": "it's"},
+				map[string]interface{}{"k": "\"1\""},
+			},
+			s: "k\n'it''s'\n\"1\"\n",
+		},
+		{
+			name: "no-header",
+			setup: func(e *Emitter, p *Parser) {
+				e.NoHeader, p.NoHeader = true, true
+			},
+			rows: []interface{}{
+				[]interface{}{int64(1), "a"},
+				[]interface{}{int64(2), "b"},
+			},
+			s: "1,a\n2,b\n",
+		},
+		{
+			name: "flatten",
+			setup: func(e *Emitter, p *Parser) {
+				e.Flatten, p.Flatten = true, true
+			},
+			rows: []interface{}{
+				flatRow{ID: 1, Pos: point{1, 2}, Tags: []string{"a", "b"}},
+			},
+			s: "id,pos.x,pos.y,tags.0,tags.1\n1,1,2,a,b\n",
+			out: []interface{}{
+				map[string]interface{}{
+					"id":   int64(1),
+					"pos":  map[interface{}]interface{}{"x": int64(1), "y": int64(2)},
+					"tags": []interface{}{"a", "b"},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := &bytes.Buffer{}
+			e := NewEmitter(b)
+			p := NewParser(b)
+			test.setup(e, p)
+
+			enc := objconv.NewStreamEncoder(e)
+
+			for _, r := range test.rows {
+				if err := enc.Encode(r); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if s := b.String(); s != test.s {
+				t.Errorf("%q", s)
+			}
+
+			dec := objconv.NewStreamDecoder(p)
+
+			out := test.out
+			if out == nil {
+				out = test.rows
+			}
+
+			for _, r := range out {
+				var v interface{}
+
+				if err := dec.Decode(&v); err != nil {
+					t.Fatal(err)
+				}
+
+				if m, ok := r.(map[string]interface{}); ok {
+					r = toInterfaceMap(m)
+				}
+
+				if !reflect.DeepEqual(v, r) {
+					t.Errorf("%#v", v)
+				}
+			}
+
+			if err := dec.Decode(nil); err != objconv.End {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+type flatRow struct {
+	ID   int      `objconv:"id"`
+	Pos  point    `objconv:"pos"`
+	Tags []string `objconv:"tags"`
+}
+
+type point struct {
+	X int `objconv:"x"`
+	Y int `objconv:"y"`
+}
+
+func toInterfaceMap(m map[string]interface{}) map[interface{}]interface{} {
+	r := make(map[interface{}]interface{}, len(m))
+	for k, v := range m {
+		r[k] = v
+	}
+	return r
+}
+
+func TestEmitterErrors(t *testing.T) {
+	for _, rows := range [][]interface{}{
+		{map[string]interface{}{"a": []int{1}}},
+		{map[string]int{"a": 1}, map[string]int{"b": 2}},
+	} {
+		e := NewStreamEncoder(&bytes.Buffer{})
+		var err error
+
+		for _, r := range rows {
+			if err = e.Encode(r); err != nil {
+				break
+			}
+		}
+
+		if err == nil {
+			t.Errorf("encoding %#v should have failed", rows)
+		}
+	}
+}
+
+func TestParserErrors(t *testing.T) {
+	for _, s := range []string{
+		"a,b\n1\n",
+		"a\n\"1\n",
+		"a\n\"1\"2\n",
+	} {
+		d := NewStreamDecoder(strings.NewReader(s))
+		var v interface{}
+		var err error
+
+		for err == nil {
+			err = d.Decode(&v)
+		}
+
+		if err == objconv.End || err == io.EOF || !strings.HasPrefix(err.Error(), "objconv/csv: line ") {
+			t.Errorf("%q: %v", s, err)
+		}
+	}
+}
+
+func TestCodecOptions(t *testing.T) {
+	codec, err := Codec.With(map[string]string{"delimiter": ";"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &bytes.Buffer{}
+	rows := []struct {
+		A string `objconv:"a"`
+		B int64  `objconv:"b"`
+	}{{A: "1,5", B: 2}}
+
+	if err := codec.NewEncoder(b).Encode(rows); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := b.String(); s != "a;b\n1,5;2\n" {
+		t.Errorf("bad output: %q", s)
+	}
+
+	d := codec.NewStreamDecoder(b)
+	var out []map[string]interface{}
+
+	for {
+		var row map[string]interface{}
+		if d.Decode(&row) != nil {
+			break
+		}
+		out = append(out, row)
+	}
+
+	if err := d.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp := []map[string]interface{}{{"a": "1,5", "b": int64(2)}}; !reflect.DeepEqual(exp, out) {
+		t.Errorf("%#v != %#v", exp, out)
+	}
+
+	for _, v := range []string{"", ";;", `"`, "\n"} {
+		if _, err := Codec.With(map[string]string{"delimiter": v}); err == nil {
+			t.Errorf("%q: expected an error", v)
+		}
+	}
+}
+
+func TestTSVCodec(t *testing.T) {
+	codec, ok := objconv.LookupExtension(".tsv")
+	if !ok {
+		t.Fatal(".tsv: codec not found")
+	}
+
+	b := &bytes.Buffer{}
+
+	if err := codec.NewEncoder(b).Encode([]interface{}{"a b", 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := b.String(); s != "a b\t1\n" {
+		t.Errorf("bad output: %q", s)
+	}
+
+	if _, err := TSVCodec.With(map[string]string{"delimiter": "|"}); err != nil {
+		t.Error(err)
+	}
+}
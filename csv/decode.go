@@ -0,0 +1,23 @@
+package csv
+
+import (
+	"io"
+
+	"github.com/segmentio/objconv"
+)
+
+// NewDecoder returns a new CSV decoder that parses values from r.
+//
+// The decoder only parses the first row of the input, use a stream decoder to
+// read all rows.
+func NewDecoder(r io.Reader) *objconv.Decoder {
+	return objconv.NewDecoder(NewParser(r))
+}
+
+// NewStreamDecoder returns a new CSV stream decoder that parses values from r.
+//
+// Each value decoded from the stream is a row, which can be decoded into a map
+// keyed by the names of the header row or into a struct.
+func NewStreamDecoder(r io.Reader) *objconv.StreamDecoder {
+	return objconv.NewStreamDecoder(NewParser(r))
+}
@@ -0,0 +1,445 @@
+package csv
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/objconv/objutil"
+)
+
+// Emitter implements a CSV emitter that satisfies the objconv.Emitter
+// interface.
+//
+// Each map emitted at the top level is written as a row, the header row is
+// written before the first one and is made of its keys. Arrays of maps are
+// written as multiple rows, other arrays are written as a single row.
+//
+// Strings that would otherwise be parsed as other types (like "42" or "")
+// are quoted, nil values are written as empty cells.
+type Emitter struct {
+	// Comma is the field delimiter, it defaults to DefaultComma when zero.
+	Comma rune
+
+	// Quote is the character used to quote fields, it defaults to
+	// DefaultQuote when zero.
+	Quote rune
+
+	// NoHeader disables writing the header row.
+	NoHeader bool
+
+	// Flatten enables writing nested maps and arrays as multiple columns named
+	// by joining the keys and indexes with dots. By default the emitter returns
+	// an error when a row contains nested values.
+	Flatten bool
+
+	w      io.Writer
+	b      []byte
+	n      int // number of rows written
+	header []string
+	index  map[string]int
+	cells  []interface{}
+	// The stack is used to keep track of the container being built by the
+	// emitter, which may be an arrayEmitter or rowEmitter.
+	stack []emitter
+}
+
+func NewEmitter(w io.Writer) *Emitter {
+	return &Emitter{w: w}
+}
+
+// NewTSVEmitter returns a new emitter which writes tab-separated values to w.
+func NewTSVEmitter(w io.Writer) *Emitter {
+	return &Emitter{Comma: '\t', w: w}
+}
+
+func (e *Emitter) Reset(w io.Writer) {
+	e.w = w
+	e.n = 0
+	e.header = nil
+	e.index = nil
+	e.stack = e.stack[:0]
+}
+
+// SequenceEmitter returns true, CSV documents are sequences of rows.
+func (e *Emitter) SequenceEmitter() bool {
+	return true
+}
+
+func (e *Emitter) EmitNil() error {
+	return e.emit(nil)
+}
+
+func (e *Emitter) EmitBool(v bool) error {
+	return e.emit(v)
+}
+
+func (e *Emitter) EmitInt(v int64, _ int) error {
+	return e.emit(v)
+}
+
+func (e *Emitter) EmitUint(v uint64, _ int) error {
+	return e.emit(v)
+}
+
+func (e *Emitter) EmitFloat(v float64, bitSize int) error {
+	if bitSize == 32 {
+		return e.emit(float32(v))
+	}
+	return e.emit(v)
+}
+
+func (e *Emitter) EmitString(v string) error {
+	return e.emit(v)
+}
+
+func (e *Emitter) EmitBytes(v []byte) error {
+	return e.emit(base64.StdEncoding.EncodeToString(v))
+}
+
+func (e *Emitter) EmitTime(v time.Time) error {
+	return e.emit(v.Format(time.RFC3339Nano))
+}
+
+func (e *Emitter) EmitDuration(v time.Duration) error {
+	return e.emit(v.String())
+}
+
+func (e *Emitter) EmitError(v error) error {
+	return e.emit(v.Error())
+}
+
+func (e *Emitter) EmitArrayBegin(_ int) (err error) {
+	e.push(&arrayEmitter{self: []interface{}{}})
+	return
+}
+
+func (e *Emitter) EmitArrayEnd() (err error) {
+	return e.emit(e.pop().value())
+}
+
+func (e *Emitter) EmitArrayNext() (err error) {
+	return
+}
+
+func (e *Emitter) EmitMapBegin(_ int) (err error) {
+	e.push(&rowEmitter{self: newRow()})
+	return
+}
+
+func (e *Emitter) EmitMapEnd() (err error) {
+	return e.emit(e.pop().value())
+}
+
+func (e *Emitter) EmitMapValue() (err error) {
+	return
+}
+
+func (e *Emitter) EmitMapNext() (err error) {
+	return
+}
+
+func (e *Emitter) TextEmitter() bool {
+	return true
+}
+
+// SortedMapEmitter returns true, the header columns built from Go maps are
+// sorted so they don't depend on the iteration order of the maps.
+func (e *Emitter) SortedMapEmitter() bool {
+	return true
+}
+
+func (e *Emitter) emit(v interface{}) error {
+	if n := len(e.stack); n != 0 {
+		return e.stack[n-1].emit(v)
+	}
+
+	if a, ok := v.([]interface{}); ok && isListOfRows(a) {
+		for _, r := range a {
+			if err := e.writeRow(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return e.writeRow(v)
+}
+
+func (e *Emitter) writeRow(v interface{}) (err error) {
+	var cells []interface{}
+
+	switch x := v.(type) {
+	case *row:
+		if cells, err = e.mapCells(x); err != nil {
+			return
+		}
+
+	case []interface{}:
+		cells = e.cells[:0]
+
+		for i, c := range x {
+			if isNested(c) {
+				if !e.Flatten {
+					return errNested
+				}
+				if cells, _, err = e.flatten(cells, nil, strconv.Itoa(i), c); err != nil {
+					return
+				}
+			} else {
+				cells = append(cells, c)
+			}
+		}
+
+	default:
+		cells = append(e.cells[:0], v)
+	}
+
+	e.cells = cells
+	e.b = e.b[:0]
+
+	if e.n == 0 && !e.NoHeader && e.header != nil {
+		for i, k := range e.header {
+			e.b = e.appendCell(e.b, i, k, true)
+		}
+		e.b = append(e.b, '\n')
+	}
+
+	for i, c := range cells {
+		e.b = e.appendCell(e.b, i, c, false)
+	}
+
+	e.b = append(e.b, '\n')
+	e.n++
+
+	_, err = e.w.Write(e.b)
+	return
+}
+
+// mapCells returns the list of cells for a row represented by a map, the
+// header is initialized by the first row.
+func (e *Emitter) mapCells(r *row) (cells []interface{}, err error) {
+	var keys []string
+
+	for _, k := range r.keys {
+		v := r.values[k]
+
+		if isNested(v) {
+			if !e.Flatten {
+				return nil, errNested
+			}
+			if cells, keys, err = e.flatten(cells, keys, k, v); err != nil {
+				return
+			}
+		} else {
+			cells = append(cells, v)
+			keys = append(keys, k)
+		}
+	}
+
+	if e.header == nil {
+		e.header = keys
+		e.index = make(map[string]int, len(keys))
+
+		for i, k := range keys {
+			if _, exists := e.index[k]; exists {
+				return nil, fmt.Errorf("objconv/csv: column %q appears more than once in the header", k)
+			}
+			e.index[k] = i
+		}
+
+		return
+	}
+
+	// Rows after the first one may have keys in a different order, or may be
+	// missing some of them.
+	ordered := make([]interface{}, len(e.header))
+
+	for i, k := range keys {
+		j, ok := e.index[k]
+		if !ok {
+			return nil, fmt.Errorf("objconv/csv: column %q of row %d is not in the header", k, e.n+1)
+		}
+		ordered[j] = cells[i]
+	}
+
+	return ordered, nil
+}
+
+// flatten appends the cells of the nested value v to cells, and their column
+// names to keys.
+func (e *Emitter) flatten(cells []interface{}, keys []string, prefix string, v interface{}) ([]interface{}, []string, error) {
+	var err error
+
+	switch x := v.(type) {
+	case *row:
+		for _, k := range x.keys {
+			if cells, keys, err = e.flatten(cells, keys, prefix+"."+k, x.values[k]); err != nil {
+				break
+			}
+		}
+
+	case []interface{}:
+		for i, c := range x {
+			if cells, keys, err = e.flatten(cells, keys, prefix+"."+strconv.Itoa(i), c); err != nil {
+				break
+			}
+		}
+
+	default:
+		cells = append(cells, v)
+		keys = append(keys, prefix)
+	}
+
+	return cells, keys, err
+}
+
+func (e *Emitter) appendCell(b []byte, i int, v interface{}, header bool) []byte {
+	if i != 0 {
+		b = append(b, runeString(e.Comma, DefaultComma)...)
+	}
+
+	switch x := v.(type) {
+	case nil:
+	case bool:
+		b = strconv.AppendBool(b, x)
+	case int64:
+		b = strconv.AppendInt(b, x, 10)
+	case uint64:
+		b = strconv.AppendUint(b, x, 10)
+	case float32:
+		b = strconv.AppendFloat(b, float64(x), 'g', -1, 32)
+	case float64:
+		b = strconv.AppendFloat(b, x, 'g', -1, 64)
+	case string:
+		b = e.appendString(b, x, header)
+	}
+
+	return b
+}
+
+func (e *Emitter) appendString(b []byte, s string, header bool) []byte {
+	comma := runeString(e.Comma, DefaultComma)
+	quote := runeString(e.Quote, DefaultQuote)
+
+	mustQuote := strings.Contains(s, comma) ||
+		strings.Contains(s, quote) ||
+		strings.ContainsAny(s, "\r\n")
+
+	if !header {
+		// The string must also be quoted if it would be parsed as a value of a
+		// different type.
+		if _, ok := objutil.InferValue(s).(string); !ok {
+			mustQuote = true
+		}
+	}
+
+	if !mustQuote {
+		return append(b, s...)
+	}
+
+	b = append(b, quote...)
+	b = append(b, strings.Replace(s, quote, quote+quote, -1)...)
+	return append(b, quote...)
+}
+
+func (e *Emitter) push(v emitter) {
+	e.stack = append(e.stack, v)
+}
+
+func (e *Emitter) pop() emitter {
+	i := len(e.stack) - 1
+	v := e.stack[i]
+	e.stack = e.stack[:i]
+	return v
+}
+
+var errNested = errors.New("objconv/csv: nested values cannot be written to CSV rows unless flattening is enabled on the emitter")
+
+func isNested(v interface{}) bool {
+	switch v.(type) {
+	case *row, []interface{}:
+		return true
+	}
+	return false
+}
+
+func isListOfRows(a []interface{}) bool {
+	if len(a) == 0 {
+		return true
+	}
+	for _, v := range a {
+		if !isNested(v) {
+			return false
+		}
+	}
+	return true
+}
+
+type emitter interface {
+	emit(interface{}) error
+	value() interface{}
+}
+
+type arrayEmitter struct {
+	self []interface{}
+}
+
+func (e *arrayEmitter) emit(v interface{}) error {
+	e.self = append(e.self, v)
+	return nil
+}
+
+func (e *arrayEmitter) value() interface{} {
+	return e.self
+}
+
+type rowEmitter struct {
+	self *row
+	key  string
+	val  bool
+}
+
+func (e *rowEmitter) emit(v interface{}) error {
+	if e.val {
+		e.val = false
+		e.self.set(e.key, v)
+		return nil
+	}
+
+	e.val = true
+
+	switch k := v.(type) {
+	case string:
+		e.key = k
+	default:
+		e.key = string(e.appendKey(nil, v))
+	}
+
+	return nil
+}
+
+func (e *rowEmitter) appendKey(b []byte, v interface{}) []byte {
+	switch x := v.(type) {
+	case bool:
+		return strconv.AppendBool(b, x)
+	case int64:
+		return strconv.AppendInt(b, x, 10)
+	case uint64:
+		return strconv.AppendUint(b, x, 10)
+	case float32:
+		return strconv.AppendFloat(b, float64(x), 'g', -1, 32)
+	case float64:
+		return strconv.AppendFloat(b, x, 'g', -1, 64)
+	default:
+		return append(b, fmt.Sprint(x)...)
+	}
+}
+
+func (e *rowEmitter) value() interface{} {
+	return e.self
+}
@@ -0,0 +1,76 @@
+package csv
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/segmentio/objconv"
+)
+
+// Codec for the CSV format.
+var Codec = objconv.Codec{
+	NewEmitter: func(w io.Writer) objconv.Emitter { return NewEmitter(w) },
+	NewParser:  func(r io.Reader) objconv.Parser { return NewParser(r) },
+	Extensions: []string{".csv"},
+	Options:    codecOptions,
+	Configure:  configure,
+}
+
+// TSVCodec for the tab-separated values format.
+var TSVCodec = objconv.Codec{
+	NewEmitter: func(w io.Writer) objconv.Emitter { return NewTSVEmitter(w) },
+	NewParser:  func(r io.Reader) objconv.Parser { return NewTSVParser(r) },
+	Extensions: []string{".tsv"},
+	Options:    codecOptions,
+	Configure:  configure,
+}
+
+func init() {
+	for _, name := range [...]string{
+		"text/csv",
+		"csv",
+	} {
+		objconv.Register(name, Codec)
+	}
+
+	for _, name := range [...]string{
+		"text/tab-separated-values",
+		"tsv",
+	} {
+		objconv.Register(name, TSVCodec)
+	}
+}
+
+var codecOptions = []objconv.Option{
+	{Name: "delimiter", Doc: `character separating the fields of records, or "tab"`},
+}
+
+// configure returns a copy of c which creates emitters and parsers configured
+// with the options of the CSV codec.
+func configure(c objconv.Codec, options map[string]string) (objconv.Codec, error) {
+	if v, ok := options["delimiter"]; ok {
+		comma, n := utf8.DecodeRuneInString(v)
+
+		if v == "tab" {
+			comma, n = '\t', len(v)
+		}
+
+		if n != len(v) || comma == utf8.RuneError || comma == '"' || comma == '\r' || comma == '\n' {
+			return c, fmt.Errorf("objconv/csv: invalid value %q for the delimiter option", v)
+		}
+
+		c.NewEmitter = func(w io.Writer) objconv.Emitter {
+			e := NewEmitter(w)
+			e.Comma = comma
+			return e
+		}
+
+		c.NewParser = func(r io.Reader) objconv.Parser {
+			p := NewParser(r)
+			p.Comma = comma
+			return p
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,575 @@
+package csv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/objconv"
+	"github.com/segmentio/objconv/objutil"
+)
+
+// Parser implements a CSV parser that satisfies the objconv.Parser interface.
+//
+// CSV documents are parsed as sequences of rows, each row is a map keyed by
+// the names found in the header row, or an array when NoHeader is set.
+//
+// Quoted cells are always parsed as strings, unquoted cells are inferred to be
+// nil when empty, booleans when equal to "true" or "false", and numbers when
+// following the JSON syntax of numbers.
+type Parser struct {
+	// Comma is the field delimiter, it defaults to DefaultComma when zero.
+	Comma rune
+
+	// Quote is the character used to quote fields, it defaults to
+	// DefaultQuote when zero.
+	Quote rune
+
+	// NoHeader disables the parsing of the first row as a header, rows are
+	// then produced as arrays.
+	NoHeader bool
+
+	// Flatten enables rebuilding nested values from dotted column names, for
+	// example the columns "a.b" and "a.c" produce a map under the "a" key.
+	// Maps which have consecutive integer keys starting at zero are converted
+	// to arrays.
+	Flatten bool
+
+	r      *bufio.Reader
+	line   int
+	header []string
+	fields []field
+	buf    []byte
+	s      []byte // string buffer
+	// This stack is used to iterate over the arrays and maps of the row being
+	// parsed.
+	stack []parser
+}
+
+type field struct {
+	s      string
+	quoted bool
+}
+
+func NewParser(r io.Reader) *Parser {
+	return &Parser{r: bufio.NewReader(r)}
+}
+
+// NewTSVParser returns a new parser which reads tab-separated values from r.
+func NewTSVParser(r io.Reader) *Parser {
+	return &Parser{Comma: '\t', r: bufio.NewReader(r)}
+}
+
+func (p *Parser) Reset(r io.Reader) {
+	if p.r == nil {
+		p.r = bufio.NewReader(r)
+	} else {
+		p.r.Reset(r)
+	}
+	p.line = 0
+	p.header = nil
+	p.fields = p.fields[:0]
+	p.s = nil
+	p.stack = nil
+}
+
+func (p *Parser) Buffered() io.Reader {
+	b, _ := p.r.Peek(p.r.Buffered())
+	return bytes.NewReader(b)
+}
+
+// SequenceParser returns true, CSV documents are sequences of rows.
+func (p *Parser) SequenceParser() bool {
+	return true
+}
+
+func (p *Parser) ParseType() (typ objconv.Type, err error) {
+	if len(p.stack) == 0 {
+		var v interface{}
+
+		if v, err = p.readRow(); err != nil {
+			return
+		}
+
+		p.push(newParser(v))
+	}
+
+	switch v := p.value(); v.(type) {
+	case nil:
+		typ = objconv.Nil
+
+	case bool:
+		typ = objconv.Bool
+
+	case int64:
+		typ = objconv.Int
+
+	case uint64:
+		typ = objconv.Uint
+
+	case float64:
+		typ = objconv.Float
+
+	case string:
+		typ = objconv.String
+
+	case *row:
+		typ = objconv.Map
+
+	case []interface{}:
+		typ = objconv.Array
+
+	default:
+		err = fmt.Errorf("objconv/csv: unsupported value of type %T", v)
+	}
+
+	return
+}
+
+func (p *Parser) ParseNil() (err error) {
+	p.pop()
+	return
+}
+
+func (p *Parser) ParseBool() (v bool, err error) {
+	v = p.pop().value().(bool)
+	return
+}
+
+func (p *Parser) ParseInt() (v int64, err error) {
+	v = p.pop().value().(int64)
+	return
+}
+
+func (p *Parser) ParseUint() (v uint64, err error) {
+	v = p.pop().value().(uint64)
+	return
+}
+
+func (p *Parser) ParseFloat() (v float64, err error) {
+	v = p.pop().value().(float64)
+	return
+}
+
+func (p *Parser) ParseString() (v []byte, err error) {
+	s := p.pop().value().(string)
+	n := len(s)
+
+	if cap(p.s) < n {
+		p.s = make([]byte, 0, ((n/1024)+1)*1024)
+	}
+
+	v = p.s[:n]
+	copy(v, s)
+	return
+}
+
+func (p *Parser) ParseBytes() (v []byte, err error) {
+	panic("objconv/csv: ParseBytes should never be called because CSV has no bytes type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseTime() (v time.Time, err error) {
+	panic("objconv/csv: ParseTime should never be called because CSV has no time type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseDuration() (v time.Duration, err error) {
+	panic("objconv/csv: ParseDuration should never be called because CSV has no duration type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseError() (v error, err error) {
+	panic("objconv/csv: ParseError should never be called because CSV has no error type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseArrayBegin() (n int, err error) {
+	if n = p.top().len(); n != 0 {
+		p.push(newParser(p.top().next()))
+	}
+	return
+}
+
+func (p *Parser) ParseArrayEnd(n int) (err error) {
+	p.pop()
+	return
+}
+
+func (p *Parser) ParseArrayNext(n int) (err error) {
+	p.push(newParser(p.top().next()))
+	return
+}
+
+func (p *Parser) ParseMapBegin() (n int, err error) {
+	if n = p.top().len(); n != 0 {
+		p.push(newParser(p.top().next()))
+	}
+	return
+}
+
+func (p *Parser) ParseMapEnd(n int) (err error) {
+	p.pop()
+	return
+}
+
+func (p *Parser) ParseMapValue(n int) (err error) {
+	p.push(newParser(p.top().next()))
+	return
+}
+
+func (p *Parser) ParseMapNext(n int) (err error) {
+	p.push(newParser(p.top().next()))
+	return
+}
+
+func (p *Parser) TextParser() bool {
+	return true
+}
+
+func (p *Parser) DecodeBytes(b []byte) (v []byte, err error) {
+	var n int
+	if n, err = base64.StdEncoding.Decode(b, b); err != nil {
+		return
+	}
+	v = b[:n]
+	return
+}
+
+func (p *Parser) push(v parser) {
+	p.stack = append(p.stack, v)
+}
+
+func (p *Parser) pop() parser {
+	i := len(p.stack) - 1
+	v := p.stack[i]
+	p.stack = p.stack[:i]
+	return v
+}
+
+func (p *Parser) top() parser {
+	return p.stack[len(p.stack)-1]
+}
+
+func (p *Parser) value() interface{} {
+	return p.stack[len(p.stack)-1].value()
+}
+
+// readRow reads the next record of the input and converts it to the value
+// produced by the parser for this row.
+func (p *Parser) readRow() (v interface{}, err error) {
+	if p.header == nil && !p.NoHeader {
+		if err = p.readRecord(); err != nil {
+			return
+		}
+
+		p.header = make([]string, len(p.fields))
+
+		for i, f := range p.fields {
+			p.header[i] = f.s
+		}
+	}
+
+	if err = p.readRecord(); err != nil {
+		return
+	}
+
+	if p.NoHeader {
+		a := make([]interface{}, len(p.fields))
+		for i, f := range p.fields {
+			a[i] = f.value()
+		}
+		return a, nil
+	}
+
+	if len(p.fields) != len(p.header) {
+		return nil, p.errorf("expected %d fields but found %d", len(p.header), len(p.fields))
+	}
+
+	r := newRow()
+
+	for i, f := range p.fields {
+		if !p.Flatten {
+			if _, exists := r.get(p.header[i]); exists {
+				return nil, p.errorf("column %q appears more than once in the header", p.header[i])
+			}
+			r.set(p.header[i], f.value())
+		} else if err = p.setPath(r, p.header[i], f.value()); err != nil {
+			return
+		}
+	}
+
+	if p.Flatten {
+		return unflatten(r), nil
+	}
+
+	return r, nil
+}
+
+func (p *Parser) setPath(r *row, path string, v interface{}) error {
+	keys := strings.Split(path, ".")
+
+	for _, k := range keys[:len(keys)-1] {
+		x, exists := r.get(k)
+
+		if !exists {
+			sub := newRow()
+			r.set(k, sub)
+			r = sub
+			continue
+		}
+
+		sub, ok := x.(*row)
+
+		if !ok {
+			return p.errorf("column %q conflicts with another column of the header", path)
+		}
+
+		r = sub
+	}
+
+	k := keys[len(keys)-1]
+
+	if _, exists := r.get(k); exists {
+		return p.errorf("column %q conflicts with another column of the header", path)
+	}
+
+	r.set(k, v)
+	return nil
+}
+
+// unflatten converts the maps of r that have consecutive integer keys starting
+// at zero to arrays.
+func unflatten(r *row) interface{} {
+	for _, k := range r.keys {
+		if sub, ok := r.values[k].(*row); ok {
+			r.values[k] = unflatten(sub)
+		}
+	}
+
+	if len(r.keys) == 0 {
+		return r
+	}
+
+	indexes := make([]int, len(r.keys))
+
+	for i, k := range r.keys {
+		n, err := strconv.Atoi(k)
+		if err != nil || n < 0 || strconv.Itoa(n) != k {
+			return r
+		}
+		indexes[i] = n
+	}
+
+	sort.Ints(indexes)
+
+	for i, n := range indexes {
+		if i != n {
+			return r
+		}
+	}
+
+	a := make([]interface{}, len(r.keys))
+
+	for _, k := range r.keys {
+		n, _ := strconv.Atoi(k)
+		a[n] = r.values[k]
+	}
+
+	return a
+}
+
+func (f field) value() interface{} {
+	if f.quoted {
+		return f.s
+	}
+	return objutil.InferValue(f.s)
+}
+
+// readRecord reads the next record of the input into p.fields, empty lines
+// are skipped.
+func (p *Parser) readRecord() (err error) {
+	comma := runeString(p.Comma, DefaultComma)
+	quote := runeString(p.Quote, DefaultQuote)
+
+	var line string
+
+	for {
+		if line, err = p.readLine(); err != nil {
+			return
+		}
+		if line != "\n" && line != "\r\n" {
+			break
+		}
+	}
+
+	p.fields = p.fields[:0]
+	start := p.line
+	pos := 0
+
+	for {
+		if strings.HasPrefix(line[pos:], quote) {
+			b := p.buf[:0]
+			pos += len(quote)
+
+			for {
+				i := strings.Index(line[pos:], quote)
+
+				if i < 0 {
+					b = append(b, line[pos:]...)
+
+					if line, err = p.readLine(); err != nil {
+						if err == io.EOF {
+							p.line = start
+							err = p.errorf("unterminated quoted field")
+						}
+						return
+					}
+
+					pos = 0
+					continue
+				}
+
+				b = append(b, line[pos:pos+i]...)
+				pos += i + len(quote)
+
+				if !strings.HasPrefix(line[pos:], quote) {
+					break
+				}
+
+				b = append(b, quote...)
+				pos += len(quote)
+			}
+
+			p.buf = b
+			p.fields = append(p.fields, field{s: strings.Replace(string(b), "\r\n", "\n", -1), quoted: true})
+
+			switch rest := line[pos:]; {
+			case strings.HasPrefix(rest, comma):
+				pos += len(comma)
+			case rest == "" || rest == "\n" || rest == "\r\n":
+				return nil
+			default:
+				return p.errorf("unexpected characters after quoted field")
+			}
+
+			continue
+		}
+
+		if i := strings.Index(line[pos:], comma); i >= 0 {
+			p.fields = append(p.fields, field{s: line[pos : pos+i]})
+			pos += i + len(comma)
+			continue
+		}
+
+		s := strings.TrimSuffix(line[pos:], "\n")
+		s = strings.TrimSuffix(s, "\r")
+		p.fields = append(p.fields, field{s: s})
+		return nil
+	}
+}
+
+// readLine returns the next line of the input, including the newline if there
+// was one. The method returns io.EOF if there are no more lines to read.
+func (p *Parser) readLine() (line string, err error) {
+	line, err = p.r.ReadString('\n')
+
+	if err == io.EOF && len(line) != 0 {
+		err = nil
+	}
+
+	if err == nil {
+		p.line++
+	}
+
+	return
+}
+
+func (p *Parser) errorf(msg string, args ...interface{}) error {
+	return fmt.Errorf("objconv/csv: line %d: %s", p.line, fmt.Sprintf(msg, args...))
+}
+
+type parser interface {
+	value() interface{}
+	next() interface{}
+	len() int
+}
+
+type valueParser struct {
+	self interface{}
+}
+
+func (p *valueParser) value() interface{} {
+	return p.self
+}
+
+func (p *valueParser) next() interface{} {
+	panic("objconv/csv: invalid call of next method on simple value parser")
+}
+
+func (p *valueParser) len() int {
+	panic("objconv/csv: invalid call of len method on simple value parser")
+}
+
+type arrayParser struct {
+	self []interface{}
+	off  int
+}
+
+func (p *arrayParser) value() interface{} {
+	return p.self
+}
+
+func (p *arrayParser) next() interface{} {
+	v := p.self[p.off]
+	p.off++
+	return v
+}
+
+func (p *arrayParser) len() int {
+	return len(p.self)
+}
+
+type rowParser struct {
+	self *row
+	off  int
+	val  bool
+}
+
+func (p *rowParser) value() interface{} {
+	return p.self
+}
+
+func (p *rowParser) next() (v interface{}) {
+	k := p.self.keys[p.off]
+
+	if p.val {
+		v = p.self.values[k]
+		p.val = false
+		p.off++
+	} else {
+		v = k
+		p.val = true
+	}
+
+	return
+}
+
+func (p *rowParser) len() int {
+	return len(p.self.keys)
+}
+
+func newParser(v interface{}) parser {
+	switch x := v.(type) {
+	case *row:
+		return &rowParser{self: x}
+
+	case []interface{}:
+		return &arrayParser{self: x}
+
+	default:
+		return &valueParser{self: x}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding"
 	"errors"
 	"fmt"
+	"io"
 	"reflect"
 	"strconv"
 	"time"
@@ -99,7 +100,11 @@ func (d Decoder) decodeBoolFromType(t Type, to reflect.Value) (err error) {
 		v, err = d.Parser.ParseBool()
 
 	default:
-		err = typeConversionError(t, Bool)
+		if bd, ok := d.Parser.(boolDecoder); ok {
+			v, err = bd.DecodeBool(t)
+		} else {
+			err = typeConversionError(t, Bool)
+		}
 	}
 
 	if err != nil {
@@ -925,13 +930,23 @@ func (d Decoder) decodeStructWith(to reflect.Value, s *structType) (t Type, err
 }
 
 func (d Decoder) decodeStructFromTypeWith(typ Type, to reflect.Value, s *structType) (err error) {
+	attrs := s.attrsByName != nil && isAttrParser(d.Parser)
+
 	if err = d.decodeMapImpl(typ, func(kd Decoder, vd Decoder) (err error) {
 		var b []byte
+		var f *structField
 
 		if _, b, err = d.decodeTypeAndString(); err != nil {
 			return
 		}
-		f := s.fieldsByName[string(b)]
+
+		if attrs {
+			f = s.attrsByName[string(b)]
+		}
+
+		if f == nil {
+			f = s.fieldsByName[string(b)]
+		}
 
 		if err = d.Parser.ParseMapValue(vd.off - 1); err != nil {
 			return
@@ -1045,6 +1060,17 @@ func (d Decoder) decodeInterface(to reflect.Value) (t Type, err error) {
 }
 
 func (d Decoder) decodeInterfaceFromType(t Type, to reflect.Value) (err error) {
+	if id, ok := d.Parser.(interfaceDecoder); ok && (!to.IsValid() || to.NumMethod() == 0) {
+		var v interface{}
+
+		if v, ok, err = id.DecodeInterface(t); ok || err != nil {
+			if err == nil && to.IsValid() {
+				to.Set(reflect.ValueOf(v))
+			}
+			return
+		}
+	}
+
 	switch t {
 	case Nil:
 		err = d.decodeInterfaceFromNil(to)
@@ -1159,6 +1185,10 @@ func (d Decoder) decodeArrayImpl(t Type, f func(Decoder) error) (err error) {
 		n, err = d.Parser.ParseArrayBegin()
 
 	default:
+		if isImplicitArrayParser(d.Parser) {
+			err = f(d)
+			return
+		}
 		err = typeConversionError(t, Array)
 	}
 
@@ -1274,6 +1304,7 @@ type StreamDecoder struct {
 	typ Type
 	cnt int
 	max int
+	seq bool
 }
 
 // NewStreamDecoder returns a new stream decoder that takes input from p.
@@ -1328,11 +1359,17 @@ func (d *StreamDecoder) Decode(v interface{}) error {
 		MapType: d.MapType,
 	}
 
-	switch d.typ {
-	case Unknown:
+	switch {
+	case d.typ == Unknown:
 		err = d.init()
 		max = d.max
-	case Array:
+	case d.seq:
+		// Sequences end when there are no more bytes to read before the
+		// beginning of the next value.
+		if _, err = dec.Parser.ParseType(); err == io.EOF {
+			err = End
+		}
+	case d.typ == Array:
 		if cnt == max {
 			err = dec.Parser.ParseArrayEnd(cnt)
 		} else if cnt != 0 {
@@ -1351,7 +1388,13 @@ func (d *StreamDecoder) Decode(v interface{}) error {
 				cnt++
 				max = cnt
 			default:
-				if max < 0 && dec.Parser.ParseArrayEnd(cnt) == nil {
+				if d.seq {
+					// Reaching the end of the input in the middle of a value
+					// means that the last value of the sequence was truncated.
+					if err == io.EOF {
+						err = io.ErrUnexpectedEOF
+					}
+				} else if max < 0 && dec.Parser.ParseArrayEnd(cnt) == nil {
 					err = End
 				}
 			}
@@ -1373,7 +1416,7 @@ func (d *StreamDecoder) Encoder(e Emitter) (enc *StreamEncoder, err error) {
 
 	if typ, err = d.Parser.ParseType(); err == nil {
 		enc = NewStreamEncoder(e)
-		enc.oneshot = typ != Array
+		enc.oneshot = typ != Array && !isSequenceParser(d.Parser)
 	}
 
 	return
@@ -1384,16 +1427,23 @@ func (d *StreamDecoder) init() error {
 	typ := Unknown
 	max := 0
 
+	seq := isSequenceParser(d.Parser)
+
 	if typ, err = d.Parser.ParseType(); err == nil {
-		switch typ {
+		switch {
+		case seq:
+			max = -1
+		case typ == Array:
+			max, err = d.Parser.ParseArrayBegin()
 		default:
 			max = 1
-		case Array:
-			max, err = d.Parser.ParseArrayBegin()
 		}
+	} else if seq && err == io.EOF {
+		err = End
 	}
 
 	d.err = err
+	d.seq = seq
 	d.typ = typ
 	d.max = max
 	return err
@@ -0,0 +1,333 @@
+package objconv
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Change represents a difference found by Diff between two values.
+//
+// Changes implement ValueEncoder, they are encoded as RFC 6902 JSON Patch
+// operations so encoding the list returned by Diff produces a patch which
+// transforms the first value into the second.
+type Change struct {
+	// Op is the type of change, "add", "remove" or "replace", the names of the
+	// JSON Patch operations.
+	Op string
+
+	// Path is the JSON Pointer (RFC 6901) of the value that changed, the empty
+	// string represents the root value.
+	Path string
+
+	// From is the value in the first document, nil for additions.
+	From interface{}
+
+	// To is the value in the second document, nil for removals.
+	To interface{}
+}
+
+// EncodeValue satisfies the ValueEncoder interface.
+func (c Change) EncodeValue(e Encoder) error {
+	i := 0
+	n := 3
+	if c.Op == "remove" {
+		n = 2
+	}
+	return e.EncodeMap(n, func(k Encoder, v Encoder) (err error) {
+		switch i {
+		case 0:
+			err = encodeField(k, v, "op", c.Op)
+		case 1:
+			err = encodeField(k, v, "path", c.Path)
+		case 2:
+			err = encodeField(k, v, "value", c.To)
+		}
+		i++
+		return
+	})
+}
+
+func encodeField(k Encoder, v Encoder, name string, value interface{}) error {
+	if err := k.Encode(name); err != nil {
+		return err
+	}
+	return v.Encode(value)
+}
+
+// String returns a readable representation of the change.
+func (c Change) String() string {
+	path := c.Path
+	if path == "" {
+		path = "(root)"
+	}
+	switch c.Op {
+	case "add":
+		return fmt.Sprintf("+ %s: %s", path, formatValue(c.To))
+	case "remove":
+		return fmt.Sprintf("- %s: %s", path, formatValue(c.From))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", path, formatValue(c.From), formatValue(c.To))
+	}
+}
+
+// Diff compares a and b and returns the list of changes transforming a into
+// b, which is empty if the values are equal.
+//
+// The values are compared after being encoded to their generic
+// representation, so structs, maps and values decoded by any codec can be
+// compared. Map keys are matched by their string representation and the keys
+// of changes are listed in lexicographical order, arrays are compared element
+// by element.
+//
+// The comparison is typed: numbers are equal if they represent the same value
+// regardless of their type, times and durations are equal to strings of their
+// RFC 3339 and time.Duration representation, and byte slices are equal to
+// strings of their content or of its base64 encoding. This makes it possible
+// to compare values decoded from formats which don't support the same types.
+func Diff(a interface{}, b interface{}) ([]Change, error) {
+	va, err := genericValue(a)
+	if err != nil {
+		return nil, err
+	}
+
+	vb, err := genericValue(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffValues(nil, "", va, vb), nil
+}
+
+// genericValue returns the representation of v produced by a ValueEmitter.
+func genericValue(v interface{}) (interface{}, error) {
+	e := NewValueEmitter()
+	if err := NewEncoder(e).Encode(v); err != nil {
+		return nil, err
+	}
+	return e.Value(), nil
+}
+
+func diffValues(changes []Change, path string, a interface{}, b interface{}) []Change {
+	switch x := a.(type) {
+	case map[interface{}]interface{}:
+		if y, ok := b.(map[interface{}]interface{}); ok {
+			return diffMaps(changes, path, x, y)
+		}
+
+	case []interface{}:
+		if y, ok := b.([]interface{}); ok {
+			return diffArrays(changes, path, x, y)
+		}
+	}
+
+	if !equalValues(a, b) {
+		changes = append(changes, Change{Op: "replace", Path: path, From: a, To: b})
+	}
+
+	return changes
+}
+
+func diffMaps(changes []Change, path string, a map[interface{}]interface{}, b map[interface{}]interface{}) []Change {
+	ka := stringKeys(a)
+	kb := stringKeys(b)
+	keys := make([]string, 0, len(ka)+len(kb))
+
+	for k := range ka {
+		keys = append(keys, k)
+	}
+	for k := range kb {
+		if _, ok := ka[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		p := path + "/" + EscapePointer(k)
+		x, inA := ka[k]
+		y, inB := kb[k]
+
+		switch {
+		case !inA:
+			changes = append(changes, Change{Op: "add", Path: p, To: y})
+		case !inB:
+			changes = append(changes, Change{Op: "remove", Path: p, From: x})
+		default:
+			changes = diffValues(changes, p, x, y)
+		}
+	}
+
+	return changes
+}
+
+func diffArrays(changes []Change, path string, a []interface{}, b []interface{}) []Change {
+	n := min(len(a), len(b))
+
+	for i := 0; i != n; i++ {
+		changes = diffValues(changes, path+"/"+strconv.Itoa(i), a[i], b[i])
+	}
+
+	for i := n; i < len(b); i++ {
+		changes = append(changes, Change{Op: "add", Path: path + "/" + strconv.Itoa(i), To: b[i]})
+	}
+
+	// Elements are removed from the end so the indexes of the patch remain
+	// valid when it is applied.
+	for i := len(a) - 1; i >= n; i-- {
+		changes = append(changes, Change{Op: "remove", Path: path + "/" + strconv.Itoa(i), From: a[i]})
+	}
+
+	return changes
+}
+
+// stringKeys returns m indexed by the string representation of its keys.
+func stringKeys(m map[interface{}]interface{}) map[string]interface{} {
+	s := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		s[KeyString(k)] = v
+	}
+	return s
+}
+
+// equalValues compares the generic values a and b.
+func equalValues(a interface{}, b interface{}) bool {
+	switch x := a.(type) {
+	case nil:
+		return b == nil
+
+	case bool:
+		y, ok := b.(bool)
+		return ok && x == y
+
+	case int64, uint64, float64:
+		return equalNumbers(a, b)
+
+	case string:
+		switch b.(type) {
+		case string:
+			return x == b
+		case []byte, time.Time, time.Duration, error:
+			return equalValues(b, a)
+		}
+
+	case []byte:
+		switch y := b.(type) {
+		case []byte:
+			return bytes.Equal(x, y)
+		case string:
+			return string(x) == y || base64.StdEncoding.EncodeToString(x) == y
+		}
+
+	case time.Time:
+		switch y := b.(type) {
+		case time.Time:
+			return x.Equal(y)
+		case string:
+			t, err := time.Parse(time.RFC3339Nano, y)
+			return err == nil && x.Equal(t)
+		}
+
+	case time.Duration:
+		switch y := b.(type) {
+		case time.Duration:
+			return x == y
+		case string:
+			d, err := time.ParseDuration(y)
+			return err == nil && x == d
+		}
+
+	case error:
+		switch y := b.(type) {
+		case error:
+			return x.Error() == y.Error()
+		case string:
+			return x.Error() == y
+		}
+
+	case []interface{}, map[interface{}]interface{}:
+		return len(diffValues(nil, "", a, b)) == 0
+	}
+
+	return false
+}
+
+func equalNumbers(a interface{}, b interface{}) bool {
+	switch x := a.(type) {
+	case int64:
+		switch y := b.(type) {
+		case int64:
+			return x == y
+		case uint64:
+			return x >= 0 && uint64(x) == y
+		case float64:
+			return float64(x) == y && y >= math.MinInt64 && y < math.MaxInt64 && int64(y) == x
+		}
+
+	case uint64:
+		switch y := b.(type) {
+		case uint64:
+			return x == y
+		case int64:
+			return equalNumbers(b, a)
+		case float64:
+			return float64(x) == y && y >= 0 && y < math.MaxUint64 && uint64(y) == x
+		}
+
+	case float64:
+		switch y := b.(type) {
+		case float64:
+			// NaN values aren't equal to themselves, but a value which
+			// didn't change must not be reported as a change.
+			return x == y || (math.IsNaN(x) && math.IsNaN(y))
+		case int64, uint64:
+			return equalNumbers(b, a)
+		}
+	}
+
+	return false
+}
+
+// formatValue returns a compact representation of generic values.
+func formatValue(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(x)
+	case []byte:
+		return "b64:" + base64.StdEncoding.EncodeToString(x)
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	case error:
+		return strconv.Quote(x.Error())
+
+	case []interface{}:
+		s := make([]string, len(x))
+		for i, e := range x {
+			s[i] = formatValue(e)
+		}
+		return "[" + strings.Join(s, ", ") + "]"
+
+	case map[interface{}]interface{}:
+		m := stringKeys(x)
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		s := make([]string, len(keys))
+		for i, k := range keys {
+			s[i] = strconv.Quote(k) + ": " + formatValue(m[k])
+		}
+		return "{" + strings.Join(s, ", ") + "}"
+	}
+
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,107 @@
+package objconv
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	date := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		a       interface{}
+		b       interface{}
+		changes []string
+	}{
+		{nil, nil, nil},
+		{1, 1.0, nil},
+		{int64(1), uint64(1), nil},
+		{1, 1.5, []string{`~ (root): 1 -> 1.5`}},
+		{math.NaN(), math.NaN(), nil},
+		{math.NaN(), 1.5, []string{`~ (root): NaN -> 1.5`}},
+		{-1, uint64(1<<63 + 1), []string{`~ (root): -1 -> 9223372036854775809`}},
+		{"a", "b", []string{`~ (root): "a" -> "b"`}},
+		{[]byte("hello"), "hello", nil},
+		{[]byte("hello"), "aGVsbG8=", nil},
+		{[]byte("hello"), "world", []string{`~ (root): b64:aGVsbG8= -> "world"`}},
+		{date, "2024-01-02T03:04:05Z", nil},
+		{date, date.In(time.FixedZone("", 3600)), nil},
+		{date, "2024-01-02", []string{`~ (root): 2024-01-02T03:04:05Z -> "2024-01-02"`}},
+		{time.Minute, "1m0s", nil},
+		{errors.New("oops"), "oops", nil},
+		{true, 1, []string{`~ (root): true -> 1`}},
+		{
+			[]int{1, 2, 3},
+			[]interface{}{1, 4},
+			[]string{`~ /1: 2 -> 4`, `- /2: 3`},
+		},
+		{
+			[]int{1},
+			[]int{1, 2, 3},
+			[]string{`+ /1: 2`, `+ /2: 3`},
+		},
+		{
+			map[string]interface{}{"a": 1, "b": map[string]interface{}{"c": "d", "e/f": nil}, "x": []int{}},
+			map[interface{}]interface{}{"a": 1.0, "b": map[string]interface{}{"c": "D"}, "y": true},
+			[]string{`~ /b/c: "d" -> "D"`, `- /b/e~1f: null`, `- /x: []`, `+ /y: true`},
+		},
+		{
+			map[interface{}]interface{}{int64(1): "a"},
+			map[string]string{"1": "a"},
+			nil,
+		},
+		{
+			struct {
+				A int `objconv:"a"`
+				B []string
+			}{A: 1, B: []string{"x"}},
+			map[string]interface{}{"a": 2, "B": []interface{}{"x"}},
+			[]string{`~ /a: 1 -> 2`},
+		},
+	}
+
+	for _, test := range tests {
+		changes, err := Diff(test.a, test.b)
+		if err != nil {
+			t.Errorf("%v %v: %s", test.a, test.b, err)
+			continue
+		}
+
+		var s []string
+		for _, c := range changes {
+			s = append(s, c.String())
+		}
+
+		if !reflect.DeepEqual(s, test.changes) {
+			t.Errorf("%v %v:\n<<< %q\n>>> %q", test.a, test.b, test.changes, s)
+		}
+	}
+}
+
+func TestDiffPatch(t *testing.T) {
+	changes, err := Diff(
+		map[string]interface{}{"a": []int{1, 2}, "b": "x"},
+		map[string]interface{}{"a": []int{1}, "c": map[string]int{"d": 1}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewValueEmitter()
+	if err := NewEncoder(e).Encode(changes); err != nil {
+		t.Fatal(err)
+	}
+
+	patch := []interface{}{
+		map[interface{}]interface{}{"op": "remove", "path": "/a/1"},
+		map[interface{}]interface{}{"op": "remove", "path": "/b"},
+		map[interface{}]interface{}{"op": "add", "path": "/c", "value": map[interface{}]interface{}{"d": int64(1)}},
+	}
+
+	if !reflect.DeepEqual(e.Value(), patch) {
+		t.Errorf("\n<<< %#v\n>>> %#v", patch, e.Value())
+	}
+}
@@ -82,6 +82,58 @@ func isTextEmitter(emitter Emitter) bool {
 	return e != nil && e.TextEmitter()
 }
 
+// The sequenceEmitter interface may be implemented by emitters of formats where
+// streams are represented as sequences of values written back-to-back instead
+// of arrays. Stream encoders don't emit array delimiters when they use such
+// emitters.
+type sequenceEmitter interface {
+	// SequenceEmitter returns true if the emitter produces streams as
+	// sequences of values.
+	SequenceEmitter() bool
+}
+
+func isSequenceEmitter(emitter Emitter) bool {
+	e, _ := emitter.(sequenceEmitter)
+	return e != nil && e.SequenceEmitter()
+}
+
+// The sortedMapEmitter interface may be implemented by emitters of formats
+// where the order of map keys is significant, like the columns of CSV headers.
+// Encoders sort the keys of Go maps when they use such emitters, so the output
+// doesn't depend on the iteration order of maps.
+type sortedMapEmitter interface {
+	// SortedMapEmitter returns true if the emitter requires the keys of Go
+	// maps to be sorted.
+	SortedMapEmitter() bool
+}
+
+func isSortedMapEmitter(emitter Emitter) bool {
+	e, _ := emitter.(sortedMapEmitter)
+	return e != nil && e.SortedMapEmitter()
+}
+
+// The attrEmitter interface may be implemented by emitters of formats which
+// distinguish attributes from other values, like XML. Encoders prefix the names
+// of struct fields tagged with `attr` with objutil.AttrPrefix when they use
+// such emitters.
+type attrEmitter interface {
+	// AttrEmitter returns true if the emitter writes map keys prefixed with
+	// objutil.AttrPrefix as attributes.
+	AttrEmitter() bool
+}
+
+func isAttrEmitter(emitter Emitter) bool {
+	e, _ := emitter.(attrEmitter)
+	return e != nil && e.AttrEmitter()
+}
+
+// The streamEmitter interface is implemented by emitters which need to know
+// that they are used by a stream encoder, like compressed emitters which close
+// themselves after the first value when used by an Encoder.
+type streamEmitter interface {
+	openStream()
+}
+
 type discardEmitter struct{}
 
 func (e discardEmitter) EmitNil() error                     { return nil }
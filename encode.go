@@ -96,12 +96,21 @@ func (e Encoder) Encode(v interface{}) (err error) {
 		return e.encodeSliceOfInterface(x)
 
 	case map[string]string:
+		if e.sortMapKeys() {
+			return e.encode(reflect.ValueOf(x))
+		}
 		return e.encodeMapStringString(x)
 
 	case map[string]interface{}:
+		if e.sortMapKeys() {
+			return e.encode(reflect.ValueOf(x))
+		}
 		return e.encodeMapStringInterface(x)
 
 	case map[interface{}]interface{}:
+		if e.sortMapKeys() {
+			return e.encode(reflect.ValueOf(x))
+		}
 		return e.encodeMapInterfaceInterface(x)
 
 		// Also checks for pointer types so the program can use this as a way
@@ -229,6 +238,12 @@ func (e Encoder) Encode(v interface{}) (err error) {
 	}
 }
 
+// sortMapKeys returns true if the keys of Go maps must be sorted, either
+// because the encoder was configured to or because the emitter requires it.
+func (e Encoder) sortMapKeys() bool {
+	return e.SortMapKeys || isSortedMapEmitter(e.Emitter)
+}
+
 func (e *Encoder) encodeMapValueMaybe() (err error) {
 	if e.key {
 		e.key, err = false, e.Emitter.EmitMapValue()
@@ -377,7 +392,9 @@ func (e Encoder) encodeMap(v reflect.Value) error {
 func (e Encoder) encodeMapWith(v reflect.Value, kf encodeFunc, vf encodeFunc) error {
 	t := v.Type()
 
-	if !e.SortMapKeys {
+	sorted := e.sortMapKeys()
+
+	if !sorted {
 		switch {
 		case t.ConvertibleTo(mapInterfaceInterfaceType):
 			return e.encodeMapInterfaceInterfaceValue(v.Convert(mapInterfaceInterfaceType))
@@ -397,7 +414,7 @@ func (e Encoder) encodeMapWith(v reflect.Value, kf encodeFunc, vf encodeFunc) er
 	if n != 0 {
 		k = v.MapKeys()
 
-		if e.SortMapKeys {
+		if sorted {
 			sortValues(t.Key(), k)
 		}
 	}
@@ -534,6 +551,7 @@ func (e Encoder) encodeStructWith(v reflect.Value, s *structType) (err error) {
 		return
 	}
 	n = 0
+	attrs := s.attrsByName != nil && isAttrEmitter(e.Emitter)
 
 	for i := range s.fields {
 		f := &s.fields[i]
@@ -543,7 +561,11 @@ func (e Encoder) encodeStructWith(v reflect.Value, s *structType) (err error) {
 					return
 				}
 			}
-			if err = e.Emitter.EmitString(f.name); err != nil {
+			name := f.name
+			if attrs && f.attr != "" {
+				name = f.attr
+			}
+			if err = e.Emitter.EmitString(name); err != nil {
 				return
 			}
 			if err = e.Emitter.EmitMapValue(); err != nil {
@@ -710,6 +732,7 @@ type StreamEncoder struct {
 	opened  bool
 	closed  bool
 	oneshot bool
+	seq     bool
 }
 
 // NewStreamEncoder returns a new stream encoder that outputs to e.
@@ -740,8 +763,13 @@ func (e *StreamEncoder) Open(n int) error {
 	if !e.opened {
 		e.max = n
 		e.opened = true
+		e.seq = isSequenceEmitter(e.Emitter)
+
+		if s, ok := e.Emitter.(streamEmitter); ok {
+			s.openStream()
+		}
 
-		if !e.oneshot {
+		if !e.oneshot && !e.seq {
 			e.err = e.Emitter.EmitArrayBegin(n)
 		}
 	}
@@ -749,7 +777,8 @@ func (e *StreamEncoder) Open(n int) error {
 	return e.err
 }
 
-// Close terminates the stream encoder.
+// Close terminates the stream encoder, the emitter is closed if it implements
+// io.Closer.
 func (e *StreamEncoder) Close() error {
 	if !e.closed {
 		if err := e.Open(-1); err != nil {
@@ -758,9 +787,15 @@ func (e *StreamEncoder) Close() error {
 
 		e.closed = true
 
-		if !e.oneshot {
+		if !e.oneshot && !e.seq {
 			e.err = e.Emitter.EmitArrayEnd()
 		}
+
+		// Emitters that buffer or transform their output, like compressed
+		// ones, implement io.Closer to complete the stream.
+		if c, ok := e.Emitter.(io.Closer); ok && e.err == nil {
+			e.err = c.Close()
+		}
 	}
 
 	return e.err
@@ -777,7 +812,7 @@ func (e *StreamEncoder) Encode(v interface{}) error {
 		return fmt.Errorf("objconv: too many values sent to a stream encoder exceed the configured limit of %d", e.max)
 	}
 
-	if !e.oneshot && e.cnt != 0 {
+	if !e.oneshot && !e.seq && e.cnt != 0 {
 		e.err = e.Emitter.EmitArrayNext()
 	}
 
@@ -0,0 +1,8 @@
+// Package http provides helpers to use the codecs of the objconv registry in
+// HTTP handlers.
+//
+// The package negotiates the codec used to encode responses from the Accept
+// header of requests, selects the codec used to decode request bodies from
+// their Content-Type header, and reports failures with the appropriate 406 or
+// 415 status codes.
+package http
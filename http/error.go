@@ -0,0 +1,42 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Error is the error type returned when requests can't be served by the codecs
+// of a registry.
+type Error struct {
+	// Status is the HTTP status code that should be sent to the client,
+	// http.StatusNotAcceptable when no codec satisfies the Accept header,
+	// http.StatusUnsupportedMediaType when no codec is registered for the
+	// Content-Type header, and http.StatusBadRequest when decoding the request
+	// body failed.
+	Status int
+
+	// Type is the value of the Accept or Content-Type header that could not be
+	// satisfied.
+	Type string
+
+	// Err is the decoding error of http.StatusBadRequest errors.
+	Err error
+}
+
+func (e *Error) Error() string {
+	switch e.Status {
+	case http.StatusNotAcceptable:
+		return "objconv/http: no codecs match the accepted media types " + strconv.Quote(e.Type)
+	case http.StatusUnsupportedMediaType:
+		return "objconv/http: no codecs match the media type " + strconv.Quote(e.Type)
+	}
+	if e.Err == nil {
+		return "objconv/http: " + http.StatusText(e.Status)
+	}
+	return "objconv/http: malformed " + e.Type + " request body: " + e.Err.Error()
+}
+
+// Unwrap returns the decoding error that caused e, if any.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,247 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/segmentio/objconv"
+	"github.com/segmentio/objconv/json"
+	"github.com/segmentio/objconv/msgpack"
+)
+
+func testNegotiator() *Negotiator {
+	reg := &objconv.Registry{}
+	reg.Register("application/json", json.Codec)
+	reg.Register("text/json", json.Codec)
+	reg.Register("json", json.Codec)
+	reg.Register("application/msgpack", msgpack.Codec)
+	return &Negotiator{Registry: reg}
+}
+
+func TestNegotiateEncoder(t *testing.T) {
+	tests := []struct {
+		accept   string
+		mimetype string
+	}{
+		{"", "application/json"},
+		{"*/*", "application/json"},
+		{"application/*", "application/json"},
+		{"text/*", "text/json"},
+		{"application/msgpack", "application/msgpack"},
+		{"Application/MsgPack", "application/msgpack"},
+		{"text/html, */*;q=0.1", "application/json"},
+		{"*/*;q=0.1, application/msgpack", "application/msgpack"},
+		{"application/msgpack, application/json", "application/msgpack"},
+		{"application/json;q=0.5, application/msgpack;q=0.9", "application/msgpack"},
+		{"*/*, application/json;q=0, text/json;q=0", "application/msgpack"},
+		{"application/msgpack;q=oops, text/json", "text/json"},
+		{"application/json; charset=utf-8", "application/json"},
+	}
+
+	n := testNegotiator()
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if test.accept != "" {
+			r.Header.Set("Accept", test.accept)
+		}
+
+		_, mimetype, err := n.NegotiateEncoder(r)
+
+		if err != nil {
+			t.Errorf("%q: %s", test.accept, err)
+			continue
+		}
+
+		if mimetype != test.mimetype {
+			t.Errorf("%q: %q != %q", test.accept, test.mimetype, mimetype)
+		}
+	}
+}
+
+func TestNegotiateEncoderNotAcceptable(t *testing.T) {
+	for _, accept := range []string{
+		"text/html",
+		"*/*;q=0",
+		"application/json;q=0, application/msgpack;q=0, text/*;q=0",
+		"json",
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept", accept)
+
+		_, _, err := testNegotiator().NegotiateEncoder(r)
+
+		if e, ok := err.(*Error); !ok || e.Status != http.StatusNotAcceptable {
+			t.Errorf("%q: expected a 406 error but got %v", accept, err)
+		}
+	}
+}
+
+func TestDecodeRequest(t *testing.T) {
+	n := testNegotiator()
+
+	r := httptest.NewRequest("POST", "/", strings.NewReader(`{"A":1}`))
+	r.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	var v struct{ A int }
+
+	if err := n.DecodeRequest(r, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.A != 1 {
+		t.Error("bad value decoded:", v)
+	}
+}
+
+func TestDecodeRequestError(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+		status      int
+	}{
+		{"", `{}`, http.StatusUnsupportedMediaType},
+		{"text/plain", `{}`, http.StatusUnsupportedMediaType},
+		{"application/json; charset", `{}`, http.StatusUnsupportedMediaType},
+		{"application/json", `{"A":`, http.StatusBadRequest},
+	}
+
+	n := testNegotiator()
+
+	for _, test := range tests {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(test.body))
+		if test.contentType != "" {
+			r.Header.Set("Content-Type", test.contentType)
+		}
+
+		var v interface{}
+		var e *Error
+
+		if err := n.DecodeRequest(r, &v); !errors.As(err, &e) || e.Status != test.status {
+			t.Errorf("%q: expected a %d error but got %v", test.contentType, test.status, err)
+		}
+	}
+}
+
+func TestWriteResponse(t *testing.T) {
+	n := testNegotiator()
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept", "application/msgpack")
+	w := httptest.NewRecorder()
+
+	if err := n.WriteResponse(w, r, http.StatusCreated, map[string]int{"A": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	res := w.Result()
+
+	if res.StatusCode != http.StatusCreated {
+		t.Error("bad status code:", res.StatusCode)
+	}
+	if h := res.Header.Get("Content-Type"); h != "application/msgpack" {
+		t.Error("bad content type:", h)
+	}
+	if h := res.Header.Get("Vary"); h != "Accept" {
+		t.Error("bad vary header:", h)
+	}
+
+	var v map[string]int
+
+	if err := msgpack.NewDecoder(res.Body).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(v, map[string]int{"A": 1}) {
+		t.Error("bad value written:", v)
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	n := testNegotiator()
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept", "text/html")
+	w := httptest.NewRecorder()
+
+	err := n.WriteResponse(w, r, http.StatusOK, "hello")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	n.WriteError(w, err)
+
+	res := w.Result()
+	b, _ := io.ReadAll(res.Body)
+
+	if res.StatusCode != http.StatusNotAcceptable {
+		t.Error("bad status code:", res.StatusCode)
+	}
+	if !strings.Contains(string(b), "application/json\napplication/msgpack\ntext/json") {
+		t.Errorf("the available media types are missing from the response:\n%s", b)
+	}
+
+	w = httptest.NewRecorder()
+	n.WriteError(w, errors.New("secret"))
+
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "secret") {
+		t.Errorf("bad response to internal errors: %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var v struct{ Name string }
+
+		if err := DecodeRequest(r, &v); err != nil {
+			WriteError(w, err)
+			return
+		}
+
+		if err := WriteResponse(w, r, http.StatusOK, "hello "+v.Name); err != nil {
+			WriteError(w, err)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		contentType string
+		accept      string
+		body        string
+		status      int
+		response    string
+	}{
+		{"application/json", "", `{"Name":"Luke"}`, http.StatusOK, `"hello Luke"`},
+		{"application/json", "text/json", `{"Name":"Luke"}`, http.StatusOK, `"hello Luke"`},
+		{"application/json", "image/png", `{"Name":"Luke"}`, http.StatusNotAcceptable, ""},
+		{"image/png", "", `{"Name":"Luke"}`, http.StatusUnsupportedMediaType, ""},
+		{"application/json", "", `{"Name":`, http.StatusBadRequest, ""},
+	}
+
+	for _, test := range tests {
+		req, _ := http.NewRequest("POST", server.URL, strings.NewReader(test.body))
+		req.Header.Set("Content-Type", test.contentType)
+		if test.accept != "" {
+			req.Header.Set("Accept", test.accept)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+
+		if res.StatusCode != test.status {
+			t.Errorf("%+v: bad status code %d", test, res.StatusCode)
+		}
+		if test.response != "" && strings.TrimSpace(string(b)) != test.response {
+			t.Errorf("%+v: bad response %q", test, b)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package http
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/segmentio/objconv"
+)
+
+// DefaultType is the media type used by negotiators that don't set one.
+const DefaultType = "application/json"
+
+// A Negotiator selects codecs from a registry to decode HTTP requests and
+// encode HTTP responses.
+//
+// The zero-value is a valid negotiator which uses the global registry.
+type Negotiator struct {
+	// Registry holds the codecs that the negotiator can choose from, the
+	// global registry is used when it is nil. Only codecs registered under
+	// names that look like media types (type/subtype) are candidates.
+	Registry *objconv.Registry
+
+	// DefaultType is the media type preferred when several codecs are equally
+	// acceptable, like when requests have no Accept header. DefaultType is used
+	// when it is empty.
+	DefaultType string
+}
+
+// NegotiateEncoder returns the codec that should be used to encode the
+// response to r according to its Accept header, and the media type that it
+// was registered under.
+//
+// If none of the registered codecs are acceptable the method returns an
+// *Error with the status code 406.
+func (n *Negotiator) NegotiateEncoder(r *http.Request) (codec objconv.Codec, mimetype string, err error) {
+	codecs := n.codecs()
+	accept := strings.Join(r.Header.Values("Accept"), ",")
+	ranges := parseAccept(accept)
+
+	best := match{}
+
+	for _, t := range n.candidates(codecs) {
+		if m := ranges.match(t); m.better(best) {
+			best, mimetype = m, t
+		}
+	}
+
+	if best.q == 0 {
+		err = &Error{Status: http.StatusNotAcceptable, Type: accept}
+		return
+	}
+
+	codec = codecs[mimetype]
+	return
+}
+
+// DecodeRequest decodes the body of r into v with the codec registered for
+// its Content-Type header, parameters of the media type like the charset are
+// ignored.
+//
+// If no codec was registered for the media type the method returns an *Error
+// with the status code 415, and if the body could not be decoded it returns
+// an *Error with the status code 400.
+func (n *Negotiator) DecodeRequest(r *http.Request, v interface{}) error {
+	contentType := r.Header.Get("Content-Type")
+	mimetype, _, err := mime.ParseMediaType(contentType)
+
+	if err != nil {
+		return &Error{Status: http.StatusUnsupportedMediaType, Type: contentType}
+	}
+
+	codec, ok := n.codecs()[mimetype]
+
+	if !ok {
+		return &Error{Status: http.StatusUnsupportedMediaType, Type: mimetype}
+	}
+
+	if err := codec.NewDecoder(r.Body).Decode(v); err != nil {
+		return &Error{Status: http.StatusBadRequest, Type: mimetype, Err: err}
+	}
+
+	return nil
+}
+
+// WriteResponse encodes v with the codec negotiated for r and writes it to w
+// with the given status code.
+//
+// The value is fully encoded before anything is written to w, so nothing is
+// written when the method returns an error and the caller may still report
+// it, for example by passing it to WriteError.
+func (n *Negotiator) WriteResponse(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	h := w.Header()
+	h.Add("Vary", "Accept")
+
+	codec, mimetype, err := n.NegotiateEncoder(r)
+	if err != nil {
+		return err
+	}
+
+	b := &bytes.Buffer{}
+
+	if err := codec.NewEncoder(b).Encode(v); err != nil {
+		return err
+	}
+
+	h.Set("Content-Type", mimetype)
+	h.Set("Content-Length", strconv.Itoa(b.Len()))
+	w.WriteHeader(status)
+	_, err = b.WriteTo(w)
+	return err
+}
+
+// WriteError writes the response for an error returned by the negotiator to
+// w. The status code of *Error values is used, other errors are reported as
+// internal server errors without exposing their message.
+//
+// The body of 406 responses lists the media types that the negotiator can
+// produce.
+func (n *Negotiator) WriteError(w http.ResponseWriter, err error) {
+	e, ok := err.(*Error)
+
+	if !ok {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	msg := e.Error()
+
+	if e.Status == http.StatusNotAcceptable {
+		msg += "\n\navailable media types:\n" + strings.Join(n.candidates(n.codecs()), "\n")
+	}
+
+	http.Error(w, msg, e.Status)
+}
+
+func (n *Negotiator) codecs() map[string]objconv.Codec {
+	if n.Registry != nil {
+		return n.Registry.Codecs()
+	}
+	return objconv.Codecs()
+}
+
+// candidates returns the media types of codecs, sorted with the default type
+// first so it wins ties.
+func (n *Negotiator) candidates(codecs map[string]objconv.Codec) []string {
+	defaultType := n.DefaultType
+	if defaultType == "" {
+		defaultType = DefaultType
+	}
+
+	list := make([]string, 0, len(codecs))
+
+	for t := range codecs {
+		if isMediaType(t) {
+			list = append(list, t)
+		}
+	}
+
+	sort.Slice(list, func(i int, j int) bool {
+		if list[i] == defaultType || list[j] == defaultType {
+			return list[i] == defaultType
+		}
+		return list[i] < list[j]
+	})
+
+	return list
+}
+
+// The default negotiator used by the package-level functions.
+var negotiator Negotiator
+
+// NegotiateEncoder returns the codec of the global registry that should be
+// used to encode the response to r, and the media type that it was registered
+// under.
+func NegotiateEncoder(r *http.Request) (objconv.Codec, string, error) {
+	return negotiator.NegotiateEncoder(r)
+}
+
+// DecodeRequest decodes the body of r into v with the codec of the global
+// registry matching its Content-Type header.
+func DecodeRequest(r *http.Request, v interface{}) error {
+	return negotiator.DecodeRequest(r, v)
+}
+
+// WriteResponse encodes v with the codec of the global registry negotiated
+// for r and writes it to w with the given status code.
+func WriteResponse(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	return negotiator.WriteResponse(w, r, status, v)
+}
+
+// WriteError writes the response for an error returned by the package-level
+// functions to w.
+func WriteError(w http.ResponseWriter, err error) {
+	negotiator.WriteError(w, err)
+}
+
+// mediaRange is a parsed element of an Accept header.
+type mediaRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+// specificity returns 2 for type/subtype, 1 for type/* and 0 for */*.
+func (r mediaRange) specificity() int {
+	switch {
+	case r.typ == "*":
+		return 0
+	case r.subtype == "*":
+		return 1
+	default:
+		return 2
+	}
+}
+
+type mediaRanges []mediaRange
+
+// parseAccept parses the media ranges of an Accept header, invalid elements
+// are ignored and an empty header accepts everything.
+func parseAccept(s string) (ranges mediaRanges) {
+	if strings.TrimSpace(s) == "" {
+		return mediaRanges{{typ: "*", subtype: "*", q: 1}}
+	}
+
+	for _, elem := range strings.Split(s, ",") {
+		params := strings.Split(elem, ";")
+		typ, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(params[0])), "/")
+
+		if !ok || typ == "" || subtype == "" || (typ == "*" && subtype != "*") {
+			continue
+		}
+
+		r := mediaRange{typ: typ, subtype: subtype, q: 1}
+
+		for _, p := range params[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+
+			if strings.EqualFold(strings.TrimSpace(k), "q") {
+				q, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+				if err != nil || q < 0 || q > 1 {
+					ok = false
+				}
+				r.q = q
+			}
+		}
+
+		if ok {
+			ranges = append(ranges, r)
+		}
+	}
+
+	return
+}
+
+// match is the result of matching a media type against media ranges.
+type match struct {
+	q   float64
+	spc int // specificity of the media range that matched
+	pos int // position of the media range that matched
+}
+
+func (m match) better(other match) bool {
+	switch {
+	case m.q != other.q:
+		return m.q > other.q
+	case m.spc != other.spc:
+		return m.spc > other.spc
+	default:
+		return m.pos < other.pos
+	}
+}
+
+// match returns the quality of the most specific media range matching the
+// media type t, the quality is zero if no ranges matched.
+func (ranges mediaRanges) match(t string) (m match) {
+	typ, subtype, _ := strings.Cut(strings.ToLower(t), "/")
+	m.spc = -1
+
+	for i, r := range ranges {
+		if (r.typ != "*" && r.typ != typ) || (r.subtype != "*" && r.subtype != subtype) {
+			continue
+		}
+		if spc := r.specificity(); spc > m.spc {
+			m = match{q: r.q, spc: spc, pos: i}
+		}
+	}
+
+	if m.spc < 0 {
+		m = match{}
+	}
+
+	return
+}
+
+func isMediaType(s string) bool {
+	typ, subtype, ok := strings.Cut(s, "/")
+	return ok && typ != "" && subtype != "" && !strings.ContainsAny(s, " ;,*")
+}
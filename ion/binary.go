@@ -0,0 +1,256 @@
+package ion
+
+import (
+	"errors"
+	"math/big"
+)
+
+// Type codes of the binary Ion format, stored in the high nibble of type
+// descriptors.
+const (
+	typeNull       = 0x0
+	typeBool       = 0x1
+	typePosInt     = 0x2
+	typeNegInt     = 0x3
+	typeFloat      = 0x4
+	typeDecimal    = 0x5
+	typeTimestamp  = 0x6
+	typeSymbol     = 0x7
+	typeString     = 0x8
+	typeClob       = 0x9
+	typeBlob       = 0xA
+	typeList       = 0xB
+	typeSexp       = 0xC
+	typeStruct     = 0xD
+	typeAnnotation = 0xE
+
+	// Lengths stored in the low nibble of type descriptors.
+	lenVar  = 0xE // the length is encoded as a VarUInt after the descriptor
+	lenNull = 0xF // the value is null
+)
+
+// versionMarker is the binary Ion version marker for Ion 1.0, it starts every
+// binary stream.
+var versionMarker = [...]byte{0xE0, 0x01, 0x00, 0xEA}
+
+// systemSymbols is the system symbol table of Ion 1.0, symbol IDs are the
+// indexes in this table (zero being reserved).
+var systemSymbols = [...]string{
+	"",
+	"$ion",
+	"$ion_1_0",
+	"$ion_symbol_table",
+	"name",
+	"version",
+	"imports",
+	"symbols",
+	"max_id",
+	"$ion_shared_symbol_table",
+}
+
+// System symbol IDs used by the emitter and parser.
+const (
+	symbolIonSymbolTable = 3
+	symbolImports        = 6
+	symbolSymbols        = 7
+)
+
+func appendVarUint(b []byte, v uint64) []byte {
+	var a [10]byte
+	i := len(a) - 1
+	a[i] = byte(v&0x7F) | 0x80
+
+	for v >>= 7; v != 0; v >>= 7 {
+		i--
+		a[i] = byte(v & 0x7F)
+	}
+
+	return append(b, a[i:]...)
+}
+
+// appendVarInt appends v as a VarInt, neg is used to represent the negative
+// zero which has a special meaning for timestamp offsets.
+func appendVarInt(b []byte, v int64, neg bool) []byte {
+	u := uint64(v)
+	if v < 0 {
+		u, neg = uint64(-v), true
+	}
+
+	var a [10]byte
+	i := len(a) - 1
+	a[i] = byte(u&0x7F) | 0x80
+
+	for u >>= 7; u != 0; u >>= 7 {
+		i--
+		a[i] = byte(u & 0x7F)
+	}
+
+	// The first byte holds only 6 bits of magnitude, the 7th being the sign.
+	if a[i]&0x40 != 0 {
+		i--
+		a[i] = 0
+	}
+
+	if neg {
+		a[i] |= 0x40
+	}
+
+	return append(b, a[i:]...)
+}
+
+// appendUint appends the minimal big-endian representation of v, zero has an
+// empty representation.
+func appendUint(b []byte, v uint64) []byte {
+	n := uintLen(v)
+	for i := n - 1; i >= 0; i-- {
+		b = append(b, byte(v>>(8*uint(i))))
+	}
+	return b
+}
+
+func uintLen(v uint64) (n int) {
+	for ; v != 0; v >>= 8 {
+		n++
+	}
+	return
+}
+
+// appendInt appends v as a signed-magnitude integer.
+func appendInt(b []byte, v int64) []byte {
+	if v == 0 {
+		return b
+	}
+
+	u, neg := uint64(v), false
+	if v < 0 {
+		u, neg = uint64(-v), true
+	}
+
+	i := len(b)
+	b = appendUint(b, u)
+
+	// The most significant bit of the first byte holds the sign.
+	if b[i]&0x80 != 0 {
+		b = append(b, 0)
+		copy(b[i+1:], b[i:])
+		b[i] = 0
+	}
+
+	if neg {
+		b[i] |= 0x80
+	}
+
+	return b
+}
+
+// appendDescriptor appends the type descriptor and length of a value of type
+// t and n bytes.
+func appendDescriptor(b []byte, t byte, n int) []byte {
+	if n < lenVar {
+		return append(b, t<<4|byte(n))
+	}
+	return appendVarUint(append(b, t<<4|lenVar), uint64(n))
+}
+
+// insertDescriptor inserts the type descriptor of the value of type t that
+// starts at offset i and extends to the end of b.
+func insertDescriptor(b []byte, i int, t byte, prefix []byte) []byte {
+	var a [16]byte
+	h := appendDescriptor(a[:0], t, len(b)-i+len(prefix))
+	h = append(h, prefix...)
+	return insertBytes(b, i, h)
+}
+
+func insertBytes(b []byte, i int, h []byte) []byte {
+	n := len(b)
+	b = append(b, h...)
+	copy(b[i+len(h):], b[i:n])
+	copy(b[i:], h)
+	return b
+}
+
+// binaryReader reads values from a buffer holding the binary representation
+// of Ion values.
+type binaryReader struct {
+	b       []byte
+	i       int
+	symbols []string // symbol table used to resolve symbol IDs
+}
+
+func (r *binaryReader) readVarUint() (v uint64, err error) {
+	for n := 0; r.i < len(r.b); n++ {
+		if n == 9 {
+			return 0, errVarIntOverflow
+		}
+		c := r.b[r.i]
+		r.i++
+		v = v<<7 | uint64(c&0x7F)
+		if c&0x80 != 0 {
+			return v, nil
+		}
+	}
+	return 0, errTruncated
+}
+
+// readVarInt reads a VarInt, neg is true if the value was negative (which is
+// used to detect the negative zero).
+func (r *binaryReader) readVarInt() (v int64, neg bool, err error) {
+	if r.i == len(r.b) {
+		return 0, false, errTruncated
+	}
+
+	c := r.b[r.i]
+	r.i++
+	neg = c&0x40 != 0
+	u := uint64(c & 0x3F)
+
+	for n := 0; c&0x80 == 0; n++ {
+		if n == 9 {
+			return 0, false, errVarIntOverflow
+		}
+		if r.i == len(r.b) {
+			return 0, false, errTruncated
+		}
+		c = r.b[r.i]
+		r.i++
+		u = u<<7 | uint64(c&0x7F)
+	}
+
+	if v = int64(u); neg {
+		v = -v
+	}
+
+	return
+}
+
+func (r *binaryReader) read(n int) ([]byte, error) {
+	if n > len(r.b)-r.i {
+		return nil, errTruncated
+	}
+	b := r.b[r.i : r.i+n]
+	r.i += n
+	return b, nil
+}
+
+// readInt reads a signed-magnitude integer of n bytes.
+func (r *binaryReader) readInt(n int) (*big.Int, error) {
+	b, err := r.read(n)
+	if err != nil || n == 0 {
+		return new(big.Int), err
+	}
+
+	neg := b[0]&0x80 != 0
+	m := append([]byte{b[0] & 0x7F}, b[1:]...)
+	v := new(big.Int).SetBytes(m)
+
+	if neg {
+		v.Neg(v)
+	}
+
+	return v, nil
+}
+
+var (
+	errTruncated      = errors.New("objconv/ion: truncated binary value")
+	errVarIntOverflow = errors.New("objconv/ion: variable length integer overflows 64 bits")
+)
@@ -0,0 +1,350 @@
+package ion
+
+import (
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+// BinaryEmitter implements a binary Ion emitter that satisfies the
+// objconv.Emitter interface.
+//
+// The emitter maintains a local symbol table for the struct field names and
+// annotations it writes. Each top-level value is buffered until it is
+// complete, then written after the version marker (for the first value of the
+// stream) and a local symbol table that appends the symbols introduced by the
+// value to the ones already declared.
+//
+// Durations and errors are written as strings, map keys that aren't strings
+// are converted to their text representation.
+type BinaryEmitter struct {
+	w io.Writer
+	b []byte // value buffer
+	h []byte // header buffer (version marker and symbol tables)
+	// This stack tracks the lists and structs being emitted.
+	stack []binaryFrame
+	// Annotations of the next value.
+	ann []string
+	// Local symbol table, symbol IDs of local symbols start after the system
+	// symbols.
+	symbols map[string]int
+	table   []string
+	// Number of symbols of the table that were written to the output.
+	written int
+	// Set when the version marker was written to the output.
+	marker bool
+}
+
+type binaryFrame struct {
+	typ   byte   // typeList or typeStruct
+	start int    // offset of the first byte of the container's content
+	ann   []byte // annotation symbol IDs of the container
+	key   bool   // next value is a struct field name
+}
+
+func NewBinaryEmitter(w io.Writer) *BinaryEmitter {
+	return &BinaryEmitter{w: w}
+}
+
+func (e *BinaryEmitter) Reset(w io.Writer) {
+	e.w = w
+	e.b = e.b[:0]
+	e.stack = e.stack[:0]
+	e.ann = nil
+	e.symbols = nil
+	e.table = e.table[:0]
+	e.written = 0
+	e.marker = false
+}
+
+// SequenceEmitter returns true, Ion streams are sequences of values.
+func (e *BinaryEmitter) SequenceEmitter() bool {
+	return true
+}
+
+// EmitAnnotations sets the annotations of the next value emitted.
+func (e *BinaryEmitter) EmitAnnotations(annotations []string) error {
+	if e.isKey() {
+		return errKey
+	}
+	e.ann = append(e.ann, annotations...)
+	return nil
+}
+
+func (e *BinaryEmitter) EmitNil() error {
+	if e.isKey() {
+		return errKey
+	}
+	start, ann := e.begin()
+	e.b = append(e.b, typeNull<<4|lenNull)
+	return e.end(start, ann)
+}
+
+func (e *BinaryEmitter) EmitBool(v bool) error {
+	if e.isKey() {
+		return e.emitKey(strconv.FormatBool(v))
+	}
+	start, ann := e.begin()
+	if v {
+		e.b = append(e.b, typeBool<<4|1)
+	} else {
+		e.b = append(e.b, typeBool<<4)
+	}
+	return e.end(start, ann)
+}
+
+func (e *BinaryEmitter) EmitInt(v int64, _ int) error {
+	if e.isKey() {
+		return e.emitKey(strconv.FormatInt(v, 10))
+	}
+	if v >= 0 {
+		return e.emitUint(typePosInt, uint64(v))
+	}
+	return e.emitUint(typeNegInt, uint64(-v))
+}
+
+func (e *BinaryEmitter) EmitUint(v uint64, _ int) error {
+	if e.isKey() {
+		return e.emitKey(strconv.FormatUint(v, 10))
+	}
+	return e.emitUint(typePosInt, v)
+}
+
+func (e *BinaryEmitter) emitUint(t byte, v uint64) error {
+	start, ann := e.begin()
+	e.b = appendDescriptor(e.b, t, uintLen(v))
+	e.b = appendUint(e.b, v)
+	return e.end(start, ann)
+}
+
+func (e *BinaryEmitter) EmitFloat(v float64, bitSize int) error {
+	if e.isKey() {
+		return e.emitKey(strconv.FormatFloat(v, 'g', -1, bitSize))
+	}
+	start, ann := e.begin()
+	if bitSize == 32 {
+		e.b = append(e.b, typeFloat<<4|4)
+		e.b = appendUint32(e.b, math.Float32bits(float32(v)))
+	} else {
+		e.b = append(e.b, typeFloat<<4|8)
+		e.b = appendUint64(e.b, math.Float64bits(v))
+	}
+	return e.end(start, ann)
+}
+
+func (e *BinaryEmitter) EmitString(v string) error {
+	if e.isKey() {
+		return e.emitKey(v)
+	}
+	start, ann := e.begin()
+	e.b = appendDescriptor(e.b, typeString, len(v))
+	e.b = append(e.b, v...)
+	return e.end(start, ann)
+}
+
+func (e *BinaryEmitter) EmitBytes(v []byte) error {
+	if e.isKey() {
+		return errKey
+	}
+	start, ann := e.begin()
+	e.b = appendDescriptor(e.b, typeBlob, len(v))
+	e.b = append(e.b, v...)
+	return e.end(start, ann)
+}
+
+// EmitTime writes v as a timestamp with second precision, or nanosecond
+// precision if v has a fractional part.
+func (e *BinaryEmitter) EmitTime(v time.Time) error {
+	if e.isKey() {
+		return e.emitKey(v.Format(time.RFC3339Nano))
+	}
+
+	start, ann := e.begin()
+	_, offset := v.Zone()
+	v = v.UTC()
+
+	i := len(e.b)
+	e.b = appendVarInt(e.b, int64(offset/60), false)
+	e.b = appendVarUint(e.b, uint64(v.Year()))
+	e.b = appendVarUint(e.b, uint64(v.Month()))
+	e.b = appendVarUint(e.b, uint64(v.Day()))
+	e.b = appendVarUint(e.b, uint64(v.Hour()))
+	e.b = appendVarUint(e.b, uint64(v.Minute()))
+	e.b = appendVarUint(e.b, uint64(v.Second()))
+
+	if nsec := v.Nanosecond(); nsec != 0 {
+		e.b = appendVarInt(e.b, -9, false)
+		e.b = appendInt(e.b, int64(nsec))
+	}
+
+	e.b = insertDescriptor(e.b, i, typeTimestamp, nil)
+	return e.end(start, ann)
+}
+
+func (e *BinaryEmitter) EmitDuration(v time.Duration) error {
+	return e.EmitString(v.String())
+}
+
+func (e *BinaryEmitter) EmitError(v error) error {
+	return e.EmitString(v.Error())
+}
+
+func (e *BinaryEmitter) EmitArrayBegin(_ int) (err error) {
+	return e.emitContainerBegin(typeList)
+}
+
+func (e *BinaryEmitter) EmitArrayEnd() (err error) {
+	return e.emitContainerEnd()
+}
+
+func (e *BinaryEmitter) EmitArrayNext() (err error) {
+	return
+}
+
+func (e *BinaryEmitter) EmitMapBegin(_ int) (err error) {
+	return e.emitContainerBegin(typeStruct)
+}
+
+func (e *BinaryEmitter) EmitMapEnd() (err error) {
+	return e.emitContainerEnd()
+}
+
+func (e *BinaryEmitter) EmitMapValue() (err error) {
+	e.stack[len(e.stack)-1].key = false
+	return
+}
+
+func (e *BinaryEmitter) EmitMapNext() (err error) {
+	e.stack[len(e.stack)-1].key = true
+	return
+}
+
+func (e *BinaryEmitter) emitContainerBegin(t byte) error {
+	if e.isKey() {
+		return errKey
+	}
+	start, ann := e.begin()
+	e.stack = append(e.stack, binaryFrame{
+		typ:   t,
+		start: start,
+		ann:   ann,
+		key:   t == typeStruct,
+	})
+	return nil
+}
+
+func (e *BinaryEmitter) emitContainerEnd() error {
+	i := len(e.stack) - 1
+	f := e.stack[i]
+	e.stack = e.stack[:i]
+	e.b = insertDescriptor(e.b, f.start, f.typ, nil)
+	return e.end(f.start, f.ann)
+}
+
+// isKey returns true if the next value emitted is a struct field name.
+func (e *BinaryEmitter) isKey() bool {
+	n := len(e.stack)
+	return n != 0 && e.stack[n-1].key
+}
+
+func (e *BinaryEmitter) emitKey(k string) error {
+	e.b = appendVarUint(e.b, uint64(e.symbol(k)))
+	return nil
+}
+
+// begin returns the offset where the next value starts and the symbol IDs of
+// its annotations.
+func (e *BinaryEmitter) begin() (start int, ann []byte) {
+	for _, a := range e.ann {
+		ann = appendVarUint(ann, uint64(e.symbol(a)))
+	}
+	e.ann = e.ann[:0]
+	return len(e.b), ann
+}
+
+// end wraps the value that starts at offset start with its annotations, and
+// flushes the output buffer when a top-level value was completed.
+func (e *BinaryEmitter) end(start int, ann []byte) (err error) {
+	if len(ann) != 0 {
+		var a [10]byte
+		prefix := append(appendVarUint(a[:0], uint64(len(ann))), ann...)
+		e.b = insertDescriptor(e.b, start, typeAnnotation, prefix)
+	}
+
+	if len(e.stack) == 0 {
+		h := e.h[:0]
+
+		if !e.marker {
+			h = append(h, versionMarker[:]...)
+			e.marker = true
+		}
+
+		if e.written != len(e.table) {
+			h = e.appendSymbolTable(h)
+			e.written = len(e.table)
+		}
+
+		e.h = append(h, e.b...)
+		e.b = e.b[:0]
+		_, err = e.w.Write(e.h)
+	}
+
+	return
+}
+
+// symbol returns the symbol ID of s, adding it to the local symbol table if
+// it wasn't declared yet.
+func (e *BinaryEmitter) symbol(s string) int {
+	if e.symbols == nil {
+		e.symbols = make(map[string]int, 2*len(systemSymbols))
+		for id, name := range systemSymbols[1:] {
+			e.symbols[name] = id + 1
+		}
+	}
+
+	id, ok := e.symbols[s]
+
+	if !ok {
+		id = len(systemSymbols) + len(e.table)
+		e.symbols[s] = id
+		e.table = append(e.table, s)
+	}
+
+	return id
+}
+
+// appendSymbolTable appends a local symbol table declaring the symbols that
+// weren't written yet, which imports the previous table if there was one.
+func (e *BinaryEmitter) appendSymbolTable(b []byte) []byte {
+	start := len(b)
+
+	if e.written != 0 {
+		b = appendVarUint(b, symbolImports)
+		b = append(b, typeSymbol<<4|1, symbolIonSymbolTable)
+	}
+
+	b = appendVarUint(b, symbolSymbols)
+	list := len(b)
+
+	for _, s := range e.table[e.written:] {
+		b = appendDescriptor(b, typeString, len(s))
+		b = append(b, s...)
+	}
+
+	b = insertDescriptor(b, list, typeList, nil)
+	b = insertDescriptor(b, start, typeStruct, nil)
+	b = insertDescriptor(b, start, typeAnnotation, []byte{0x81, 0x80 | symbolIonSymbolTable})
+	return b
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return append(b,
+		byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32),
+		byte(v>>24), byte(v>>16), byte(v>>8), byte(v),
+	)
+}
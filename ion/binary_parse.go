@@ -0,0 +1,516 @@
+package ion
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/objconv"
+)
+
+// BinaryParser implements a binary Ion parser that satisfies the
+// objconv.Parser interface.
+//
+// The parser reads one top-level value at a time, version markers and local
+// symbol tables found in the stream update the symbol table used to resolve
+// struct field names, symbols and annotations. Symbols with unknown text are
+// parsed as "$<id>" strings. Values are mapped to objconv types with the same
+// rules as the text Parser.
+type BinaryParser struct {
+	r       *bufio.Reader
+	b       []byte   // buffer of the top-level value being loaded
+	symbols []string // current symbol table
+	walker
+}
+
+func NewBinaryParser(r io.Reader) *BinaryParser {
+	return &BinaryParser{r: bufio.NewReader(r)}
+}
+
+func (p *BinaryParser) Reset(r io.Reader) {
+	if p.r == nil {
+		p.r = bufio.NewReader(r)
+	} else {
+		p.r.Reset(r)
+	}
+	p.symbols = nil
+	p.walker.reset()
+}
+
+func (p *BinaryParser) Buffered() io.Reader {
+	b, _ := p.r.Peek(p.r.Buffered())
+	return bytes.NewReader(b)
+}
+
+// SequenceParser returns true, Ion streams are sequences of values.
+func (p *BinaryParser) SequenceParser() bool {
+	return true
+}
+
+func (p *BinaryParser) ParseType() (objconv.Type, error) {
+	if err := p.load(); err != nil {
+		return objconv.Unknown, err
+	}
+	return p.parseType()
+}
+
+// ParseAnnotations returns the annotations of the next value.
+func (p *BinaryParser) ParseAnnotations() ([]string, error) {
+	if err := p.load(); err != nil {
+		return nil, err
+	}
+	return p.top().annotations(), nil
+}
+
+// load reads the next top-level value if the previous one was entirely
+// consumed.
+func (p *BinaryParser) load() (err error) {
+	if len(p.stack) != 0 {
+		return
+	}
+
+	if p.symbols == nil {
+		p.symbols = systemSymbols[:]
+	}
+
+	for {
+		var v interface{}
+		var pad bool
+
+		if v, pad, err = p.readTopLevel(); err != nil || pad {
+			if err != nil {
+				return
+			}
+			continue
+		}
+
+		if a, ok := v.(*annotatedValue); ok && a.annotations[0] == "$ion_symbol_table" {
+			if s, ok := a.value.(*structValue); ok {
+				if err = p.loadSymbolTable(s); err != nil {
+					return
+				}
+				continue
+			}
+		}
+
+		p.push(newParser(v))
+		return
+	}
+}
+
+// readTopLevel reads the next top-level value from the stream, resetting the
+// symbol table when a version marker is found.
+func (p *BinaryParser) readTopLevel() (v interface{}, pad bool, err error) {
+	var c byte
+	var n uint64
+
+	if c, err = p.r.ReadByte(); err != nil {
+		return
+	}
+
+	if c == versionMarker[0] {
+		var b [len(versionMarker) - 1]byte
+
+		if _, err = io.ReadFull(p.r, b[:]); err != nil {
+			err = noEOF(err)
+			return
+		}
+
+		if !bytes.Equal(b[:], versionMarker[1:]) {
+			err = fmt.Errorf("objconv/ion: unsupported binary version marker % x", append([]byte{c}, b[:]...))
+			return
+		}
+
+		p.symbols = systemSymbols[:]
+		return nil, true, nil
+	}
+
+	p.b = append(p.b[:0], c)
+
+	switch t, l := c>>4, c&0xF; {
+	case t == typeBool || l == lenNull:
+	case l == lenVar || (t == typeStruct && l == 1):
+		for {
+			if c, err = p.r.ReadByte(); err != nil {
+				err = noEOF(err)
+				return
+			}
+			if p.b = append(p.b, c); len(p.b) > 10 {
+				err = errVarIntOverflow
+				return
+			}
+			if n = n<<7 | uint64(c&0x7F); c&0x80 != 0 {
+				break
+			}
+		}
+	default:
+		n = uint64(l)
+	}
+
+	if n > math.MaxInt32 {
+		err = fmt.Errorf("objconv/ion: binary value of %d bytes is too large", n)
+		return
+	}
+
+	i := len(p.b)
+	p.b = append(p.b, make([]byte, int(n))...)
+
+	if _, err = io.ReadFull(p.r, p.b[i:]); err != nil {
+		err = noEOF(err)
+		return
+	}
+
+	r := binaryReader{b: p.b, symbols: p.symbols}
+	return r.readValue()
+}
+
+// loadSymbolTable updates the symbol table of the parser with the local
+// symbol table s.
+func (p *BinaryParser) loadSymbolTable(s *structValue) error {
+	var symbols []string
+	var imports bool
+
+	for i, k := range s.keys {
+		switch v := s.values[i]; k {
+		case "imports":
+			switch v {
+			case "$ion_symbol_table":
+				imports = true
+			default:
+				if _, ok := v.([]interface{}); ok {
+					return errors.New("objconv/ion: local symbol tables importing shared symbol tables are not supported")
+				}
+			}
+
+		case "symbols":
+			if list, ok := v.([]interface{}); ok {
+				for _, x := range list {
+					s, ok := x.(string)
+					if !ok {
+						s = "$" + strconv.Itoa(len(p.symbols)+len(symbols))
+					}
+					symbols = append(symbols, s)
+				}
+			}
+		}
+	}
+
+	if !imports {
+		p.symbols = systemSymbols[:]
+	}
+
+	// Always copy, the previous table may be shared with the values that
+	// were already parsed.
+	p.symbols = append(p.symbols[:len(p.symbols):len(p.symbols)], symbols...)
+	return nil
+}
+
+// readValue reads the next value, pad is true if the value was a NOP pad.
+func (r *binaryReader) readValue() (v interface{}, pad bool, err error) {
+	var c byte
+	var n uint64
+	var b []byte
+
+	if r.i == len(r.b) {
+		return nil, false, errTruncated
+	}
+
+	c = r.b[r.i]
+	r.i++
+	t, l := c>>4, c&0xF
+
+	switch {
+	case t == typeBool:
+		switch l {
+		case 0, 1:
+			return l == 1, false, nil
+		case lenNull:
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("objconv/ion: invalid boolean type descriptor 0x%02x", c)
+
+	case l == lenNull:
+		if t == typeAnnotation {
+			return nil, false, fmt.Errorf("objconv/ion: invalid annotation wrapper type descriptor 0x%02x", c)
+		}
+		return nil, false, nil
+
+	case l == lenVar || (t == typeStruct && l == 1):
+		if n, err = r.readVarUint(); err != nil {
+			return
+		}
+
+	default:
+		n = uint64(l)
+	}
+
+	if n > uint64(len(r.b)-r.i) {
+		return nil, false, errTruncated
+	}
+
+	if b, err = r.read(int(n)); err != nil {
+		return
+	}
+
+	switch t {
+	case typeNull:
+		return nil, true, nil
+
+	case typePosInt, typeNegInt:
+		v, err = parseInt(b, t == typeNegInt)
+
+	case typeFloat:
+		switch len(b) {
+		case 0:
+			v = 0.0
+		case 4:
+			v = float64(math.Float32frombits(uint32(getUint(b))))
+		case 8:
+			v = math.Float64frombits(getUint(b))
+		default:
+			err = fmt.Errorf("objconv/ion: invalid binary float of %d bytes", len(b))
+		}
+
+	case typeDecimal:
+		v, err = parseDecimal(b)
+
+	case typeTimestamp:
+		v, err = parseBinaryTimestamp(b)
+
+	case typeSymbol:
+		if len(b) > 8 {
+			return nil, false, fmt.Errorf("objconv/ion: symbol ID of %d bytes is too large", len(b))
+		}
+		v = r.symbol(getUint(b))
+
+	case typeString:
+		v = string(b)
+
+	case typeClob, typeBlob:
+		v = append([]byte{}, b...)
+
+	case typeList, typeSexp:
+		s := binaryReader{b: b, symbols: r.symbols}
+		a := make([]interface{}, 0, 10)
+
+		for s.i != len(s.b) {
+			var x interface{}
+			var skip bool
+
+			if x, skip, err = s.readValue(); err != nil {
+				return
+			}
+
+			if !skip {
+				a = append(a, x)
+			}
+		}
+
+		v = a
+
+	case typeStruct:
+		s := binaryReader{b: b, symbols: r.symbols}
+		m := &structValue{}
+
+		for s.i != len(s.b) {
+			var id uint64
+			var x interface{}
+			var skip bool
+
+			if id, err = s.readVarUint(); err != nil {
+				return
+			}
+
+			if x, skip, err = s.readValue(); err != nil {
+				return
+			}
+
+			if !skip {
+				m.keys = append(m.keys, s.symbol(id))
+				m.values = append(m.values, x)
+			}
+		}
+
+		v = m
+
+	case typeAnnotation:
+		s := binaryReader{b: b, symbols: r.symbols}
+		a := &annotatedValue{}
+
+		if n, err = s.readVarUint(); err != nil {
+			return
+		}
+
+		if n == 0 || n > uint64(len(s.b)-s.i) {
+			return nil, false, errors.New("objconv/ion: invalid annotation wrapper length")
+		}
+
+		ids := binaryReader{b: s.b[s.i : s.i+int(n)]}
+		s.i += int(n)
+
+		for ids.i != len(ids.b) {
+			var id uint64
+			if id, err = ids.readVarUint(); err != nil {
+				return
+			}
+			a.annotations = append(a.annotations, s.symbol(id))
+		}
+
+		if s.i == len(s.b) || s.b[s.i]>>4 == typeAnnotation {
+			return nil, false, errors.New("objconv/ion: annotation wrappers must wrap exactly one value")
+		}
+
+		if a.value, pad, err = s.readValue(); err != nil {
+			return
+		}
+
+		if pad || s.i != len(s.b) {
+			return nil, false, errors.New("objconv/ion: annotation wrappers must wrap exactly one value")
+		}
+
+		v = a
+
+	default:
+		err = fmt.Errorf("objconv/ion: invalid type descriptor 0x%02x", c)
+	}
+
+	return
+}
+
+// symbol returns the text of the symbol with the given ID.
+func (r *binaryReader) symbol(id uint64) string {
+	if id != 0 && id < uint64(len(r.symbols)) {
+		return r.symbols[id]
+	}
+	return "$" + strconv.FormatUint(id, 10)
+}
+
+func parseInt(b []byte, neg bool) (interface{}, error) {
+	if len(b) > 8 {
+		return nil, errors.New("objconv/ion: integer overflows the range of 64 bits integers")
+	}
+
+	u := getUint(b)
+
+	switch {
+	case !neg && u <= math.MaxInt64:
+		return int64(u), nil
+	case !neg:
+		return u, nil
+	case u == 0:
+		return nil, errors.New("objconv/ion: negative zero is not a valid integer")
+	case u <= 1<<63:
+		return int64(-u), nil
+	}
+
+	return nil, errors.New("objconv/ion: integer overflows the range of 64 bits integers")
+}
+
+func parseDecimal(b []byte) (interface{}, error) {
+	if len(b) == 0 {
+		return 0.0, nil
+	}
+
+	r := binaryReader{b: b}
+
+	exp, _, err := r.readVarInt()
+	if err != nil {
+		return nil, err
+	}
+
+	coef, err := r.readInt(len(b) - r.i)
+	if err != nil {
+		return nil, err
+	}
+
+	return strconv.ParseFloat(coef.String()+"e"+strconv.FormatInt(exp, 10), 64)
+}
+
+func parseBinaryTimestamp(b []byte) (interface{}, error) {
+	var f [5]uint64 // month, day, hour, minute, second
+	var nsec int64
+	var n int
+
+	r := binaryReader{b: b}
+
+	offset, unknown, err := r.readVarInt()
+	if err != nil {
+		return nil, err
+	}
+	unknown = unknown && offset == 0
+
+	year, err := r.readVarUint()
+	if err != nil {
+		return nil, err
+	}
+
+	for n = 0; n != 5 && r.i != len(r.b); n++ {
+		if f[n], err = r.readVarUint(); err != nil {
+			return nil, err
+		}
+	}
+
+	if n == 3 {
+		return nil, errors.New("objconv/ion: binary timestamp has an hour but no minute")
+	}
+
+	if r.i != len(r.b) {
+		exp, _, err := r.readVarInt()
+		if err != nil {
+			return nil, err
+		}
+
+		coef, err := r.readInt(len(r.b) - r.i)
+		if err != nil {
+			return nil, err
+		}
+
+		if exp += 9; exp >= 0 {
+			coef.Mul(coef, new(big.Int).Exp(big.NewInt(10), big.NewInt(exp), nil))
+		} else {
+			coef.Quo(coef, new(big.Int).Exp(big.NewInt(10), big.NewInt(-exp), nil))
+		}
+
+		if coef.Sign() < 0 || coef.Cmp(big.NewInt(1e9)) >= 0 {
+			return nil, errors.New("objconv/ion: binary timestamp has an invalid fraction of second")
+		}
+
+		nsec = coef.Int64()
+	}
+
+	// Fields that are missing default to the first month or day.
+	if n < 1 {
+		f[0] = 1
+	}
+	if n < 2 {
+		f[1] = 1
+	}
+
+	t := time.Date(int(year), time.Month(f[0]), int(f[1]), int(f[2]), int(f[3]), int(f[4]), int(nsec), time.UTC)
+
+	if !unknown && offset != 0 {
+		t = t.In(time.FixedZone("", int(offset)*60))
+	}
+
+	return t, nil
+}
+
+func getUint(b []byte) (u uint64) {
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+	return
+}
+
+func noEOF(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,90 @@
+package ion
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/segmentio/objconv"
+)
+
+// NewDecoder returns a new Ion text decoder that parses values from r.
+func NewDecoder(r io.Reader) *objconv.Decoder {
+	return objconv.NewDecoder(NewParser(r))
+}
+
+// NewStreamDecoder returns a new Ion text stream decoder that parses values
+// from r.
+func NewStreamDecoder(r io.Reader) *objconv.StreamDecoder {
+	return objconv.NewStreamDecoder(NewParser(r))
+}
+
+// NewBinaryDecoder returns a new binary Ion decoder that parses values from r.
+func NewBinaryDecoder(r io.Reader) *objconv.Decoder {
+	return objconv.NewDecoder(NewBinaryParser(r))
+}
+
+// NewBinaryStreamDecoder returns a new binary Ion stream decoder that parses
+// values from r.
+func NewBinaryStreamDecoder(r io.Reader) *objconv.StreamDecoder {
+	return objconv.NewStreamDecoder(NewBinaryParser(r))
+}
+
+// Unmarshal decodes an Ion text representation of v from b.
+func Unmarshal(b []byte, v interface{}) error {
+	u := unmarshalerPool.Get().(*unmarshaler)
+	u.reset(b)
+
+	err := (objconv.Decoder{Parser: u}).Decode(v)
+
+	u.reset(nil)
+	unmarshalerPool.Put(u)
+	return err
+}
+
+// UnmarshalBinary decodes a binary Ion representation of v from b.
+func UnmarshalBinary(b []byte, v interface{}) error {
+	u := binaryUnmarshalerPool.Get().(*binaryUnmarshaler)
+	u.reset(b)
+
+	err := (objconv.Decoder{Parser: u}).Decode(v)
+
+	u.reset(nil)
+	binaryUnmarshalerPool.Put(u)
+	return err
+}
+
+var unmarshalerPool = sync.Pool{
+	New: func() interface{} { return &unmarshaler{} },
+}
+
+var binaryUnmarshalerPool = sync.Pool{
+	New: func() interface{} { return newBinaryUnmarshaler() },
+}
+
+type unmarshaler struct {
+	Parser
+	b bytes.Buffer
+}
+
+func (u *unmarshaler) reset(b []byte) {
+	u.b = *bytes.NewBuffer(b)
+	u.Reset(&u.b)
+}
+
+type binaryUnmarshaler struct {
+	BinaryParser
+	b bytes.Buffer
+}
+
+func newBinaryUnmarshaler() *binaryUnmarshaler {
+	u := &binaryUnmarshaler{}
+	u.r = bufio.NewReader(&u.b)
+	return u
+}
+
+func (u *binaryUnmarshaler) reset(b []byte) {
+	u.b = *bytes.NewBuffer(b)
+	u.Reset(&u.b)
+}
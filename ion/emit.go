@@ -0,0 +1,310 @@
+package ion
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// Emitter implements an Ion text emitter that satisfies the objconv.Emitter
+// interface.
+//
+// Top-level values are written on separate lines, which makes streams of
+// values valid Ion documents. Durations and errors are written as strings,
+// map keys that aren't strings are converted to their text representation.
+type Emitter struct {
+	w io.Writer
+	b []byte
+	// This stack tracks whether the next value of maps being emitted is a
+	// key.
+	stack []bool
+	// Annotations of the next value.
+	ann []string
+}
+
+func NewEmitter(w io.Writer) *Emitter {
+	return &Emitter{w: w}
+}
+
+func (e *Emitter) Reset(w io.Writer) {
+	e.w = w
+	e.b = e.b[:0]
+	e.stack = e.stack[:0]
+	e.ann = nil
+}
+
+// SequenceEmitter returns true, Ion streams are sequences of values.
+func (e *Emitter) SequenceEmitter() bool {
+	return true
+}
+
+// TextEmitter returns true, Ion text is a human-readable format.
+func (e *Emitter) TextEmitter() bool {
+	return true
+}
+
+// EmitAnnotations sets the annotations of the next value emitted.
+func (e *Emitter) EmitAnnotations(annotations []string) error {
+	if e.isKey() {
+		return errKey
+	}
+	e.ann = append(e.ann, annotations...)
+	return nil
+}
+
+func (e *Emitter) EmitNil() error {
+	if e.isKey() {
+		return errKey
+	}
+	e.begin()
+	e.b = append(e.b, "null"...)
+	return e.end()
+}
+
+func (e *Emitter) EmitBool(v bool) error {
+	if e.isKey() {
+		return e.emitKey(strconv.FormatBool(v))
+	}
+	e.begin()
+	e.b = strconv.AppendBool(e.b, v)
+	return e.end()
+}
+
+func (e *Emitter) EmitInt(v int64, _ int) error {
+	if e.isKey() {
+		return e.emitKey(strconv.FormatInt(v, 10))
+	}
+	e.begin()
+	e.b = strconv.AppendInt(e.b, v, 10)
+	return e.end()
+}
+
+func (e *Emitter) EmitUint(v uint64, _ int) error {
+	if e.isKey() {
+		return e.emitKey(strconv.FormatUint(v, 10))
+	}
+	e.begin()
+	e.b = strconv.AppendUint(e.b, v, 10)
+	return e.end()
+}
+
+func (e *Emitter) EmitFloat(v float64, bitSize int) error {
+	if e.isKey() {
+		return e.emitKey(strconv.FormatFloat(v, 'g', -1, bitSize))
+	}
+	e.begin()
+	e.b = appendFloat(e.b, v, bitSize)
+	return e.end()
+}
+
+func (e *Emitter) EmitString(v string) error {
+	if e.isKey() {
+		return e.emitKey(v)
+	}
+	e.begin()
+	e.b = appendString(e.b, v, '"')
+	return e.end()
+}
+
+func (e *Emitter) EmitBytes(v []byte) error {
+	if e.isKey() {
+		return errKey
+	}
+	e.begin()
+	e.b = append(e.b, "{{"...)
+	n := len(e.b)
+	m := base64.StdEncoding.EncodedLen(len(v))
+	e.b = append(e.b, make([]byte, m)...)
+	base64.StdEncoding.Encode(e.b[n:], v)
+	e.b = append(e.b, "}}"...)
+	return e.end()
+}
+
+func (e *Emitter) EmitTime(v time.Time) error {
+	if e.isKey() {
+		return e.emitKey(v.Format(time.RFC3339Nano))
+	}
+	e.begin()
+	e.b = v.AppendFormat(e.b, time.RFC3339Nano)
+	return e.end()
+}
+
+func (e *Emitter) EmitDuration(v time.Duration) error {
+	return e.EmitString(v.String())
+}
+
+func (e *Emitter) EmitError(v error) error {
+	return e.EmitString(v.Error())
+}
+
+func (e *Emitter) EmitArrayBegin(_ int) (err error) {
+	if e.isKey() {
+		return errKey
+	}
+	e.begin()
+	e.b = append(e.b, '[')
+	e.stack = append(e.stack, false)
+	return
+}
+
+func (e *Emitter) EmitArrayEnd() (err error) {
+	e.stack = e.stack[:len(e.stack)-1]
+	e.b = append(e.b, ']')
+	return e.end()
+}
+
+func (e *Emitter) EmitArrayNext() (err error) {
+	e.b = append(e.b, ',')
+	return
+}
+
+func (e *Emitter) EmitMapBegin(_ int) (err error) {
+	if e.isKey() {
+		return errKey
+	}
+	e.begin()
+	e.b = append(e.b, '{')
+	e.stack = append(e.stack, true)
+	return
+}
+
+func (e *Emitter) EmitMapEnd() (err error) {
+	e.stack = e.stack[:len(e.stack)-1]
+	e.b = append(e.b, '}')
+	return e.end()
+}
+
+func (e *Emitter) EmitMapValue() (err error) {
+	e.stack[len(e.stack)-1] = false
+	e.b = append(e.b, ':')
+	return
+}
+
+func (e *Emitter) EmitMapNext() (err error) {
+	e.stack[len(e.stack)-1] = true
+	e.b = append(e.b, ',')
+	return
+}
+
+// isKey returns true if the next value emitted is the key of a map.
+func (e *Emitter) isKey() bool {
+	n := len(e.stack)
+	return n != 0 && e.stack[n-1]
+}
+
+func (e *Emitter) emitKey(k string) error {
+	e.b = appendSymbol(e.b, k)
+	return nil
+}
+
+// begin writes the annotations of the value about to be emitted.
+func (e *Emitter) begin() {
+	for _, a := range e.ann {
+		e.b = appendSymbol(e.b, a)
+		e.b = append(e.b, "::"...)
+	}
+	e.ann = e.ann[:0]
+}
+
+// end flushes the output buffer when a top-level value was completed.
+func (e *Emitter) end() (err error) {
+	if len(e.stack) == 0 {
+		e.b = append(e.b, '\n')
+		_, err = e.w.Write(e.b)
+		e.b = e.b[:0]
+	}
+	return
+}
+
+var errKey = errors.New("objconv/ion: struct field names must be strings or scalar values")
+
+func appendFloat(b []byte, f float64, bitSize int) []byte {
+	switch {
+	case math.IsNaN(f):
+		return append(b, "nan"...)
+	case math.IsInf(f, +1):
+		return append(b, "+inf"...)
+	case math.IsInf(f, -1):
+		return append(b, "-inf"...)
+	}
+
+	i := len(b)
+	b = strconv.AppendFloat(b, f, 'g', -1, bitSize)
+
+	// Ion floats must have an exponent, numbers without one are decimals.
+	for _, c := range b[i:] {
+		if c == 'e' {
+			return b
+		}
+	}
+
+	return append(b, "e0"...)
+}
+
+// appendSymbol appends s as an identifier if possible, or as a quoted symbol.
+func appendSymbol(b []byte, s string) []byte {
+	if isIdentifier(s) {
+		return append(b, s...)
+	}
+	return appendString(b, s, '\'')
+}
+
+func isIdentifier(s string) bool {
+	switch s {
+	case "", "null", "true", "false", "nan":
+		return false
+	}
+
+	for i := 0; i != len(s); i++ {
+		if !isIdentifierPart(s[i]) || (i == 0 && !isIdentifierStart(s[i])) {
+			return false
+		}
+	}
+
+	// $ followed by digits is a symbol identifier, not a symbol name.
+	if s[0] == '$' {
+		for i := 1; i != len(s); i++ {
+			if s[i] < '0' || s[i] > '9' {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+func appendString(b []byte, s string, quote byte) []byte {
+	b = append(b, quote)
+
+	for i := 0; i < len(s); {
+		r, n := utf8.DecodeRuneInString(s[i:])
+
+		switch {
+		case r == rune(quote):
+			b = append(b, '\\', quote)
+		case r == '\\':
+			b = append(b, '\\', '\\')
+		case r == '\n':
+			b = append(b, '\\', 'n')
+		case r == '\r':
+			b = append(b, '\\', 'r')
+		case r == '\t':
+			b = append(b, '\\', 't')
+		case r < 0x20 || r == 0x7F || (r == utf8.RuneError && n == 1):
+			b = append(b, '\\', 'x', hex[s[i]>>4], hex[s[i]&0xF])
+		default:
+			b = append(b, s[i:i+n]...)
+		}
+
+		i += n
+	}
+
+	return append(b, quote)
+}
+
+const hex = "0123456789abcdef"
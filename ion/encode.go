@@ -0,0 +1,92 @@
+package ion
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/segmentio/objconv"
+)
+
+// NewEncoder returns a new Ion text encoder that writes to w.
+func NewEncoder(w io.Writer) *objconv.Encoder {
+	return objconv.NewEncoder(NewEmitter(w))
+}
+
+// NewStreamEncoder returns a new Ion text stream encoder that writes to w.
+func NewStreamEncoder(w io.Writer) *objconv.StreamEncoder {
+	return objconv.NewStreamEncoder(NewEmitter(w))
+}
+
+// NewBinaryEncoder returns a new binary Ion encoder that writes to w.
+func NewBinaryEncoder(w io.Writer) *objconv.Encoder {
+	return objconv.NewEncoder(NewBinaryEmitter(w))
+}
+
+// NewBinaryStreamEncoder returns a new binary Ion stream encoder that writes
+// to w.
+func NewBinaryStreamEncoder(w io.Writer) *objconv.StreamEncoder {
+	return objconv.NewStreamEncoder(NewBinaryEmitter(w))
+}
+
+// Marshal writes the Ion text representation of v to a byte slice returned in
+// b.
+func Marshal(v interface{}) (b []byte, err error) {
+	m := marshalerPool.Get().(*marshaler)
+	m.Reset(&m.b)
+	m.b.Truncate(0)
+
+	if err = (objconv.Encoder{Emitter: m}).Encode(v); err == nil {
+		b = make([]byte, m.b.Len())
+		copy(b, m.b.Bytes())
+	}
+
+	marshalerPool.Put(m)
+	return
+}
+
+// MarshalBinary writes the binary Ion representation of v to a byte slice
+// returned in b.
+func MarshalBinary(v interface{}) (b []byte, err error) {
+	m := binaryMarshalerPool.Get().(*binaryMarshaler)
+	m.Reset(&m.b)
+	m.b.Truncate(0)
+
+	if err = (objconv.Encoder{Emitter: m}).Encode(v); err == nil {
+		b = make([]byte, m.b.Len())
+		copy(b, m.b.Bytes())
+	}
+
+	binaryMarshalerPool.Put(m)
+	return
+}
+
+var marshalerPool = sync.Pool{
+	New: func() interface{} { return newMarshaler() },
+}
+
+var binaryMarshalerPool = sync.Pool{
+	New: func() interface{} { return newBinaryMarshaler() },
+}
+
+type marshaler struct {
+	Emitter
+	b bytes.Buffer
+}
+
+func newMarshaler() *marshaler {
+	m := &marshaler{}
+	m.w = &m.b
+	return m
+}
+
+type binaryMarshaler struct {
+	BinaryEmitter
+	b bytes.Buffer
+}
+
+func newBinaryMarshaler() *binaryMarshaler {
+	m := &binaryMarshaler{}
+	m.w = &m.b
+	return m
+}
@@ -0,0 +1,65 @@
+package ion
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/segmentio/objconv"
+)
+
+// Codec for the Ion text format.
+var Codec = objconv.Codec{
+	NewEmitter: func(w io.Writer) objconv.Emitter { return NewEmitter(w) },
+	NewParser:  func(r io.Reader) objconv.Parser { return NewParser(r) },
+	Extensions: []string{".ion"},
+	Sniff:      sniff,
+}
+
+// BinaryCodec for the binary Ion format.
+var BinaryCodec = objconv.Codec{
+	NewEmitter: func(w io.Writer) objconv.Emitter { return NewBinaryEmitter(w) },
+	NewParser:  func(r io.Reader) objconv.Parser { return NewBinaryParser(r) },
+	Extensions: []string{".10n"},
+	Sniff:      sniffBinary,
+}
+
+func init() {
+	for _, name := range [...]string{
+		"text/ion",
+		"ion",
+	} {
+		objconv.Register(name, Codec)
+	}
+
+	for _, name := range [...]string{
+		"application/ion",
+		"application/x-amzn-ion",
+		"ion-binary",
+	} {
+		objconv.Register(name, BinaryCodec)
+	}
+}
+
+// sniff recognizes the version marker of Ion text documents, and to a lesser
+// degree structs whose first field name is an identifier, which is not valid
+// JSON.
+func sniff(b []byte) float64 {
+	b = bytes.TrimLeft(b, " \t\r\n")
+
+	switch {
+	case bytes.HasPrefix(b, []byte("$ion_1_0")):
+		return 1
+	case len(b) > 1 && b[0] == '{' && isIdentifierStart(b[1]):
+		return 0.4
+	}
+
+	return 0
+}
+
+// sniffBinary recognizes the version marker of Ion binary documents.
+func sniffBinary(b []byte) float64 {
+	if bytes.HasPrefix(b, versionMarker[:]) {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,73 @@
+package ion
+
+import "github.com/segmentio/objconv"
+
+// Annotated is a wrapper for values that carry Ion annotations.
+//
+// Annotations are written before the wrapped value when encoding with an Ion
+// emitter, other emitters only see the value. When decoding, the annotations
+// of the next value are loaded into the Annotations field, they are empty
+// when decoding from formats other than Ion.
+//
+// Parsers discard annotations of values that aren't decoded into an Annotated
+// wrapper.
+type Annotated struct {
+	Annotations []string
+	Value       interface{}
+}
+
+// EncodeValue satisfies the objconv.ValueEncoder interface.
+func (a Annotated) EncodeValue(e objconv.Encoder) error {
+	if emitter, ok := e.Emitter.(annotationEmitter); ok && len(a.Annotations) != 0 {
+		if err := emitter.EmitAnnotations(a.Annotations); err != nil {
+			return err
+		}
+	}
+	return e.Encode(a.Value)
+}
+
+// DecodeValue satisfies the objconv.ValueDecoder interface.
+//
+// The Value field is decoded as is, which makes it possible to preset it to a
+// pointer to the type that the value should be decoded into.
+func (a *Annotated) DecodeValue(d objconv.Decoder) error {
+	a.Annotations = nil
+
+	if parser, ok := d.Parser.(annotationParser); ok {
+		annotations, err := parser.ParseAnnotations()
+		if err != nil {
+			return err
+		}
+		a.Annotations = annotations
+	}
+
+	if a.Value != nil {
+		return d.Decode(a.Value)
+	}
+
+	return d.Decode(&a.Value)
+}
+
+type annotationEmitter interface {
+	// EmitAnnotations sets the annotations of the next value emitted.
+	EmitAnnotations([]string) error
+}
+
+type annotationParser interface {
+	// ParseAnnotations returns the annotations of the next value to be
+	// parsed.
+	ParseAnnotations() ([]string, error)
+}
+
+// structValue is the in-memory representation of Ion structs, fields are
+// kept in order and may be repeated.
+type structValue struct {
+	keys   []string
+	values []interface{}
+}
+
+// annotatedValue is the in-memory representation of values with annotations.
+type annotatedValue struct {
+	annotations []string
+	value       interface{}
+}
@@ -0,0 +1,315 @@
+package ion
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/segmentio/objconv/objtests"
+)
+
+func TestCodec(t *testing.T) {
+	objtests.TestCodec(t, Codec)
+}
+
+func TestBinaryCodec(t *testing.T) {
+	objtests.TestCodec(t, BinaryCodec)
+}
+
+func BenchmarkCodec(b *testing.B) {
+	objtests.BenchmarkCodec(b, Codec)
+}
+
+func BenchmarkBinaryCodec(b *testing.B) {
+	objtests.BenchmarkCodec(b, BinaryCodec)
+}
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		v interface{}
+		s string
+	}{
+		{nil, "null\n"},
+		{-42, "-42\n"},
+		{0.5, "0.5e0\n"},
+		{math.Inf(-1), "-inf\n"},
+		{"a\"b", "\"a\\\"b\"\n"},
+		{[]byte("hello"), "{{aGVsbG8=}}\n"},
+		{[]int{1, 2}, "[1,2]\n"},
+		{map[string]int{"a b": 1}, "{'a b':1}\n"},
+		{Annotated{[]string{"id", "a b"}, 1}, "id::'a b'::1\n"},
+	}
+
+	for _, test := range tests {
+		b, err := Marshal(test.v)
+		if err != nil {
+			t.Errorf("%#v: %s", test.v, err)
+			continue
+		}
+		if string(b) != test.s {
+			t.Errorf("%#v: %q != %q", test.v, test.s, b)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	tests := []struct {
+		s string
+		v interface{}
+	}{
+		{"null.struct", nil},
+		{"$ion_1_0 1_000", int64(1000)},
+		{"0x1F", int64(31)},
+		{"-0b101", int64(-5)},
+		{"18446744073709551615", uint64(math.MaxUint64)},
+		{"1.5", 1.5},
+		{"15d-1", 1.5},
+		{"-inf", math.Inf(-1)},
+		{"2017-01-02T03:04:05Z", time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"2017-01-02", time.Date(2017, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"'hello world'", "hello world"},
+		{"'''a''' /* comment */ '''b'''", "ab"},
+		{"{{ aGVsbG8= }}", []byte("hello")},
+		{`{{ "hi" }}`, []byte("hi")},
+		{"(+ 1 2) // comment", []interface{}{"+", int64(1), int64(2)}},
+		{"$ion_symbol_table::{symbols:[\"x\"]} {a:1, 'b':[true]}", map[interface{}]interface{}{
+			"a": int64(1),
+			"b": []interface{}{true},
+		}},
+	}
+
+	for _, test := range tests {
+		var v interface{}
+
+		if err := Unmarshal([]byte(test.s), &v); err != nil {
+			t.Errorf("%q: %s", test.s, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(v, test.v) {
+			t.Errorf("%q: %#v != %#v", test.s, test.v, v)
+		}
+	}
+}
+
+func TestMarshalBinary(t *testing.T) {
+	tests := []struct {
+		v interface{}
+		b []byte
+	}{
+		{nil, []byte{0xE0, 0x01, 0x00, 0xEA, 0x0F}},
+		{true, []byte{0xE0, 0x01, 0x00, 0xEA, 0x11}},
+		{0, []byte{0xE0, 0x01, 0x00, 0xEA, 0x20}},
+		{-256, []byte{0xE0, 0x01, 0x00, 0xEA, 0x32, 0x01, 0x00}},
+		{"abc", []byte{0xE0, 0x01, 0x00, 0xEA, 0x83, 'a', 'b', 'c'}},
+		{[]int{1}, []byte{0xE0, 0x01, 0x00, 0xEA, 0xB2, 0x21, 0x01}},
+		{
+			map[string]int{"name": 1}, // system symbol, no symbol table
+			[]byte{0xE0, 0x01, 0x00, 0xEA, 0xD3, 0x84, 0x21, 0x01},
+		},
+		{
+			map[string]int{"a": 1},
+			[]byte{
+				0xE0, 0x01, 0x00, 0xEA,
+				0xE7, 0x81, 0x83, 0xD4, 0x87, 0xB2, 0x81, 'a',
+				0xD3, 0x8A, 0x21, 0x01,
+			},
+		},
+		{
+			time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC),
+			[]byte{0xE0, 0x01, 0x00, 0xEA, 0x68, 0x80, 0x0F, 0xE1, 0x81, 0x82, 0x83, 0x84, 0x85},
+		},
+	}
+
+	for _, test := range tests {
+		b, err := MarshalBinary(test.v)
+		if err != nil {
+			t.Errorf("%#v: %s", test.v, err)
+			continue
+		}
+		if !bytes.Equal(b, test.b) {
+			t.Errorf("%#v: % x != % x", test.v, test.b, b)
+		}
+	}
+}
+
+func TestUnmarshalBinary(t *testing.T) {
+	tests := []struct {
+		b []byte
+		v interface{}
+	}{
+		{[]byte{0xE0, 0x01, 0x00, 0xEA, 0x2F}, nil},
+		{[]byte{0xE0, 0x01, 0x00, 0xEA, 0x00, 0x21, 0x2A}, int64(42)}, // NOP pad
+		{[]byte{0xE0, 0x01, 0x00, 0xEA, 0x52, 0xC1, 0x0F}, 1.5},       // 15d-1
+		{[]byte{0xE0, 0x01, 0x00, 0xEA, 0x71, 0x04}, "name"},
+		{[]byte{0xE0, 0x01, 0x00, 0xEA, 0x71, 0x63}, "$99"},
+		{[]byte{0xE0, 0x01, 0x00, 0xEA, 0xC2, 0x21, 0x01}, []interface{}{int64(1)}},
+		{
+			// Second symbol table imports the first one.
+			[]byte{
+				0xE0, 0x01, 0x00, 0xEA,
+				0xE7, 0x81, 0x83, 0xD4, 0x87, 0xB2, 0x81, 'a',
+				0xEA, 0x81, 0x83, 0xD7, 0x86, 0x71, 0x03, 0x87, 0xB2, 0x81, 'b',
+				0xD6, 0x8A, 0x21, 0x01, 0x8B, 0x21, 0x02,
+			},
+			map[interface{}]interface{}{"a": int64(1), "b": int64(2)},
+		},
+		{
+			// Timestamp with a -08:00 offset and a millisecond fraction.
+			[]byte{0xE0, 0x01, 0x00, 0xEA, 0x6B, 0x43, 0xE0, 0x0F, 0xE1, 0x81, 0x82, 0x8B, 0x84, 0x85, 0xC3, 0x7B},
+			time.Date(2017, 1, 2, 3, 4, 5, 123e6, time.FixedZone("", -8*3600)),
+		},
+	}
+
+	for _, test := range tests {
+		var v interface{}
+
+		if err := UnmarshalBinary(test.b, &v); err != nil {
+			t.Errorf("% x: %s", test.b, err)
+			continue
+		}
+
+		if tm, ok := v.(time.Time); ok {
+			if !tm.Equal(test.v.(time.Time)) {
+				t.Errorf("% x: %v != %v", test.b, test.v, tm)
+			}
+			continue
+		}
+
+		if !reflect.DeepEqual(v, test.v) {
+			t.Errorf("% x: %#v != %#v", test.b, test.v, v)
+		}
+	}
+}
+
+func TestUnmarshalBinaryError(t *testing.T) {
+	for _, b := range [][]byte{
+		{},
+		{0xE0, 0x01, 0x00},
+		{0xE0, 0x02, 0x00, 0xEA, 0x20},
+		{0xE0, 0x01, 0x00, 0xEA, 0x30},
+		{0xE0, 0x01, 0x00, 0xEA, 0x83, 'a'},
+		{0xE0, 0x01, 0x00, 0xEA, 0x12},
+		{0xE0, 0x01, 0x00, 0xEA, 0xE3, 0x80, 0x21, 0x01},
+	} {
+		var v interface{}
+
+		if err := UnmarshalBinary(b, &v); err == nil {
+			t.Errorf("% x: expected an error", b)
+		}
+	}
+}
+
+func TestAnnotated(t *testing.T) {
+	type point struct {
+		X int `objconv:"x"`
+		Y int `objconv:"y"`
+	}
+
+	tests := []struct {
+		name      string
+		marshal   func(interface{}) ([]byte, error)
+		unmarshal func([]byte, interface{}) error
+	}{
+		{"text", Marshal, Unmarshal},
+		{"binary", MarshalBinary, UnmarshalBinary},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a1 := []Annotated{
+				{Annotations: []string{"point", "2d"}, Value: point{1, 2}},
+				{Annotations: []string{"empty"}, Value: []int{}},
+				{Value: 42},
+			}
+
+			b, err := test.marshal(a1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var a2 []Annotated
+
+			if err := test.unmarshal(b, &a2); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(a2) != len(a1) {
+				t.Fatalf("bad length: %d", len(a2))
+			}
+
+			for i := range a1 {
+				if !reflect.DeepEqual(a1[i].Annotations, a2[i].Annotations) {
+					t.Errorf("annotations #%d: %#v != %#v", i, a1[i].Annotations, a2[i].Annotations)
+				}
+			}
+
+			// Presetting the value selects the type it is decoded into.
+			var p point
+			var a Annotated
+			a.Value = &p
+
+			if err := test.unmarshal(b[:0], &a); err == nil {
+				t.Error("expected an error when decoding from an empty input")
+			}
+
+			if b, err = test.marshal(a1[0]); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := test.unmarshal(b, &a); err != nil {
+				t.Fatal(err)
+			}
+
+			if p != (point{1, 2}) || !reflect.DeepEqual(a.Annotations, []string{"point", "2d"}) {
+				t.Errorf("bad annotated value: %#v %#v", a.Annotations, p)
+			}
+		})
+	}
+}
+
+func TestBinaryStream(t *testing.T) {
+	b := &bytes.Buffer{}
+	e := NewBinaryStreamEncoder(b)
+
+	for _, v := range []interface{}{
+		map[string]int{"a": 1},
+		map[string]int{"a": 2},
+		map[string]int{"b": 3},
+	} {
+		if err := e.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The version marker is written once and symbol tables only declare the
+	// symbols that weren't used yet.
+	if n := bytes.Count(b.Bytes(), versionMarker[:]); n != 1 {
+		t.Errorf("bad number of version markers: %d", n)
+	}
+
+	d := NewBinaryStreamDecoder(b)
+	var vs []map[string]int
+
+	for {
+		var v map[string]int
+		if d.Decode(&v) != nil {
+			break
+		}
+		vs = append(vs, v)
+	}
+
+	if err := d.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(vs, []map[string]int{{"a": 1}, {"a": 2}, {"b": 3}}) {
+		t.Errorf("bad values: %#v", vs)
+	}
+}
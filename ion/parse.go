@@ -0,0 +1,97 @@
+package ion
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+
+	"github.com/segmentio/objconv"
+)
+
+// Parser implements an Ion text parser that satisfies the objconv.Parser
+// interface.
+//
+// Ion streams are sequences of top-level values, the parser loads the whole
+// input in memory then produces values one at a time. Values are mapped to
+// objconv types with these rules:
+//
+//   - typed nulls are all parsed as nil values
+//   - integers are parsed as signed integers, or unsigned integers when they
+//     overflow the range of int64
+//   - decimals are parsed as floats, which may lose precision
+//   - symbols are parsed as strings
+//   - blobs and clobs are parsed as bytes
+//   - lists and s-expressions are parsed as arrays, structs as maps
+//
+// Annotations are discarded unless the value is decoded into an Annotated
+// wrapper.
+type Parser struct {
+	r      io.Reader // reader to load bytes from
+	loaded bool
+	text   textReader
+	walker
+}
+
+func NewParser(r io.Reader) *Parser {
+	return &Parser{r: r}
+}
+
+func (p *Parser) Reset(r io.Reader) {
+	p.r = r
+	p.loaded = false
+	p.text = textReader{}
+	p.walker.reset()
+}
+
+func (p *Parser) Buffered() io.Reader {
+	return bytes.NewReader(p.text.b[p.text.i:])
+}
+
+// SequenceParser returns true, Ion streams are sequences of values.
+func (p *Parser) SequenceParser() bool {
+	return true
+}
+
+// TextParser returns true, Ion text is a human-readable format.
+func (p *Parser) TextParser() bool {
+	return true
+}
+
+func (p *Parser) ParseType() (objconv.Type, error) {
+	if err := p.load(); err != nil {
+		return objconv.Unknown, err
+	}
+	return p.parseType()
+}
+
+// ParseAnnotations returns the annotations of the next value.
+func (p *Parser) ParseAnnotations() ([]string, error) {
+	if err := p.load(); err != nil {
+		return nil, err
+	}
+	return p.top().annotations(), nil
+}
+
+// load reads the next top-level value if the previous one was entirely
+// consumed.
+func (p *Parser) load() (err error) {
+	if len(p.stack) != 0 {
+		return
+	}
+
+	if !p.loaded {
+		if p.text.b, err = ioutil.ReadAll(p.r); err != nil {
+			return
+		}
+		p.loaded = true
+	}
+
+	var v interface{}
+
+	if v, err = p.text.readTopLevel(); err != nil {
+		return
+	}
+
+	p.push(newParser(v))
+	return
+}
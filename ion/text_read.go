@@ -0,0 +1,712 @@
+package ion
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// textReader reads values from Ion text documents loaded in memory.
+type textReader struct {
+	b []byte
+	i int
+}
+
+// readTopLevel returns the next user value of the document, skipping version
+// markers and symbol tables. io.EOF is returned when there are no more values.
+func (r *textReader) readTopLevel() (v interface{}, err error) {
+	for {
+		if err = r.skip(); err != nil {
+			return
+		}
+
+		if r.i == len(r.b) {
+			return nil, io.EOF
+		}
+
+		if r.hasKeyword("$ion_1_0") {
+			r.i += len("$ion_1_0")
+			continue
+		}
+
+		if v, err = r.readValue(false); err != nil {
+			return
+		}
+
+		if a, ok := v.(*annotatedValue); ok && a.annotations[0] == "$ion_symbol_table" {
+			// Text documents reference symbols by name, there is no need to
+			// track the local symbol tables they declare.
+			continue
+		}
+
+		return
+	}
+}
+
+// readValue reads a value and its annotations, sexp is true if the value is
+// an element of an s-expression, where operators are symbols.
+func (r *textReader) readValue(sexp bool) (v interface{}, err error) {
+	var annotations []string
+
+	for {
+		start := r.i
+		var s string
+		var ok bool
+
+		if s, ok, err = r.readSymbol(); err != nil {
+			return
+		}
+
+		if ok {
+			if err = r.skip(); err != nil {
+				return
+			}
+			if bytes.HasPrefix(r.b[r.i:], []byte("::")) {
+				r.i += 2
+				annotations = append(annotations, s)
+				if err = r.skip(); err != nil {
+					return
+				}
+				continue
+			}
+		}
+
+		r.i = start
+		break
+	}
+
+	if v, err = r.readPlainValue(sexp); err != nil {
+		return
+	}
+
+	if len(annotations) != 0 {
+		v = &annotatedValue{annotations: annotations, value: v}
+	}
+
+	return
+}
+
+// readSymbol reads a symbol which may be an annotation or a field name,
+// returning false if the next token is not a symbol.
+func (r *textReader) readSymbol() (s string, ok bool, err error) {
+	if r.i == len(r.b) {
+		return
+	}
+
+	switch c := r.b[r.i]; {
+	case c == '\'' && !bytes.HasPrefix(r.b[r.i:], []byte("'''")):
+		s, err = r.readQuoted('\'')
+		return s, err == nil, err
+
+	case isIdentifierStart(c):
+		j := r.i
+		for j < len(r.b) && isIdentifierPart(r.b[j]) {
+			j++
+		}
+		s = string(r.b[r.i:j])
+		switch s {
+		case "null", "true", "false", "nan":
+			return "", false, nil
+		}
+		r.i = j
+		return s, true, nil
+	}
+
+	return
+}
+
+func (r *textReader) readPlainValue(sexp bool) (v interface{}, err error) {
+	if r.i == len(r.b) {
+		return nil, r.errorf("unexpected end of input")
+	}
+
+	switch c := r.b[r.i]; {
+	case c == '{':
+		if r.i+1 < len(r.b) && r.b[r.i+1] == '{' {
+			return r.readLob()
+		}
+		return r.readStruct()
+
+	case c == '[':
+		return r.readList(']', false)
+
+	case c == '(':
+		return r.readList(')', true)
+
+	case c == '"':
+		return r.readQuoted('"')
+
+	case c == '\'':
+		if bytes.HasPrefix(r.b[r.i:], []byte("'''")) {
+			return r.readLongString()
+		}
+		return r.readQuoted('\'')
+
+	case c >= '0' && c <= '9':
+		return r.readNumber()
+
+	case c == '-' || c == '+':
+		if r.hasKeyword(string(c) + "inf") {
+			r.i += 4
+			if c == '-' {
+				return math.Inf(-1), nil
+			}
+			return math.Inf(+1), nil
+		}
+		if c == '-' && r.i+1 < len(r.b) && r.b[r.i+1] >= '0' && r.b[r.i+1] <= '9' {
+			return r.readNumber()
+		}
+
+	case isIdentifierStart(c):
+		j := r.i
+		for j < len(r.b) && isIdentifierPart(r.b[j]) {
+			j++
+		}
+		s := string(r.b[r.i:j])
+		r.i = j
+
+		switch s {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "nan":
+			return math.NaN(), nil
+		case "null":
+			// Typed nulls like null.int are all represented by nil values.
+			if r.i < len(r.b) && r.b[r.i] == '.' {
+				r.i++
+				for r.i < len(r.b) && isIdentifierPart(r.b[r.i]) {
+					r.i++
+				}
+			}
+			return nil, nil
+		}
+
+		return s, nil
+	}
+
+	if sexp && isOperator(r.b[r.i]) {
+		j := r.i
+		for j < len(r.b) && isOperator(r.b[j]) {
+			j++
+		}
+		s := string(r.b[r.i:j])
+		r.i = j
+		return s, nil
+	}
+
+	return nil, r.errorf("unexpected character %q", r.b[r.i])
+}
+
+func (r *textReader) readList(end byte, sexp bool) (v interface{}, err error) {
+	list := []interface{}{}
+	r.i++ // '[' or '('
+
+	for {
+		if err = r.skip(); err != nil {
+			return
+		}
+
+		if r.i == len(r.b) {
+			return nil, r.errorf("unexpected end of input in list")
+		}
+
+		if r.b[r.i] == end {
+			r.i++
+			return list, nil
+		}
+
+		var elem interface{}
+
+		if elem, err = r.readValue(sexp); err != nil {
+			return
+		}
+
+		list = append(list, elem)
+
+		if sexp {
+			continue
+		}
+
+		if err = r.skip(); err != nil {
+			return
+		}
+
+		if r.i < len(r.b) && r.b[r.i] == ',' {
+			r.i++
+		} else if r.i < len(r.b) && r.b[r.i] != end {
+			return nil, r.errorf("expected ',' or %q after list element", end)
+		}
+	}
+}
+
+func (r *textReader) readStruct() (v interface{}, err error) {
+	s := &structValue{}
+	r.i++ // '{'
+
+	for {
+		if err = r.skip(); err != nil {
+			return
+		}
+
+		if r.i == len(r.b) {
+			return nil, r.errorf("unexpected end of input in struct")
+		}
+
+		if r.b[r.i] == '}' {
+			r.i++
+			return s, nil
+		}
+
+		var k string
+		var ok bool
+
+		switch {
+		case r.b[r.i] == '"':
+			k, err = r.readQuoted('"')
+		case bytes.HasPrefix(r.b[r.i:], []byte("'''")):
+			k, err = r.readLongString()
+		default:
+			if k, ok, err = r.readSymbol(); err == nil && !ok {
+				err = r.errorf("expected a field name")
+			}
+		}
+
+		if err != nil {
+			return
+		}
+
+		if err = r.skip(); err != nil {
+			return
+		}
+
+		if r.i == len(r.b) || r.b[r.i] != ':' {
+			return nil, r.errorf("expected ':' after field name %q", k)
+		}
+
+		r.i++
+
+		if err = r.skip(); err != nil {
+			return
+		}
+
+		var val interface{}
+
+		if val, err = r.readValue(false); err != nil {
+			return
+		}
+
+		s.keys = append(s.keys, k)
+		s.values = append(s.values, val)
+
+		if err = r.skip(); err != nil {
+			return
+		}
+
+		if r.i < len(r.b) && r.b[r.i] == ',' {
+			r.i++
+		} else if r.i < len(r.b) && r.b[r.i] != '}' {
+			return nil, r.errorf("expected ',' or '}' after struct field")
+		}
+	}
+}
+
+// readLob reads a blob or clob, both are represented as byte slices.
+func (r *textReader) readLob() (v interface{}, err error) {
+	r.i += 2 // '{{'
+
+	if err = r.skipSpaces(); err != nil {
+		return
+	}
+
+	var b []byte
+
+	switch {
+	case r.i < len(r.b) && r.b[r.i] == '"':
+		var s string
+		if s, err = r.readQuoted('"'); err != nil {
+			return
+		}
+		b = []byte(s)
+
+	case bytes.HasPrefix(r.b[r.i:], []byte("'''")):
+		var s string
+		if s, err = r.readLongString(); err != nil {
+			return
+		}
+		b = []byte(s)
+
+	default:
+		j := bytes.Index(r.b[r.i:], []byte("}}"))
+		if j < 0 {
+			return nil, r.errorf("unterminated blob")
+		}
+
+		s := strings.Map(func(r rune) rune {
+			if isSpace(r) {
+				return -1
+			}
+			return r
+		}, string(r.b[r.i:r.i+j]))
+
+		if b, err = base64.StdEncoding.DecodeString(s); err != nil {
+			return nil, r.errorf("invalid blob: %s", err)
+		}
+
+		r.i += j
+	}
+
+	if err = r.skipSpaces(); err != nil {
+		return
+	}
+
+	if !bytes.HasPrefix(r.b[r.i:], []byte("}}")) {
+		return nil, r.errorf("expected '}}' at the end of a blob or clob")
+	}
+
+	r.i += 2
+	return b, nil
+}
+
+// readQuoted reads a string or symbol enclosed in quote characters.
+func (r *textReader) readQuoted(quote byte) (s string, err error) {
+	var b []byte
+	r.i++
+
+	for {
+		if r.i == len(r.b) {
+			return "", r.errorf("unterminated string")
+		}
+
+		c := r.b[r.i]
+
+		switch {
+		case c == quote:
+			r.i++
+			return string(b), nil
+
+		case c == '\\':
+			if b, err = r.readEscape(b); err != nil {
+				return
+			}
+
+		case c == '\n' && quote == '"':
+			return "", r.errorf("new lines are not allowed in short strings")
+
+		default:
+			b = append(b, c)
+			r.i++
+		}
+	}
+}
+
+// readLongString reads one or more long strings separated by white spaces or
+// comments, which are concatenated.
+func (r *textReader) readLongString() (s string, err error) {
+	var b []byte
+
+	for bytes.HasPrefix(r.b[r.i:], []byte("'''")) {
+		r.i += 3
+
+		for {
+			if r.i == len(r.b) {
+				return "", r.errorf("unterminated long string")
+			}
+
+			if bytes.HasPrefix(r.b[r.i:], []byte("'''")) {
+				r.i += 3
+				break
+			}
+
+			if r.b[r.i] == '\\' {
+				if b, err = r.readEscape(b); err != nil {
+					return
+				}
+			} else {
+				b = append(b, r.b[r.i])
+				r.i++
+			}
+		}
+
+		start := r.i
+
+		if err = r.skip(); err != nil {
+			return
+		}
+
+		if !bytes.HasPrefix(r.b[r.i:], []byte("'''")) {
+			r.i = start
+		}
+	}
+
+	return string(b), nil
+}
+
+func (r *textReader) readEscape(b []byte) ([]byte, error) {
+	r.i++ // '\\'
+
+	if r.i == len(r.b) {
+		return b, r.errorf("unterminated escape sequence")
+	}
+
+	c := r.b[r.i]
+	r.i++
+
+	switch c {
+	case 'a':
+		return append(b, '\a'), nil
+	case 'b':
+		return append(b, '\b'), nil
+	case 't':
+		return append(b, '\t'), nil
+	case 'n':
+		return append(b, '\n'), nil
+	case 'f':
+		return append(b, '\f'), nil
+	case 'r':
+		return append(b, '\r'), nil
+	case 'v':
+		return append(b, '\v'), nil
+	case '0':
+		return append(b, 0), nil
+	case '?', '\'', '"', '/', '\\':
+		return append(b, c), nil
+	case '\n':
+		return b, nil // line continuation
+	case '\r':
+		if r.i < len(r.b) && r.b[r.i] == '\n' {
+			r.i++
+		}
+		return b, nil
+	}
+
+	var n int
+
+	switch c {
+	case 'x':
+		n = 2
+	case 'u':
+		n = 4
+	case 'U':
+		n = 8
+	default:
+		return b, r.errorf("invalid escape sequence '\\%c'", c)
+	}
+
+	if r.i+n > len(r.b) {
+		return b, r.errorf("unterminated escape sequence")
+	}
+
+	x, err := strconv.ParseUint(string(r.b[r.i:r.i+n]), 16, 32)
+	if err != nil {
+		return b, r.errorf("invalid escape sequence '\\%c%s'", c, r.b[r.i:r.i+n])
+	}
+	r.i += n
+
+	if x > utf8.MaxRune {
+		return b, r.errorf("invalid code point %#x", x)
+	}
+
+	var a [utf8.UTFMax]byte
+	return append(b, a[:utf8.EncodeRune(a[:], rune(x))]...), nil
+}
+
+// readNumber reads integers, floats, decimals and timestamps.
+func (r *textReader) readNumber() (v interface{}, err error) {
+	j := r.i
+	for j < len(r.b) && isNumberPart(r.b[j]) {
+		j++
+	}
+
+	s := string(r.b[r.i:j])
+	pos := r.i
+	r.i = j
+
+	if isTimestamp(s) {
+		if v, err = parseTimestamp(s); err != nil {
+			r.i = pos
+			err = r.errorf("invalid timestamp %q", s)
+		}
+		return
+	}
+
+	if v, err = parseNumber(s); err != nil {
+		r.i = pos
+		err = r.errorf("%s", err)
+	}
+
+	return
+}
+
+func parseNumber(s string) (interface{}, error) {
+	if strings.Contains(s, "__") || strings.HasSuffix(s, "_") {
+		return nil, fmt.Errorf("invalid number %q", s)
+	}
+
+	x := strings.Replace(s, "_", "", -1)
+	neg := strings.HasPrefix(x, "-")
+	abs := strings.TrimPrefix(x, "-")
+
+	base := 10
+	switch {
+	case strings.HasPrefix(abs, "0x") || strings.HasPrefix(abs, "0X"):
+		base, abs = 16, abs[2:]
+	case strings.HasPrefix(abs, "0b") || strings.HasPrefix(abs, "0B"):
+		base, abs = 2, abs[2:]
+	}
+
+	if base == 10 && strings.ContainsAny(abs, ".eEdD") {
+		// Decimals are represented as floats, which may lose precision.
+		f, err := strconv.ParseFloat(strings.NewReplacer("d", "e", "D", "e").Replace(x), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", s)
+		}
+		return f, nil
+	}
+
+	if base == 10 && len(abs) > 1 && abs[0] == '0' {
+		return nil, fmt.Errorf("invalid number %q, leading zeros are not allowed", s)
+	}
+
+	u, err := strconv.ParseUint(abs, base, 64)
+	if err != nil {
+		if e, ok := err.(*strconv.NumError); ok && e.Err == strconv.ErrRange {
+			return nil, fmt.Errorf("integer %s overflows the range of 64 bits integers", s)
+		}
+		return nil, fmt.Errorf("invalid number %q", s)
+	}
+
+	switch {
+	case !neg && u <= math.MaxInt64:
+		return int64(u), nil
+	case !neg:
+		return u, nil
+	case u <= 1<<63:
+		return -int64(u), nil
+	default:
+		return nil, fmt.Errorf("integer %s overflows the range of 64 bits integers", s)
+	}
+}
+
+// isTimestamp returns true if s starts with a year followed by 'T' or '-'.
+func isTimestamp(s string) bool {
+	if len(s) < 5 {
+		return false
+	}
+	for i := 0; i != 4; i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s[4] == 'T' || s[4] == '-'
+}
+
+func parseTimestamp(s string) (t time.Time, err error) {
+	switch {
+	case len(s) == 5: // 2007T
+		t, err = time.Parse("2006T", s)
+	case len(s) == 8: // 2007-02T
+		t, err = time.Parse("2006-01T", s)
+	case len(s) == 10: // 2007-02-23
+		t, err = time.Parse("2006-01-02", s)
+	case len(s) == 11: // 2007-02-23T
+		t, err = time.Parse("2006-01-02T", s)
+	case len(s) > 16 && s[16] != ':': // 2007-02-23T12:14Z
+		t, err = time.Parse("2006-01-02T15:04Z07:00", s)
+	default:
+		t, err = time.Parse(time.RFC3339Nano, s)
+	}
+
+	if err == nil {
+		if _, offset := t.Zone(); offset == 0 {
+			// The unknown offset -00:00 is also represented in UTC.
+			t = t.UTC()
+		}
+	}
+
+	return
+}
+
+func (r *textReader) hasKeyword(k string) bool {
+	if !bytes.HasPrefix(r.b[r.i:], []byte(k)) {
+		return false
+	}
+	j := r.i + len(k)
+	return j == len(r.b) || !isIdentifierPart(r.b[j])
+}
+
+// skip skips white spaces and comments.
+func (r *textReader) skip() error {
+	for r.i < len(r.b) {
+		switch c := r.b[r.i]; {
+		case isSpace(rune(c)):
+			r.i++
+
+		case bytes.HasPrefix(r.b[r.i:], []byte("//")):
+			if j := bytes.IndexByte(r.b[r.i:], '\n'); j < 0 {
+				r.i = len(r.b)
+			} else {
+				r.i += j + 1
+			}
+
+		case bytes.HasPrefix(r.b[r.i:], []byte("/*")):
+			j := bytes.Index(r.b[r.i+2:], []byte("*/"))
+			if j < 0 {
+				return r.errorf("unterminated comment")
+			}
+			r.i += j + 4
+
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// skipSpaces skips white spaces only, comments are not allowed within blobs.
+func (r *textReader) skipSpaces() error {
+	for r.i < len(r.b) && isSpace(rune(r.b[r.i])) {
+		r.i++
+	}
+	if r.i == len(r.b) {
+		return r.errorf("unexpected end of input")
+	}
+	return nil
+}
+
+func (r *textReader) errorf(format string, args ...interface{}) error {
+	line := 1 + bytes.Count(r.b[:r.i], []byte{'\n'})
+	return fmt.Errorf("objconv/ion: line %d: "+format, append([]interface{}{line}, args...)...)
+}
+
+func isSpace(r rune) bool {
+	switch r {
+	case ' ', '\t', '\n', '\r', '\v', '\f':
+		return true
+	}
+	return false
+}
+
+func isIdentifierStart(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_' || c == '$'
+}
+
+func isIdentifierPart(c byte) bool {
+	return isIdentifierStart(c) || (c >= '0' && c <= '9')
+}
+
+func isNumberPart(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+		c == '_' || c == '.' || c == '-' || c == '+' || c == ':'
+}
+
+func isOperator(c byte) bool {
+	return strings.IndexByte("!#%&*+-./;<=>?@^`|~", c) >= 0
+}
@@ -0,0 +1,278 @@
+package ion
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/segmentio/objconv"
+)
+
+// walker implements the methods of the objconv.Parser interface that iterate
+// over a value loaded in memory, it is shared by the text and binary parsers
+// which load top-level values before producing them.
+type walker struct {
+	s []byte // string buffer
+	// This stack is used to iterate over the lists and structs of the loaded
+	// value.
+	stack []parser
+}
+
+func (p *walker) reset() {
+	p.s = nil
+	p.stack = p.stack[:0]
+}
+
+func (p *walker) parseType() (typ objconv.Type, err error) {
+	switch v := p.value(); v.(type) {
+	case nil:
+		typ = objconv.Nil
+
+	case bool:
+		typ = objconv.Bool
+
+	case int64:
+		typ = objconv.Int
+
+	case uint64:
+		typ = objconv.Uint
+
+	case float64:
+		typ = objconv.Float
+
+	case string:
+		typ = objconv.String
+
+	case []byte:
+		typ = objconv.Bytes
+
+	case time.Time:
+		typ = objconv.Time
+
+	case *structValue:
+		typ = objconv.Map
+
+	case []interface{}:
+		typ = objconv.Array
+
+	default:
+		err = fmt.Errorf("objconv/ion: unsupported value of type %T", v)
+	}
+
+	return
+}
+
+func (p *walker) ParseNil() (err error) {
+	p.pop()
+	return
+}
+
+func (p *walker) ParseBool() (v bool, err error) {
+	v = p.pop().value().(bool)
+	return
+}
+
+func (p *walker) ParseInt() (v int64, err error) {
+	v = p.pop().value().(int64)
+	return
+}
+
+func (p *walker) ParseUint() (v uint64, err error) {
+	v = p.pop().value().(uint64)
+	return
+}
+
+func (p *walker) ParseFloat() (v float64, err error) {
+	v = p.pop().value().(float64)
+	return
+}
+
+func (p *walker) ParseString() (v []byte, err error) {
+	s := p.pop().value().(string)
+	n := len(s)
+
+	if cap(p.s) < n {
+		p.s = make([]byte, 0, ((n/1024)+1)*1024)
+	}
+
+	v = p.s[:n]
+	copy(v, s)
+	return
+}
+
+func (p *walker) ParseBytes() (v []byte, err error) {
+	v = p.pop().value().([]byte)
+	return
+}
+
+func (p *walker) ParseTime() (v time.Time, err error) {
+	v = p.pop().value().(time.Time)
+	return
+}
+
+func (p *walker) ParseDuration() (v time.Duration, err error) {
+	panic("objconv/ion: ParseDuration should never be called because Ion has no duration type, this is likely a bug in the decoder code")
+}
+
+func (p *walker) ParseError() (v error, err error) {
+	panic("objconv/ion: ParseError should never be called because Ion has no error type, this is likely a bug in the decoder code")
+}
+
+func (p *walker) ParseArrayBegin() (n int, err error) {
+	if n = p.top().len(); n != 0 {
+		p.push(newParser(p.top().next()))
+	}
+	return
+}
+
+func (p *walker) ParseArrayEnd(n int) (err error) {
+	p.pop()
+	return
+}
+
+func (p *walker) ParseArrayNext(n int) (err error) {
+	p.push(newParser(p.top().next()))
+	return
+}
+
+func (p *walker) ParseMapBegin() (n int, err error) {
+	if n = p.top().len(); n != 0 {
+		p.push(newParser(p.top().next()))
+	}
+	return
+}
+
+func (p *walker) ParseMapEnd(n int) (err error) {
+	p.pop()
+	return
+}
+
+func (p *walker) ParseMapValue(n int) (err error) {
+	p.push(newParser(p.top().next()))
+	return
+}
+
+func (p *walker) ParseMapNext(n int) (err error) {
+	p.push(newParser(p.top().next()))
+	return
+}
+
+func (p *walker) push(v parser) {
+	p.stack = append(p.stack, v)
+}
+
+func (p *walker) pop() parser {
+	i := len(p.stack) - 1
+	v := p.stack[i]
+	p.stack = p.stack[:i]
+	return v
+}
+
+func (p *walker) top() parser {
+	return p.stack[len(p.stack)-1]
+}
+
+func (p *walker) value() interface{} {
+	return p.stack[len(p.stack)-1].value()
+}
+
+type parser interface {
+	value() interface{}
+	next() interface{}
+	len() int
+	annotations() []string
+}
+
+type valueParser struct {
+	self interface{}
+	ann  []string
+}
+
+func (p *valueParser) value() interface{} {
+	return p.self
+}
+
+func (p *valueParser) next() interface{} {
+	panic("objconv/ion: invalid call of next method on simple value parser")
+}
+
+func (p *valueParser) len() int {
+	panic("objconv/ion: invalid call of len method on simple value parser")
+}
+
+func (p *valueParser) annotations() []string {
+	return p.ann
+}
+
+type arrayParser struct {
+	self []interface{}
+	off  int
+	ann  []string
+}
+
+func (p *arrayParser) value() interface{} {
+	return p.self
+}
+
+func (p *arrayParser) next() interface{} {
+	v := p.self[p.off]
+	p.off++
+	return v
+}
+
+func (p *arrayParser) len() int {
+	return len(p.self)
+}
+
+func (p *arrayParser) annotations() []string {
+	return p.ann
+}
+
+type structParser struct {
+	self *structValue
+	off  int
+	val  bool
+	ann  []string
+}
+
+func (p *structParser) value() interface{} {
+	return p.self
+}
+
+func (p *structParser) next() (v interface{}) {
+	if p.val {
+		v = p.self.values[p.off]
+		p.val = false
+		p.off++
+	} else {
+		v = p.self.keys[p.off]
+		p.val = true
+	}
+	return
+}
+
+func (p *structParser) len() int {
+	return len(p.self.keys)
+}
+
+func (p *structParser) annotations() []string {
+	return p.ann
+}
+
+func newParser(v interface{}) parser {
+	var ann []string
+
+	if a, ok := v.(*annotatedValue); ok {
+		ann, v = a.annotations, a.value
+	}
+
+	switch x := v.(type) {
+	case *structValue:
+		return &structParser{self: x, ann: ann}
+
+	case []interface{}:
+		return &arrayParser{self: x, ann: ann}
+
+	default:
+		return &valueParser{self: x, ann: ann}
+	}
+}
@@ -28,11 +28,17 @@ var (
 
 	newline = [...]byte{'\n'}
 	spaces  = [...]byte{' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' '}
+
+	hex = [...]byte{'0', '1', '2', '3', '4', '5', '6', '7', '8', '9', 'a', 'b', 'c', 'd', 'e', 'f'}
 )
 
 // Emitter implements a JSON emitter that satisfies the objconv.Emitter
 // interface.
 type Emitter struct {
+	// EscapeHTML controls whether the <, > and & characters are escaped in
+	// strings, which makes the output safe to embed in HTML documents.
+	EscapeHTML bool
+
 	w io.Writer
 	s []byte
 	a [128]byte
@@ -118,6 +124,15 @@ func (e *Emitter) EmitString(v string) (err error) {
 		case '\t':
 			b = 't'
 
+		case '<', '>', '&':
+			if !e.EscapeHTML {
+				continue
+			}
+			s = append(s, v[i:j-1]...)
+			s = append(s, '\\', 'u', '0', '0', hex[b>>4], hex[b&0xF])
+			i = j
+			continue
+
 		default:
 			continue
 		}
@@ -221,7 +236,9 @@ func (e *Emitter) TextEmitter() bool {
 }
 
 func (e *Emitter) PrettyEmitter() objconv.Emitter {
-	return NewPrettyEmitter(e.w)
+	p := NewPrettyEmitter(e.w)
+	p.EscapeHTML = e.EscapeHTML
+	return p
 }
 
 func align(n int, a int) int {
@@ -233,6 +250,11 @@ func align(n int, a int) int {
 
 type PrettyEmitter struct {
 	Emitter
+
+	// Indent is the string written for each level of indentation, it defaults
+	// to two spaces when empty.
+	Indent string
+
 	i int
 	s []int
 	a [8]int
@@ -317,6 +339,18 @@ func (e *PrettyEmitter) TextEmitter() bool {
 }
 
 func (e *PrettyEmitter) indent() (err error) {
+	if e.Indent != "" {
+		s := append(e.Emitter.s[:0], '\n')
+
+		for i := 0; i != e.i; i++ {
+			s = append(s, e.Indent...)
+		}
+
+		e.Emitter.s = s[:0] // in case the buffer was reallocated
+		_, err = e.w.Write(s)
+		return
+	}
+
 	if _, err = e.w.Write(newline[:]); err != nil {
 		return
 	}
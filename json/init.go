@@ -1,7 +1,11 @@
 package json
 
 import (
+	"bytes"
+	"fmt"
 	"io"
+	"strconv"
+	"strings"
 
 	"github.com/segmentio/objconv"
 )
@@ -10,6 +14,10 @@ import (
 var Codec = objconv.Codec{
 	NewEmitter: func(w io.Writer) objconv.Emitter { return NewEmitter(w) },
 	NewParser:  func(r io.Reader) objconv.Parser { return NewParser(r) },
+	Extensions: []string{".json"},
+	Sniff:      sniff,
+	Options:    codecOptions,
+	Configure:  configure,
 }
 
 // PrettyCodec for the JSON format.
@@ -27,3 +35,109 @@ func init() {
 		objconv.Register(name, Codec)
 	}
 }
+
+var codecOptions = []objconv.Option{
+	{Name: "indent", Doc: `number of spaces, or "tab", to indent the output with, 0 for compact output`},
+	{Name: "pretty", Doc: `"true" to indent the output with two spaces`},
+	{Name: "escape-html", Doc: `"true" to escape the <, > and & characters in strings`},
+	{Name: "lenient", Doc: `"true" to accept comments and trailing commas in the input`},
+}
+
+// configure returns a copy of c which creates emitters and parsers configured
+// with the options of the JSON codec.
+func configure(c objconv.Codec, options map[string]string) (objconv.Codec, error) {
+	var indent string
+	var escapeHTML bool
+	var lenient bool
+
+	if v, ok := options["pretty"]; ok {
+		pretty, err := strconv.ParseBool(v)
+		if err != nil {
+			return c, errOption("pretty", v)
+		}
+		if pretty {
+			indent = "  "
+		}
+	}
+
+	if v, ok := options["indent"]; ok {
+		if v == "tab" {
+			indent = "\t"
+		} else if n, err := strconv.Atoi(v); err != nil || n < 0 || n > 16 {
+			return c, errOption("indent", v)
+		} else {
+			indent = strings.Repeat(" ", n)
+		}
+	}
+
+	if v, ok := options["escape-html"]; ok {
+		var err error
+		if escapeHTML, err = strconv.ParseBool(v); err != nil {
+			return c, errOption("escape-html", v)
+		}
+	}
+
+	if v, ok := options["lenient"]; ok {
+		var err error
+		if lenient, err = strconv.ParseBool(v); err != nil {
+			return c, errOption("lenient", v)
+		}
+	}
+
+	c.NewParser = func(r io.Reader) objconv.Parser {
+		p := NewParser(r)
+		p.Lenient = lenient
+		return p
+	}
+
+	c.NewEmitter = func(w io.Writer) objconv.Emitter {
+		if indent == "" {
+			e := NewEmitter(w)
+			e.EscapeHTML = escapeHTML
+			return e
+		}
+		e := NewPrettyEmitter(w)
+		e.Indent = indent
+		e.EscapeHTML = escapeHTML
+		return e
+	}
+
+	return c, nil
+}
+
+func errOption(name string, value string) error {
+	return fmt.Errorf("objconv/json: invalid value %q for the %s option", value, name)
+}
+
+// sniff recognizes JSON objects and arrays, other values are too ambiguous to
+// be detected with much confidence.
+func sniff(b []byte) float64 {
+	b = bytes.TrimLeft(bytes.TrimPrefix(b, []byte("\xEF\xBB\xBF")), " \t\r\n")
+
+	if len(b) == 0 {
+		return 0
+	}
+
+	next := bytes.TrimLeft(b[1:], " \t\r\n")
+
+	switch c := b[0]; {
+	case c == '{':
+		if len(next) == 0 || next[0] == '"' || next[0] == '}' {
+			return 0.9
+		}
+		return 0.3
+	case c == '[':
+		if len(next) == 0 || bytes.IndexByte([]byte("{[\"-0123456789tfn]"), next[0]) >= 0 {
+			return 0.8
+		}
+		return 0.2
+	case c == '"':
+		return 0.5
+	case bytes.HasPrefix(b, []byte("true")), bytes.HasPrefix(b, []byte("false")), bytes.HasPrefix(b, []byte("null")):
+		return 0.4
+	case c == '-', c >= '0' && c <= '9':
+		return 0.2
+	}
+
+	return 0
+}
@@ -1,11 +1,14 @@
 package json
 
 import (
+	"bytes"
 	"fmt"
 	"math"
+	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/segmentio/objconv"
 	"github.com/segmentio/objconv/objtests"
 )
 
@@ -49,6 +52,174 @@ func TestUnicode(t *testing.T) {
 	}
 }
 
+func TestParserOffset(t *testing.T) {
+	src := strings.Repeat(" ", 300) + `[1, "` + strings.Repeat("a", 200) + `",` + strings.Repeat("\n", 200) + "tru]"
+
+	var v interface{}
+	p := NewParser(strings.NewReader(src))
+
+	if err := objconv.NewDecoder(p).Decode(&v); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if off, exp := p.Offset(), int64(strings.Index(src, "tru")); off != exp {
+		t.Errorf("bad offset: %d != %d", off, exp)
+	}
+}
+
+func TestDecodeAt(t *testing.T) {
+	large := `{"values":[` + strings.Repeat(`{"a":[1,2.5,true,null],"b":"\u00e9\n"},`, 1000) + `{}],`
+	small := `{"values":[],`
+	meta := `"meta":{"request_id":42}}`
+
+	r := strings.NewReader("")
+	p := NewParser(r)
+	d := objconv.NewDecoder(p)
+
+	decodeAt := func(src string) {
+		var id int
+
+		r.Reset(src + meta + " 1")
+		p.Reset(r)
+
+		if err := d.DecodeAt("/meta/request_id", &id); err != nil {
+			t.Fatal(err)
+		}
+
+		if id != 42 {
+			t.Fatal("bad request_id:", id)
+		}
+
+		// The parser must be positioned after the value.
+		if err := d.Decode(&id); err != nil || id != 1 {
+			t.Fatal("bad next value:", id, err)
+		}
+	}
+
+	// Skipping values must not allocate memory, the lookup on the large input
+	// must do as many allocations as the one on the small input.
+	n1 := testing.AllocsPerRun(10, func() { decodeAt(small) })
+	n2 := testing.AllocsPerRun(10, func() { decodeAt(large) })
+
+	if n1 != n2 {
+		t.Errorf("skipping values allocated memory: %g != %g", n1, n2)
+	}
+}
+
+func TestAttrField(t *testing.T) {
+	type T struct {
+		ID   int    `objconv:"id,attr"`
+		Name string `objconv:"name"`
+	}
+
+	// Only formats with attributes, like XML, prefix the names of fields
+	// tagged with attr.
+	b, err := Marshal(T{ID: 1, Name: "A"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := string(b); s != `{"id":1,"name":"A"}` {
+		t.Error(s)
+	}
+
+	var v T
+
+	if err := Unmarshal(b, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v != (T{ID: 1, Name: "A"}) {
+		t.Errorf("%+v", v)
+	}
+}
+
+func TestEncoderCompressed(t *testing.T) {
+	value := map[string]interface{}{"a": []interface{}{int64(1), "b", nil}}
+
+	for name, c := range map[string]objconv.Compressor{
+		"gzip":    objconv.GzipCompressor,
+		"zlib":    objconv.ZlibCompressor,
+		"deflate": objconv.DeflateCompressor,
+	} {
+		t.Run(name, func(t *testing.T) {
+			codec := objconv.Compress(Codec, c)
+			b := &bytes.Buffer{}
+			e := codec.NewEncoder(b)
+
+			// The emitter must complete the compressed stream after the value
+			// without being closed explicitly.
+			if err := e.Encode(value); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := e.Encode(value); err == nil {
+				t.Error("encoding a second value should have failed")
+			}
+
+			var v map[string]interface{}
+
+			if err := codec.NewDecoder(b).Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(v, value) {
+				t.Errorf("%#v != %#v", value, v)
+			}
+		})
+	}
+}
+
+func TestLenientParser(t *testing.T) {
+	src := `// configuration
+{
+	"a": [1, 2, /* three */ 3,],
+	/* "b": false, */
+	"c": {"d": "/* not a comment */",},
+} // end`
+
+	codec, err := Codec.With(map[string]string{"lenient": "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v map[string]interface{}
+
+	if err := codec.NewDecoder(strings.NewReader(src)).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string]interface{}{
+		"a": []interface{}{int64(1), int64(2), int64(3)},
+		"c": map[interface{}]interface{}{"d": "/* not a comment */"},
+	}
+
+	if !reflect.DeepEqual(v, exp) {
+		t.Errorf("%#v != %#v", exp, v)
+	}
+
+	// The default parser is strict, lenient parsers still reject other
+	// syntax errors.
+	for _, test := range []struct {
+		codec objconv.Codec
+		src   string
+	}{
+		{Codec, `[1,]`},
+		{Codec, `{"a":1,}`},
+		{Codec, `// comment` + "\n" + `1`},
+		{codec, `[,]`},
+		{codec, `[1,,]`},
+		{codec, `[1 /* unterminated`},
+		{codec, `[1 / 2]`},
+	} {
+		var v interface{}
+
+		if err := test.codec.NewDecoder(strings.NewReader(test.src)).Decode(&v); err == nil {
+			t.Errorf("%q: expected an error", test.src)
+		}
+	}
+}
+
 func TestMapValueOverflow(t *testing.T) {
 	src := fmt.Sprintf(
 		`{"A":"good","skip1":"%s","B":"bad","skip2":"%sA"}`,
@@ -85,3 +256,72 @@ func TestEmitImpossibleFloats(t *testing.T) {
 		})
 	}
 }
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		s string
+		c float64
+	}{
+		{``, 0},
+		{`{"hello":"world"}`, 0.9},
+		{"\xEF\xBB\xBF \n{ }", 0.9},
+		{`[1,2,3]`, 0.8},
+		{`"hello"`, 0.5},
+		{`null`, 0.4},
+		{`-42`, 0.2},
+		{`{i`, 0.3},
+		{"\x81\xa1a\x01", 0},
+	}
+
+	for _, test := range tests {
+		if c := sniff([]byte(test.s)); c != test.c {
+			t.Errorf("%q: expected confidence %g but got %g", test.s, test.c, c)
+		}
+	}
+}
+
+func TestCodecOptions(t *testing.T) {
+	tests := []struct {
+		options map[string]string
+		output  string
+	}{
+		{nil, `{"a":["<&>"]}`},
+		{map[string]string{"escape-html": "true"}, `{"a":["\u003c\u0026\u003e"]}`},
+		{map[string]string{"pretty": "true"}, "{\n  \"a\": [\n    \"<&>\"\n  ]\n}"},
+		{map[string]string{"indent": "tab"}, "{\n\t\"a\": [\n\t\t\"<&>\"\n\t]\n}"},
+		{map[string]string{"indent": "0", "pretty": "true"}, `{"a":["<&>"]}`},
+		{map[string]string{"indent": "1", "escape-html": "1"}, "{\n \"a\": [\n  \"\\u003c\\u0026\\u003e\"\n ]\n}"},
+	}
+
+	for _, test := range tests {
+		codec, err := Codec.With(test.options)
+		if err != nil {
+			t.Errorf("%v: %s", test.options, err)
+			continue
+		}
+
+		b := &strings.Builder{}
+
+		if err := codec.NewEncoder(b).Encode(map[string][]string{"a": {"<&>"}}); err != nil {
+			t.Errorf("%v: %s", test.options, err)
+			continue
+		}
+
+		if s := b.String(); s != test.output {
+			t.Errorf("%v:\n%s\n!=\n%s", test.options, test.output, s)
+		}
+	}
+
+	for _, options := range []map[string]string{
+		{"indent": "-1"},
+		{"indent": "x"},
+		{"pretty": "maybe"},
+		{"escape-html": ""},
+		{"lenient": "yes"},
+		{"unknown": "true"},
+	} {
+		if _, err := Codec.With(options); err == nil {
+			t.Errorf("%v: expected an error", options)
+		}
+	}
+}
@@ -17,10 +17,17 @@ import (
 )
 
 type Parser struct {
+	// Lenient enables parsing of documents which are not strictly valid JSON
+	// but are commonly found in configuration files: comments (both // and
+	// /* */) are treated as white spaces, and arrays and objects may have a
+	// trailing comma.
+	Lenient bool
+
 	r io.Reader // reader to load bytes from
 	s []byte    // buffer used for building strings
 	i int       // offset of the first byte in b
 	j int       // offset of the last byte in b
+	n int64     // number of bytes consumed before b[0]
 	b [128]byte // buffer where bytes are loaded from the reader
 	c [128]byte // initial backend array for s
 }
@@ -35,6 +42,7 @@ func (p *Parser) Reset(r io.Reader) {
 	p.r = r
 	p.i = 0
 	p.j = 0
+	p.n = 0
 }
 
 func (p *Parser) Buffered() io.Reader {
@@ -271,6 +279,7 @@ func (p *Parser) ParseArrayNext(n int) (err error) {
 	switch {
 	case b == ',' && n != 0:
 		p.i++
+		err = p.skipTrailingComma(']')
 	case b == ']':
 		err = objconv.End
 	default:
@@ -314,6 +323,7 @@ func (p *Parser) ParseMapNext(n int) (err error) {
 	switch b {
 	case ',':
 		p.i++
+		err = p.skipTrailingComma('}')
 	case '}':
 		err = objconv.End
 	default:
@@ -325,6 +335,31 @@ func (p *Parser) ParseMapNext(n int) (err error) {
 	return
 }
 
+// skipTrailingComma returns objconv.End if the parser is lenient and the comma
+// that was just read is followed by the end delimiter of the array or object.
+func (p *Parser) skipTrailingComma(end byte) (err error) {
+	if !p.Lenient {
+		return
+	}
+
+	var b byte
+
+	if err = p.skipSpaces(); err != nil {
+		return
+	}
+
+	if b, err = p.peekByteAt(0); err == nil && b == end {
+		err = objconv.End
+	}
+
+	return
+}
+
+// Offset returns the number of bytes of the input consumed by p.
+func (p *Parser) Offset() int64 {
+	return p.n + int64(p.i)
+}
+
 func (p *Parser) TextParser() bool {
 	return true
 }
@@ -451,25 +486,96 @@ func (p *Parser) skipSpaces() (err error) {
 			}
 		}
 
+		comment := false
+
 		// seek the first byte in the read buffer that isn't a space character.
+	seek:
 		for _, b := range p.b[p.i:p.j] {
 			switch b {
 			case ' ', '\n', '\t', '\r', '\b', '\f':
 				p.i++
+			case '/':
+				if !p.Lenient {
+					return
+				}
+				comment = true
+				break seek
 			default:
 				return
 			}
 		}
 
+		if comment {
+			if err = p.skipComment(); err != nil {
+				return
+			}
+			continue
+		}
+
 		// all trailing bytes in the read buffer were spaces, clear and refill.
+		p.n += int64(p.j)
 		p.i = 0
 		p.j = 0
 	}
 }
 
+// skipComment skips the comment starting at the current position of the
+// parser, which must be on a '/' character.
+func (p *Parser) skipComment() (err error) {
+	var b byte
+
+	if b, err = p.peekByteAt(1); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+
+	switch b {
+	case '/':
+		p.i += 2
+
+		for b != '\n' {
+			if b, err = p.peekByteAt(0); err != nil {
+				if err == io.EOF { // comment on the last line
+					err = nil
+				}
+				return
+			}
+			p.i++
+		}
+
+	case '*':
+		p.i += 2
+
+		for {
+			if b, err = p.peekByteAt(0); err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return
+			}
+			p.i++
+
+			if b == '*' {
+				if b, err = p.peekByteAt(0); err == nil && b == '/' {
+					p.i++
+					return
+				}
+			}
+		}
+
+	default:
+		err = fmt.Errorf("objconv/json: expected '/' or '*' after '/' but found '%c'", b)
+	}
+
+	return
+}
+
 func (p *Parser) fill() (err error) {
 	n := p.j - p.i
 	copy(p.b[:n], p.b[p.i:p.j])
+	p.n += int64(p.i)
 	p.i = 0
 	p.j = n
 
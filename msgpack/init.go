@@ -10,6 +10,8 @@ import (
 var Codec = objconv.Codec{
 	NewEmitter: func(w io.Writer) objconv.Emitter { return NewEmitter(w) },
 	NewParser:  func(r io.Reader) objconv.Parser { return NewParser(r) },
+	Extensions: []string{".msgpack", ".mpk"},
+	Sniff:      sniff,
 }
 
 func init() {
@@ -20,3 +22,36 @@ func init() {
 		objconv.Register(name, Codec)
 	}
 }
+
+// sniff recognizes MessagePack maps, which is the most common type of
+// top-level values, and is more confident when the first key is a string.
+func sniff(b []byte) float64 {
+	if len(b) == 0 {
+		return 0
+	}
+
+	switch c := b[0]; {
+	case c >= 0x80 && c <= 0x8F, c == Map16, c == Map32:
+		n := 1
+		if c == Map16 {
+			n = 3
+		} else if c == Map32 {
+			n = 5
+		}
+
+		if c == 0x80 || len(b) <= n {
+			return 0.4
+		}
+
+		if k := b[n]; (k >= 0xA0 && k <= 0xBF) || k == Str8 || k == Str16 {
+			return 0.7
+		}
+
+		return 0.3
+
+	case c >= 0x90 && c <= 0x9F, c == Array16, c == Array32:
+		return 0.2
+	}
+
+	return 0
+}
@@ -13,3 +13,24 @@ func TestCodec(t *testing.T) {
 func BenchmarkCodec(b *testing.B) {
 	objtests.BenchmarkCodec(b, Codec)
 }
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		s string
+		c float64
+	}{
+		{"", 0},
+		{"\x81\xa1a\x01", 0.7},
+		{"\xde\x00\x01\xa1a\x01", 0.7},
+		{"\x80", 0.4},
+		{"\x81\x01\x01", 0.3},
+		{"\x92\x01\x02", 0.2},
+		{`{"a":1}`, 0},
+	}
+
+	for _, test := range tests {
+		if c := sniff([]byte(test.s)); c != test.c {
+			t.Errorf("%q: expected confidence %g but got %g", test.s, test.c, c)
+		}
+	}
+}
@@ -14,6 +14,7 @@ type Parser struct {
 	r io.Reader // reader to load bytes from
 	i int       // offset of the first unread byte in b
 	j int       // offset + 1 of the last unread byte in b
+	n int64     // number of bytes consumed before b[0]
 	s []byte    // string buffer
 	b [240]byte // read buffer
 }
@@ -26,12 +27,18 @@ func (p *Parser) Reset(r io.Reader) {
 	p.r = r
 	p.i = 0
 	p.j = 0
+	p.n = 0
 }
 
 func (p *Parser) Buffered() io.Reader {
 	return bytes.NewReader(p.b[p.i:p.j])
 }
 
+// Offset returns the number of bytes of the input consumed by p.
+func (p *Parser) Offset() int64 {
+	return p.n + int64(p.i)
+}
+
 func (p *Parser) ParseType() (objconv.Type, error) {
 	b, err := p.peek(1)
 	if err != nil {
@@ -482,6 +489,7 @@ func (p *Parser) read(n int) (b []byte, err error) {
 	}
 
 	copy(p.s, p.b[p.i:p.j])
+	p.n += int64(p.i + len(p.s))
 	n = p.j - p.i
 	p.i = 0
 	p.j = 0
@@ -507,6 +515,7 @@ func (p *Parser) peek(n int) (b []byte, err error) {
 func (p *Parser) fill() (err error) {
 	n := p.j - p.i
 	copy(p.b[:], p.b[p.i:p.j])
+	p.n += int64(p.i)
 	p.i = 0
 	p.j = n
 
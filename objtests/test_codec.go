@@ -186,6 +186,8 @@ func testName(v interface{}) string {
 func TestCodec(t *testing.T, codec objconv.Codec) {
 	t.Run("Values", func(t *testing.T) { testCodecValues(t, codec) })
 	t.Run("Stream", func(t *testing.T) { testCodecStream(t, codec) })
+	t.Run("Pointer", func(t *testing.T) { testCodecPointer(t, codec) })
+	t.Run("Offset", func(t *testing.T) { testCodecOffset(t, codec) })
 }
 
 func newValue(model interface{}) reflect.Value {
@@ -300,6 +302,107 @@ func testCodecStreamEmpty(t *testing.T, codec objconv.Codec) {
 	}
 }
 
+func testCodecOffset(t *testing.T, codec objconv.Codec) {
+	b := &bytes.Buffer{}
+
+	if _, ok := codec.NewParser(b).(objconv.OffsetParser); !ok {
+		t.Skip("the parser doesn't report offsets")
+	}
+
+	for _, v1 := range TestValues {
+		b.Reset()
+
+		if err := objconv.NewEncoder(codec.NewEmitter(b)).Encode(v1); err != nil {
+			t.Error(err)
+			continue
+		}
+
+		n := int64(b.Len())
+		p := codec.NewParser(b)
+		v2 := newValue(v1)
+
+		if err := objconv.NewDecoder(p).Decode(v2.Interface()); err != nil {
+			t.Error(err)
+			continue
+		}
+
+		if off := p.(objconv.OffsetParser).Offset(); off != n {
+			t.Errorf("%s: offset %d after decoding a value of %d bytes", testName(v1), off, n)
+		}
+	}
+}
+
+func testCodecPointer(t *testing.T, codec objconv.Codec) {
+	b := &bytes.Buffer{}
+
+	doc := map[string]interface{}{
+		"values": TestValues,
+		"ints":   map[int]string{1: "one", 2: "two"},
+		"a/b~c":  []string{"x", "y"},
+		"meta": map[string]interface{}{
+			"request_id": "1234",
+			"tags":       []string{"a", "b"},
+		},
+	}
+
+	if err := objconv.NewEncoder(codec.NewEmitter(b)).Encode(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	input := b.String()
+
+	decodeAt := func(pointer string, v interface{}) error {
+		return objconv.NewDecoder(codec.NewParser(strings.NewReader(input))).DecodeAt(pointer, v)
+	}
+
+	for i, v1 := range TestValues {
+		v2 := newValue(v1)
+
+		if err := decodeAt("/values/"+strconv.Itoa(i), v2.Interface()); err != nil {
+			t.Errorf("/values/%d: %s", i, err)
+			continue
+		}
+
+		if x2 := v2.Elem().Interface(); !reflect.DeepEqual(v1, x2) {
+			t.Errorf("/values/%d: %#v", i, x2)
+		}
+	}
+
+	for _, test := range []struct {
+		pointer string
+		value   string
+	}{
+		{"/meta/request_id", "1234"},
+		{"/meta/tags/1", "b"},
+		{"/ints/2", "two"},
+		{"/a~1b~0c/0", "x"},
+	} {
+		var s string
+
+		if err := decodeAt(test.pointer, &s); err != nil {
+			t.Errorf("%s: %s", test.pointer, err)
+		} else if s != test.value {
+			t.Errorf("%s: %q != %q", test.pointer, test.value, s)
+		}
+	}
+
+	for _, pointer := range []string{"/meta/trace_id", "/meta/tags/2", "/meta/tags/-", "/meta/request_id/0"} {
+		var v interface{}
+
+		if err := decodeAt(pointer, &v); !errors.Is(err, objconv.ErrNotFound) {
+			t.Errorf("%s: expected a not found error, got %v", pointer, err)
+		}
+	}
+
+	for _, pointer := range []string{"/values/01", "/values/+1", "/meta/tags/-1", "/a~2b"} {
+		var v interface{}
+
+		if err := decodeAt(pointer, &v); err == nil || errors.Is(err, objconv.ErrNotFound) {
+			t.Errorf("%s: expected a syntax error, got %v", pointer, err)
+		}
+	}
+}
+
 type counter struct {
 	n int
 }
@@ -0,0 +1,100 @@
+package objutil
+
+import "strconv"
+
+// InferValue returns the value represented by s in text formats that have no
+// type information, like CSV cells or XML text.
+//
+// Empty strings produce nil, "true" and "false" produce booleans, strings that
+// follow the syntax of JSON numbers produce int64, uint64 or float64 values,
+// and other strings are returned unchanged.
+func InferValue(s string) interface{} {
+	switch s {
+	case "":
+		return nil
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	integer, ok := IsNumber(s)
+
+	if !ok {
+		return s
+	}
+
+	if integer {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return u
+		}
+	}
+
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+
+	return s
+}
+
+// IsNumber returns true if s follows the syntax of JSON numbers, integer is
+// set to true if s has no fractional part or exponent.
+func IsNumber(s string) (integer bool, ok bool) {
+	i := 0
+
+	if i != len(s) && s[i] == '-' {
+		i++
+	}
+
+	switch {
+	case i == len(s):
+		return
+	case s[i] == '0':
+		i++
+	case isDigit(s[i]):
+		for i != len(s) && isDigit(s[i]) {
+			i++
+		}
+	default:
+		return
+	}
+
+	integer = true
+
+	if i != len(s) && s[i] == '.' {
+		integer = false
+		i++
+		j := i
+		for i != len(s) && isDigit(s[i]) {
+			i++
+		}
+		if i == j {
+			return
+		}
+	}
+
+	if i != len(s) && (s[i] == 'e' || s[i] == 'E') {
+		integer = false
+		i++
+		if i != len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		j := i
+		for i != len(s) && isDigit(s[i]) {
+			i++
+		}
+		if i == j {
+			return
+		}
+	}
+
+	ok = i == len(s)
+	return
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,39 @@
+package objutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInferValue(t *testing.T) {
+	tests := []struct {
+		s string
+		v interface{}
+	}{
+		{"", nil},
+		{"true", true},
+		{"false", false},
+		{"True", "True"},
+		{"0", int64(0)},
+		{"-42", int64(-42)},
+		{"18446744073709551615", uint64(18446744073709551615)},
+		{"1e400", "1e400"},
+		{"1.5", 1.5},
+		{"-0.5e-3", -0.5e-3},
+		{"01234", "01234"},
+		{"+1", "+1"},
+		{"1.", "1."},
+		{".5", ".5"},
+		{"1e", "1e"},
+		{"NaN", "NaN"},
+		{"Hello", "Hello"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.s, func(t *testing.T) {
+			if v := InferValue(test.s); !reflect.DeepEqual(v, test.v) {
+				t.Errorf("%#v", v)
+			}
+		})
+	}
+}
@@ -12,14 +12,24 @@ type Tag struct {
 
 	// Omitzero is true if the tag had `omitzero` set.
 	Omitzero bool
+
+	// Attr is true if the tag had `attr` set, formats like XML then serialize
+	// the field as an attribute, under a key prefixed with AttrPrefix. Other
+	// formats ignore it.
+	Attr bool
 }
 
+// AttrPrefix is the prefix of keys representing attributes, for example in
+// the XML format.
+const AttrPrefix = "@"
+
 // ParseTag parses a raw tag obtained from a struct field, returning the results
 // as a tag value.
 func ParseTag(s string) Tag {
 	var name string
 	var omitzero bool
 	var omitempty bool
+	var attr bool
 
 	name, s = parseNextTagToken(s)
 
@@ -30,6 +40,8 @@ func ParseTag(s string) Tag {
 			omitempty = true
 		case "omitzero":
 			omitzero = true
+		case "attr":
+			attr = true
 		}
 	}
 
@@ -37,6 +49,7 @@ func ParseTag(s string) Tag {
 		Name:      name,
 		Omitempty: omitempty,
 		Omitzero:  omitzero,
+		Attr:      attr,
 	}
 }
 
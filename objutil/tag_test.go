@@ -39,6 +39,14 @@ func TestParseTag(t *testing.T) {
 			tag: "-,omitempty,omitzero",
 			res: Tag{Name: "-", Omitempty: true, Omitzero: true},
 		},
+		{
+			tag: "id,attr",
+			res: Tag{Name: "id", Attr: true},
+		},
+		{
+			tag: ",attr,omitempty",
+			res: Tag{Omitempty: true, Attr: true},
+		},
 	}
 
 	for _, test := range tests {
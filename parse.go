@@ -124,6 +124,36 @@ type bytesDecoder interface {
 	DecodeBytes([]byte) ([]byte, error)
 }
 
+// The boolDecoder interface may optionnaly be implemented by a Parser of a
+// format which has no boolean type, and represents booleans with values of
+// other types.
+type boolDecoder interface {
+	// DecodeBool is called when the destination variable is a boolean and the
+	// next value is of type t, which is neither Bool nor Nil. The method must
+	// parse the value and return the boolean it represents.
+	DecodeBool(t Type) (bool, error)
+}
+
+// The interfaceDecoder interface may optionnaly be implemented by a Parser of
+// a format which has types with no equivalent in objconv, to decode their
+// values into specific Go types when the destination variable is an empty
+// interface, so they can be encoded again without losing information.
+type interfaceDecoder interface {
+	// DecodeInterface is called when the destination variable is an empty
+	// interface and the next value is of type t. If ok is true the value was
+	// parsed and v is its Go representation, otherwise the decoder uses the
+	// default type for t.
+	DecodeInterface(t Type) (v interface{}, ok bool, err error)
+}
+
+// The OffsetParser interface may be implemented by parsers able to report
+// their position in the input stream, which helps locating malformed input.
+type OffsetParser interface {
+	// Offset returns the number of bytes of the input stream consumed by the
+	// parser.
+	Offset() int64
+}
+
 // The textParser interface may be implemented by parsers of human-readable
 // formats. Such parsers instruct the encoder to prefer using
 // encoding.TextUnmarshaler over encoding.BinaryUnmarshaler for example.
@@ -136,3 +166,48 @@ func isTextParser(parser Parser) bool {
 	p, _ := parser.(textParser)
 	return p != nil && p.TextParser()
 }
+
+// The sequenceParser interface may be implemented by parsers of formats where
+// streams are represented as sequences of values written back-to-back instead
+// of arrays. Stream decoders read values from such parsers until they reach
+// the end of their input.
+type sequenceParser interface {
+	// SequenceParser returns true if the parser reads streams as sequences of
+	// values.
+	SequenceParser() bool
+}
+
+func isSequenceParser(parser Parser) bool {
+	p, _ := parser.(sequenceParser)
+	return p != nil && p.SequenceParser()
+}
+
+// The implicitArrayParser interface may be implemented by parsers of formats
+// where arrays of a single element cannot be distinguished from the element
+// itself, like repeated elements in XML. Decoders accept such values where
+// arrays are expected and decode them as arrays of one element.
+type implicitArrayParser interface {
+	// ImplicitArrayParser returns true if the parser may produce single
+	// values in place of arrays of one element.
+	ImplicitArrayParser() bool
+}
+
+func isImplicitArrayParser(parser Parser) bool {
+	p, _ := parser.(implicitArrayParser)
+	return p != nil && p.ImplicitArrayParser()
+}
+
+// The attrParser interface may be implemented by parsers of formats which
+// distinguish attributes from other values, like XML. Decoders match the keys
+// prefixed with objutil.AttrPrefix with the struct fields tagged with `attr`
+// when they use such parsers.
+type attrParser interface {
+	// AttrParser returns true if the parser produces attributes as map keys
+	// prefixed with objutil.AttrPrefix.
+	AttrParser() bool
+}
+
+func isAttrParser(parser Parser) bool {
+	p, _ := parser.(attrParser)
+	return p != nil && p.AttrParser()
+}
@@ -0,0 +1,32 @@
+package patch
+
+import "github.com/segmentio/objconv"
+
+// merge implements the MergePatch function of RFC 7386, doc and patch must be
+// generic values which merge is allowed to modify.
+func merge(doc interface{}, patch interface{}) interface{} {
+	p, ok := patch.(map[interface{}]interface{})
+	if !ok {
+		return patch
+	}
+
+	d, ok := doc.(map[interface{}]interface{})
+	if !ok {
+		d = make(map[interface{}]interface{}, len(p))
+	}
+
+	for k, v := range p {
+		key, found := lookupKey(d, objconv.KeyString(k))
+		if !found {
+			key = k
+		}
+
+		if v == nil {
+			delete(d, key)
+		} else {
+			d[key] = merge(d[key], v)
+		}
+	}
+
+	return d
+}
@@ -0,0 +1,176 @@
+package patch
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/segmentio/objconv"
+)
+
+// operation is an operation of a JSON Patch.
+type operation struct {
+	Op       string
+	Path     string
+	From     string
+	Value    interface{}
+	hasValue bool
+}
+
+// parseOperations loads the operations of patch, any value with the structure
+// of a JSON Patch is accepted.
+func parseOperations(patch interface{}) ([]operation, error) {
+	v, err := genericValue(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("objconv/patch: a JSON Patch must be an array of operations, got %s", typeName(v))
+	}
+
+	ops := make([]operation, len(list))
+
+	for i, e := range list {
+		m, ok := e.(map[interface{}]interface{})
+		if !ok {
+			return nil, fmt.Errorf("objconv/patch: operation %d must be an object, got %s", i, typeName(e))
+		}
+
+		op := &ops[i]
+		var hasPath, hasFrom bool
+
+		for _, f := range []struct {
+			name string
+			dst  *string
+			has  *bool
+		}{
+			{"op", &op.Op, nil},
+			{"path", &op.Path, &hasPath},
+			{"from", &op.From, &hasFrom},
+		} {
+			x, found := m[f.name]
+			if !found {
+				continue
+			}
+			s, ok := x.(string)
+			if !ok {
+				return nil, fmt.Errorf("objconv/patch: operation %d: %q must be a string, got %s", i, f.name, typeName(x))
+			}
+			*f.dst = s
+			if f.has != nil {
+				*f.has = true
+			}
+		}
+
+		op.Value, op.hasValue = m["value"]
+
+		var err error
+
+		switch op.Op {
+		case "add", "replace", "test":
+			if !op.hasValue {
+				err = fmt.Errorf("missing \"value\"")
+			}
+		case "remove":
+		case "move", "copy":
+			if !hasFrom {
+				err = fmt.Errorf("missing \"from\"")
+			} else {
+				_, err = objconv.ParsePointer(op.From)
+			}
+		case "":
+			err = fmt.Errorf("missing \"op\"")
+		default:
+			err = fmt.Errorf("unknown operation %q", op.Op)
+		}
+
+		if err == nil && !hasPath {
+			err = fmt.Errorf("missing \"path\"")
+		}
+
+		if err == nil {
+			_, err = objconv.ParsePointer(op.Path)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("objconv/patch: operation %d: %v", i, err)
+		}
+	}
+
+	return ops, nil
+}
+
+func (op *operation) apply(doc interface{}) (interface{}, error) {
+	path, _ := objconv.ParsePointer(op.Path)
+
+	switch op.Op {
+	case "add":
+		value, err := copyValue(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+
+	case "remove":
+		doc, _, err := remove(doc, path)
+		return doc, err
+
+	case "replace":
+		value, err := copyValue(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		if doc, _, err = remove(doc, path); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+
+	case "move":
+		from, _ := objconv.ParsePointer(op.From)
+
+		if op.Path == op.From {
+			_, err := get(doc, from)
+			return doc, err
+		}
+
+		if strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, fmt.Errorf("cannot move %q into one of its children", op.From)
+		}
+
+		doc, value, err := remove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+
+	case "copy":
+		from, _ := objconv.ParsePointer(op.From)
+
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if value, err = copyValue(value); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+
+	default: // "test"
+		value, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+
+		changes, err := objconv.Diff(value, op.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(changes) != 0 {
+			return nil, ErrTestFailed
+		}
+
+		return doc, nil
+	}
+}
@@ -0,0 +1,125 @@
+// Package patch applies JSON Patch (RFC 6902) and JSON Merge Patch (RFC 7386)
+// documents to the generic values produced by objconv.
+//
+// Documents and patches are values decoded into interface{} by any codec, or
+// built by an objconv.ValueEmitter, so patches written in YAML or msgpack
+// apply as well as JSON ones, and the result can be encoded with any codec.
+// The changes returned by objconv.Diff are valid JSON Patches.
+//
+// ApplyTo and MergeTo patch structs and other Go values, the value is encoded
+// to its generic representation, patched, then decoded back into the value.
+package patch
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/segmentio/objconv"
+)
+
+// Apply applies the JSON Patch (RFC 6902) patch to doc and returns the patched
+// document. The patch is a list of operations, either decoded from a JSON
+// Patch document or built as a list of objconv.Change values.
+//
+// The patch is atomic: doc is not modified, and no document is returned if an
+// operation fails.
+func Apply(doc interface{}, patch interface{}) (interface{}, error) {
+	ops, err := parseOperations(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	if doc, err = copyValue(doc); err != nil {
+		return nil, err
+	}
+
+	for i, op := range ops {
+		if doc, err = op.apply(doc); err != nil {
+			return nil, &Error{Index: i, Op: op.Op, Path: op.Path, Err: err}
+		}
+	}
+
+	return doc, nil
+}
+
+// Merge applies the JSON Merge Patch (RFC 7386) patch to doc and returns the
+// patched document. Null values of the patch remove the keys of maps, other
+// values replace or are merged with the values of doc.
+//
+// doc is not modified.
+func Merge(doc interface{}, patch interface{}) (interface{}, error) {
+	doc, err := copyValue(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if patch, err = copyValue(patch); err != nil {
+		return nil, err
+	}
+
+	return merge(doc, patch), nil
+}
+
+// ApplyTo applies the JSON Patch patch to the value pointed by v, which is
+// left unchanged if the patch fails.
+func ApplyTo(v interface{}, patch interface{}) error {
+	return patchValue(v, func(doc interface{}) (interface{}, error) { return Apply(doc, patch) })
+}
+
+// MergeTo applies the JSON Merge Patch patch to the value pointed by v.
+func MergeTo(v interface{}, patch interface{}) error {
+	return patchValue(v, func(doc interface{}) (interface{}, error) { return Merge(doc, patch) })
+}
+
+func patchValue(v interface{}, patch func(interface{}) (interface{}, error)) error {
+	ptr := reflect.ValueOf(v)
+
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() {
+		return fmt.Errorf("objconv/patch: the target must be a non-nil pointer, got %T", v)
+	}
+
+	doc, err := genericValue(v)
+	if err != nil {
+		return err
+	}
+
+	if doc, err = patch(doc); err != nil {
+		return err
+	}
+
+	// Decoding into a zero value makes sure the values removed by the patch
+	// don't remain in the target.
+	tmp := reflect.New(ptr.Type().Elem())
+
+	if err = objconv.NewDecoder(objconv.NewValueParser(doc)).Decode(tmp.Interface()); err != nil {
+		return fmt.Errorf("objconv/patch: decoding the patched value: %w", err)
+	}
+
+	ptr.Elem().Set(tmp.Elem())
+	return nil
+}
+
+// Error is returned when an operation of a JSON Patch fails.
+type Error struct {
+	Index int    // index of the operation in the patch
+	Op    string // name of the operation
+	Path  string // path of the operation
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("objconv/patch: operation %d (%s %q): %v", e.Index, e.Op, e.Path, e.Err)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+var (
+	// ErrTestFailed is returned when the value of a "test" operation doesn't
+	// match the document.
+	ErrTestFailed = errors.New("test failed")
+
+	// ErrNotFound is returned when an operation references a value which
+	// doesn't exist, it's the same error as objconv.ErrNotFound.
+	ErrNotFound = objconv.ErrNotFound
+)
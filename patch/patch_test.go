@@ -0,0 +1,259 @@
+package patch
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/segmentio/objconv"
+	"github.com/segmentio/objconv/json"
+)
+
+func parse(t *testing.T, s string) interface{} {
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatalf("%s: %s", s, err)
+	}
+	return v
+}
+
+func assertEqual(t *testing.T, name string, found interface{}, expect interface{}) {
+	changes, err := objconv.Diff(expect, found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("%s: unexpected result: %v", name, changes)
+	}
+}
+
+func TestApply(t *testing.T) {
+	// Examples of RFC 6902, appendix A.
+	tests := []struct {
+		doc    string
+		patch  string
+		result string
+	}{
+		{
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz","value":"qux"}]`,
+			`{"baz":"qux","foo":"bar"}`,
+		},
+		{
+			`{"foo":["bar","baz"]}`,
+			`[{"op":"add","path":"/foo/1","value":"qux"}]`,
+			`{"foo":["bar","qux","baz"]}`,
+		},
+		{
+			`{"baz":"qux","foo":"bar"}`,
+			`[{"op":"remove","path":"/baz"}]`,
+			`{"foo":"bar"}`,
+		},
+		{
+			`{"foo":["bar","qux","baz"]}`,
+			`[{"op":"remove","path":"/foo/1"}]`,
+			`{"foo":["bar","baz"]}`,
+		},
+		{
+			`{"baz":"qux","foo":"bar"}`,
+			`[{"op":"replace","path":"/baz","value":"boo"}]`,
+			`{"baz":"boo","foo":"bar"}`,
+		},
+		{
+			`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{
+			`{"foo":["all","grass","cows","eat"]}`,
+			`[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+			`{"foo":["all","cows","eat","grass"]}`,
+		},
+		{
+			`{"baz":"qux","foo":["a",2,"c"]}`,
+			`[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`,
+			`{"baz":"qux","foo":["a",2,"c"]}`,
+		},
+		{
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/child","value":{"grandchild":{}}}]`,
+			`{"foo":"bar","child":{"grandchild":{}}}`,
+		},
+		{
+			`{"foo":["bar"]}`,
+			`[{"op":"add","path":"/foo/-","value":["abc","def"]}]`,
+			`{"foo":["bar",["abc","def"]]}`,
+		},
+		{
+			`{"/":9,"~1":10}`,
+			`[{"op":"test","path":"/~01","value":10}]`,
+			`{"/":9,"~1":10}`,
+		},
+		{
+			`{"a":{"b":[1]}}`,
+			`[{"op":"copy","from":"/a/b","path":"/c"},{"op":"add","path":"/c/0","value":0}]`,
+			`{"a":{"b":[1]},"c":[0,1]}`,
+		},
+		{
+			`{"a":1}`,
+			`[{"op":"replace","path":"","value":[1,2]}]`,
+			`[1,2]`,
+		},
+	}
+
+	for _, test := range tests {
+		doc := parse(t, test.doc)
+
+		res, err := Apply(doc, parse(t, test.patch))
+		if err != nil {
+			t.Errorf("%s: %s", test.patch, err)
+			continue
+		}
+
+		assertEqual(t, test.patch, res, parse(t, test.result))
+		assertEqual(t, "original document", doc, parse(t, test.doc))
+	}
+}
+
+func TestApplyError(t *testing.T) {
+	tests := []struct {
+		doc   string
+		patch string
+		err   error
+	}{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, ErrNotFound},
+		{`{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, ErrTestFailed},
+		{`{"foo":[1]}`, `[{"op":"remove","path":"/foo/1"}]`, ErrNotFound},
+		{`{"foo":[1]}`, `[{"op":"add","path":"/foo/2","value":2}]`, ErrNotFound},
+		{`{"foo":[1]}`, `[{"op":"replace","path":"/bar","value":2}]`, ErrNotFound},
+		{`{"foo":[1]}`, `[{"op":"remove","path":"/foo/01"}]`, nil},
+		{`{"foo":{}}`, `[{"op":"move","from":"/foo","path":"/foo/bar"}]`, nil},
+		{`{}`, `[{"op":"add","path":"/foo"}]`, nil},
+		{`{}`, `[{"op":"jump","path":"/foo"}]`, nil},
+		{`{}`, `[{"op":"remove","path":"foo"}]`, nil},
+		{`{}`, `[{"op":"remove","path":"/~2"}]`, nil},
+		{`{}`, `{"op":"remove","path":"/foo"}`, nil},
+	}
+
+	for _, test := range tests {
+		doc := parse(t, test.doc)
+
+		if _, err := Apply(doc, parse(t, test.patch)); err == nil {
+			t.Errorf("%s: expected an error", test.patch)
+		} else if test.err != nil && !errors.Is(err, test.err) {
+			t.Errorf("%s: expected %v, got %v", test.patch, test.err, err)
+		}
+	}
+}
+
+func TestApplyAtomic(t *testing.T) {
+	doc := parse(t, `{"a":[1,2]}`)
+
+	_, err := Apply(doc, parse(t, `[{"op":"add","path":"/a/-","value":3},{"op":"remove","path":"/b"}]`))
+
+	var e *Error
+	if !errors.As(err, &e) || e.Index != 1 || e.Op != "remove" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	assertEqual(t, "original document", doc, parse(t, `{"a":[1,2]}`))
+}
+
+func TestApplyDiff(t *testing.T) {
+	a := parse(t, `{"a":[1,2,3,4],"b":{"c":"d","e/f":true},"x":null}`)
+	b := parse(t, `{"a":[1,5],"b":{"c":"D","g":[]},"y":1.5}`)
+
+	changes, err := objconv.Diff(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Apply(a, changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertEqual(t, "diff", res, b)
+}
+
+func TestMerge(t *testing.T) {
+	// Examples of RFC 7386, appendix A.
+	tests := []struct {
+		doc    string
+		patch  string
+		result string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, test := range tests {
+		doc := parse(t, test.doc)
+
+		res, err := Merge(doc, parse(t, test.patch))
+		if err != nil {
+			t.Errorf("%s: %s", test.patch, err)
+			continue
+		}
+
+		assertEqual(t, test.patch, res, parse(t, test.result))
+		assertEqual(t, "original document", doc, parse(t, test.doc))
+	}
+}
+
+type config struct {
+	Name  string            `objconv:"name"`
+	Tags  []string          `objconv:"tags"`
+	Attrs map[string]string `objconv:"attrs"`
+	Port  int               `objconv:"port,omitempty"`
+}
+
+func TestApplyTo(t *testing.T) {
+	c := config{Name: "A", Tags: []string{"x"}, Attrs: map[string]string{"k": "v"}, Port: 80}
+
+	err := ApplyTo(&c, parse(t, `[
+		{"op":"replace","path":"/name","value":"B"},
+		{"op":"add","path":"/tags/0","value":"w"},
+		{"op":"remove","path":"/attrs/k"},
+		{"op":"remove","path":"/port"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertEqual(t, "ApplyTo", c, config{Name: "B", Tags: []string{"w", "x"}, Attrs: map[string]string{}})
+
+	if err := ApplyTo(&c, parse(t, `[{"op":"replace","path":"/name","value":"C"},{"op":"test","path":"/port","value":1}]`)); err == nil {
+		t.Error("expected an error")
+	}
+
+	if c.Name != "B" {
+		t.Errorf("the value was modified by a failed patch: %+v", c)
+	}
+}
+
+func TestMergeTo(t *testing.T) {
+	c := config{Name: "A", Tags: []string{"x"}, Attrs: map[string]string{"k": "v"}, Port: 80}
+
+	if err := MergeTo(&c, parse(t, `{"attrs":{"k":null,"l":"w"},"port":null}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	assertEqual(t, "MergeTo", c, config{Name: "A", Tags: []string{"x"}, Attrs: map[string]string{"l": "w"}})
+
+	if err := MergeTo(c, `{}`); err == nil {
+		t.Error("expected an error for a non-pointer target")
+	}
+}
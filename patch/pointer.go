@@ -0,0 +1,212 @@
+package patch
+
+import (
+	"fmt"
+
+	"github.com/segmentio/objconv"
+)
+
+// parseIndex parses the array index t, n is the length of the array and the
+// highest index accepted.
+func parseIndex(t string, n int) (int, error) {
+	i, err := objconv.ParsePointerIndex(t)
+	if err != nil {
+		return 0, err
+	}
+
+	if i > n {
+		return 0, fmt.Errorf("array index %d out of bounds: %w", i, ErrNotFound)
+	}
+
+	return i, nil
+}
+
+// get returns the value of doc at path.
+func get(doc interface{}, path []string) (interface{}, error) {
+	for _, t := range path {
+		switch x := doc.(type) {
+		case map[interface{}]interface{}:
+			k, ok := lookupKey(x, t)
+			if !ok {
+				return nil, fmt.Errorf("key %q: %w", t, ErrNotFound)
+			}
+			doc = x[k]
+
+		case []interface{}:
+			i, err := parseIndex(t, len(x)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = x[i]
+
+		default:
+			return nil, fmt.Errorf("cannot lookup %q in %s: %w", t, typeName(doc), ErrNotFound)
+		}
+	}
+	return doc, nil
+}
+
+// update calls f with the value of doc at path and replaces it with the value
+// returned by f.
+func update(doc interface{}, path []string, f func(interface{}) (interface{}, error)) (interface{}, error) {
+	if len(path) == 0 {
+		return f(doc)
+	}
+
+	t := path[0]
+
+	switch x := doc.(type) {
+	case map[interface{}]interface{}:
+		k, ok := lookupKey(x, t)
+		if !ok {
+			return nil, fmt.Errorf("key %q: %w", t, ErrNotFound)
+		}
+		v, err := update(x[k], path[1:], f)
+		if err != nil {
+			return nil, err
+		}
+		x[k] = v
+
+	case []interface{}:
+		i, err := parseIndex(t, len(x)-1)
+		if err != nil {
+			return nil, err
+		}
+		v, err := update(x[i], path[1:], f)
+		if err != nil {
+			return nil, err
+		}
+		x[i] = v
+
+	default:
+		return nil, fmt.Errorf("cannot lookup %q in %s: %w", t, typeName(doc), ErrNotFound)
+	}
+
+	return doc, nil
+}
+
+// add inserts value in doc at path, replacing the existing value of a map
+// key, or inserting it before the existing element of an array.
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	last := path[len(path)-1]
+
+	return update(doc, path[:len(path)-1], func(parent interface{}) (interface{}, error) {
+		switch x := parent.(type) {
+		case map[interface{}]interface{}:
+			if k, ok := lookupKey(x, last); ok {
+				x[k] = value
+			} else {
+				x[last] = value
+			}
+			return x, nil
+
+		case []interface{}:
+			if last == "-" {
+				return append(x, value), nil
+			}
+			i, err := parseIndex(last, len(x))
+			if err != nil {
+				return nil, err
+			}
+			x = append(x, nil)
+			copy(x[i+1:], x[i:])
+			x[i] = value
+			return x, nil
+
+		default:
+			return nil, fmt.Errorf("cannot add %q to %s", last, typeName(parent))
+		}
+	})
+}
+
+// remove deletes the value of doc at path and returns it.
+func remove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, doc, nil
+	}
+
+	last := path[len(path)-1]
+	var value interface{}
+
+	doc, err := update(doc, path[:len(path)-1], func(parent interface{}) (interface{}, error) {
+		switch x := parent.(type) {
+		case map[interface{}]interface{}:
+			k, ok := lookupKey(x, last)
+			if !ok {
+				return nil, fmt.Errorf("key %q: %w", last, ErrNotFound)
+			}
+			value = x[k]
+			delete(x, k)
+			return x, nil
+
+		case []interface{}:
+			i, err := parseIndex(last, len(x)-1)
+			if err != nil {
+				return nil, err
+			}
+			value = x[i]
+			return append(x[:i], x[i+1:]...), nil
+
+		default:
+			return nil, fmt.Errorf("cannot remove %q from %s: %w", last, typeName(parent), ErrNotFound)
+		}
+	})
+
+	return doc, value, err
+}
+
+// lookupKey returns the key of m matching the reference token t. Keys which
+// aren't strings, like the integer keys of YAML or msgpack maps, are matched
+// by their string representation.
+func lookupKey(m map[interface{}]interface{}, t string) (interface{}, bool) {
+	if _, ok := m[t]; ok {
+		return t, true
+	}
+
+	for k := range m {
+		if objconv.KeyString(k) == t {
+			return k, true
+		}
+	}
+
+	return nil, false
+}
+
+// genericValue returns the representation of v built by an
+// objconv.ValueEmitter, which is always a new value.
+func genericValue(v interface{}) (interface{}, error) {
+	e := objconv.NewValueEmitter()
+	if err := objconv.NewEncoder(e).Encode(v); err != nil {
+		return nil, fmt.Errorf("objconv/patch: %w", err)
+	}
+	return e.Value(), nil
+}
+
+// copyValue returns a deep copy of v.
+func copyValue(v interface{}) (interface{}, error) {
+	return genericValue(v)
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "a boolean"
+	case int64, uint64, float64:
+		return "a number"
+	case string:
+		return "a string"
+	case []byte:
+		return "bytes"
+	case []interface{}:
+		return "an array"
+	case map[interface{}]interface{}:
+		return "an object"
+	}
+	return fmt.Sprintf("a value of type %T", v)
+}
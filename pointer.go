@@ -0,0 +1,363 @@
+package objconv
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned by Decoder.DecodeAt when the JSON Pointer doesn't
+// reference any value of the input.
+var ErrNotFound = errors.New("objconv: JSON Pointer not found")
+
+// DecodeAt decodes into v the value referenced by the JSON Pointer (RFC 6901)
+// pointer in the next value of the parser, for example "/meta/request_id".
+//
+// The values which aren't referenced by the pointer are skipped using only the
+// Parser interface, without being decoded or allocating memory, so a small
+// value can be extracted from a large input in any format. The rest of the
+// enclosing value is skipped as well, leaving the parser positioned after it
+// like Decode would.
+//
+// Map keys which aren't strings, like integer keys in msgpack, are matched by
+// their KeyString representation. The error wraps ErrNotFound when the pointer
+// doesn't reference any value.
+func (d Decoder) DecodeAt(pointer string, v interface{}) (err error) {
+	if d.off != 0 {
+		if d.off, err = 0, d.Parser.ParseMapValue(d.off-1); err != nil {
+			return
+		}
+	}
+
+	var tokens []string
+
+	if tokens, err = ParsePointer(pointer); err != nil {
+		return
+	}
+
+	if len(tokens) == 0 {
+		return d.Decode(v)
+	}
+
+	var found bool
+
+	if found, err = d.decodeAt(tokens, v); err == nil && !found {
+		err = fmt.Errorf("%w: %q", ErrNotFound, pointer)
+	}
+
+	return
+}
+
+func (d Decoder) decodeAt(tokens []string, v interface{}) (found bool, err error) {
+	var typ Type
+	var n int
+
+	if typ, err = d.Parser.ParseType(); err != nil {
+		return
+	}
+
+	switch typ {
+	case Array:
+		n, err = d.Parser.ParseArrayBegin()
+	case Map:
+		n, err = d.Parser.ParseMapBegin()
+	default:
+		err = skipValue(d.Parser, typ)
+		return
+	}
+
+	if err != nil {
+		return
+	}
+
+	index := -1
+
+	if typ == Array {
+		if index, err = ParsePointerIndex(tokens[0]); err != nil {
+			return
+		}
+	}
+
+	i := 0
+
+	for n < 0 || i < n {
+		if n < 0 || i != 0 {
+			if typ == Array {
+				err = d.Parser.ParseArrayNext(i)
+			} else {
+				err = d.Parser.ParseMapNext(i)
+			}
+			if err != nil {
+				if err == End {
+					err = nil
+					break
+				}
+				return
+			}
+		}
+
+		match := i == index
+
+		if typ == Map {
+			if match, err = matchKey(d.Parser, tokens[0]); err != nil {
+				return
+			}
+			if err = d.Parser.ParseMapValue(i); err != nil {
+				return
+			}
+		}
+
+		switch {
+		case !match || found:
+			err = skip(d.Parser)
+		case len(tokens) == 1:
+			found, err = true, Decoder{Parser: d.Parser, MapType: d.MapType}.Decode(v)
+		default:
+			found, err = d.decodeAt(tokens[1:], v)
+		}
+
+		if err != nil {
+			return
+		}
+
+		i++
+	}
+
+	if typ == Array {
+		err = d.Parser.ParseArrayEnd(i)
+	} else {
+		err = d.Parser.ParseMapEnd(i)
+	}
+
+	return
+}
+
+// ParsePointer splits the JSON Pointer (RFC 6901) p into its unescaped
+// reference tokens, the empty pointer has no tokens and references the whole
+// value.
+func ParsePointer(p string) ([]string, error) {
+	if len(p) == 0 {
+		return nil, nil
+	}
+
+	if p[0] != '/' {
+		return nil, fmt.Errorf("objconv: invalid JSON Pointer %q: must be empty or start with '/'", p)
+	}
+
+	for i := 0; i != len(p); i++ {
+		if p[i] == '~' && (i+1 == len(p) || (p[i+1] != '0' && p[i+1] != '1')) {
+			return nil, fmt.Errorf("objconv: invalid JSON Pointer %q: '~' must be followed by '0' or '1'", p)
+		}
+	}
+
+	tokens := strings.Split(p[1:], "/")
+
+	for i, t := range tokens {
+		if strings.IndexByte(t, '~') >= 0 {
+			tokens[i] = pointerUnescaper.Replace(t)
+		}
+	}
+
+	return tokens, nil
+}
+
+// EscapePointer escapes s to be used as a reference token of a JSON Pointer.
+func EscapePointer(s string) string {
+	return pointerEscaper.Replace(s)
+}
+
+var (
+	pointerEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+)
+
+// ParsePointerIndex parses the reference token t as an array index, which must
+// be a decimal number without sign or leading zeros. The "-" token references
+// the element after the last one, which never exists, so the error returned
+// for it wraps ErrNotFound.
+func ParsePointerIndex(t string) (int, error) {
+	if t == "-" {
+		return 0, fmt.Errorf("%w: array index \"-\" references the end of the array", ErrNotFound)
+	}
+
+	if len(t) == 0 || (len(t) > 1 && t[0] == '0') {
+		return 0, fmt.Errorf("objconv: invalid array index %q in JSON Pointer", t)
+	}
+
+	for i := 0; i != len(t); i++ {
+		if t[i] < '0' || t[i] > '9' {
+			return 0, fmt.Errorf("objconv: invalid array index %q in JSON Pointer", t)
+		}
+	}
+
+	index, err := strconv.Atoi(t)
+	if err != nil {
+		return 0, fmt.Errorf("objconv: invalid array index %q in JSON Pointer", t)
+	}
+
+	return index, nil
+}
+
+// KeyString returns the string representation of the map key k that JSON
+// Pointer reference tokens are matched against. Keys of formats like msgpack
+// or YAML aren't always strings, times use RFC 3339 and other values are
+// formatted by fmt.Sprint, so an integer key 1 is matched by the token "1".
+func KeyString(k interface{}) string {
+	switch x := k.(type) {
+	case string:
+		return x
+	case []byte:
+		return string(x)
+	case nil:
+		return "null"
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(k)
+}
+
+// matchKey parses the next map key and reports whether its KeyString
+// representation is equal to the reference token.
+func matchKey(p Parser, token string) (match bool, err error) {
+	var typ Type
+	var b []byte
+	var a [24]byte
+
+	if typ, err = p.ParseType(); err != nil {
+		return
+	}
+
+	switch typ {
+	case String:
+		b, err = p.ParseString()
+	case Bytes:
+		b, err = p.ParseBytes()
+	case Int:
+		var i int64
+		if i, err = p.ParseInt(); err == nil {
+			b = strconv.AppendInt(a[:0], i, 10)
+		}
+	case Uint:
+		var u uint64
+		if u, err = p.ParseUint(); err == nil {
+			b = strconv.AppendUint(a[:0], u, 10)
+		}
+	case Array, Map:
+		err = skipValue(p, typ)
+		return
+	default:
+		// Other scalar keys are rare, they're decoded to get the same
+		// representation as KeyString.
+		var k interface{}
+		if err = (Decoder{Parser: p}).decodeInterfaceFromType(typ, reflect.ValueOf(&k).Elem()); err == nil {
+			match = KeyString(k) == token
+		}
+		return
+	}
+
+	if err == nil {
+		match = string(b) == token
+	}
+
+	return
+}
+
+// skip skips the next value of p.
+func skip(p Parser) error {
+	typ, err := p.ParseType()
+	if err != nil {
+		return err
+	}
+	return skipValue(p, typ)
+}
+
+// skipValue skips the next value of p, which is of type typ. Only the methods
+// of the Parser interface are used, and the values aren't copied.
+func skipValue(p Parser, typ Type) (err error) {
+	var n int
+
+	switch typ {
+	case Nil:
+		return p.ParseNil()
+	case Bool:
+		_, err = p.ParseBool()
+	case Int:
+		_, err = p.ParseInt()
+	case Uint:
+		_, err = p.ParseUint()
+	case Float:
+		_, err = p.ParseFloat()
+	case String:
+		_, err = p.ParseString()
+	case Bytes:
+		_, err = p.ParseBytes()
+	case Time:
+		_, err = p.ParseTime()
+	case Duration:
+		_, err = p.ParseDuration()
+	case Error:
+		_, err = p.ParseError()
+
+	case Array:
+		if n, err = p.ParseArrayBegin(); err != nil {
+			return
+		}
+
+		i := 0
+
+		for n < 0 || i < n {
+			if n < 0 || i != 0 {
+				if err = p.ParseArrayNext(i); err != nil {
+					if err == End {
+						break
+					}
+					return
+				}
+			}
+			if err = skip(p); err != nil {
+				return
+			}
+			i++
+		}
+
+		err = p.ParseArrayEnd(i)
+
+	case Map:
+		if n, err = p.ParseMapBegin(); err != nil {
+			return
+		}
+
+		i := 0
+
+		for n < 0 || i < n {
+			if n < 0 || i != 0 {
+				if err = p.ParseMapNext(i); err != nil {
+					if err == End {
+						break
+					}
+					return
+				}
+			}
+			if err = skip(p); err != nil {
+				return
+			}
+			if err = p.ParseMapValue(i); err != nil {
+				return
+			}
+			if err = skip(p); err != nil {
+				return
+			}
+			i++
+		}
+
+		err = p.ParseMapEnd(i)
+
+	default:
+		err = fmt.Errorf("objconv: unsupported type %s found while skipping a value", typ)
+	}
+
+	return
+}
@@ -0,0 +1,76 @@
+package objconv
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParsePointer(t *testing.T) {
+	tests := []struct {
+		pointer string
+		tokens  []string
+	}{
+		{"", nil},
+		{"/", []string{""}},
+		{"/a/0", []string{"a", "0"}},
+		{"/a~1b/c~0d/~01", []string{"a/b", "c~d", "~1"}},
+	}
+
+	for _, test := range tests {
+		tokens, err := ParsePointer(test.pointer)
+		if err != nil {
+			t.Errorf("%q: %s", test.pointer, err)
+		} else if !reflect.DeepEqual(tokens, test.tokens) {
+			t.Errorf("%q: %q != %q", test.pointer, test.tokens, tokens)
+		}
+	}
+
+	for _, pointer := range []string{"a", "/a~", "/a~2"} {
+		if _, err := ParsePointer(pointer); err == nil {
+			t.Errorf("%q: expected an error", pointer)
+		}
+	}
+}
+
+func TestParsePointerIndex(t *testing.T) {
+	for token, index := range map[string]int{"0": 0, "1": 1, "42": 42} {
+		if i, err := ParsePointerIndex(token); err != nil || i != index {
+			t.Errorf("%q: %d != %d (%v)", token, index, i, err)
+		}
+	}
+
+	for _, token := range []string{"", "01", "+1", "-1", "1e2", "a"} {
+		if _, err := ParsePointerIndex(token); err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("%q: expected a syntax error, got %v", token, err)
+		}
+	}
+
+	if _, err := ParsePointerIndex("-"); !errors.Is(err, ErrNotFound) {
+		t.Errorf(`"-": expected a not found error, got %v`, err)
+	}
+}
+
+func TestDecodeAtKeyString(t *testing.T) {
+	date := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	doc := map[interface{}]interface{}{
+		int64(-1): "int",
+		true:      "bool",
+		1.5:       "float",
+		date:      "time",
+	}
+
+	for k, v := range doc {
+		var s string
+
+		pointer := "/" + EscapePointer(KeyString(k))
+
+		if err := NewDecoder(NewValueParser(doc)).DecodeAt(pointer, &s); err != nil {
+			t.Errorf("%s: %s", pointer, err)
+		} else if s != v {
+			t.Errorf("%s: %q != %q", pointer, v, s)
+		}
+	}
+}
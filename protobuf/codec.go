@@ -0,0 +1,17 @@
+package protobuf
+
+import (
+	"io"
+
+	"github.com/segmentio/objconv"
+)
+
+// NewCodec returns a codec for messages of type msg, which can be registered
+// with objconv.Register.
+func NewCodec(msg *Message) objconv.Codec {
+	return objconv.Codec{
+		NewEmitter: func(w io.Writer) objconv.Emitter { return NewEmitter(w, msg) },
+		NewParser:  func(r io.Reader) objconv.Parser { return NewParser(r, msg) },
+		Extensions: []string{".pb", ".binpb"},
+	}
+}
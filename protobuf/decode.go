@@ -0,0 +1,20 @@
+package protobuf
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/segmentio/objconv"
+)
+
+// NewDecoder returns a new Protocol Buffers decoder that parses a message of
+// type msg from r.
+func NewDecoder(r io.Reader, msg *Message) *objconv.Decoder {
+	return objconv.NewDecoder(NewParser(r, msg))
+}
+
+// Unmarshal decodes the Protocol Buffers representation of v, a message of
+// type msg, from b.
+func Unmarshal(b []byte, msg *Message, v interface{}) error {
+	return NewDecoder(bytes.NewReader(b), msg).Decode(v)
+}
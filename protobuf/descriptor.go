@@ -0,0 +1,442 @@
+package protobuf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// Kind represents the type of message fields, the values match the ones of
+// FieldDescriptorProto.Type.
+type Kind int
+
+const (
+	DoubleKind   Kind = 1
+	FloatKind    Kind = 2
+	Int64Kind    Kind = 3
+	Uint64Kind   Kind = 4
+	Int32Kind    Kind = 5
+	Fixed64Kind  Kind = 6
+	Fixed32Kind  Kind = 7
+	BoolKind     Kind = 8
+	StringKind   Kind = 9
+	GroupKind    Kind = 10
+	MessageKind  Kind = 11
+	BytesKind    Kind = 12
+	Uint32Kind   Kind = 13
+	EnumKind     Kind = 14
+	Sfixed32Kind Kind = 15
+	Sfixed64Kind Kind = 16
+	Sint32Kind   Kind = 17
+	Sint64Kind   Kind = 18
+)
+
+var kindNames = [...]string{
+	DoubleKind:   "double",
+	FloatKind:    "float",
+	Int64Kind:    "int64",
+	Uint64Kind:   "uint64",
+	Int32Kind:    "int32",
+	Fixed64Kind:  "fixed64",
+	Fixed32Kind:  "fixed32",
+	BoolKind:     "bool",
+	StringKind:   "string",
+	GroupKind:    "group",
+	MessageKind:  "message",
+	BytesKind:    "bytes",
+	Uint32Kind:   "uint32",
+	EnumKind:     "enum",
+	Sfixed32Kind: "sfixed32",
+	Sfixed64Kind: "sfixed64",
+	Sint32Kind:   "sint32",
+	Sint64Kind:   "sint64",
+}
+
+func (k Kind) String() string {
+	if k > 0 && int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// wireType returns the wire type of fields of kind k.
+func (k Kind) wireType() int {
+	switch k {
+	case DoubleKind, Fixed64Kind, Sfixed64Kind:
+		return wireFixed64
+	case FloatKind, Fixed32Kind, Sfixed32Kind:
+		return wireFixed32
+	case StringKind, BytesKind, MessageKind:
+		return wireBytes
+	case GroupKind:
+		return wireStart
+	default:
+		return wireVarint
+	}
+}
+
+// DescriptorSet is a collection of message and enum descriptors loaded from a
+// FileDescriptorSet.
+type DescriptorSet struct {
+	messages map[string]*Message
+	enums    map[string]*Enum
+}
+
+// Message describes a message type.
+type Message struct {
+	// Fully qualified name of the message, without a leading dot.
+	Name string
+	// Fields of the message in declaration order.
+	Fields []*Field
+
+	byNumber map[int]*Field
+	byName   map[string]*Field
+	mapEntry bool
+}
+
+// Field describes a field of a message.
+type Field struct {
+	Name     string
+	JSONName string
+	Number   int
+	Kind     Kind
+	Repeated bool
+	// Packed is true if repeated scalar values are written in packed form.
+	Packed bool
+	// Message is set for fields of kind MessageKind or GroupKind.
+	Message *Message
+	// Enum is set for fields of kind EnumKind.
+	Enum *Enum
+}
+
+// Enum describes an enum type.
+type Enum struct {
+	// Fully qualified name of the enum, without a leading dot.
+	Name string
+
+	names   map[int32]string
+	numbers map[string]int32
+}
+
+// ParseDescriptorSet parses b, the wire representation of a
+// google.protobuf.FileDescriptorSet message.
+func ParseDescriptorSet(b []byte) (*DescriptorSet, error) {
+	var fds fileDescriptorSet
+
+	if err := Unmarshal(b, fileDescriptorSetMessage, &fds); err != nil {
+		return nil, fmt.Errorf("objconv/protobuf: invalid descriptor set: %s", err)
+	}
+
+	s := &DescriptorSet{
+		messages: make(map[string]*Message),
+		enums:    make(map[string]*Enum),
+	}
+
+	// Types are registered first so fields can refer to types declared in
+	// any file of the set.
+	for _, file := range fds.File {
+		scope := ""
+		if file.Package != "" {
+			scope = "." + file.Package
+		}
+		s.registerEnums(scope, file.EnumType)
+		s.registerMessages(scope, file.MessageType)
+	}
+
+	for _, file := range fds.File {
+		scope := ""
+		if file.Package != "" {
+			scope = "." + file.Package
+		}
+		if err := s.resolveMessages(scope, file.MessageType, file.Syntax == "proto3"); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// LoadDescriptorSet loads a FileDescriptorSet from the file at path.
+func LoadDescriptorSet(path string) (*DescriptorSet, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDescriptorSet(b)
+}
+
+// Message returns the descriptor of the message with the given fully
+// qualified name, or nil if the set has no such message. The leading dot of
+// the name is optional.
+func (s *DescriptorSet) Message(name string) *Message {
+	return s.messages[qualify(name)]
+}
+
+// Enum returns the descriptor of the enum with the given fully qualified name,
+// or nil if the set has no such enum. The leading dot of the name is optional.
+func (s *DescriptorSet) Enum(name string) *Enum {
+	return s.enums[qualify(name)]
+}
+
+func (s *DescriptorSet) registerEnums(scope string, enums []enumDescriptorProto) {
+	for _, e := range enums {
+		name := scope + "." + e.Name
+		enum := &Enum{
+			Name:    name[1:],
+			names:   make(map[int32]string, len(e.Value)),
+			numbers: make(map[string]int32, len(e.Value)),
+		}
+
+		for _, v := range e.Value {
+			if _, dup := enum.names[v.Number]; !dup { // aliases keep the first name
+				enum.names[v.Number] = v.Name
+			}
+			enum.numbers[v.Name] = v.Number
+		}
+
+		s.enums[name] = enum
+	}
+}
+
+func (s *DescriptorSet) registerMessages(scope string, messages []descriptorProto) {
+	for _, m := range messages {
+		name := scope + "." + m.Name
+		s.messages[name] = &Message{
+			Name:     name[1:],
+			mapEntry: m.Options.MapEntry,
+		}
+		s.registerEnums(name, m.EnumType)
+		s.registerMessages(name, m.NestedType)
+	}
+}
+
+func (s *DescriptorSet) resolveMessages(scope string, messages []descriptorProto, proto3 bool) error {
+	for _, m := range messages {
+		name := scope + "." + m.Name
+		msg := s.messages[name]
+
+		for _, f := range m.Field {
+			field := &Field{
+				Name:     f.Name,
+				JSONName: f.JSONName,
+				Number:   int(f.Number),
+				Kind:     Kind(f.Type),
+				Repeated: f.Label == labelRepeated,
+			}
+
+			if field.JSONName == "" {
+				field.JSONName = jsonName(f.Name)
+			}
+
+			if field.Kind < DoubleKind || field.Kind > Sint64Kind {
+				return fmt.Errorf("objconv/protobuf: field %s.%s has an invalid type %d", msg.Name, f.Name, f.Type)
+			}
+
+			switch field.Kind {
+			case MessageKind, GroupKind:
+				if field.Message = s.messages[qualify(f.TypeName)]; field.Message == nil {
+					return fmt.Errorf("objconv/protobuf: field %s.%s has an unknown message type %s", msg.Name, f.Name, f.TypeName)
+				}
+
+			case EnumKind:
+				if field.Enum = s.enums[qualify(f.TypeName)]; field.Enum == nil {
+					return fmt.Errorf("objconv/protobuf: field %s.%s has an unknown enum type %s", msg.Name, f.Name, f.TypeName)
+				}
+			}
+
+			if field.Repeated && field.Kind.wireType() != wireBytes && field.Kind != GroupKind {
+				if f.Options.Packed != nil {
+					field.Packed = *f.Options.Packed
+				} else {
+					field.Packed = proto3
+				}
+			}
+
+			msg.add(field)
+		}
+
+		if err := s.resolveMessages(name, m.NestedType, proto3); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Message) add(f *Field) {
+	if m.byNumber == nil {
+		m.byNumber = make(map[int]*Field)
+		m.byName = make(map[string]*Field)
+	}
+	m.Fields = append(m.Fields, f)
+	m.byNumber[f.Number] = f
+	m.byName[f.Name] = f
+	if f.JSONName != f.Name {
+		m.byName[f.JSONName] = f
+	}
+}
+
+// field returns the field with the given name or JSON name.
+func (m *Message) field(name string) *Field {
+	return m.byName[name]
+}
+
+// IsMap returns true if the field is a map, which is represented as a
+// repeated field of map entry messages.
+func (f *Field) IsMap() bool {
+	return f.Repeated && f.Message != nil && f.Message.mapEntry
+}
+
+// mapKey and mapValue return the key and value fields of map entries.
+func (f *Field) mapKey() *Field   { return f.Message.byNumber[1] }
+func (f *Field) mapValue() *Field { return f.Message.byNumber[2] }
+
+func qualify(name string) string {
+	if !strings.HasPrefix(name, ".") {
+		name = "." + name
+	}
+	return name
+}
+
+// jsonName converts a field name to lowerCamelCase the way protoc does.
+func jsonName(name string) string {
+	b := make([]byte, 0, len(name))
+	upper := false
+
+	for i := 0; i != len(name); i++ {
+		switch c := name[i]; {
+		case c == '_':
+			upper = true
+		case upper && c >= 'a' && c <= 'z':
+			b = append(b, c-'a'+'A')
+			upper = false
+		default:
+			b = append(b, c)
+			upper = false
+		}
+	}
+
+	return string(b)
+}
+
+// The types below represent the subset of descriptor.proto needed to load
+// descriptor sets, they are decoded with the bootstrap descriptors declared
+// at the end of this file.
+
+const labelRepeated = 3
+
+type fileDescriptorSet struct {
+	File []fileDescriptorProto `objconv:"file"`
+}
+
+type fileDescriptorProto struct {
+	Name        string                `objconv:"name"`
+	Package     string                `objconv:"package"`
+	MessageType []descriptorProto     `objconv:"message_type"`
+	EnumType    []enumDescriptorProto `objconv:"enum_type"`
+	Syntax      string                `objconv:"syntax"`
+}
+
+type descriptorProto struct {
+	Name       string                 `objconv:"name"`
+	Field      []fieldDescriptorProto `objconv:"field"`
+	NestedType []descriptorProto      `objconv:"nested_type"`
+	EnumType   []enumDescriptorProto  `objconv:"enum_type"`
+	Options    messageOptions         `objconv:"options"`
+}
+
+type messageOptions struct {
+	MapEntry bool `objconv:"map_entry"`
+}
+
+type fieldDescriptorProto struct {
+	Name     string       `objconv:"name"`
+	Number   int32        `objconv:"number"`
+	Label    int32        `objconv:"label"`
+	Type     int32        `objconv:"type"`
+	TypeName string       `objconv:"type_name"`
+	Options  fieldOptions `objconv:"options"`
+	JSONName string       `objconv:"json_name"`
+}
+
+type fieldOptions struct {
+	Packed *bool `objconv:"packed"`
+}
+
+type enumDescriptorProto struct {
+	Name  string                     `objconv:"name"`
+	Value []enumValueDescriptorProto `objconv:"value"`
+}
+
+type enumValueDescriptorProto struct {
+	Name   string `objconv:"name"`
+	Number int32  `objconv:"number"`
+}
+
+var (
+	fileDescriptorSetMessage        = &Message{Name: "google.protobuf.FileDescriptorSet"}
+	fileDescriptorProtoMessage      = &Message{Name: "google.protobuf.FileDescriptorProto"}
+	descriptorProtoMessage          = &Message{Name: "google.protobuf.DescriptorProto"}
+	messageOptionsMessage           = &Message{Name: "google.protobuf.MessageOptions"}
+	fieldDescriptorProtoMessage     = &Message{Name: "google.protobuf.FieldDescriptorProto"}
+	fieldOptionsMessage             = &Message{Name: "google.protobuf.FieldOptions"}
+	enumDescriptorProtoMessage      = &Message{Name: "google.protobuf.EnumDescriptorProto"}
+	enumValueDescriptorProtoMessage = &Message{Name: "google.protobuf.EnumValueDescriptorProto"}
+)
+
+func init() {
+	fields := func(m *Message, fields ...*Field) {
+		for _, f := range fields {
+			f.JSONName = jsonName(f.Name)
+			m.add(f)
+		}
+	}
+
+	fields(fileDescriptorSetMessage,
+		&Field{Name: "file", Number: 1, Kind: MessageKind, Repeated: true, Message: fileDescriptorProtoMessage},
+	)
+
+	fields(fileDescriptorProtoMessage,
+		&Field{Name: "name", Number: 1, Kind: StringKind},
+		&Field{Name: "package", Number: 2, Kind: StringKind},
+		&Field{Name: "message_type", Number: 4, Kind: MessageKind, Repeated: true, Message: descriptorProtoMessage},
+		&Field{Name: "enum_type", Number: 5, Kind: MessageKind, Repeated: true, Message: enumDescriptorProtoMessage},
+		&Field{Name: "syntax", Number: 12, Kind: StringKind},
+	)
+
+	fields(descriptorProtoMessage,
+		&Field{Name: "name", Number: 1, Kind: StringKind},
+		&Field{Name: "field", Number: 2, Kind: MessageKind, Repeated: true, Message: fieldDescriptorProtoMessage},
+		&Field{Name: "nested_type", Number: 3, Kind: MessageKind, Repeated: true, Message: descriptorProtoMessage},
+		&Field{Name: "enum_type", Number: 4, Kind: MessageKind, Repeated: true, Message: enumDescriptorProtoMessage},
+		&Field{Name: "options", Number: 7, Kind: MessageKind, Message: messageOptionsMessage},
+	)
+
+	fields(messageOptionsMessage,
+		&Field{Name: "map_entry", Number: 7, Kind: BoolKind},
+	)
+
+	fields(fieldDescriptorProtoMessage,
+		&Field{Name: "name", Number: 1, Kind: StringKind},
+		&Field{Name: "number", Number: 3, Kind: Int32Kind},
+		&Field{Name: "label", Number: 4, Kind: Int32Kind},
+		&Field{Name: "type", Number: 5, Kind: Int32Kind},
+		&Field{Name: "type_name", Number: 6, Kind: StringKind},
+		&Field{Name: "options", Number: 8, Kind: MessageKind, Message: fieldOptionsMessage},
+		&Field{Name: "json_name", Number: 10, Kind: StringKind},
+	)
+
+	fields(fieldOptionsMessage,
+		&Field{Name: "packed", Number: 2, Kind: BoolKind},
+	)
+
+	fields(enumDescriptorProtoMessage,
+		&Field{Name: "name", Number: 1, Kind: StringKind},
+		&Field{Name: "value", Number: 2, Kind: MessageKind, Repeated: true, Message: enumValueDescriptorProtoMessage},
+	)
+
+	fields(enumValueDescriptorProtoMessage,
+		&Field{Name: "name", Number: 1, Kind: StringKind},
+		&Field{Name: "number", Number: 2, Kind: Int32Kind},
+	)
+}
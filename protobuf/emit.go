@@ -0,0 +1,506 @@
+package protobuf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/segmentio/objconv"
+)
+
+// Emitter implements a Protocol Buffers emitter that satisfies the
+// objconv.Emitter interface.
+//
+// The emitter writes maps or structs as messages of the type given to
+// NewEmitter, their keys are matched against the field names (or JSON names)
+// of the message. Values are converted to the types of the fields when
+// possible, for example integers may be written to fields of any numeric type
+// if they are in range, and strings may be written to enum fields (by name),
+// bytes fields, or numeric fields if they can be parsed as numbers. Time
+// values, durations and errors are written as strings.
+//
+// Nil values are skipped, and unknown field names produce errors.
+type Emitter struct {
+	w   io.Writer
+	msg *Message
+	b   []byte
+	// This stack tracks the messages, repeated fields and map fields being
+	// emitted.
+	stack []frame
+}
+
+type frame struct {
+	typ   int // messageFrame, listFrame or mapFrame
+	msg   *Message
+	field *Field // current field of messages, field of lists and maps
+	key   bool   // next value is a field name or map key
+	start int    // offset of the content of messages, packed lists or map entries
+	tag   int    // offset of the tag of packed lists
+	n     int    // number of elements in packed lists
+}
+
+const (
+	messageFrame = iota
+	listFrame
+	mapFrame
+)
+
+// NewEmitter returns a new emitter which writes messages of type msg to w.
+func NewEmitter(w io.Writer, msg *Message) *Emitter {
+	return &Emitter{w: w, msg: msg}
+}
+
+func (e *Emitter) Reset(w io.Writer) {
+	e.w = w
+	e.b = e.b[:0]
+	e.stack = e.stack[:0]
+}
+
+func (e *Emitter) EmitNil() error {
+	if e.isKey() {
+		return errKey
+	}
+
+	switch f := e.top(); {
+	case f == nil:
+		return errTopLevel
+	case f.typ == listFrame:
+		return fmt.Errorf("objconv/protobuf: repeated field %s cannot contain null values", f.field.Name)
+	}
+
+	return nil
+}
+
+func (e *Emitter) EmitBool(v bool) error {
+	if e.isKey() {
+		return e.emitKey(v, strconv.FormatBool(v))
+	}
+	return e.emit(value{typ: objconv.Bool, b: v})
+}
+
+func (e *Emitter) EmitInt(v int64, _ int) error {
+	if e.isKey() {
+		return e.emitKey(v, strconv.FormatInt(v, 10))
+	}
+	return e.emit(value{typ: objconv.Int, i: v})
+}
+
+func (e *Emitter) EmitUint(v uint64, _ int) error {
+	if e.isKey() {
+		return e.emitKey(v, strconv.FormatUint(v, 10))
+	}
+	return e.emit(value{typ: objconv.Uint, u: v})
+}
+
+func (e *Emitter) EmitFloat(v float64, bitSize int) error {
+	if e.isKey() {
+		return e.emitKey(v, strconv.FormatFloat(v, 'g', -1, bitSize))
+	}
+	return e.emit(value{typ: objconv.Float, f: v})
+}
+
+func (e *Emitter) EmitString(v string) error {
+	if e.isKey() {
+		return e.emitKey(v, v)
+	}
+	return e.emit(value{typ: objconv.String, s: v})
+}
+
+func (e *Emitter) EmitBytes(v []byte) error {
+	if e.isKey() {
+		return errKey
+	}
+	return e.emit(value{typ: objconv.Bytes, s: string(v)})
+}
+
+func (e *Emitter) EmitTime(v time.Time) error {
+	return e.EmitString(v.Format(time.RFC3339Nano))
+}
+
+func (e *Emitter) EmitDuration(v time.Duration) error {
+	return e.EmitString(v.String())
+}
+
+func (e *Emitter) EmitError(v error) error {
+	return e.EmitString(v.Error())
+}
+
+func (e *Emitter) EmitArrayBegin(_ int) error {
+	if e.isKey() {
+		return errKey
+	}
+
+	p := e.top()
+
+	if p == nil {
+		return errTopLevel
+	}
+
+	if p.typ == listFrame {
+		return fmt.Errorf("objconv/protobuf: repeated field %s cannot contain arrays", p.field.Name)
+	}
+
+	f := e.target()
+
+	if !f.Repeated || f.IsMap() {
+		return fmt.Errorf("objconv/protobuf: cannot encode array as field %s of type %s", f.Name, f.Kind)
+	}
+
+	list := frame{typ: listFrame, field: f, tag: len(e.b)}
+
+	if f.Packed {
+		e.b = appendTag(e.b, f.Number, wireBytes)
+		list.start = len(e.b)
+	}
+
+	e.stack = append(e.stack, list)
+	return nil
+}
+
+func (e *Emitter) EmitArrayEnd() error {
+	f := e.pop()
+
+	if f.field.Packed {
+		if f.n == 0 { // omit empty packed fields
+			e.b = e.b[:f.tag]
+		} else {
+			e.b = insertLength(e.b, f.start)
+		}
+	}
+
+	return nil
+}
+
+func (e *Emitter) EmitArrayNext() error {
+	return nil
+}
+
+func (e *Emitter) EmitMapBegin(_ int) error {
+	if e.isKey() {
+		return errKey
+	}
+
+	p := e.top()
+
+	if p == nil {
+		e.stack = append(e.stack, frame{typ: messageFrame, msg: e.msg, key: true})
+		return nil
+	}
+
+	f := e.target()
+
+	if p.typ != listFrame && f.IsMap() {
+		e.stack = append(e.stack, frame{typ: mapFrame, field: f, key: true})
+		return nil
+	}
+
+	if f.Kind != MessageKind {
+		return fmt.Errorf("objconv/protobuf: cannot encode map as field %s of type %s", f.Name, f.Kind)
+	}
+
+	e.b = appendTag(e.b, f.Number, wireBytes)
+	e.stack = append(e.stack, frame{typ: messageFrame, msg: f.Message, key: true, start: len(e.b)})
+	return nil
+}
+
+func (e *Emitter) EmitMapEnd() (err error) {
+	f := e.pop()
+
+	if f.typ == mapFrame && !f.key {
+		e.b = insertLength(e.b, f.start)
+	}
+
+	if f.typ == messageFrame {
+		if len(e.stack) != 0 {
+			e.b = insertLength(e.b, f.start)
+		} else {
+			_, err = e.w.Write(e.b)
+			e.b = e.b[:0]
+		}
+	}
+
+	return
+}
+
+func (e *Emitter) EmitMapValue() error {
+	e.top().key = false
+	return nil
+}
+
+func (e *Emitter) EmitMapNext() error {
+	f := e.top()
+
+	if f.typ == mapFrame {
+		e.b = insertLength(e.b, f.start)
+	}
+
+	f.key = true
+	return nil
+}
+
+// isKey returns true if the next value emitted is a field name or map key.
+func (e *Emitter) isKey() bool {
+	n := len(e.stack)
+	return n != 0 && e.stack[n-1].key
+}
+
+func (e *Emitter) top() *frame {
+	if n := len(e.stack); n != 0 {
+		return &e.stack[n-1]
+	}
+	return nil
+}
+
+func (e *Emitter) pop() frame {
+	i := len(e.stack) - 1
+	f := e.stack[i]
+	e.stack = e.stack[:i]
+	return f
+}
+
+// target returns the field that the next value is written to.
+func (e *Emitter) target() *Field {
+	switch f := e.top(); f.typ {
+	case mapFrame:
+		return f.field.mapValue()
+	default:
+		return f.field
+	}
+}
+
+// emitKey handles map keys, k is the value of the key and s its string
+// representation.
+func (e *Emitter) emitKey(k interface{}, s string) error {
+	f := e.top()
+
+	if f.typ == messageFrame {
+		if f.field = f.msg.field(s); f.field == nil {
+			return fmt.Errorf("objconv/protobuf: message %s has no field named %q", f.msg.Name, s)
+		}
+		return nil
+	}
+
+	// Map entries are written as messages with the key as field 1 and the
+	// value as field 2.
+	e.b = appendTag(e.b, f.field.Number, wireBytes)
+	f.start = len(e.b)
+
+	v := value{s: s}
+
+	switch x := k.(type) {
+	case bool:
+		v.typ, v.b = objconv.Bool, x
+	case int64:
+		v.typ, v.i = objconv.Int, x
+	case uint64:
+		v.typ, v.u = objconv.Uint, x
+	default:
+		v.typ = objconv.String
+	}
+
+	var err error
+	e.b, err = appendField(e.b, f.field.mapKey(), v, true)
+	return err
+}
+
+// emit writes a scalar value to the current field.
+func (e *Emitter) emit(v value) (err error) {
+	f := e.top()
+
+	if f == nil {
+		return errTopLevel
+	}
+
+	if f.typ == listFrame && f.field.Packed {
+		f.n++
+		e.b, err = appendField(e.b, f.field, v, false)
+		return
+	}
+
+	field := e.target()
+
+	if field.IsMap() && f.typ == messageFrame {
+		return fmt.Errorf("objconv/protobuf: cannot encode %s value as map field %s", v.typ, field.Name)
+	}
+
+	e.b, err = appendField(e.b, field, v, true)
+	return
+}
+
+// value holds scalar values passed to appendField.
+type value struct {
+	typ objconv.Type
+	b   bool
+	i   int64
+	u   uint64
+	f   float64
+	s   string // string or bytes
+}
+
+// appendField appends the encoding of v as a value of field f, prefixed with
+// the field tag if tag is true.
+func appendField(b []byte, f *Field, v value, tag bool) ([]byte, error) {
+	if tag {
+		b = appendTag(b, f.Number, f.Kind.wireType())
+	}
+
+	switch f.Kind {
+	case BoolKind:
+		switch v.typ {
+		case objconv.Bool:
+		case objconv.Int, objconv.Uint:
+			v.b = v.i != 0 || v.u != 0
+		case objconv.String:
+			x, err := strconv.ParseBool(v.s)
+			if err != nil {
+				return nil, conversionError(f, v)
+			}
+			v.b = x
+		default:
+			return nil, conversionError(f, v)
+		}
+		if v.b {
+			return append(b, 1), nil
+		}
+		return append(b, 0), nil
+
+	case Int32Kind, Int64Kind, Sint32Kind, Sint64Kind, Sfixed32Kind, Sfixed64Kind:
+		i, ok := toInt(v)
+		if !ok || (f.Kind.is32() && (i < math.MinInt32 || i > math.MaxInt32)) {
+			return nil, conversionError(f, v)
+		}
+		switch f.Kind {
+		case Sint32Kind, Sint64Kind:
+			return binary.AppendUvarint(b, uint64(i<<1)^uint64(i>>63)), nil
+		case Sfixed32Kind:
+			return binary.LittleEndian.AppendUint32(b, uint32(i)), nil
+		case Sfixed64Kind:
+			return binary.LittleEndian.AppendUint64(b, uint64(i)), nil
+		default:
+			return binary.AppendUvarint(b, uint64(i)), nil
+		}
+
+	case Uint32Kind, Uint64Kind, Fixed32Kind, Fixed64Kind:
+		u, ok := toUint(v)
+		if !ok || (f.Kind.is32() && u > math.MaxUint32) {
+			return nil, conversionError(f, v)
+		}
+		switch f.Kind {
+		case Fixed32Kind:
+			return binary.LittleEndian.AppendUint32(b, uint32(u)), nil
+		case Fixed64Kind:
+			return binary.LittleEndian.AppendUint64(b, u), nil
+		default:
+			return binary.AppendUvarint(b, u), nil
+		}
+
+	case FloatKind, DoubleKind:
+		x, ok := toFloat(v)
+		if !ok {
+			return nil, conversionError(f, v)
+		}
+		if f.Kind == FloatKind {
+			return binary.LittleEndian.AppendUint32(b, math.Float32bits(float32(x))), nil
+		}
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(x)), nil
+
+	case StringKind:
+		if v.typ != objconv.String && !(v.typ == objconv.Bytes && utf8.ValidString(v.s)) {
+			return nil, conversionError(f, v)
+		}
+		return append(binary.AppendUvarint(b, uint64(len(v.s))), v.s...), nil
+
+	case BytesKind:
+		if v.typ != objconv.String && v.typ != objconv.Bytes {
+			return nil, conversionError(f, v)
+		}
+		return append(binary.AppendUvarint(b, uint64(len(v.s))), v.s...), nil
+
+	case EnumKind:
+		if v.typ == objconv.String {
+			if n, ok := f.Enum.numbers[v.s]; ok {
+				return binary.AppendUvarint(b, uint64(int64(n))), nil
+			}
+		}
+		i, ok := toInt(v)
+		if !ok || i < math.MinInt32 || i > math.MaxInt32 {
+			return nil, conversionError(f, v)
+		}
+		return binary.AppendUvarint(b, uint64(i)), nil
+	}
+
+	return nil, conversionError(f, v)
+}
+
+func (k Kind) is32() bool {
+	switch k {
+	case Int32Kind, Sint32Kind, Sfixed32Kind, Uint32Kind, Fixed32Kind:
+		return true
+	}
+	return false
+}
+
+func toInt(v value) (int64, bool) {
+	switch v.typ {
+	case objconv.Int:
+		return v.i, true
+	case objconv.Uint:
+		return int64(v.u), v.u <= math.MaxInt64
+	case objconv.Float:
+		return int64(v.f), v.f == math.Trunc(v.f) && v.f >= math.MinInt64 && v.f < math.MaxInt64
+	case objconv.String:
+		i, err := strconv.ParseInt(v.s, 10, 64)
+		return i, err == nil
+	}
+	return 0, false
+}
+
+func toUint(v value) (uint64, bool) {
+	switch v.typ {
+	case objconv.Int:
+		return uint64(v.i), v.i >= 0
+	case objconv.Uint:
+		return v.u, true
+	case objconv.Float:
+		return uint64(v.f), v.f == math.Trunc(v.f) && v.f >= 0 && v.f < math.MaxUint64
+	case objconv.String:
+		u, err := strconv.ParseUint(v.s, 10, 64)
+		return u, err == nil
+	}
+	return 0, false
+}
+
+func toFloat(v value) (float64, bool) {
+	switch v.typ {
+	case objconv.Int:
+		return float64(v.i), true
+	case objconv.Uint:
+		return float64(v.u), true
+	case objconv.Float:
+		return v.f, true
+	case objconv.String:
+		switch v.s {
+		case "Infinity":
+			return math.Inf(1), true
+		case "-Infinity":
+			return math.Inf(-1), true
+		}
+		f, err := strconv.ParseFloat(v.s, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func conversionError(f *Field, v value) error {
+	return fmt.Errorf("objconv/protobuf: cannot encode %s value as field %s of type %s", v.typ, f.Name, f.Kind)
+}
+
+var (
+	errKey      = errors.New("objconv/protobuf: map keys must be strings or scalar values")
+	errTopLevel = errors.New("objconv/protobuf: top-level values must be messages")
+)
@@ -0,0 +1,26 @@
+package protobuf
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/segmentio/objconv"
+)
+
+// NewEncoder returns a new Protocol Buffers encoder that writes messages of
+// type msg to w.
+func NewEncoder(w io.Writer, msg *Message) *objconv.Encoder {
+	return objconv.NewEncoder(NewEmitter(w, msg))
+}
+
+// Marshal writes the Protocol Buffers representation of v, as a message of
+// type msg, to a byte slice returned in b.
+func Marshal(v interface{}, msg *Message) (b []byte, err error) {
+	buf := &bytes.Buffer{}
+
+	if err = NewEncoder(buf, msg).Encode(v); err == nil {
+		b = buf.Bytes()
+	}
+
+	return
+}
@@ -0,0 +1,542 @@
+package protobuf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"time"
+
+	"github.com/segmentio/objconv"
+)
+
+// Parser implements a Protocol Buffers parser that satisfies the
+// objconv.Parser interface.
+//
+// The parser loads the whole input in memory and decodes it as a single
+// message of the type given to NewParser, producing a map keyed by field name
+// where fields appear in declaration order. Values are mapped to objconv types
+// with these rules:
+//
+//   - int32, int64, sint32, sint64, sfixed32 and sfixed64 are parsed as signed
+//     integers, uint32, uint64, fixed32 and fixed64 as unsigned integers
+//   - enums are parsed as the names of their values, or as integers for values
+//     that aren't declared
+//   - map fields are parsed as maps, other repeated fields as arrays
+//   - fields that are absent from the input are omitted
+//
+// Unknown fields are skipped.
+type Parser struct {
+	r      io.Reader // reader to load bytes from
+	msg    *Message
+	s      []byte // string buffer
+	loaded bool
+	// This stack is used to iterate over the message loaded in memory.
+	stack []parser
+}
+
+// NewParser returns a new parser which reads a message of type msg from r.
+func NewParser(r io.Reader, msg *Message) *Parser {
+	return &Parser{r: r, msg: msg}
+}
+
+func (p *Parser) Reset(r io.Reader) {
+	p.r = r
+	p.loaded = false
+	p.stack = p.stack[:0]
+}
+
+func (p *Parser) Buffered() io.Reader {
+	return bytes.NewReader(nil)
+}
+
+func (p *Parser) ParseType() (typ objconv.Type, err error) {
+	if !p.loaded {
+		var b []byte
+		var m *object
+
+		if b, err = ioutil.ReadAll(p.r); err != nil {
+			return
+		}
+		if m, err = readMessage(b, p.msg); err != nil {
+			return
+		}
+
+		p.loaded = true
+		p.push(newParser(m))
+	}
+
+	if len(p.stack) == 0 {
+		err = io.EOF
+		return
+	}
+
+	switch v := p.top().value(); v.(type) {
+	case bool:
+		typ = objconv.Bool
+
+	case int64:
+		typ = objconv.Int
+
+	case uint64:
+		typ = objconv.Uint
+
+	case float64:
+		typ = objconv.Float
+
+	case string:
+		typ = objconv.String
+
+	case []byte:
+		typ = objconv.Bytes
+
+	case *object:
+		typ = objconv.Map
+
+	case []interface{}:
+		typ = objconv.Array
+
+	default:
+		err = fmt.Errorf("objconv/protobuf: unsupported value of type %T", v)
+	}
+
+	return
+}
+
+func (p *Parser) ParseNil() (err error) {
+	panic("objconv/protobuf: ParseNil should never be called because Protocol Buffers have no nil type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseBool() (v bool, err error) {
+	v = p.pop().value().(bool)
+	return
+}
+
+func (p *Parser) ParseInt() (v int64, err error) {
+	v = p.pop().value().(int64)
+	return
+}
+
+func (p *Parser) ParseUint() (v uint64, err error) {
+	v = p.pop().value().(uint64)
+	return
+}
+
+func (p *Parser) ParseFloat() (v float64, err error) {
+	v = p.pop().value().(float64)
+	return
+}
+
+func (p *Parser) ParseString() (v []byte, err error) {
+	v = append(p.s[:0], p.pop().value().(string)...)
+	p.s = v
+	return
+}
+
+func (p *Parser) ParseBytes() (v []byte, err error) {
+	v = p.pop().value().([]byte)
+	return
+}
+
+func (p *Parser) ParseTime() (v time.Time, err error) {
+	panic("objconv/protobuf: ParseTime should never be called because Protocol Buffers have no time type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseDuration() (v time.Duration, err error) {
+	panic("objconv/protobuf: ParseDuration should never be called because Protocol Buffers have no duration type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseError() (v error, err error) {
+	panic("objconv/protobuf: ParseError should never be called because Protocol Buffers have no error type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseArrayBegin() (n int, err error) {
+	if n = p.top().len(); n != 0 {
+		p.push(newParser(p.top().next()))
+	}
+	return
+}
+
+func (p *Parser) ParseArrayEnd(n int) (err error) {
+	p.pop()
+	return
+}
+
+func (p *Parser) ParseArrayNext(n int) (err error) {
+	p.push(newParser(p.top().next()))
+	return
+}
+
+func (p *Parser) ParseMapBegin() (n int, err error) {
+	if n = p.top().len(); n != 0 {
+		p.push(newParser(p.top().next()))
+	}
+	return
+}
+
+func (p *Parser) ParseMapEnd(n int) (err error) {
+	p.pop()
+	return
+}
+
+func (p *Parser) ParseMapValue(n int) (err error) {
+	p.push(newParser(p.top().next()))
+	return
+}
+
+func (p *Parser) ParseMapNext(n int) (err error) {
+	p.push(newParser(p.top().next()))
+	return
+}
+
+func (p *Parser) push(v parser) {
+	p.stack = append(p.stack, v)
+}
+
+func (p *Parser) pop() parser {
+	i := len(p.stack) - 1
+	v := p.stack[i]
+	p.stack = p.stack[:i]
+	return v
+}
+
+func (p *Parser) top() parser {
+	return p.stack[len(p.stack)-1]
+}
+
+// object is the in-memory representation of decoded messages and map fields.
+// Fields of messages are ordered by declaration, entries of maps are kept in
+// the order they were found in.
+type object struct {
+	keys   []interface{}
+	values []interface{}
+	index  map[interface{}]int // only used by maps
+}
+
+func (m *object) set(k interface{}, v interface{}) {
+	if i, ok := m.index[k]; ok {
+		m.values[i] = v // the last entry of a key wins
+		return
+	}
+	m.index[k] = len(m.keys)
+	m.keys = append(m.keys, k)
+	m.values = append(m.values, v)
+}
+
+// readMessage decodes b as a message of type msg.
+func readMessage(b []byte, msg *Message) (*object, error) {
+	r := wireReader{b: b}
+	values := make([]interface{}, len(msg.Fields))
+	index := make(map[*Field]int, len(msg.Fields))
+
+	for i, f := range msg.Fields {
+		index[f] = i
+	}
+
+	for r.i != len(r.b) {
+		tag, err := r.readVarint()
+		if err != nil {
+			return nil, err
+		}
+
+		number, wire := int(tag>>3), int(tag&7)
+		f := msg.byNumber[number]
+
+		if number == 0 {
+			return nil, fmt.Errorf("objconv/protobuf: invalid field number 0 in message %s", msg.Name)
+		}
+
+		if f == nil || f.Kind == GroupKind {
+			if err = r.skip(number, wire); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		i := index[f]
+
+		switch {
+		case f.IsMap():
+			b, err := r.readBytesOf(f, wire)
+			if err != nil {
+				return nil, err
+			}
+
+			entry, err := readMapEntry(b, f)
+			if err != nil {
+				return nil, err
+			}
+
+			m, _ := values[i].(*object)
+			if m == nil {
+				m = &object{index: make(map[interface{}]int)}
+				values[i] = m
+			}
+
+			m.set(entry[0], entry[1])
+
+		case f.Repeated:
+			a, _ := values[i].([]interface{})
+
+			if wire == wireBytes && f.Kind.wireType() != wireBytes { // packed
+				b, err := r.readBytes()
+				if err != nil {
+					return nil, err
+				}
+
+				for s := (wireReader{b: b}); s.i != len(s.b); {
+					v, err := s.readValue(f, f.Kind.wireType())
+					if err != nil {
+						return nil, err
+					}
+					a = append(a, v)
+				}
+			} else {
+				v, err := r.readValue(f, wire)
+				if err != nil {
+					return nil, err
+				}
+				a = append(a, v)
+			}
+
+			values[i] = a
+
+		default:
+			v, err := r.readValue(f, wire)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+	}
+
+	m := &object{}
+
+	for i, f := range msg.Fields {
+		if values[i] != nil {
+			m.keys = append(m.keys, f.Name)
+			m.values = append(m.values, values[i])
+		}
+	}
+
+	return m, nil
+}
+
+// readMapEntry decodes b as an entry of the map field f, returning the key and
+// value. Missing keys or values are set to their zero value.
+func readMapEntry(b []byte, f *Field) (entry [2]interface{}, err error) {
+	r := wireReader{b: b}
+
+	for r.i != len(r.b) {
+		var tag uint64
+
+		if tag, err = r.readVarint(); err != nil {
+			return
+		}
+
+		number, wire := int(tag>>3), int(tag&7)
+
+		if number != 1 && number != 2 {
+			if err = r.skip(number, wire); err != nil {
+				return
+			}
+			continue
+		}
+
+		if entry[number-1], err = r.readValue(f.Message.byNumber[number], wire); err != nil {
+			return
+		}
+	}
+
+	for i, x := range [...]*Field{f.mapKey(), f.mapValue()} {
+		if entry[i] == nil {
+			entry[i] = zeroValue(x)
+		}
+	}
+
+	return
+}
+
+// readValue reads a value of field f, which was written with the given wire
+// type.
+func (r *wireReader) readValue(f *Field, wire int) (v interface{}, err error) {
+	if wire != f.Kind.wireType() {
+		return nil, fmt.Errorf("objconv/protobuf: field %s of type %s has an invalid wire type %d", f.Name, f.Kind, wire)
+	}
+
+	var u uint64
+
+	switch wire {
+	case wireVarint:
+		u, err = r.readVarint()
+	case wireFixed64:
+		u, err = r.readFixed64()
+	case wireFixed32:
+		var x uint32
+		x, err = r.readFixed32()
+		u = uint64(x)
+	default:
+		var b []byte
+
+		if b, err = r.readBytes(); err != nil {
+			return
+		}
+
+		switch f.Kind {
+		case StringKind:
+			v = string(b)
+		case BytesKind:
+			v = append([]byte{}, b...)
+		default:
+			v, err = readMessage(b, f.Message)
+		}
+
+		return
+	}
+
+	if err != nil {
+		return
+	}
+
+	switch f.Kind {
+	case BoolKind:
+		v = u != 0
+	case Int32Kind, Sfixed32Kind:
+		v = int64(int32(u))
+	case Int64Kind, Sfixed64Kind:
+		v = int64(u)
+	case Uint32Kind, Fixed32Kind:
+		v = uint64(uint32(u))
+	case Uint64Kind, Fixed64Kind:
+		v = u
+	case Sint32Kind:
+		v = int64(int32(uint32(u)>>1) ^ -int32(u&1))
+	case Sint64Kind:
+		v = int64(u>>1) ^ -int64(u&1)
+	case FloatKind:
+		v = float64(math.Float32frombits(uint32(u)))
+	case DoubleKind:
+		v = math.Float64frombits(u)
+	case EnumKind:
+		n := int32(u)
+		if name, ok := f.Enum.names[n]; ok {
+			v = name
+		} else {
+			v = int64(n)
+		}
+	}
+
+	return
+}
+
+func (r *wireReader) readBytesOf(f *Field, wire int) ([]byte, error) {
+	if wire != wireBytes {
+		return nil, fmt.Errorf("objconv/protobuf: field %s of type %s has an invalid wire type %d", f.Name, f.Kind, wire)
+	}
+	return r.readBytes()
+}
+
+func zeroValue(f *Field) interface{} {
+	switch f.Kind {
+	case BoolKind:
+		return false
+	case Int32Kind, Int64Kind, Sint32Kind, Sint64Kind, Sfixed32Kind, Sfixed64Kind:
+		return int64(0)
+	case Uint32Kind, Uint64Kind, Fixed32Kind, Fixed64Kind:
+		return uint64(0)
+	case FloatKind, DoubleKind:
+		return 0.0
+	case StringKind:
+		return ""
+	case BytesKind:
+		return []byte{}
+	case EnumKind:
+		if name, ok := f.Enum.names[0]; ok {
+			return name
+		}
+		return int64(0)
+	default:
+		return &object{}
+	}
+}
+
+type parser interface {
+	value() interface{}
+	next() interface{}
+	len() int
+}
+
+type valueParser struct {
+	self interface{}
+}
+
+func (p *valueParser) value() interface{} {
+	return p.self
+}
+
+func (p *valueParser) next() interface{} {
+	panic("objconv/protobuf: invalid call of next method on simple value parser")
+}
+
+func (p *valueParser) len() int {
+	panic("objconv/protobuf: invalid call of len method on simple value parser")
+}
+
+type arrayParser struct {
+	self []interface{}
+	off  int
+}
+
+func (p *arrayParser) value() interface{} {
+	return p.self
+}
+
+func (p *arrayParser) next() interface{} {
+	v := p.self[p.off]
+	p.off++
+	return v
+}
+
+func (p *arrayParser) len() int {
+	return len(p.self)
+}
+
+type mapParser struct {
+	self *object
+	off  int
+	val  bool
+}
+
+func (p *mapParser) value() interface{} {
+	return p.self
+}
+
+func (p *mapParser) next() (v interface{}) {
+	if p.val {
+		v = p.self.values[p.off]
+		p.val = false
+		p.off++
+	} else {
+		v = p.self.keys[p.off]
+		p.val = true
+	}
+	return
+}
+
+func (p *mapParser) len() int {
+	return len(p.self.keys)
+}
+
+func newParser(v interface{}) parser {
+	switch x := v.(type) {
+	case *object:
+		return &mapParser{self: x}
+
+	case []interface{}:
+		return &arrayParser{self: x}
+
+	default:
+		return &valueParser{self: x}
+	}
+}
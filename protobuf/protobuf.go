@@ -0,0 +1,145 @@
+// Package protobuf provides an implementation of the Protocol Buffers wire
+// format for objconv.
+//
+// Protocol Buffers messages don't carry field names or types, emitters and
+// parsers are bound to a message descriptor loaded from a FileDescriptorSet
+// (as produced by protoc --descriptor_set_out) which maps field numbers to
+// names and types. No generated code is required, messages are decoded to and
+// encoded from plain Go structs and maps.
+//
+// Because they can't be constructed without a descriptor, the codecs of this
+// package aren't registered in the objconv registry, NewCodec can be used to
+// create a codec for a message type.
+package protobuf
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Wire types of the Protocol Buffers encoding.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireStart   = 3 // start of a group, deprecated
+	wireEnd     = 4 // end of a group, deprecated
+	wireFixed32 = 5
+)
+
+func appendTag(b []byte, number int, wire int) []byte {
+	return binary.AppendUvarint(b, uint64(number)<<3|uint64(wire))
+}
+
+// insertLength inserts the varint encoding of the length of b[i:] at offset
+// i.
+func insertLength(b []byte, i int) []byte {
+	var a [binary.MaxVarintLen64]byte
+	h := binary.AppendUvarint(a[:0], uint64(len(b)-i))
+	n := len(b)
+	b = append(b, h...)
+	copy(b[i+len(h):], b[i:n])
+	copy(b[i:], h)
+	return b
+}
+
+// wireReader reads the fields of a message from a buffer.
+type wireReader struct {
+	b []byte
+	i int
+}
+
+func (r *wireReader) readVarint() (uint64, error) {
+	v, n := binary.Uvarint(r.b[r.i:])
+	if n <= 0 {
+		if n == 0 {
+			return 0, errTruncated
+		}
+		return 0, errVarintOverflow
+	}
+	r.i += n
+	return v, nil
+}
+
+func (r *wireReader) readFixed32() (uint32, error) {
+	if len(r.b)-r.i < 4 {
+		return 0, errTruncated
+	}
+	v := binary.LittleEndian.Uint32(r.b[r.i:])
+	r.i += 4
+	return v, nil
+}
+
+func (r *wireReader) readFixed64() (uint64, error) {
+	if len(r.b)-r.i < 8 {
+		return 0, errTruncated
+	}
+	v := binary.LittleEndian.Uint64(r.b[r.i:])
+	r.i += 8
+	return v, nil
+}
+
+func (r *wireReader) readBytes() ([]byte, error) {
+	n, err := r.readVarint()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(r.b)-r.i) {
+		return nil, errTruncated
+	}
+	b := r.b[r.i : r.i+int(n)]
+	r.i += int(n)
+	return b, nil
+}
+
+// skip skips the value of a field of the given wire type and number.
+func (r *wireReader) skip(number int, wire int) (err error) {
+	switch wire {
+	case wireVarint:
+		_, err = r.readVarint()
+
+	case wireFixed64:
+		_, err = r.readFixed64()
+
+	case wireFixed32:
+		_, err = r.readFixed32()
+
+	case wireBytes:
+		_, err = r.readBytes()
+
+	case wireStart:
+		for {
+			var tag uint64
+
+			if r.i == len(r.b) {
+				return errTruncated
+			}
+
+			if tag, err = r.readVarint(); err != nil {
+				return
+			}
+
+			if int(tag&7) == wireEnd {
+				if int(tag>>3) != number {
+					return errors.New("objconv/protobuf: mismatched end of group")
+				}
+				return
+			}
+
+			if err = r.skip(int(tag>>3), int(tag&7)); err != nil {
+				return
+			}
+		}
+
+	default:
+		err = errWireType
+	}
+
+	return
+}
+
+var (
+	errTruncated      = errors.New("objconv/protobuf: truncated message")
+	errVarintOverflow = errors.New("objconv/protobuf: varint overflows 64 bits")
+	errWireType       = errors.New("objconv/protobuf: invalid wire type")
+)
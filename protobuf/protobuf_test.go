@@ -0,0 +1,388 @@
+package protobuf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/segmentio/objconv"
+)
+
+// testDescriptorSet is the descriptor set of this file, as protoc would
+// produce it:
+//
+//	syntax = "proto3";
+//	package test;
+//
+//	message Person {
+//	  enum Kind { UNKNOWN = 0; ADMIN = 1; }
+//	  message Address { string city = 1; }
+//
+//	  string name = 1;
+//	  int32 id = 2;
+//	  repeated string emails = 3;
+//	  Kind kind = 4;
+//	  map<string, int64> scores = 5;
+//	  repeated int32 lucky_numbers = 6;
+//	  Address address = 7;
+//	  repeated Address past_addresses = 8;
+//	  sint64 delta = 9;
+//	  fixed64 hash = 10;
+//	  double ratio = 11;
+//	  bytes blob = 12;
+//	  bool active = 13;
+//	  repeated uint32 flags = 14 [packed = false];
+//	}
+var testDescriptorSet = fileDescriptorSet{
+	File: []fileDescriptorProto{{
+		Name:    "test.proto",
+		Package: "test",
+		Syntax:  "proto3",
+		MessageType: []descriptorProto{{
+			Name: "Person",
+			Field: []fieldDescriptorProto{
+				{Name: "name", Number: 1, Label: 1, Type: int32(StringKind), JSONName: "name"},
+				{Name: "id", Number: 2, Label: 1, Type: int32(Int32Kind), JSONName: "id"},
+				{Name: "emails", Number: 3, Label: 3, Type: int32(StringKind), JSONName: "emails"},
+				{Name: "kind", Number: 4, Label: 1, Type: int32(EnumKind), TypeName: ".test.Person.Kind", JSONName: "kind"},
+				{Name: "scores", Number: 5, Label: 3, Type: int32(MessageKind), TypeName: ".test.Person.ScoresEntry", JSONName: "scores"},
+				{Name: "lucky_numbers", Number: 6, Label: 3, Type: int32(Int32Kind), JSONName: "luckyNumbers"},
+				{Name: "address", Number: 7, Label: 1, Type: int32(MessageKind), TypeName: ".test.Person.Address", JSONName: "address"},
+				{Name: "past_addresses", Number: 8, Label: 3, Type: int32(MessageKind), TypeName: ".test.Person.Address", JSONName: "pastAddresses"},
+				{Name: "delta", Number: 9, Label: 1, Type: int32(Sint64Kind), JSONName: "delta"},
+				{Name: "hash", Number: 10, Label: 1, Type: int32(Fixed64Kind), JSONName: "hash"},
+				{Name: "ratio", Number: 11, Label: 1, Type: int32(DoubleKind), JSONName: "ratio"},
+				{Name: "blob", Number: 12, Label: 1, Type: int32(BytesKind), JSONName: "blob"},
+				{Name: "active", Number: 13, Label: 1, Type: int32(BoolKind), JSONName: "active"},
+				{Name: "flags", Number: 14, Label: 3, Type: int32(Uint32Kind), JSONName: "flags", Options: fieldOptions{Packed: new(bool)}},
+			},
+			NestedType: []descriptorProto{
+				{
+					Name: "Address",
+					Field: []fieldDescriptorProto{
+						{Name: "city", Number: 1, Label: 1, Type: int32(StringKind), JSONName: "city"},
+					},
+				},
+				{
+					Name: "ScoresEntry",
+					Field: []fieldDescriptorProto{
+						{Name: "key", Number: 1, Label: 1, Type: int32(StringKind), JSONName: "key"},
+						{Name: "value", Number: 2, Label: 1, Type: int32(Int64Kind), JSONName: "value"},
+					},
+					Options: messageOptions{MapEntry: true},
+				},
+			},
+			EnumType: []enumDescriptorProto{{
+				Name: "Kind",
+				Value: []enumValueDescriptorProto{
+					{Name: "UNKNOWN", Number: 0},
+					{Name: "ADMIN", Number: 1},
+				},
+			}},
+		}},
+	}},
+}
+
+type address struct {
+	City string `objconv:"city"`
+}
+
+type person struct {
+	Name          string           `objconv:"name"`
+	ID            int32            `objconv:"id"`
+	Emails        []string         `objconv:"emails"`
+	Kind          string           `objconv:"kind"`
+	Scores        map[string]int64 `objconv:"scores"`
+	LuckyNumbers  []int32          `objconv:"lucky_numbers"`
+	Address       *address         `objconv:"address"`
+	PastAddresses []address        `objconv:"past_addresses"`
+	Delta         int64            `objconv:"delta"`
+	Hash          uint64           `objconv:"hash"`
+	Ratio         float64          `objconv:"ratio"`
+	Blob          []byte           `objconv:"blob"`
+	Active        bool             `objconv:"active"`
+	Flags         []uint32         `objconv:"flags"`
+}
+
+func loadTestMessage(t *testing.T) *Message {
+	b, err := Marshal(testDescriptorSet, fileDescriptorSetMessage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	set, err := ParseDescriptorSet(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := set.Message("test.Person")
+	if msg == nil {
+		t.Fatal("message test.Person not found")
+	}
+
+	return msg
+}
+
+func TestDescriptorSet(t *testing.T) {
+	msg := loadTestMessage(t)
+
+	if len(msg.Fields) != 14 {
+		t.Fatalf("bad number of fields: %d", len(msg.Fields))
+	}
+
+	tests := []struct {
+		name   string
+		kind   Kind
+		packed bool
+		isMap  bool
+	}{
+		{"kind", EnumKind, false, false},
+		{"scores", MessageKind, false, true},
+		{"lucky_numbers", Int32Kind, true, false},
+		{"past_addresses", MessageKind, false, false},
+		{"flags", Uint32Kind, false, false},
+	}
+
+	for _, test := range tests {
+		f := msg.field(test.name)
+		if f == nil {
+			t.Errorf("field %s not found", test.name)
+			continue
+		}
+		if f.Kind != test.kind || f.Packed != test.packed || f.IsMap() != test.isMap {
+			t.Errorf("field %s: bad descriptor: %+v", test.name, f)
+		}
+	}
+
+	if msg.field("luckyNumbers") != msg.field("lucky_numbers") {
+		t.Error("fields are not indexed by JSON name")
+	}
+}
+
+func TestLoadDescriptorSet(t *testing.T) {
+	b, err := Marshal(testDescriptorSet, fileDescriptorSetMessage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "objconv-protobuf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.pb")
+
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	set, err := LoadDescriptorSet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if set.Message(".test.Person.Address") == nil || set.Enum("test.Person.Kind") == nil {
+		t.Error("nested types not found")
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	msg := loadTestMessage(t)
+
+	tests := []struct {
+		v interface{}
+		b []byte
+	}{
+		{map[string]interface{}{}, []byte{}},
+		{map[string]interface{}{"id": 150}, []byte{0x10, 0x96, 0x01}},
+		{map[string]interface{}{"name": "testing"}, []byte{0x0A, 0x07, 't', 'e', 's', 't', 'i', 'n', 'g'}},
+		{map[string]interface{}{"id": -1}, []byte{0x10, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x01}},
+		{map[string]interface{}{"kind": "ADMIN"}, []byte{0x20, 0x01}},
+		{map[string]interface{}{"luckyNumbers": []int{3, 270}}, []byte{0x32, 0x03, 0x03, 0x8E, 0x02}},
+		{map[string]interface{}{"lucky_numbers": []int{}}, []byte{}},
+		{map[string]interface{}{"flags": []int{1, 2}}, []byte{0x70, 0x01, 0x70, 0x02}},
+		{map[string]interface{}{"delta": -2}, []byte{0x48, 0x03}},
+		{map[string]interface{}{"hash": "1"}, []byte{0x51, 1, 0, 0, 0, 0, 0, 0, 0}},
+		{map[string]interface{}{"address": map[string]string{"city": "a"}}, []byte{0x3A, 0x03, 0x0A, 0x01, 'a'}},
+		{map[string]interface{}{"scores": map[string]int{"a": 1}}, []byte{0x2A, 0x05, 0x0A, 0x01, 'a', 0x10, 0x01}},
+		{map[string]interface{}{"name": nil, "active": true}, []byte{0x68, 0x01}},
+	}
+
+	for _, test := range tests {
+		b, err := Marshal(test.v, msg)
+		if err != nil {
+			t.Errorf("%#v: %s", test.v, err)
+			continue
+		}
+		if !bytes.Equal(b, test.b) {
+			t.Errorf("%#v: % x != % x", test.v, test.b, b)
+		}
+	}
+}
+
+func TestMarshalError(t *testing.T) {
+	msg := loadTestMessage(t)
+
+	for _, v := range []interface{}{
+		42,
+		[]int{1},
+		map[string]interface{}{"unknown": 1},
+		map[string]interface{}{"id": int64(math.MaxInt32) + 1},
+		map[string]interface{}{"id": "abc"},
+		map[string]interface{}{"hash": -1},
+		map[string]interface{}{"kind": "NOPE"},
+		map[string]interface{}{"name": 1},
+		map[string]interface{}{"address": "a"},
+		map[string]interface{}{"scores": []int{1}},
+		map[string]interface{}{"emails": [][]string{{"a"}}},
+		map[string]interface{}{"emails": []interface{}{nil}},
+	} {
+		if _, err := Marshal(v, msg); err == nil {
+			t.Errorf("%#v: expected an error", v)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	msg := loadTestMessage(t)
+
+	b := []byte{
+		0x10, 0x2A, // id: 42
+		0x0A, 0x01, 'a', // name: "a"
+		0x20, 0x07, // kind: 7 (undeclared)
+		0x30, 0x01, 0x30, 0x02, // lucky_numbers: unpacked
+		0x72, 0x02, 0x03, 0x04, // flags: packed
+		0x98, 0x06, 0x01, // unknown varint field 99
+		0xA3, 0x06, 0x08, 0x01, 0xA4, 0x06, // unknown group 100
+		0x2A, 0x05, 0x0A, 0x01, 'a', 0x10, 0x01, // scores: {a: 1}
+		0x2A, 0x03, 0x0A, 0x01, 'b', // scores: {b: 0}
+		0x2A, 0x05, 0x0A, 0x01, 'a', 0x10, 0x02, // scores: {a: 2}
+	}
+
+	var v map[string]interface{}
+
+	if err := Unmarshal(b, msg, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := map[string]interface{}{
+		"name":          "a",
+		"id":            int64(42),
+		"kind":          int64(7),
+		"scores":        map[interface{}]interface{}{"a": int64(2), "b": int64(0)},
+		"lucky_numbers": []interface{}{int64(1), int64(2)},
+		"flags":         []interface{}{uint64(3), uint64(4)},
+	}
+
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("%#v != %#v", expect, v)
+	}
+}
+
+func TestUnmarshalFieldOrder(t *testing.T) {
+	msg := loadTestMessage(t)
+
+	// Fields are written in reverse order, but parsed in declaration order.
+	p := NewParser(bytes.NewReader([]byte{0x68, 0x01, 0x10, 0x01, 0x0A, 0x00}), msg)
+	d := objconv.NewDecoder(p)
+
+	var keys []string
+
+	err := d.DecodeMap(func(k objconv.Decoder, v objconv.Decoder) error {
+		var key string
+		if err := k.Decode(&key); err != nil {
+			return err
+		}
+		keys = append(keys, key)
+		return v.Decode(nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(keys, []string{"name", "id", "active"}) {
+		t.Errorf("bad key order: %v", keys)
+	}
+}
+
+func TestUnmarshalError(t *testing.T) {
+	msg := loadTestMessage(t)
+
+	for _, b := range [][]byte{
+		{0x10},                   // truncated varint
+		{0x0A, 0x05, 'a'},        // truncated string
+		{0x15, 0x01, 0x00, 0x00}, // id written as fixed32
+		{0x00, 0x01},             // field number 0
+		{0xA3, 0x06, 0x08, 0x01}, // unterminated group
+		{0x3A, 0x02, 0x0A, 0x05}, // truncated nested message
+		{0x10, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x01},
+	} {
+		var v interface{}
+
+		if err := Unmarshal(b, msg, &v); err == nil {
+			t.Errorf("% x: expected an error", b)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	msg := loadTestMessage(t)
+
+	p1 := person{
+		Name:          "Luke",
+		ID:            -42,
+		Emails:        []string{"luke@example.com", "skywalker@example.com"},
+		Kind:          "ADMIN",
+		Scores:        map[string]int64{"x": 1, "y": -1},
+		LuckyNumbers:  []int32{7, -13, math.MaxInt32},
+		Address:       &address{City: "Mos Eisley"},
+		PastAddresses: []address{{City: "Tatooine"}, {City: ""}},
+		Delta:         math.MinInt64,
+		Hash:          math.MaxUint64,
+		Ratio:         0.25,
+		Blob:          []byte{0, 1, 2},
+		Active:        true,
+		Flags:         []uint32{math.MaxUint32},
+	}
+
+	b, err := Marshal(p1, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var p2 person
+
+	if err := Unmarshal(b, msg, &p2); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(p1, p2) {
+		t.Errorf("%#v != %#v", p1, p2)
+	}
+}
+
+func TestCodec(t *testing.T) {
+	msg := loadTestMessage(t)
+	codec := NewCodec(msg)
+
+	b := &bytes.Buffer{}
+
+	if err := objconv.NewEncoder(codec.NewEmitter(b)).Encode(map[string]interface{}{"name": "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var v person
+
+	if err := objconv.NewDecoder(codec.NewParser(b)).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Name != "a" {
+		t.Errorf("bad value: %#v", v)
+	}
+}
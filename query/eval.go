@@ -0,0 +1,318 @@
+package query
+
+import (
+	"fmt"
+)
+
+// term is a stage of a query, it produces the results of the stage for an
+// input value.
+type term interface {
+	eval(v interface{}, yield func(interface{}) error) error
+}
+
+func evalPipe(pipe []term, v interface{}, yield func(interface{}) error) error {
+	if len(pipe) == 0 {
+		return yield(v)
+	}
+	return pipe[0].eval(v, func(r interface{}) error {
+		return evalPipe(pipe[1:], r, yield)
+	})
+}
+
+type identity struct{}
+
+func (identity) eval(v interface{}, yield func(interface{}) error) error {
+	return yield(v)
+}
+
+// field selects the value of a map key. Lenient fields ignore values which
+// aren't maps or don't have the key instead of producing errors and nulls.
+type field struct {
+	name    string
+	lenient bool
+}
+
+func (f field) eval(v interface{}, yield func(interface{}) error) error {
+	if v == nil {
+		if f.lenient {
+			return nil
+		}
+		return yield(nil)
+	}
+
+	r, found, ok := lookup(v, f.name)
+
+	switch {
+	case !ok:
+		if f.lenient {
+			return nil
+		}
+		return fmt.Errorf("objconv/query: cannot index %s with %q", typeName(v), f.name)
+	case !found && f.lenient:
+		return nil
+	}
+
+	return yield(r)
+}
+
+// index selects an element of an array, or the value of a map key when the
+// input is a map.
+type index struct {
+	n int
+}
+
+func (x index) eval(v interface{}, yield func(interface{}) error) error {
+	if v == nil {
+		return yield(nil)
+	}
+
+	if n, ok := length(v); ok && !isBytes(v) {
+		i := x.n
+		if i < 0 {
+			i += n
+		}
+		if i < 0 || i >= n {
+			return yield(nil)
+		}
+		return yield(elem(v, i))
+	}
+
+	if r, _, ok := lookup(v, int64(x.n)); ok {
+		return yield(r)
+	}
+
+	return fmt.Errorf("objconv/query: cannot index %s with %d", typeName(v), x.n)
+}
+
+// slice selects a range of elements of an array, string or byte slice.
+type slice struct {
+	from *int
+	to   *int
+}
+
+func (s slice) eval(v interface{}, yield func(interface{}) error) error {
+	if v == nil {
+		return yield(nil)
+	}
+
+	n, ok := length(v)
+	if !ok {
+		return fmt.Errorf("objconv/query: cannot slice %s", typeName(v))
+	}
+
+	i, j := bound(s.from, 0, n), bound(s.to, n, n)
+	if j < i {
+		j = i
+	}
+
+	switch x := v.(type) {
+	case string:
+		return yield(x[i:j])
+	case []byte:
+		return yield(x[i:j])
+	}
+
+	r := make([]interface{}, 0, j-i)
+	for k := i; k < j; k++ {
+		r = append(r, elem(v, k))
+	}
+	return yield(r)
+}
+
+func bound(p *int, def int, n int) int {
+	if p == nil {
+		return def
+	}
+	i := *p
+	if i < 0 {
+		i += n
+	}
+	return min(max(i, 0), n)
+}
+
+// iterate produces the elements of arrays and the values of maps.
+type iterate struct{}
+
+func (iterate) eval(v interface{}, yield func(interface{}) error) error {
+	if v == nil {
+		return nil
+	}
+
+	ok, err := each(v, func(_ interface{}, r interface{}) error { return yield(r) })
+
+	if err == nil && !ok {
+		err = fmt.Errorf("objconv/query: cannot iterate over %s", typeName(v))
+	}
+
+	return err
+}
+
+// recurse produces its input and all the values it contains, depth first.
+type recurse struct{}
+
+func (r recurse) eval(v interface{}, yield func(interface{}) error) error {
+	if err := yield(v); err != nil {
+		return err
+	}
+	_, err := each(v, func(_ interface{}, x interface{}) error { return r.eval(x, yield) })
+	return err
+}
+
+// selectTerm produces its input if the predicate is true.
+type selectTerm struct {
+	pred expr
+}
+
+func (s selectTerm) eval(v interface{}, yield func(interface{}) error) error {
+	ok, err := test(s.pred, v)
+	if err != nil || !ok {
+		return err
+	}
+	return yield(v)
+}
+
+// expr is the interface of the expressions of predicates, each expression
+// produces results for an input value.
+type expr interface {
+	eval(v interface{}, yield func(interface{}) error) error
+}
+
+// test returns true if any result of e is truthy.
+func test(e expr, v interface{}) (ok bool, err error) {
+	err = e.eval(v, func(r interface{}) error {
+		if truthy(r) {
+			ok = true
+			return errStop
+		}
+		return nil
+	})
+	if err == errStop {
+		err = nil
+	}
+	return
+}
+
+func truthy(v interface{}) bool {
+	return v != nil && v != false
+}
+
+type pathExpr []term
+
+func (p pathExpr) eval(v interface{}, yield func(interface{}) error) error {
+	return evalPipe(p, v, yield)
+}
+
+type literal struct {
+	v interface{}
+}
+
+func (l literal) eval(_ interface{}, yield func(interface{}) error) error {
+	return yield(l.v)
+}
+
+type notExpr struct {
+	e expr
+}
+
+func (n notExpr) eval(v interface{}, yield func(interface{}) error) error {
+	ok, err := test(n.e, v)
+	if err != nil {
+		return err
+	}
+	return yield(!ok)
+}
+
+type andExpr struct {
+	l expr
+	r expr
+}
+
+func (a andExpr) eval(v interface{}, yield func(interface{}) error) error {
+	ok, err := test(a.l, v)
+	if err == nil && ok {
+		ok, err = test(a.r, v)
+	}
+	if err != nil {
+		return err
+	}
+	return yield(ok)
+}
+
+type orExpr struct {
+	l expr
+	r expr
+}
+
+func (o orExpr) eval(v interface{}, yield func(interface{}) error) error {
+	ok, err := test(o.l, v)
+	if err == nil && !ok {
+		ok, err = test(o.r, v)
+	}
+	if err != nil {
+		return err
+	}
+	return yield(ok)
+}
+
+// compareExpr is true if any pair of results of its operands satisfies the
+// comparison.
+type compareExpr struct {
+	op string
+	l  expr
+	r  expr
+}
+
+func (c compareExpr) eval(v interface{}, yield func(interface{}) error) error {
+	var rs []interface{}
+
+	if err := c.r.eval(v, func(r interface{}) error {
+		rs = append(rs, r)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	match := false
+
+	err := c.l.eval(v, func(l interface{}) error {
+		for _, r := range rs {
+			if c.match(l, r) {
+				match = true
+				return errStop
+			}
+		}
+		return nil
+	})
+
+	switch err {
+	case nil, errStop:
+		return yield(match)
+	default:
+		return err
+	}
+}
+
+func (c compareExpr) match(l interface{}, r interface{}) bool {
+	switch c.op {
+	case "==":
+		return equal(l, r)
+	case "!=":
+		return !equal(l, r)
+	}
+
+	cmp, ok := compare(l, r)
+	if !ok {
+		return false
+	}
+
+	switch c.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default: // ">="
+		return cmp >= 0
+	}
+}
@@ -0,0 +1,145 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+)
+
+type tokenType int
+
+const (
+	tokEOF    tokenType = iota
+	tokDot              // .
+	tokDotDot           // ..
+	tokLBrack           // [
+	tokRBrack           // ]
+	tokLParen           // (
+	tokRParen           // )
+	tokColon            // :
+	tokPipe             // |
+	tokStar             // *
+	tokOp               // == != < <= > >=
+	tokIdent
+	tokString
+	tokNumber
+)
+
+var punctuation = map[byte]tokenType{
+	'[': tokLBrack,
+	']': tokRBrack,
+	'(': tokLParen,
+	')': tokRParen,
+	':': tokColon,
+	'|': tokPipe,
+	'*': tokStar,
+}
+
+type token struct {
+	typ tokenType
+	off int    // offset of the token in the expression
+	s   string // source of the token, or value of strings
+}
+
+// lexer splits query expressions in tokens.
+type lexer struct {
+	s string
+	i int
+}
+
+func (l *lexer) errorf(off int, msg string) *Error {
+	return &Error{Expr: l.s, Offset: off, Msg: msg}
+}
+
+func (l *lexer) next() (t token, err error) {
+	for l.i < len(l.s) && strings.IndexByte(" \t\r\n", l.s[l.i]) >= 0 {
+		l.i++
+	}
+
+	t.off = l.i
+
+	if l.i == len(l.s) {
+		return
+	}
+
+	c := l.s[l.i]
+
+	switch {
+	case c == '.':
+		if strings.HasPrefix(l.s[l.i:], "..") {
+			t.typ, t.s = tokDotDot, ".."
+		} else {
+			t.typ, t.s = tokDot, "."
+		}
+
+	case punctuation[c] != tokEOF:
+		t.typ, t.s = punctuation[c], l.s[l.i:l.i+1]
+
+	case c == '=' || c == '!' || c == '<' || c == '>':
+		t.typ, t.s = tokOp, l.s[l.i:l.i+1]
+		if strings.HasPrefix(l.s[l.i+1:], "=") {
+			t.s = l.s[l.i : l.i+2]
+		}
+		if t.s == "=" || t.s == "!" {
+			err = l.errorf(t.off, "unexpected character '"+t.s+"'")
+			return
+		}
+
+	case c == '"':
+		return l.string()
+
+	case c == '-' || isDigit(c):
+		j := l.i + 1
+		for j < len(l.s) && (isDigit(l.s[j]) || strings.IndexByte(".eE+-", l.s[j]) >= 0) {
+			// Signs are part of the number only after an exponent.
+			if (l.s[j] == '+' || l.s[j] == '-') && l.s[j-1] != 'e' && l.s[j-1] != 'E' {
+				break
+			}
+			j++
+		}
+		t.typ, t.s = tokNumber, l.s[l.i:j]
+
+	case isIdentStart(c):
+		j := l.i + 1
+		for j < len(l.s) && (isIdentStart(l.s[j]) || isDigit(l.s[j])) {
+			j++
+		}
+		t.typ, t.s = tokIdent, l.s[l.i:j]
+
+	default:
+		err = l.errorf(t.off, "unexpected character "+strconv.QuoteRune(rune(c)))
+		return
+	}
+
+	l.i += len(t.s)
+	return
+}
+
+func (l *lexer) string() (t token, err error) {
+	t.off = l.i
+
+	for j := l.i + 1; j < len(l.s); j++ {
+		switch l.s[j] {
+		case '\\':
+			j++
+		case '"':
+			if t.s, err = strconv.Unquote(l.s[l.i : j+1]); err != nil {
+				err = l.errorf(t.off, "invalid string")
+				return
+			}
+			t.typ = tokString
+			l.i = j + 1
+			return
+		}
+	}
+
+	err = l.errorf(t.off, "unterminated string")
+	return
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,358 @@
+package query
+
+import (
+	"strconv"
+)
+
+// parser builds the terms of query expressions from the tokens produced by
+// its lexer.
+type parser struct {
+	lexer
+	tok token
+}
+
+func (p *parser) advance() (err error) {
+	p.tok, err = p.next()
+	return
+}
+
+func (p *parser) unexpected() error {
+	if p.tok.typ == tokEOF {
+		return p.errorf(p.tok.off, "unexpected end of expression")
+	}
+	return p.errorf(p.tok.off, "unexpected "+strconv.Quote(p.tok.s))
+}
+
+func (p *parser) expect(typ tokenType) (err error) {
+	if p.tok.typ != typ {
+		return p.unexpected()
+	}
+	return p.advance()
+}
+
+func (p *parser) parse() (pipe []term, err error) {
+	if err = p.advance(); err != nil {
+		return
+	}
+
+	if pipe, err = p.parsePipe(); err != nil {
+		return
+	}
+
+	if p.tok.typ != tokEOF {
+		err = p.unexpected()
+	}
+	return
+}
+
+func (p *parser) parsePipe() (pipe []term, err error) {
+	for {
+		var terms []term
+
+		if terms, err = p.parseTerm(); err != nil {
+			return
+		}
+
+		pipe = append(pipe, terms...)
+
+		if p.tok.typ != tokPipe {
+			return
+		}
+
+		if err = p.advance(); err != nil {
+			return
+		}
+	}
+}
+
+func (p *parser) parseTerm() (terms []term, err error) {
+	switch {
+	case p.tok.typ == tokDot || p.tok.typ == tokDotDot:
+		return p.parsePath()
+
+	case p.tok.typ == tokIdent && p.tok.s == "select":
+		var pred expr
+
+		if err = p.advance(); err != nil {
+			return
+		}
+		if err = p.expect(tokLParen); err != nil {
+			return
+		}
+		if pred, err = p.parseOr(); err != nil {
+			return
+		}
+		if err = p.expect(tokRParen); err != nil {
+			return
+		}
+
+		terms = append(terms, selectTerm{pred})
+		return
+	}
+
+	err = p.unexpected()
+	return
+}
+
+// parsePath parses a sequence of path steps, the current token is either a
+// dot or a double dot.
+func (p *parser) parsePath() (terms []term, err error) {
+	for {
+		switch p.tok.typ {
+		case tokDot:
+			if err = p.advance(); err != nil {
+				return
+			}
+
+			switch p.tok.typ {
+			case tokIdent, tokString:
+				terms = append(terms, field{name: p.tok.s})
+				err = p.advance()
+
+			case tokStar:
+				terms = append(terms, iterate{})
+				err = p.advance()
+
+			case tokLBrack:
+				// The bracket is parsed by the next iteration.
+
+			default:
+				if len(terms) != 0 {
+					err = p.unexpected()
+				} else {
+					terms = append(terms, identity{})
+				}
+			}
+
+		case tokDotDot:
+			if err = p.advance(); err != nil {
+				return
+			}
+
+			terms = append(terms, recurse{})
+
+			if p.tok.typ == tokIdent || p.tok.typ == tokString {
+				terms = append(terms, field{name: p.tok.s, lenient: true})
+				err = p.advance()
+			}
+
+		case tokLBrack:
+			var t term
+
+			if t, err = p.parseBracket(); err != nil {
+				return
+			}
+
+			terms = append(terms, t)
+
+		default:
+			return
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// parseBracket parses the index, key, slice or wildcard within brackets.
+func (p *parser) parseBracket() (t term, err error) {
+	if err = p.advance(); err != nil {
+		return
+	}
+
+	switch p.tok.typ {
+	case tokRBrack:
+		t = iterate{}
+
+	case tokStar:
+		t = iterate{}
+		if err = p.advance(); err != nil {
+			return
+		}
+
+	case tokString:
+		t = field{name: p.tok.s}
+		if err = p.advance(); err != nil {
+			return
+		}
+
+	default:
+		var from, to *int
+
+		if from, err = p.parseIndex(); err != nil {
+			return
+		}
+
+		if p.tok.typ != tokColon {
+			if from == nil {
+				err = p.unexpected()
+				return
+			}
+			t = index{*from}
+			break
+		}
+
+		if err = p.advance(); err != nil {
+			return
+		}
+
+		if to, err = p.parseIndex(); err != nil {
+			return
+		}
+
+		t = slice{from, to}
+	}
+
+	err = p.expect(tokRBrack)
+	return
+}
+
+// parseIndex parses an optional integer.
+func (p *parser) parseIndex() (n *int, err error) {
+	if p.tok.typ != tokNumber {
+		return
+	}
+
+	i, e := strconv.Atoi(p.tok.s)
+	if e != nil {
+		err = p.errorf(p.tok.off, "invalid index "+strconv.Quote(p.tok.s))
+		return
+	}
+
+	n = &i
+	err = p.advance()
+	return
+}
+
+func (p *parser) parseOr() (e expr, err error) {
+	if e, err = p.parseAnd(); err != nil {
+		return
+	}
+
+	for p.tok.typ == tokIdent && p.tok.s == "or" {
+		var r expr
+
+		if err = p.advance(); err != nil {
+			return
+		}
+		if r, err = p.parseAnd(); err != nil {
+			return
+		}
+
+		e = orExpr{e, r}
+	}
+
+	return
+}
+
+func (p *parser) parseAnd() (e expr, err error) {
+	if e, err = p.parseNot(); err != nil {
+		return
+	}
+
+	for p.tok.typ == tokIdent && p.tok.s == "and" {
+		var r expr
+
+		if err = p.advance(); err != nil {
+			return
+		}
+		if r, err = p.parseNot(); err != nil {
+			return
+		}
+
+		e = andExpr{e, r}
+	}
+
+	return
+}
+
+func (p *parser) parseNot() (e expr, err error) {
+	if p.tok.typ == tokIdent && p.tok.s == "not" {
+		if err = p.advance(); err != nil {
+			return
+		}
+		if e, err = p.parseNot(); err != nil {
+			return
+		}
+		e = notExpr{e}
+		return
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (e expr, err error) {
+	if e, err = p.parseOperand(); err != nil {
+		return
+	}
+
+	if p.tok.typ == tokOp {
+		var r expr
+		var op = p.tok.s
+
+		if err = p.advance(); err != nil {
+			return
+		}
+		if r, err = p.parseOperand(); err != nil {
+			return
+		}
+
+		e = compareExpr{op, e, r}
+	}
+
+	return
+}
+
+func (p *parser) parseOperand() (e expr, err error) {
+	switch t := p.tok; t.typ {
+	case tokDot, tokDotDot:
+		var path []term
+		if path, err = p.parsePath(); err == nil {
+			e = pathExpr(path)
+		}
+		return
+
+	case tokLParen:
+		if err = p.advance(); err != nil {
+			return
+		}
+		if e, err = p.parseOr(); err != nil {
+			return
+		}
+		err = p.expect(tokRParen)
+		return
+
+	case tokString:
+		e = literal{t.s}
+
+	case tokNumber:
+		if i, e1 := strconv.ParseInt(t.s, 10, 64); e1 == nil {
+			e = literal{i}
+		} else if f, e2 := strconv.ParseFloat(t.s, 64); e2 == nil {
+			e = literal{f}
+		} else {
+			err = p.errorf(t.off, "invalid number "+strconv.Quote(t.s))
+			return
+		}
+
+	case tokIdent:
+		switch t.s {
+		case "true":
+			e = literal{true}
+		case "false":
+			e = literal{false}
+		case "null":
+			e = literal{nil}
+		default:
+			err = p.unexpected()
+			return
+		}
+
+	default:
+		err = p.unexpected()
+		return
+	}
+
+	err = p.advance()
+	return
+}
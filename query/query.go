@@ -0,0 +1,116 @@
+// Package query implements a jq-like expression language to select values
+// decoded by objconv.
+//
+// Queries operate on the generic values produced by decoders (nil, bool,
+// int64, uint64, float64, string, []byte, time.Time, time.Duration, error,
+// slices and maps), so the types of binary formats are preserved: bytes and
+// times are compared and returned as such instead of being converted to
+// strings. Any Go map or slice is supported, as well as values implementing
+// the Map interface.
+//
+// Expressions are made of the following terms, which can be chained and
+// combined with pipes:
+//
+//	.               the input value
+//	.name           the value of the key "name" of a map, null if missing
+//	."name"         same as above, for keys which aren't identifiers
+//	.["name"]       same as above
+//	.[n]            the n-th element of an array, negative indexes count from
+//	                the end
+//	.[i:j]          the elements i to j (excluded) of an array, string or byte
+//	                slice, both bounds are optional
+//	.[] or .*       all the elements of an array or values of a map
+//	..              the input value and all the values it contains, recursively
+//	..name          the values of the key "name" of all maps found recursively
+//	a | b           the results of b applied to every result of a
+//	select(p)       the input value if the predicate p is true
+//
+// Predicates compare expressions and literals (numbers, strings, true, false
+// and null) with ==, !=, <, <=, > and >=, and can be combined with and, or,
+// not and parentheses. When an expression produces multiple results the
+// comparison is true if any of them matches, an expression alone is true if
+// any of its results is neither false nor null. Strings are converted to times
+// or byte slices when compared to those types.
+//
+//	.users[] | select(.age >= 21 and .name != "bob") | .email
+package query
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Map is implemented by types representing maps with ordered keys, queries
+// iterate over their values in the order of the keys. Go maps are iterated in
+// the order of their sorted keys.
+type Map interface {
+	// Len returns the number of entries of the map.
+	Len() int
+
+	// Key returns the key of the entry at index i.
+	Key(i int) interface{}
+
+	// Value returns the value of the entry at index i.
+	Value(i int) interface{}
+}
+
+// Query is a compiled query expression, it is safe to use concurrently.
+type Query struct {
+	expr string
+	pipe []term
+}
+
+// Parse compiles the query expression s.
+func Parse(s string) (*Query, error) {
+	p := parser{lexer: lexer{s: s}}
+
+	pipe, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Query{expr: s, pipe: pipe}, nil
+}
+
+// MustParse is like Parse but panics if the expression is invalid.
+func MustParse(s string) *Query {
+	q, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+// String returns the expression of the query.
+func (q *Query) String() string {
+	return q.expr
+}
+
+// Each calls fn for each result of the query applied to v, stopping at the
+// first error.
+func (q *Query) Each(v interface{}, fn func(interface{}) error) error {
+	return evalPipe(q.pipe, v, fn)
+}
+
+// Eval returns the results of the query applied to v.
+func (q *Query) Eval(v interface{}) (results []interface{}, err error) {
+	err = q.Each(v, func(r interface{}) error {
+		results = append(results, r)
+		return nil
+	})
+	return
+}
+
+// Error is returned when a query expression is invalid.
+type Error struct {
+	Expr   string // the query expression
+	Offset int    // byte offset of the error in the expression
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("objconv/query: %s at offset %d of %q", e.Msg, e.Offset, e.Expr)
+}
+
+// errStop is used internally to interrupt iterations.
+var errStop = errors.New("stop")
@@ -0,0 +1,141 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// object is an implementation of Map used to test that the order of keys is
+// preserved.
+type object []interface{}
+
+func (obj object) Len() int                { return len(obj) / 2 }
+func (obj object) Key(i int) interface{}   { return obj[2*i] }
+func (obj object) Value(i int) interface{} { return obj[2*i+1] }
+
+var date = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+var testValue = map[interface{}]interface{}{
+	"name": "objconv",
+	"tags": []interface{}{"json", "msgpack", "cbor"},
+	"users": []interface{}{
+		map[interface{}]interface{}{"name": "alice", "age": int64(31), "key": []byte("A")},
+		map[interface{}]interface{}{"name": "bob", "age": uint64(20), "key": []byte("B")},
+		map[interface{}]interface{}{"name": "carol", "age": 42.5, "created": date},
+	},
+	"ordered": object{"z", int64(1), "a", int64(2), int64(3), "three"},
+	"a b":     true,
+	"nothing": nil,
+}
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		query   string
+		results []interface{}
+	}{
+		{`.`, []interface{}{testValue}},
+		{`.name`, []interface{}{"objconv"}},
+		{`.missing`, []interface{}{nil}},
+		{`.nothing.missing`, []interface{}{nil}},
+		{`."a b"`, []interface{}{true}},
+		{`.["a b"]`, []interface{}{true}},
+		{`.tags[0]`, []interface{}{"json"}},
+		{`.tags[-1]`, []interface{}{"cbor"}},
+		{`.tags[3]`, []interface{}{nil}},
+		{`.tags[1:]`, []interface{}{[]interface{}{"msgpack", "cbor"}}},
+		{`.tags[:-1]`, []interface{}{[]interface{}{"json", "msgpack"}}},
+		{`.tags[2:1]`, []interface{}{[]interface{}{}}},
+		{`.name[1:3]`, []interface{}{"bj"}},
+		{`.users[0].key[0:1]`, []interface{}{[]byte("A")}},
+		{`.tags[]`, []interface{}{"json", "msgpack", "cbor"}},
+		{`.tags.*`, []interface{}{"json", "msgpack", "cbor"}},
+		{`.tags[*]`, []interface{}{"json", "msgpack", "cbor"}},
+		{`.ordered[]`, []interface{}{int64(1), int64(2), "three"}},
+		{`.ordered[3]`, []interface{}{"three"}},
+		{`.users[].name`, []interface{}{"alice", "bob", "carol"}},
+		{`.users[] | .name`, []interface{}{"alice", "bob", "carol"}},
+		{`..name`, []interface{}{"objconv", "alice", "bob", "carol"}},
+		{`.users[] | select(.age > 30) | .name`, []interface{}{"alice", "carol"}},
+		{`.users[] | select(.age == 20) | .name`, []interface{}{"bob"}},
+		{`.users[] | select(.age <= 31 and not (.name == "bob")) | .name`, []interface{}{"alice"}},
+		{`.users[] | select(.name == "bob" or .age > 40) | .name`, []interface{}{"bob", "carol"}},
+		{`.users[] | select(.created) | .name`, []interface{}{"carol"}},
+		{`.users[] | select(.created < "2025-01-01T00:00:00Z") | .name`, []interface{}{"carol"}},
+		{`.users[] | select(.key == "B") | .name`, []interface{}{"bob"}},
+		{`.users[] | select(.key != null) | .name`, []interface{}{"alice", "bob"}},
+		{`select(.tags[] == "cbor") | .name`, []interface{}{"objconv"}},
+		{`select(.users[0] == .users[0] and .users[0] != .users[1]) | .name`, []interface{}{"objconv"}},
+		{`select(.missing) | .name`, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			q, err := Parse(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			results, err := q.Eval(testValue)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(results, test.results) {
+				t.Errorf("\n<<< %#v\n>>> %#v", test.results, results)
+			}
+		})
+	}
+}
+
+func TestQueryEvalError(t *testing.T) {
+	tests := []string{
+		`.name.first`,
+		`.name[0]`,
+		`.name[]`,
+		`.tags.name`,
+		`.users[0].age[1:]`,
+	}
+
+	for _, test := range tests {
+		if _, err := MustParse(test).Eval(testValue); err == nil {
+			t.Errorf("%s: expected an error", test)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		query  string
+		offset int
+	}{
+		{``, 0},
+		{`name`, 0},
+		{`.a.`, 3},
+		{`.a[`, 3},
+		{`.a[0`, 4},
+		{`.a["b"`, 6},
+		{`.a[b]`, 3},
+		{`."a`, 1},
+		{`.a = 1`, 3},
+		{`select(.a ==)`, 12},
+		{`select(.a`, 9},
+		{`.a | `, 5},
+		{`.a $`, 3},
+		{`.[1.5]`, 2},
+	}
+
+	for _, test := range tests {
+		_, err := Parse(test.query)
+
+		e, ok := err.(*Error)
+		if !ok {
+			t.Errorf("%q: expected a parse error but got %v", test.query, err)
+			continue
+		}
+
+		if e.Offset != test.offset {
+			t.Errorf("%q: expected an error at offset %d: %s", test.query, test.offset, e)
+		}
+	}
+}
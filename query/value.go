@@ -0,0 +1,386 @@
+package query
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// length returns the number of elements of arrays, strings and byte slices.
+func length(v interface{}) (int, bool) {
+	switch x := v.(type) {
+	case []interface{}:
+		return len(x), true
+	case string:
+		return len(x), true
+	case []byte:
+		return len(x), true
+	case Map:
+		return 0, false
+	}
+
+	switch r := reflect.ValueOf(v); r.Kind() {
+	case reflect.Slice, reflect.Array:
+		return r.Len(), true
+	}
+
+	return 0, false
+}
+
+func isBytes(v interface{}) bool {
+	_, ok := v.(string)
+	if !ok {
+		_, ok = v.([]byte)
+	}
+	return ok
+}
+
+// elem returns the element at index i of the array v.
+func elem(v interface{}, i int) interface{} {
+	if a, ok := v.([]interface{}); ok {
+		return a[i]
+	}
+	return reflect.ValueOf(v).Index(i).Interface()
+}
+
+// each calls fn with the keys and values of maps, or the indexes and elements
+// of arrays. ok is false if v is neither a map nor an array.
+func each(v interface{}, fn func(k interface{}, v interface{}) error) (ok bool, err error) {
+	switch x := v.(type) {
+	case []interface{}:
+		for i, e := range x {
+			if err = fn(i, e); err != nil {
+				break
+			}
+		}
+		return true, err
+
+	case Map:
+		for i, n := 0, x.Len(); i != n; i++ {
+			if err = fn(x.Key(i), x.Value(i)); err != nil {
+				break
+			}
+		}
+		return true, err
+
+	case string, []byte:
+		return false, nil
+	}
+
+	switch r := reflect.ValueOf(v); r.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i, n := 0, r.Len(); i != n; i++ {
+			if err = fn(i, r.Index(i).Interface()); err != nil {
+				break
+			}
+		}
+		return true, err
+
+	case reflect.Map:
+		keys := r.MapKeys()
+		sort.Slice(keys, func(i int, j int) bool {
+			return less(keys[i].Interface(), keys[j].Interface())
+		})
+		for _, k := range keys {
+			if err = fn(k.Interface(), r.MapIndex(k).Interface()); err != nil {
+				break
+			}
+		}
+		return true, err
+	}
+
+	return false, nil
+}
+
+// lookup returns the value of key in the map v. ok is false if v isn't a map.
+func lookup(v interface{}, key interface{}) (r interface{}, found bool, ok bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		if s, isString := key.(string); isString {
+			r, found = m[s]
+			return r, found, true
+		}
+		return nil, false, true
+
+	case map[interface{}]interface{}:
+		if r, found = m[key]; found {
+			return r, found, true
+		}
+
+	case []interface{}, string, []byte:
+		return nil, false, false
+	}
+
+	if !isMap(v) {
+		return nil, false, false
+	}
+
+	// Keys are compared with the rules of the == operator, so integer keys
+	// match regardless of their type for example.
+	each(v, func(k interface{}, x interface{}) error {
+		if equal(k, key) {
+			r, found = x, true
+			return errStop
+		}
+		return nil
+	})
+
+	return r, found, true
+}
+
+func isMap(v interface{}) bool {
+	if _, ok := v.(Map); ok {
+		return true
+	}
+	return reflect.ValueOf(v).Kind() == reflect.Map
+}
+
+// equal returns true if a and b are equal according to the rules of compare,
+// arrays and maps are compared deeply.
+func equal(a interface{}, b interface{}) bool {
+	if c, ok := compare(a, b); ok {
+		return c == 0
+	}
+
+	na, aok := length(a)
+	nb, bok := length(b)
+
+	if aok && bok && !isBytes(a) && !isBytes(b) {
+		if na != nb {
+			return false
+		}
+		for i := 0; i != na; i++ {
+			if !equal(elem(a, i), elem(b, i)) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if isMap(a) && isMap(b) {
+		return mapEqual(a, b) && mapEqual(b, a)
+	}
+
+	return false
+}
+
+// mapEqual returns true if all the entries of the map a are in the map b.
+func mapEqual(a interface{}, b interface{}) bool {
+	match := true
+
+	each(a, func(k interface{}, x interface{}) error {
+		if y, found, _ := lookup(b, k); !found || !equal(x, y) {
+			match = false
+			return errStop
+		}
+		return nil
+	})
+
+	return match
+}
+
+// compare returns the order of a and b, ok is false if the values can't be
+// compared. Numbers are compared regardless of their type, strings are
+// compared to byte slices, and parsed when compared to times and durations.
+func compare(a interface{}, b interface{}) (c int, ok bool) {
+	a, b = normalize(a), normalize(b)
+
+	switch x := a.(type) {
+	case nil:
+		return 0, b == nil
+
+	case bool:
+		if y, isBool := b.(bool); isBool {
+			switch {
+			case x == y:
+				return 0, true
+			case y:
+				return -1, true
+			default:
+				return 1, true
+			}
+		}
+
+	case int64, uint64, float64:
+		return compareNumbers(x, b)
+
+	case string:
+		switch y := b.(type) {
+		case string:
+			return strings.Compare(x, y), true
+		case []byte:
+			return bytes.Compare([]byte(x), y), true
+		case time.Time, time.Duration:
+			c, ok = compare(b, a)
+			return -c, ok
+		}
+
+	case []byte:
+		switch y := b.(type) {
+		case []byte:
+			return bytes.Compare(x, y), true
+		case string:
+			return bytes.Compare(x, []byte(y)), true
+		}
+
+	case time.Time:
+		switch y := b.(type) {
+		case time.Time:
+			return x.Compare(y), true
+		case string:
+			if t, err := time.Parse(time.RFC3339Nano, y); err == nil {
+				return x.Compare(t), true
+			}
+		}
+
+	case time.Duration:
+		switch y := b.(type) {
+		case time.Duration:
+			return compareInts(int64(x), int64(y)), true
+		case string:
+			if d, err := time.ParseDuration(y); err == nil {
+				return compareInts(int64(x), int64(d)), true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// less orders values of any types, values which can't be compared are ordered
+// by their type name and string representation.
+func less(a interface{}, b interface{}) bool {
+	if c, ok := compare(a, b); ok {
+		return c < 0
+	}
+	if ta, tb := typeName(a), typeName(b); ta != tb {
+		return ta < tb
+	}
+	return fmt.Sprint(a) < fmt.Sprint(b)
+}
+
+// normalize converts numbers to int64, uint64 or float64, and errors to
+// strings.
+func normalize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case nil, bool, int64, uint64, float64, string, []byte, time.Time, time.Duration:
+		return v
+	case error:
+		return x.Error()
+	}
+
+	switch r := reflect.ValueOf(v); r.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return r.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return r.Uint()
+	case reflect.Float32, reflect.Float64:
+		return r.Float()
+	case reflect.String:
+		return r.String()
+	case reflect.Bool:
+		return r.Bool()
+	}
+
+	return v
+}
+
+func compareNumbers(a interface{}, b interface{}) (int, bool) {
+	switch x := a.(type) {
+	case int64:
+		switch y := b.(type) {
+		case int64:
+			return compareInts(x, y), true
+		case uint64:
+			if x < 0 || y > math.MaxInt64 {
+				return -1, true
+			}
+			return compareInts(x, int64(y)), true
+		case float64:
+			return compareFloats(float64(x), y)
+		}
+
+	case uint64:
+		switch y := b.(type) {
+		case uint64:
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		case int64, float64:
+			c, ok := compareNumbers(b, a)
+			return -c, ok
+		}
+
+	case float64:
+		switch y := b.(type) {
+		case float64:
+			return compareFloats(x, y)
+		case int64, uint64:
+			c, ok := compareNumbers(b, a)
+			return -c, ok
+		}
+	}
+
+	return 0, false
+}
+
+func compareInts(a int64, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareFloats(a float64, b float64) (int, bool) {
+	switch {
+	case a < b:
+		return -1, true
+	case a > b:
+		return 1, true
+	case a == b:
+		return 0, true
+	}
+	return 0, false // NaN
+}
+
+// typeName returns the name of the type of v used in error messages.
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case int64, uint64, float64:
+		return "number"
+	case string:
+		return "string"
+	case []byte:
+		return "bytes"
+	case time.Time:
+		return "time"
+	case time.Duration:
+		return "duration"
+	case Map:
+		return "map"
+	}
+
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map:
+		return "map"
+	}
+
+	return fmt.Sprintf("%T", v)
+}
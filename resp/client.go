@@ -0,0 +1,261 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/objconv"
+)
+
+// CommandWriter writes redis commands to an output stream, either in the
+// multibulk format used by redis clients or in the inline format that redis
+// servers accept from telnet-like sessions.
+type CommandWriter struct {
+	w      io.Writer
+	e      *ClientEmitter
+	b      []byte
+	inline bool
+}
+
+// NewCommandWriter returns a new CommandWriter that writes commands to w in the
+// multibulk format.
+func NewCommandWriter(w io.Writer) *CommandWriter {
+	return &CommandWriter{w: w, e: NewClientEmitter(w)}
+}
+
+// NewInlineCommandWriter returns a new CommandWriter that writes commands to w
+// in the inline format.
+func NewInlineCommandWriter(w io.Writer) *CommandWriter {
+	return &CommandWriter{w: w, inline: true}
+}
+
+// WriteCommand writes a command made of args to the output stream.
+//
+// The arguments are expected to be scalar values (strings, byte slices,
+// numbers, booleans, time values or durations).
+func (cw *CommandWriter) WriteCommand(args ...interface{}) (err error) {
+	if len(args) == 0 {
+		return errors.New("objconv/resp: cannot write a command with no arguments")
+	}
+
+	if cw.inline {
+		return cw.writeInline(args)
+	}
+
+	return objconv.Encoder{Emitter: cw.e}.Encode(args)
+}
+
+func (cw *CommandWriter) writeInline(args []interface{}) (err error) {
+	b := cw.b[:0]
+
+	for i, arg := range args {
+		if i != 0 {
+			b = append(b, ' ')
+		}
+		if b, err = appendInlineArg(b, arg); err != nil {
+			return
+		}
+	}
+
+	b = appendCRLF(b)
+	cw.b = b[:0]
+	_, err = cw.w.Write(b)
+	return
+}
+
+func appendInlineArg(b []byte, arg interface{}) ([]byte, error) {
+	switch v := arg.(type) {
+	case string:
+		return appendInlineString(b, v), nil
+	case []byte:
+		return appendInlineString(b, string(v)), nil
+	case bool:
+		if v {
+			return append(b, '1'), nil
+		}
+		return append(b, '0'), nil
+	case int:
+		return appendInt(b, int64(v)), nil
+	case int8:
+		return appendInt(b, int64(v)), nil
+	case int16:
+		return appendInt(b, int64(v)), nil
+	case int32:
+		return appendInt(b, int64(v)), nil
+	case int64:
+		return appendInt(b, v), nil
+	case uint:
+		return appendUint(b, uint64(v)), nil
+	case uint8:
+		return appendUint(b, uint64(v)), nil
+	case uint16:
+		return appendUint(b, uint64(v)), nil
+	case uint32:
+		return appendUint(b, uint64(v)), nil
+	case uint64:
+		return appendUint(b, v), nil
+	case float32:
+		return appendFloat(b, float64(v), 32), nil
+	case float64:
+		return appendFloat(b, v, 64), nil
+	case time.Time:
+		return appendInt(b, v.Unix()), nil
+	case time.Duration:
+		return appendFloat(b, v.Seconds(), 64), nil
+	default:
+		return b, errors.New("objconv/resp: unsupported argument type in inline command")
+	}
+}
+
+// appendInlineString appends s to b, quoting it if it contains characters that
+// would otherwise be interpreted by the inline command parser.
+func appendInlineString(b []byte, s string) []byte {
+	quote := len(s) == 0
+
+	for i := 0; i != len(s) && !quote; i++ {
+		switch c := s[i]; {
+		case c == ' ', c == '"', c == '\'', c == '\\', c < 0x20, c >= 0x7f:
+			quote = true
+		}
+	}
+
+	if !quote {
+		return append(b, s...)
+	}
+
+	b = append(b, '"')
+
+	for i := 0; i != len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\':
+			b = append(b, '\\', c)
+		case '\n':
+			b = append(b, '\\', 'n')
+		case '\r':
+			b = append(b, '\\', 'r')
+		case '\t':
+			b = append(b, '\\', 't')
+		default:
+			if c < 0x20 || c >= 0x7f {
+				b = append(b, '\\', 'x', hex[c>>4], hex[c&0xf])
+			} else {
+				b = append(b, c)
+			}
+		}
+	}
+
+	return append(b, '"')
+}
+
+const hex = "0123456789abcdef"
+
+// ReplyReader reads redis replies from an input stream and decodes them into
+// Go values.
+type ReplyReader struct {
+	p *Parser
+}
+
+// NewReplyReader returns a new ReplyReader which reads replies from r.
+func NewReplyReader(r io.Reader) *ReplyReader {
+	return &ReplyReader{p: NewParser(r)}
+}
+
+// ReadReply reads the next reply from the input stream and decodes it into v.
+//
+// If the reply is a redis error the method returns it as a value of type
+// *Error and v is left unchanged. A nil v may be passed to discard the reply.
+func (rr *ReplyReader) ReadReply(v interface{}) (err error) {
+	var t objconv.Type
+
+	if t, err = rr.p.ParseType(); err != nil {
+		return
+	}
+
+	if t == objconv.Error {
+		var e error
+		if e, err = rr.p.ParseError(); err == nil {
+			err = e
+		}
+		return
+	}
+
+	return objconv.Decoder{Parser: rr.p}.Decode(v)
+}
+
+// Client is a redis client connection supporting pipelined commands.
+//
+// Commands sent with the Send method are buffered until Flush is called, the
+// replies must then be read in the same order with the Receive method.
+//
+// Instances of Client are not safe for use by multiple goroutines.
+type Client struct {
+	w *bufio.Writer
+	c *CommandWriter
+	r *ReplyReader
+	n int // number of replies pending
+}
+
+// NewClient returns a new redis client which writes commands to and reads
+// replies from conn.
+func NewClient(conn io.ReadWriter) *Client {
+	w := bufio.NewWriter(conn)
+	return &Client{
+		w: w,
+		c: NewCommandWriter(w),
+		r: NewReplyReader(conn),
+	}
+}
+
+// Send writes a command to the client's buffer.
+func (c *Client) Send(args ...interface{}) (err error) {
+	if err = c.c.WriteCommand(args...); err == nil {
+		c.n++
+	}
+	return
+}
+
+// Flush writes the buffered commands to the connection.
+func (c *Client) Flush() error {
+	return c.w.Flush()
+}
+
+// Pending returns the number of replies that haven't been received yet.
+func (c *Client) Pending() int {
+	return c.n
+}
+
+// Receive reads the reply to the oldest pending command and decodes it into v.
+//
+// The client's buffer is flushed first if it still holds commands.
+func (c *Client) Receive(v interface{}) (err error) {
+	if c.n == 0 {
+		return errors.New("objconv/resp: no pending replies to receive")
+	}
+
+	if c.w.Buffered() != 0 {
+		if err = c.w.Flush(); err != nil {
+			return
+		}
+	}
+
+	c.n--
+	return c.r.ReadReply(v)
+}
+
+// Do sends a command and decodes its reply into v.
+//
+// Do must not be called while replies to pipelined commands are pending.
+func (c *Client) Do(v interface{}, args ...interface{}) (err error) {
+	if c.n != 0 {
+		return errors.New("objconv/resp: cannot run a command while " + strconv.Itoa(c.n) + " replies are pending")
+	}
+
+	if err = c.Send(args...); err != nil {
+		return
+	}
+
+	return c.Receive(v)
+}
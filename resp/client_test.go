@@ -0,0 +1,276 @@
+package resp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeRedis is an in-process implementation of a tiny subset of redis used to
+// test the client and server helpers against each other.
+type fakeRedis struct {
+	store map[string][]byte
+}
+
+func (r *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+
+	w := bufio.NewWriter(conn)
+	c := NewCommandReader(conn)
+	o := NewReplyWriter(w)
+
+	for {
+		args, err := c.ReadCommand()
+		if err != nil {
+			return
+		}
+
+		switch strings.ToUpper(args[0]) {
+		case "PING":
+			err = o.WriteReply("PONG")
+
+		case "SET":
+			r.store[args[1]] = []byte(args[2])
+			err = o.WriteReply("OK")
+
+		case "GET":
+			if v, ok := r.store[args[1]]; ok {
+				err = o.WriteReply(v)
+			} else {
+				err = o.WriteReply(nil)
+			}
+
+		case "INCR":
+			n, _ := strconv.Atoi(string(r.store[args[1]]))
+			n++
+			r.store[args[1]] = []byte(strconv.Itoa(n))
+			err = o.WriteReply(n)
+
+		case "MGET":
+			values := make([]interface{}, 0, len(args)-1)
+			for _, k := range args[1:] {
+				if v, ok := r.store[k]; ok {
+					values = append(values, v)
+				} else {
+					values = append(values, nil)
+				}
+			}
+			err = o.WriteReply(values)
+
+		default:
+			err = o.WriteError(errors.New("ERR unknown command '" + args[0] + "'"))
+		}
+
+		if err != nil {
+			return
+		}
+
+		// Only flush when there are no more pipelined commands to process.
+		if c.Buffered() == 0 {
+			if w.Flush() != nil {
+				return
+			}
+		}
+	}
+}
+
+func newTestClient() (*Client, func()) {
+	c1, c2 := net.Pipe()
+	r := &fakeRedis{store: make(map[string][]byte)}
+	go r.serve(c2)
+	return NewClient(c1), func() { c1.Close() }
+}
+
+func TestClientDo(t *testing.T) {
+	c, close := newTestClient()
+	defer close()
+
+	var s string
+	var n int
+	var b []byte
+	var p *string
+
+	if err := c.Do(&s, "PING"); err != nil || s != "PONG" {
+		t.Error(s, err)
+	}
+
+	if err := c.Do(&s, "SET", "hello", "world"); err != nil || s != "OK" {
+		t.Error(s, err)
+	}
+
+	if err := c.Do(&b, "GET", "hello"); err != nil || string(b) != "world" {
+		t.Error(string(b), err)
+	}
+
+	if err := c.Do(&p, "GET", "nope"); err != nil || p != nil {
+		t.Error(p, err)
+	}
+
+	if err := c.Do(&n, "INCR", "counter"); err != nil || n != 1 {
+		t.Error(n, err)
+	}
+
+	err := c.Do(nil, "OOPS")
+
+	if e, ok := err.(*Error); !ok || e.Type() != "ERR" {
+		t.Error(err)
+	}
+}
+
+func TestClientPipeline(t *testing.T) {
+	c, close := newTestClient()
+	defer close()
+
+	for i := 0; i != 3; i++ {
+		if err := c.Send("INCR", "counter"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := c.Send("MGET", "counter", "nope"); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := c.Pending(); n != 4 {
+		t.Error("bad number of pending replies:", n)
+	}
+
+	if err := c.Do(nil, "PING"); err == nil {
+		t.Error("running a command while replies are pending should have failed")
+	}
+
+	for i := 1; i <= 3; i++ {
+		var n int
+
+		if err := c.Receive(&n); err != nil {
+			t.Fatal(err)
+		}
+
+		if n != i {
+			t.Error("bad reply:", n)
+		}
+	}
+
+	var values []*string
+
+	if err := c.Receive(&values); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(values) != 2 || values[0] == nil || *values[0] != "3" || values[1] != nil {
+		t.Errorf("bad reply: %#v", values)
+	}
+
+	if err := c.Receive(nil); err == nil {
+		t.Error("receiving with no pending replies should have failed")
+	}
+}
+
+func TestCommandWriter(t *testing.T) {
+	tests := []struct {
+		args   []interface{}
+		inline bool
+		s      string
+	}{
+		{
+			args: []interface{}{"SET", "A", 42},
+			s:    "*3\r\n$3\r\nSET\r\n$1\r\nA\r\n$2\r\n42\r\n",
+		},
+		{
+			args:   []interface{}{"SET", "A", 42},
+			inline: true,
+			s:      "SET A 42\r\n",
+		},
+		{
+			args:   []interface{}{"SET", "hello world", "\"\r\n", ""},
+			inline: true,
+			s:      "SET \"hello world\" \"\\\"\\r\\n\" \"\"\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(testName(test.s), func(t *testing.T) {
+			var w *CommandWriter
+			var b = &bytes.Buffer{}
+
+			if test.inline {
+				w = NewInlineCommandWriter(b)
+			} else {
+				w = NewCommandWriter(b)
+			}
+
+			if err := w.WriteCommand(test.args...); err != nil {
+				t.Error(err)
+			}
+
+			if s := b.String(); s != test.s {
+				t.Errorf("%#v", s)
+			}
+		})
+	}
+}
+
+func TestCommandReader(t *testing.T) {
+	r := NewCommandReader(strings.NewReader(
+		"*2\r\n$3\r\nGET\r\n$1\r\nA\r\n" +
+			"\r\n" +
+			"SET A  42\r\n" +
+			"SET \"hello world\" \"\\\"\\r\\n\\x41\" 'it\\'s'\r\n" +
+			"*1\r\n$4\r\nPING",
+	))
+
+	for _, args := range [][]string{
+		{"GET", "A"},
+		{"SET", "A", "42"},
+		{"SET", "hello world", "\"\r\nA", "it's"},
+	} {
+		a, err := r.ReadCommand()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(a, args) {
+			t.Errorf("%#v", a)
+		}
+	}
+
+	if _, err := r.ReadCommand(); err != io.ErrUnexpectedEOF {
+		t.Error(err)
+	}
+}
+
+func TestCommandRoundTrip(t *testing.T) {
+	args := []interface{}{"SET", "hello world", "\"\r\n\x00'", ""}
+
+	for _, inline := range []bool{false, true} {
+		var w *CommandWriter
+		var b = &bytes.Buffer{}
+
+		if inline {
+			w = NewInlineCommandWriter(b)
+		} else {
+			w = NewCommandWriter(b)
+		}
+
+		if err := w.WriteCommand(args...); err != nil {
+			t.Fatal(err)
+		}
+
+		a, err := NewCommandReader(b).ReadCommand()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(a, []string{"SET", "hello world", "\"\r\n\x00'", ""}) {
+			t.Errorf("inline = %t: %#v", inline, a)
+		}
+	}
+}
@@ -18,6 +18,17 @@ func NewStreamDecoder(r io.Reader) *objconv.StreamDecoder {
 	return objconv.NewStreamDecoder(NewParser(r))
 }
 
+// NewRESP3Decoder returns a new RESP3 decoder that parses values from r.
+func NewRESP3Decoder(r io.Reader) *objconv.Decoder {
+	return objconv.NewDecoder(NewRESP3Parser(r))
+}
+
+// NewRESP3StreamDecoder returns a new RESP3 stream decoder that parses values
+// from r.
+func NewRESP3StreamDecoder(r io.Reader) *objconv.StreamDecoder {
+	return objconv.NewStreamDecoder(NewRESP3Parser(r))
+}
+
 // Unmarshal decodes a RESP representation of v from b.
 func Unmarshal(b []byte, v interface{}) error {
 	u := unmarshalerPool.Get().(*unmarshaler)
@@ -1,6 +1,8 @@
 package resp
 
 import (
+	"bytes"
+	"math"
 	"reflect"
 	"strings"
 	"testing"
@@ -63,6 +65,102 @@ func TestUnmarshal(t *testing.T) {
 	}
 }
 
+var resp3DecodeTests = []struct {
+	v interface{}
+	s string
+}{
+	{nil, "_\r\n"},
+	{nil, "$-1\r\n"},
+
+	{true, "#t\r\n"},
+	{false, "#f\r\n"},
+
+	{int64(42), ":42\r\n"},
+	{int64(-42), "(-42\r\n"},
+	{uint64(18446744073709551615), "(18446744073709551615\r\n"},
+	{"3492890328409238509324850943850943825024385", "(3492890328409238509324850943850943825024385\r\n"},
+
+	{1.5, ",1.5\r\n"},
+	{math.Inf(-1), ",-inf\r\n"},
+
+	{"Hello World!", "+Hello World!\r\n"},
+	{"Some string", "=15\r\ntxt:Some string\r\n"},
+
+	{[]byte("Hello World!"), "$12\r\nHello World!\r\n"},
+	{[]byte("Hello World!"), "$?\r\n;4\r\nHell\r\n;8\r\no World!\r\n;0\r\n"},
+
+	{NewError("ERR A"), "-ERR A\r\n"},
+	{NewError("SYNTAX invalid syntax"), "!21\r\nSYNTAX invalid syntax\r\n"},
+
+	{[]interface{}{int64(1), "A"}, "*2\r\n:1\r\n+A\r\n"},
+	{[]interface{}{int64(1), "A"}, "~2\r\n:1\r\n+A\r\n"},
+	{[]interface{}{"message", "hello"}, ">2\r\n+message\r\n+hello\r\n"},
+	{[]interface{}{int64(1), []interface{}{}}, "*?\r\n:1\r\n*?\r\n.\r\n.\r\n"},
+
+	{map[interface{}]interface{}{"A": int64(1), "B": true}, "%2\r\n+A\r\n:1\r\n+B\r\n#t\r\n"},
+	{map[interface{}]interface{}{"A": []interface{}{}}, "%?\r\n+A\r\n*0\r\n.\r\n"},
+	{[]interface{}{int64(2), int64(3)}, "|1\r\n+key-popularity\r\n%1\r\n+a\r\n,0.19\r\n*2\r\n:2\r\n:3\r\n"},
+}
+
+func TestRESP3Decoder(t *testing.T) {
+	for _, test := range resp3DecodeTests {
+		t.Run(testName(test.s), func(t *testing.T) {
+			var v interface{}
+
+			if err := NewRESP3Decoder(strings.NewReader(test.s)).Decode(&v); err != nil {
+				t.Error(err)
+				return
+			}
+
+			if !reflect.DeepEqual(v, test.v) {
+				t.Errorf("%#v", v)
+			}
+		})
+	}
+}
+
+func TestRESP3Attributes(t *testing.T) {
+	var v []int
+
+	p := NewRESP3Parser(strings.NewReader("|1\r\n+ttl\r\n:3600\r\n*2\r\n:2\r\n:3\r\n"))
+
+	if err := objconv.NewDecoder(p).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(v, []int{2, 3}) {
+		t.Error(v)
+	}
+
+	if attrs := p.Attributes(); !reflect.DeepEqual(attrs, map[interface{}]interface{}{"ttl": int64(3600)}) {
+		t.Error(attrs)
+	}
+}
+
+func TestRESP3RoundTrip(t *testing.T) {
+	for _, test := range resp3EncodeTests {
+		t.Run(testName(test.s), func(t *testing.T) {
+			var v interface{}
+
+			if err := NewRESP3Decoder(strings.NewReader(test.s)).Decode(&v); err != nil {
+				t.Error(err)
+				return
+			}
+
+			b := &bytes.Buffer{}
+
+			if err := NewRESP3Encoder(b).Encode(v); err != nil {
+				t.Error(err)
+				return
+			}
+
+			if s := b.String(); s != test.s {
+				t.Errorf("%#v", s)
+			}
+		})
+	}
+}
+
 func BenchmarkUnmarshal(b *testing.B) {
 	for _, test := range respDecodeTests {
 		var t reflect.Type
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
 	"strings"
 	"sync"
@@ -398,3 +399,186 @@ func (e *ClientEmitter) EmitDuration(v time.Duration) error {
 func (e *ClientEmitter) EmitError(v error) error {
 	return e.EmitString(v.Error())
 }
+
+var (
+	resp3NullBytes  = [...]byte{'_', '\r', '\n'}
+	resp3TrueBytes  = [...]byte{'#', 't', '\r', '\n'}
+	resp3FalseBytes = [...]byte{'#', 'f', '\r', '\n'}
+	resp3EndBytes   = [...]byte{'.', '\r', '\n'}
+)
+
+// RESP3Emitter is the implementation of an emitter for the RESP3 protocol
+// introduced in redis 6.
+//
+// Unlike the RESP2 emitter, it outputs values with their native RESP3 types
+// (nulls, booleans, doubles, big numbers, maps). Arrays and maps of unknown
+// length are written as streamed aggregates.
+type RESP3Emitter struct {
+	Emitter
+
+	// This stack is used to keep track of whether the arrays and maps being
+	// emitted are streamed aggregates.
+	stream []bool
+	bback  [16]bool
+}
+
+func NewRESP3Emitter(w io.Writer) *RESP3Emitter {
+	e := &RESP3Emitter{}
+	e.w = w
+	e.s = e.a[:0]
+	e.stack = e.sback[:0]
+	e.stream = e.bback[:0]
+	return e
+}
+
+func (e *RESP3Emitter) Reset(w io.Writer) {
+	e.Emitter.Reset(w)
+	e.stream = e.stream[:0]
+}
+
+func (e *RESP3Emitter) EmitNil() (err error) {
+	_, err = e.w.Write(resp3NullBytes[:])
+	return
+}
+
+func (e *RESP3Emitter) EmitBool(v bool) (err error) {
+	if v {
+		_, err = e.w.Write(resp3TrueBytes[:])
+	} else {
+		_, err = e.w.Write(resp3FalseBytes[:])
+	}
+	return
+}
+
+func (e *RESP3Emitter) EmitUint(v uint64, bitSize int) (err error) {
+	if v <= objutil.Int64Max {
+		return e.EmitInt(int64(v), bitSize)
+	}
+
+	s := e.s[:0]
+
+	s = append(s, '(')
+	s = appendUint(s, v)
+	s = appendCRLF(s)
+
+	e.s = s[:0]
+	_, err = e.w.Write(s)
+	return
+}
+
+func (e *RESP3Emitter) EmitFloat(v float64, bitSize int) (err error) {
+	s := e.s[:0]
+
+	s = append(s, ',')
+
+	switch {
+	case math.IsNaN(v):
+		s = append(s, "nan"...)
+	case math.IsInf(v, +1):
+		s = append(s, "inf"...)
+	case math.IsInf(v, -1):
+		s = append(s, "-inf"...)
+	default:
+		s = appendFloat(s, v, bitSize)
+	}
+
+	s = appendCRLF(s)
+
+	e.s = s[:0]
+	_, err = e.w.Write(s)
+	return
+}
+
+func (e *RESP3Emitter) EmitString(v string) (err error) {
+	if indexCRLF(v) < 0 {
+		s := e.s[:0]
+		s = append(s, '+')
+		s = append(s, v...)
+		s = appendCRLF(s)
+		e.s = s[:0]
+		_, err = e.w.Write(s)
+		return
+	}
+	return e.emitBlob('$', v)
+}
+
+func (e *RESP3Emitter) EmitError(v error) (err error) {
+	x := v.Error()
+
+	if strings.IndexAny(x, "\r\n") >= 0 {
+		return e.emitBlob('!', x)
+	}
+
+	s := e.s[:0]
+	s = append(s, '-')
+	s = append(s, x...)
+	s = appendCRLF(s)
+
+	e.s = s[:0]
+	_, err = e.w.Write(s)
+	return
+}
+
+func (e *RESP3Emitter) EmitArrayBegin(n int) (err error) {
+	return e.emitAggregate('*', n)
+}
+
+func (e *RESP3Emitter) EmitArrayEnd() (err error) {
+	return e.emitAggregateEnd()
+}
+
+func (e *RESP3Emitter) EmitArrayNext() (err error) {
+	return
+}
+
+func (e *RESP3Emitter) EmitMapBegin(n int) (err error) {
+	return e.emitAggregate('%', n)
+}
+
+func (e *RESP3Emitter) EmitMapEnd() (err error) {
+	return e.emitAggregateEnd()
+}
+
+func (e *RESP3Emitter) emitAggregate(token byte, n int) (err error) {
+	s := e.s[:0]
+
+	s = append(s, token)
+
+	if n < 0 {
+		s = append(s, '?')
+	} else {
+		s = appendUint(s, uint64(n))
+	}
+
+	s = appendCRLF(s)
+
+	e.s = s[:0]
+	e.stream = append(e.stream, n < 0)
+	_, err = e.w.Write(s)
+	return
+}
+
+func (e *RESP3Emitter) emitAggregateEnd() (err error) {
+	i := len(e.stream) - 1
+	stream := e.stream[i]
+	e.stream = e.stream[:i]
+
+	if stream {
+		_, err = e.w.Write(resp3EndBytes[:])
+	}
+	return
+}
+
+func (e *RESP3Emitter) emitBlob(token byte, v string) (err error) {
+	s := e.s[:0]
+
+	s = append(s, token)
+	s = appendUint(s, uint64(len(v)))
+	s = appendCRLF(s)
+	s = append(s, v...)
+	s = appendCRLF(s)
+
+	e.s = s[:0]
+	_, err = e.w.Write(s)
+	return
+}
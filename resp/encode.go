@@ -18,6 +18,16 @@ func NewStreamEncoder(w io.Writer) *objconv.StreamEncoder {
 	return objconv.NewStreamEncoder(NewEmitter(w))
 }
 
+// NewRESP3Encoder returns a new RESP3 encoder that writes to w.
+func NewRESP3Encoder(w io.Writer) *objconv.Encoder {
+	return objconv.NewEncoder(NewRESP3Emitter(w))
+}
+
+// NewRESP3StreamEncoder returns a new RESP3 stream encoder that writes to w.
+func NewRESP3StreamEncoder(w io.Writer) *objconv.StreamEncoder {
+	return objconv.NewStreamEncoder(NewRESP3Emitter(w))
+}
+
 // Marshal writes the RESP representation of v to a byte slice returned in b.
 func Marshal(v interface{}) (b []byte, err error) {
 	m := marshalerPool.Get().(*marshaler)
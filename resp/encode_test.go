@@ -5,9 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/segmentio/objconv/objutil"
 )
 
 var respEncodeTests = []struct {
@@ -109,6 +112,79 @@ func TestStreamEncoder(t *testing.T) {
 	}
 }
 
+var resp3EncodeTests = []struct {
+	v interface{}
+	s string
+}{
+	{nil, "_\r\n"},
+
+	{true, "#t\r\n"},
+	{false, "#f\r\n"},
+
+	{0, ":0\r\n"},
+	{-1, ":-1\r\n"},
+	{uint64(objutil.Int64Max), ":9223372036854775807\r\n"},
+	{uint64(objutil.Uint64Max), "(18446744073709551615\r\n"},
+
+	{0.0, ",0\r\n"},
+	{0.5, ",0.5\r\n"},
+	{math.Inf(+1), ",inf\r\n"},
+	{math.Inf(-1), ",-inf\r\n"},
+	{math.NaN(), ",nan\r\n"},
+
+	{"", "+\r\n"},
+	{"Hello World!", "+Hello World!\r\n"},
+	{"Hello\nWorld!", "+Hello\nWorld!\r\n"},
+	{"Hello\r\nWorld!", "$13\r\nHello\r\nWorld!\r\n"},
+
+	{[]byte("Hello World!"), "$12\r\nHello World!\r\n"},
+
+	{errors.New("oops"), "-oops\r\n"},
+	{errors.New("A\r\nB"), "!4\r\nA\r\nB\r\n"},
+
+	{[]int{}, "*0\r\n"},
+	{[]int{1, 2, 3}, "*3\r\n:1\r\n:2\r\n:3\r\n"},
+
+	{struct{}{}, "%0\r\n"},
+	{struct{ A int }{42}, "%1\r\n+A\r\n:42\r\n"},
+	{map[string][]bool{"A": {true}}, "%1\r\n+A\r\n*1\r\n#t\r\n"},
+}
+
+func TestRESP3Encoder(t *testing.T) {
+	for _, test := range resp3EncodeTests {
+		t.Run(testName(test.s), func(t *testing.T) {
+			b := &bytes.Buffer{}
+
+			if err := NewRESP3Encoder(b).Encode(test.v); err != nil {
+				t.Error(err)
+			}
+
+			if s := b.String(); s != test.s {
+				t.Errorf("%#v", s)
+			}
+		})
+	}
+}
+
+func TestRESP3StreamEncoder(t *testing.T) {
+	b := &bytes.Buffer{}
+	e := NewRESP3StreamEncoder(b)
+
+	for _, v := range []interface{}{1, "A", nil} {
+		if err := e.Encode(v); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if err := e.Close(); err != nil {
+		t.Error(err)
+	}
+
+	if s := b.String(); s != "*?\r\n:1\r\n+A\r\n_\r\n.\r\n" {
+		t.Errorf("%#v", s)
+	}
+}
+
 func BenchmarkEncoder(b *testing.B) {
 	e := NewEncoder(ioutil.Discard)
 
@@ -1,7 +1,10 @@
 package resp
 
 import (
+	"bytes"
 	"io"
+	"strconv"
+	"strings"
 
 	"github.com/segmentio/objconv"
 )
@@ -10,6 +13,15 @@ import (
 var Codec = objconv.Codec{
 	NewEmitter: func(w io.Writer) objconv.Emitter { return NewEmitter(w) },
 	NewParser:  func(r io.Reader) objconv.Parser { return NewParser(r) },
+	Extensions: []string{".resp"},
+	Sniff:      sniff,
+}
+
+// RESP3Codec for the RESP3 format.
+var RESP3Codec = objconv.Codec{
+	NewEmitter: func(w io.Writer) objconv.Emitter { return NewRESP3Emitter(w) },
+	NewParser:  func(r io.Reader) objconv.Parser { return NewRESP3Parser(r) },
+	Sniff:      sniff3,
 }
 
 func init() {
@@ -20,4 +32,55 @@ func init() {
 	} {
 		objconv.Register(name, Codec)
 	}
+
+	for _, name := range [...]string{
+		"application/resp3",
+		"text/resp3",
+		"resp3",
+	} {
+		objconv.Register(name, RESP3Codec)
+	}
+}
+
+// sniff recognizes RESP values by their prefix and terminating CRLF.
+func sniff(b []byte) float64 {
+	return sniffPrefix(b, "+-:$*")
+}
+
+// sniff3 recognizes the types that were added in RESP3, the other types are
+// reported as RESP2 since both protocols share them.
+func sniff3(b []byte) float64 {
+	return sniffPrefix(b, "_,#!=(%~>|")
+}
+
+func sniffPrefix(b []byte, prefixes string) float64 {
+	if len(b) == 0 || strings.IndexByte(prefixes, b[0]) < 0 {
+		return 0
+	}
+
+	i := bytes.Index(b, []byte("\r\n"))
+
+	if i < 0 {
+		if bytes.IndexByte(b, '\n') >= 0 {
+			return 0
+		}
+		return 0.2
+	}
+
+	line := b[1:i]
+
+	switch b[0] {
+	case '$', '*', '=', '%', '~', '>', '|':
+		// Aggregates and bulk strings are followed by their length.
+		if _, err := strconv.Atoi(string(line)); err != nil && string(line) != "?" {
+			return 0
+		}
+		return 0.9
+	}
+
+	if bytes.IndexFunc(line, func(r rune) bool { return r < 0x20 }) >= 0 {
+		return 0
+	}
+
+	return 0.7
 }
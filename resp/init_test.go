@@ -0,0 +1,33 @@
+package resp
+
+import "testing"
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		s  string
+		c2 float64
+		c3 float64
+	}{
+		{"", 0, 0},
+		{"+OK\r\n", 0.7, 0},
+		{"-ERR oops\r\n", 0.7, 0},
+		{":42\r\n", 0.7, 0},
+		{"$5\r\nhello\r\n", 0.9, 0},
+		{"*2\r\n:1\r\n:2\r\n", 0.9, 0},
+		{"*oops\r\n", 0, 0},
+		{"+OK", 0.2, 0},
+		{"+OK\n", 0, 0},
+		{"%1\r\n+a\r\n:1\r\n", 0, 0.9},
+		{"_\r\n", 0, 0.7},
+		{`{"a":1}`, 0, 0},
+	}
+
+	for _, test := range tests {
+		if c := sniff([]byte(test.s)); c != test.c2 {
+			t.Errorf("RESP2 %q: expected confidence %g but got %g", test.s, test.c2, c)
+		}
+		if c := sniff3([]byte(test.s)); c != test.c3 {
+			t.Errorf("RESP3 %q: expected confidence %g but got %g", test.s, test.c3, c)
+		}
+	}
+}
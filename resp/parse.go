@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strconv"
 	"time"
 
 	"github.com/segmentio/objconv"
@@ -379,3 +380,505 @@ func bytesIndexCRLF(b []byte) int {
 	}
 	return -1
 }
+
+var (
+	stream = [...]byte{'?'}
+)
+
+// RESP3Parser implements a parser for the RESP3 protocol introduced in redis 6.
+//
+// The parser maps the RESP3 types onto objconv types: maps (%) become maps,
+// sets (~) and push messages (>) become arrays, doubles (,) are floats,
+// booleans (#) are bools, nulls (_) are nil values, verbatim strings (=) are
+// strings stripped of their format prefix, and blob errors (!) are errors.
+// Big numbers (() are exposed as integers when they fit in 64 bits, and as
+// strings otherwise.
+//
+// Attributes (|) are not exposed as values, they are decoded and made
+// available through the Attributes method.
+type RESP3Parser struct {
+	Parser
+
+	// Attributes received with the last reply that carried some.
+	attrs map[interface{}]interface{}
+
+	// This stack is used to keep track of the array and map lengths being
+	// parsed, streamed aggregates have a negative length.
+	stack []int
+	sback [16]int
+}
+
+func NewRESP3Parser(r io.Reader) *RESP3Parser {
+	p := &RESP3Parser{}
+	p.r = r
+	p.stack = p.sback[:0]
+	return p
+}
+
+func (p *RESP3Parser) Reset(r io.Reader) {
+	p.Parser.Reset(r)
+	p.i = 0
+	p.attrs = nil
+	p.stack = p.stack[:0]
+}
+
+// Attributes returns the attributes that were received with the last reply
+// carrying some, or nil if none were received.
+func (p *RESP3Parser) Attributes() map[interface{}]interface{} {
+	return p.attrs
+}
+
+func (p *RESP3Parser) ParseType() (t objconv.Type, err error) {
+	var line []byte
+
+	for {
+		if line, err = p.peekLine(); err != nil {
+			return
+		}
+
+		if len(line) == 0 {
+			err = errors.New("objconv/resp: invalid empty line at the beginning of the stream")
+			return
+		}
+
+		if line[0] != '|' {
+			break
+		}
+
+		if err = p.parseAttributes(); err != nil {
+			return
+		}
+	}
+
+	switch line[0] {
+	case '+', '=':
+		t = objconv.String
+
+	case '-', '!':
+		t = objconv.Error
+
+	case ':':
+		t = objconv.Int
+
+	case ',':
+		t = objconv.Float
+
+	case '#':
+		t = objconv.Bool
+
+	case '_':
+		t = objconv.Nil
+
+	case '(':
+		t = bigNumberType(line[1:])
+
+	case '$':
+		if bytes.Equal(line[1:], null[:]) {
+			t = objconv.Nil
+		} else {
+			t = objconv.Bytes
+		}
+
+	case '*':
+		if bytes.Equal(line[1:], null[:]) {
+			t = objconv.Nil
+		} else {
+			t = objconv.Array
+		}
+
+	case '~', '>':
+		t = objconv.Array
+
+	case '%':
+		t = objconv.Map
+
+	default:
+		err = fmt.Errorf("objconv/resp: expected type token but found %#v", string(line))
+	}
+
+	return
+}
+
+func (p *RESP3Parser) ParseNil() (err error) {
+	var line []byte
+
+	if line, err = p.peekLine(); err != nil {
+		return
+	}
+
+	if bytes.Equal(line, []byte{'_'}) {
+		p.skipLine()
+		return
+	}
+
+	return p.Parser.ParseNil()
+}
+
+func (p *RESP3Parser) ParseBool() (v bool, err error) {
+	var line []byte
+
+	if line, err = p.peekLine(); err != nil {
+		return
+	}
+
+	if len(line) != 2 || line[0] != '#' || (line[1] != 't' && line[1] != 'f') {
+		err = fmt.Errorf("objconv/resp: expected boolean value but found %#v", string(line))
+		return
+	}
+
+	v = line[1] == 't'
+	p.skipLine()
+	return
+}
+
+func (p *RESP3Parser) ParseInt() (v int64, err error) {
+	var line []byte
+
+	if line, err = p.peekLine(); err != nil {
+		return
+	}
+
+	if len(line) == 0 || (line[0] != ':' && line[0] != '(') {
+		err = fmt.Errorf("objconv/resp: expected integer value but found %#v", string(line))
+		return
+	}
+
+	if v, err = objutil.ParseInt(line[1:]); err != nil {
+		err = fmt.Errorf("objconv/resp: expected integer value but found %#v", string(line))
+		return
+	}
+
+	p.skipLine()
+	return
+}
+
+func (p *RESP3Parser) ParseUint() (v uint64, err error) {
+	var line []byte
+
+	if line, err = p.peekLine(); err != nil {
+		return
+	}
+
+	if len(line) == 0 || line[0] != '(' {
+		err = fmt.Errorf("objconv/resp: expected big number value but found %#v", string(line))
+		return
+	}
+
+	if v, err = parseUint(line[1:]); err != nil {
+		err = fmt.Errorf("objconv/resp: expected big number value but found %#v", string(line))
+		return
+	}
+
+	p.skipLine()
+	return
+}
+
+func (p *RESP3Parser) ParseFloat() (v float64, err error) {
+	var line []byte
+
+	if line, err = p.peekLine(); err != nil {
+		return
+	}
+
+	if len(line) == 0 || line[0] != ',' {
+		err = fmt.Errorf("objconv/resp: expected double value but found %#v", string(line))
+		return
+	}
+
+	if v, err = strconv.ParseFloat(string(line[1:]), 64); err != nil {
+		err = fmt.Errorf("objconv/resp: expected double value but found %#v", string(line))
+		return
+	}
+
+	p.skipLine()
+	return
+}
+
+func (p *RESP3Parser) ParseString() (v []byte, err error) {
+	var line []byte
+
+	if line, err = p.peekLine(); err != nil {
+		return
+	}
+
+	if len(line) == 0 {
+		err = errors.New("objconv/resp: invalid empty line at the beginning of a string value")
+		return
+	}
+
+	switch line[0] {
+	case '(':
+		v = line[1:]
+		p.skipLine()
+
+	case '=':
+		if v, err = p.parseBlob('='); err != nil {
+			return
+		}
+		// Verbatim strings are prefixed with a three bytes format followed
+		// by a colon, for example "txt:".
+		if len(v) < 4 || v[3] != ':' {
+			err = fmt.Errorf("objconv/resp: invalid verbatim string value %#v", string(v))
+			return
+		}
+		v = v[4:]
+
+	default:
+		v, err = p.Parser.ParseString()
+	}
+
+	return
+}
+
+func (p *RESP3Parser) ParseBytes() (v []byte, err error) {
+	return p.parseBlob('$')
+}
+
+func (p *RESP3Parser) ParseError() (v error, err error) {
+	var line []byte
+	var b []byte
+
+	if line, err = p.peekLine(); err != nil {
+		return
+	}
+
+	if len(line) == 0 || line[0] != '!' {
+		return p.Parser.ParseError()
+	}
+
+	if b, err = p.parseBlob('!'); err != nil {
+		return
+	}
+
+	v = NewError(string(b))
+	return
+}
+
+func (p *RESP3Parser) ParseArrayBegin() (n int, err error) {
+	if n, err = p.parseAggregate('*', '~', '>'); err == nil {
+		p.stack = append(p.stack, n)
+	}
+	return
+}
+
+func (p *RESP3Parser) ParseArrayEnd(n int) (err error) {
+	return p.parseAggregateEnd()
+}
+
+func (p *RESP3Parser) ParseArrayNext(n int) (err error) {
+	return p.parseAggregateNext()
+}
+
+func (p *RESP3Parser) ParseMapBegin() (n int, err error) {
+	if n, err = p.parseAggregate('%'); err == nil {
+		p.stack = append(p.stack, n)
+	}
+	return
+}
+
+func (p *RESP3Parser) ParseMapEnd(n int) (err error) {
+	return p.parseAggregateEnd()
+}
+
+func (p *RESP3Parser) ParseMapValue(n int) (err error) {
+	return
+}
+
+func (p *RESP3Parser) ParseMapNext(n int) (err error) {
+	return p.parseAggregateNext()
+}
+
+func (p *RESP3Parser) parseAttributes() (err error) {
+	var n int
+
+	if n, err = p.parseAggregate('|'); err != nil {
+		return
+	}
+
+	p.stack = append(p.stack, n)
+	d := objconv.NewDecoder(p)
+	attrs := make(map[interface{}]interface{})
+
+	for i := 0; n < 0 || i < n; i++ {
+		var k interface{}
+		var v interface{}
+
+		if err = p.parseAggregateNext(); err != nil {
+			if err == objconv.End {
+				break
+			}
+			return
+		}
+
+		if err = d.Decode(&k); err != nil {
+			return
+		}
+
+		if err = d.Decode(&v); err != nil {
+			return
+		}
+
+		if b, ok := k.([]byte); ok { // []byte is not hashable
+			k = string(b)
+		}
+
+		attrs[k] = v
+	}
+
+	if err = p.parseAggregateEnd(); err != nil {
+		return
+	}
+
+	p.attrs = attrs
+	return
+}
+
+func (p *RESP3Parser) parseAggregate(tokens ...byte) (n int, err error) {
+	var line []byte
+	var size int64
+
+	if line, err = p.peekLine(); err != nil {
+		return
+	}
+
+	if len(line) == 0 || bytes.IndexByte(tokens, line[0]) < 0 {
+		goto failure
+	}
+
+	if bytes.Equal(line[1:], stream[:]) {
+		n = -1
+	} else {
+		if size, err = objutil.ParseInt(line[1:]); err != nil || size < 0 || size > int64(objutil.IntMax) {
+			goto failure
+		}
+		n = int(size)
+	}
+
+	p.skipLine()
+	return
+failure:
+	err = fmt.Errorf("objconv/resp: expected aggregate value but found %#v", string(line))
+	return
+}
+
+func (p *RESP3Parser) parseAggregateNext() (err error) {
+	if p.stack[len(p.stack)-1] < 0 {
+		var line []byte
+
+		if line, err = p.peekLine(); err != nil {
+			return
+		}
+
+		if len(line) == 1 && line[0] == '.' {
+			err = objconv.End
+		}
+	}
+	return
+}
+
+func (p *RESP3Parser) parseAggregateEnd() (err error) {
+	i := len(p.stack) - 1
+
+	if p.stack[i] < 0 {
+		var line []byte
+
+		if line, err = p.peekLine(); err != nil {
+			return
+		}
+
+		if len(line) != 1 || line[0] != '.' {
+			err = fmt.Errorf("objconv/resp: expected end of streamed aggregate but found %#v", string(line))
+			return
+		}
+
+		p.skipLine()
+	}
+
+	p.stack = p.stack[:i]
+	return
+}
+
+// parseBlob parses blob values (blob strings, verbatim strings, blob errors),
+// including streamed strings which are made of a sequence of chunks.
+func (p *RESP3Parser) parseBlob(token byte) (v []byte, err error) {
+	var line []byte
+	var size int64
+
+	if line, err = p.peekLine(); err != nil {
+		return
+	}
+
+	if len(line) == 0 || line[0] != token {
+		goto failure
+	}
+
+	if token == '$' && bytes.Equal(line[1:], stream[:]) {
+		p.skipLine()
+		return p.parseChunks()
+	}
+
+	if size, err = objutil.ParseInt(line[1:]); err != nil || size < 0 || size > int64(objutil.IntMax) {
+		goto failure
+	}
+	p.skipLine()
+
+	if v, err = p.peekChunk(int(size)); err != nil {
+		return
+	}
+	p.n += len(v) + 2
+	return
+failure:
+	err = fmt.Errorf("objconv/resp: expected blob value but found %#v", string(line))
+	return
+}
+
+func (p *RESP3Parser) parseChunks() (v []byte, err error) {
+	var line []byte
+	var size int64
+	var chunk []byte
+
+	for {
+		if line, err = p.peekLine(); err != nil {
+			return
+		}
+
+		if len(line) == 0 || line[0] != ';' {
+			err = fmt.Errorf("objconv/resp: expected streamed string chunk but found %#v", string(line))
+			return
+		}
+
+		if size, err = objutil.ParseInt(line[1:]); err != nil || size < 0 || size > int64(objutil.IntMax) {
+			err = fmt.Errorf("objconv/resp: invalid streamed string chunk length %#v", string(line))
+			return
+		}
+		p.skipLine()
+
+		if size == 0 {
+			return
+		}
+
+		if chunk, err = p.peekChunk(int(size)); err != nil {
+			return
+		}
+		p.n += len(chunk) + 2
+
+		// The chunk points into the read buffer which may be reused when the
+		// next chunk is loaded, so the value is accumulated in a separate
+		// slice.
+		v = append(v, chunk...)
+	}
+}
+
+func parseUint(b []byte) (uint64, error) {
+	return strconv.ParseUint(string(b), 10, 64)
+}
+
+func bigNumberType(b []byte) objconv.Type {
+	if _, err := objutil.ParseInt(b); err == nil {
+		return objconv.Int
+	}
+	if _, err := parseUint(b); err == nil {
+		return objconv.Uint
+	}
+	return objconv.String
+}
@@ -0,0 +1,218 @@
+package resp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/segmentio/objconv"
+)
+
+// CommandReader reads redis commands from an input stream, it is intended to
+// be used to implement servers speaking the redis protocol.
+//
+// Both the multibulk and inline command formats are supported.
+type CommandReader struct {
+	p *Parser
+}
+
+// NewCommandReader returns a new CommandReader which reads commands from r.
+func NewCommandReader(r io.Reader) *CommandReader {
+	return &CommandReader{p: NewParser(r)}
+}
+
+// ReadCommand reads the next command from the input stream and returns its
+// arguments, the first one being the command name.
+//
+// The method returns io.EOF when the input stream has no more commands.
+func (cr *CommandReader) ReadCommand() (args []string, err error) {
+	var line []byte
+
+	for {
+		if line, err = cr.p.peekLine(); err != nil {
+			if err == io.EOF && cr.Buffered() != 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return
+		}
+
+		if len(line) != 0 {
+			break
+		}
+
+		cr.p.skipLine() // redis ignores empty inline commands
+	}
+
+	if line[0] != '*' {
+		if args, err = splitArgs(line); err == nil {
+			cr.p.skipLine()
+
+			if len(args) == 0 {
+				return cr.ReadCommand()
+			}
+		}
+		return
+	}
+
+	if err = (objconv.Decoder{Parser: cr.p}).Decode(&args); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+
+	if len(args) == 0 {
+		err = errors.New("objconv/resp: invalid command with no arguments")
+	}
+
+	return
+}
+
+// Buffered returns the number of bytes that were read from the input stream
+// but not consumed yet. Servers may use it to delay flushing replies until all
+// pipelined commands were processed.
+func (cr *CommandReader) Buffered() int {
+	return len(cr.p.s) - cr.p.n
+}
+
+// splitArgs splits an inline command into its arguments, following the
+// quoting rules of redis.
+func splitArgs(line []byte) (args []string, err error) {
+	i := 0
+	n := len(line)
+
+	for {
+		for i != n && isSpace(line[i]) {
+			i++
+		}
+
+		if i == n {
+			return
+		}
+
+		var arg []byte
+
+		switch line[i] {
+		case '"':
+			i++
+
+			for {
+				if i == n {
+					err = errors.New("objconv/resp: unbalanced quotes in inline command")
+					return
+				}
+
+				c := line[i]
+				i++
+
+				if c == '"' {
+					break
+				}
+
+				if c == '\\' && i != n {
+					switch c = line[i]; c {
+					case 'n':
+						c = '\n'
+					case 'r':
+						c = '\r'
+					case 't':
+						c = '\t'
+					case 'b':
+						c = '\b'
+					case 'a':
+						c = '\a'
+					case 'x':
+						if (i+2) < n && isHex(line[i+1]) && isHex(line[i+2]) {
+							c = unhex(line[i+1])<<4 | unhex(line[i+2])
+							i += 2
+						}
+					}
+					i++
+				}
+
+				arg = append(arg, c)
+			}
+
+		case '\'':
+			i++
+
+			for {
+				if i == n {
+					err = errors.New("objconv/resp: unbalanced quotes in inline command")
+					return
+				}
+
+				c := line[i]
+				i++
+
+				if c == '\'' {
+					break
+				}
+
+				if c == '\\' && i != n && line[i] == '\'' {
+					c = '\''
+					i++
+				}
+
+				arg = append(arg, c)
+			}
+
+		default:
+			j := i
+			for i != n && !isSpace(line[i]) {
+				i++
+			}
+			arg = line[j:i]
+		}
+
+		if i != n && !isSpace(line[i]) {
+			err = fmt.Errorf("objconv/resp: closing quote must be followed by a space in inline command %#v", string(line))
+			return
+		}
+
+		args = append(args, string(arg))
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c >= 'a':
+		return c - 'a' + 10
+	case c >= 'A':
+		return c - 'A' + 10
+	default:
+		return c - '0'
+	}
+}
+
+// ReplyWriter writes redis replies to an output stream.
+//
+// Values are encoded with the RESP emitter, strings are written as simple
+// strings (for status replies like "OK"), byte slices as bulk strings, nil
+// values as null bulk strings, and errors as error replies.
+type ReplyWriter struct {
+	e *Emitter
+}
+
+// NewReplyWriter returns a new ReplyWriter which writes replies to w.
+func NewReplyWriter(w io.Writer) *ReplyWriter {
+	return &ReplyWriter{e: NewEmitter(w)}
+}
+
+// WriteReply writes v as a reply to the output stream.
+func (rw *ReplyWriter) WriteReply(v interface{}) error {
+	return objconv.Encoder{Emitter: rw.e}.Encode(v)
+}
+
+// WriteError writes an error reply to the output stream.
+func (rw *ReplyWriter) WriteError(err error) error {
+	return rw.e.EmitError(err)
+}
@@ -0,0 +1,480 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/segmentio/objconv"
+)
+
+// schema is the compiled form of a schema object.
+type schema struct {
+	// Boolean schemas are represented by always or never.
+	always bool
+	never  bool
+
+	ref *schema
+
+	types    []string
+	enum     []interface{}
+	hasEnum  bool
+	constant interface{}
+	hasConst bool
+
+	// Numbers, nil when unset.
+	minimum          interface{}
+	maximum          interface{}
+	exclusiveMinimum interface{}
+	exclusiveMaximum interface{}
+	multipleOf       interface{}
+
+	// Strings, -1 when unset.
+	minLength int
+	maxLength int
+	pattern   *regexp.Regexp
+	format    string
+
+	// Arrays, -1 when unset.
+	prefixItems []*schema
+	items       *schema // items following prefixItems
+	contains    *schema
+	minContains int
+	maxContains int
+	minItems    int
+	maxItems    int
+	uniqueItems bool
+
+	// Objects, -1 when unset.
+	properties           map[string]*schema
+	patternProperties    []patternSchema
+	additionalProperties *schema
+	propertyNames        *schema
+	required             []string
+	dependentRequired    map[string][]string
+	dependentSchemas     map[string]*schema
+	minProperties        int
+	maxProperties        int
+
+	allOf []*schema
+	anyOf []*schema
+	oneOf []*schema
+	not   *schema
+	cond  *schema // if
+	then  *schema
+	els   *schema
+}
+
+type patternSchema struct {
+	pattern *regexp.Regexp
+	schema  *schema
+}
+
+// compiler compiles the schemas of a document, schemas are cached by their
+// JSON Pointer so references, including recursive ones, share them.
+type compiler struct {
+	doc   interface{}
+	cache map[string]*schema
+}
+
+func (c *compiler) compile(v interface{}, path string) (s *schema, err error) {
+	if s = c.cache[path]; s != nil {
+		return
+	}
+
+	s = &schema{
+		minLength:     -1,
+		maxLength:     -1,
+		minContains:   -1,
+		maxContains:   -1,
+		minItems:      -1,
+		maxItems:      -1,
+		minProperties: -1,
+		maxProperties: -1,
+	}
+	c.cache[path] = s
+
+	switch x := v.(type) {
+	case bool:
+		s.always, s.never = x, !x
+		return
+	case map[string]interface{}:
+		err = c.compileObject(s, x, path)
+		return
+	}
+
+	err = errorf(path, "a schema must be an object or a boolean")
+	return
+}
+
+func (c *compiler) compileObject(s *schema, m map[string]interface{}, path string) (err error) {
+	k := keywords{m: m, path: path, c: c}
+
+	if ref, ok := m["$ref"]; ok {
+		s.ref, err = c.resolve(ref, appendPath(path, "$ref"))
+		if err != nil {
+			return
+		}
+	}
+
+	switch t := m["type"].(type) {
+	case nil:
+	case string:
+		s.types = []string{t}
+	default:
+		s.types = k.strings("type")
+	}
+
+	if s.enum, s.hasEnum = m["enum"].([]interface{}); !s.hasEnum && m["enum"] != nil {
+		k.fail("enum", "must be an array")
+	}
+	s.constant, s.hasConst = m["const"]
+
+	s.minimum = k.number("minimum")
+	s.maximum = k.number("maximum")
+
+	// Draft 4 has boolean exclusive bounds which apply to the minimum and
+	// maximum.
+	if b, ok := m["exclusiveMinimum"].(bool); ok {
+		if b {
+			s.exclusiveMinimum, s.minimum = s.minimum, nil
+		}
+	} else {
+		s.exclusiveMinimum = k.number("exclusiveMinimum")
+	}
+	if b, ok := m["exclusiveMaximum"].(bool); ok {
+		if b {
+			s.exclusiveMaximum, s.maximum = s.maximum, nil
+		}
+	} else {
+		s.exclusiveMaximum = k.number("exclusiveMaximum")
+	}
+
+	if s.multipleOf = k.number("multipleOf"); s.multipleOf != nil && toFloat(s.multipleOf) <= 0 {
+		k.fail("multipleOf", "must be greater than zero")
+	}
+
+	s.minLength = k.count("minLength")
+	s.maxLength = k.count("maxLength")
+	s.pattern = k.regexp("pattern")
+	s.format = k.string("format")
+
+	s.prefixItems = k.schemas("prefixItems")
+
+	switch m["items"].(type) {
+	case []interface{}: // draft 4 to 2019-09 tuples
+		s.prefixItems = k.schemas("items")
+		s.items = k.schema("additionalItems")
+	default:
+		s.items = k.schema("items")
+	}
+
+	s.contains = k.schema("contains")
+	s.minContains = k.count("minContains")
+	s.maxContains = k.count("maxContains")
+	s.minItems = k.count("minItems")
+	s.maxItems = k.count("maxItems")
+	s.uniqueItems = k.boolean("uniqueItems")
+
+	s.properties = k.schemaMap("properties")
+	s.additionalProperties = k.schema("additionalProperties")
+	s.propertyNames = k.schema("propertyNames")
+	s.required = k.strings("required")
+	s.minProperties = k.count("minProperties")
+	s.maxProperties = k.count("maxProperties")
+
+	patterns := k.schemaMap("patternProperties")
+
+	for _, pattern := range sortedKeys(patterns) {
+		re, e := regexp.Compile(pattern)
+		if e != nil {
+			k.fail("patternProperties", fmt.Sprintf("invalid pattern %q: %s", pattern, e))
+			continue
+		}
+		s.patternProperties = append(s.patternProperties, patternSchema{re, patterns[pattern]})
+	}
+
+	s.dependentRequired = k.stringsMap("dependentRequired")
+	s.dependentSchemas = k.schemaMap("dependentSchemas")
+
+	// Draft 7 and earlier mix both forms of dependencies in one keyword.
+	if deps, ok := m["dependencies"].(map[string]interface{}); ok {
+		d := keywords{m: deps, path: appendPath(path, "dependencies"), c: c}
+
+		for name, dep := range deps {
+			if _, ok := dep.([]interface{}); ok {
+				if s.dependentRequired == nil {
+					s.dependentRequired = make(map[string][]string)
+				}
+				s.dependentRequired[name] = d.strings(name)
+			} else {
+				if s.dependentSchemas == nil {
+					s.dependentSchemas = make(map[string]*schema)
+				}
+				s.dependentSchemas[name] = d.schema(name)
+			}
+		}
+
+		if d.err != nil {
+			return d.err
+		}
+	}
+
+	s.allOf = k.schemas("allOf")
+	s.anyOf = k.schemas("anyOf")
+	s.oneOf = k.schemas("oneOf")
+	s.not = k.schema("not")
+	s.cond = k.schema("if")
+	s.then = k.schema("then")
+	s.els = k.schema("else")
+
+	return k.err
+}
+
+// resolve returns the schema referenced by ref, which must be a local JSON
+// Pointer.
+func (c *compiler) resolve(ref interface{}, path string) (*schema, error) {
+	s, ok := ref.(string)
+	if !ok {
+		return nil, errorf(path, "must be a string")
+	}
+
+	if !strings.HasPrefix(s, "#") {
+		return nil, errorf(path, fmt.Sprintf("unsupported reference %q, only local references are supported", s))
+	}
+
+	ptr, err := url.PathUnescape(s[1:])
+	if err != nil {
+		return nil, errorf(path, fmt.Sprintf("invalid reference %q", s))
+	}
+
+	tokens, err := objconv.ParsePointer(ptr)
+	if err != nil {
+		return nil, errorf(path, fmt.Sprintf("invalid reference %q", s))
+	}
+
+	if cached := c.cache[ptr]; cached != nil {
+		return cached, nil
+	}
+
+	v := c.doc
+
+	for _, token := range tokens {
+		switch x := v.(type) {
+		case map[string]interface{}:
+			v, ok = x[token]
+		case []interface{}:
+			i, e := objconv.ParsePointerIndex(token)
+			ok = e == nil && i < len(x)
+			if ok {
+				v = x[i]
+			}
+		default:
+			ok = false
+		}
+
+		if !ok {
+			return nil, errorf(path, fmt.Sprintf("reference %q not found", s))
+		}
+	}
+
+	return c.compile(v, ptr)
+}
+
+// keywords provides helpers to load the keywords of a schema object, the
+// first error is retained in err.
+type keywords struct {
+	m    map[string]interface{}
+	path string
+	c    *compiler
+	err  error
+}
+
+func (k *keywords) fail(key string, msg string) {
+	if k.err == nil {
+		k.err = errorf(appendPath(k.path, key), msg)
+	}
+}
+
+func (k *keywords) number(key string) interface{} {
+	v, ok := k.m[key]
+	if !ok {
+		return nil
+	}
+	if !isNumber(v) {
+		k.fail(key, "must be a number")
+		return nil
+	}
+	return v
+}
+
+func (k *keywords) count(key string) int {
+	v, ok := k.m[key]
+	if !ok {
+		return -1
+	}
+	if !isNumber(v) {
+		k.fail(key, "must be a non-negative integer")
+		return -1
+	}
+	if f := toFloat(v); f < 0 || f != math.Trunc(f) {
+		k.fail(key, "must be a non-negative integer")
+		return -1
+	} else if f > math.MaxInt32 {
+		return math.MaxInt32
+	} else {
+		return int(f)
+	}
+}
+
+func (k *keywords) boolean(key string) bool {
+	v, ok := k.m[key]
+	if !ok {
+		return false
+	}
+	b, ok := v.(bool)
+	if !ok {
+		k.fail(key, "must be a boolean")
+	}
+	return b
+}
+
+func (k *keywords) string(key string) string {
+	v, ok := k.m[key]
+	if !ok {
+		return ""
+	}
+	s, ok := v.(string)
+	if !ok {
+		k.fail(key, "must be a string")
+	}
+	return s
+}
+
+func (k *keywords) strings(key string) (list []string) {
+	v, ok := k.m[key]
+	if !ok {
+		return
+	}
+	a, ok := v.([]interface{})
+	if !ok {
+		k.fail(key, "must be an array of strings")
+		return
+	}
+	for _, e := range a {
+		s, ok := e.(string)
+		if !ok {
+			k.fail(key, "must be an array of strings")
+			return
+		}
+		list = append(list, s)
+	}
+	return
+}
+
+func (k *keywords) stringsMap(key string) map[string][]string {
+	v, ok := k.m[key]
+	if !ok {
+		return nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		k.fail(key, "must be an object")
+		return nil
+	}
+	sub := keywords{m: m, path: appendPath(k.path, key), c: k.c}
+	res := make(map[string][]string, len(m))
+	for name := range m {
+		res[name] = sub.strings(name)
+	}
+	if sub.err != nil && k.err == nil {
+		k.err = sub.err
+	}
+	return res
+}
+
+func (k *keywords) regexp(key string) *regexp.Regexp {
+	s := k.string(key)
+	if s == "" {
+		return nil
+	}
+	re, err := regexp.Compile(s)
+	if err != nil {
+		k.fail(key, fmt.Sprintf("invalid pattern %q: %s", s, err))
+	}
+	return re
+}
+
+func (k *keywords) schema(key string) *schema {
+	v, ok := k.m[key]
+	if !ok {
+		return nil
+	}
+	s, err := k.c.compile(v, appendPath(k.path, key))
+	if err != nil && k.err == nil {
+		k.err = err
+	}
+	return s
+}
+
+func (k *keywords) schemas(key string) (list []*schema) {
+	v, ok := k.m[key]
+	if !ok {
+		return
+	}
+	a, ok := v.([]interface{})
+	if !ok {
+		k.fail(key, "must be an array of schemas")
+		return
+	}
+	path := appendPath(k.path, key)
+	for i, e := range a {
+		s, err := k.c.compile(e, appendPath(path, strconv.Itoa(i)))
+		if err != nil {
+			if k.err == nil {
+				k.err = err
+			}
+			return
+		}
+		list = append(list, s)
+	}
+	return
+}
+
+func (k *keywords) schemaMap(key string) map[string]*schema {
+	v, ok := k.m[key]
+	if !ok {
+		return nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		k.fail(key, "must be an object")
+		return nil
+	}
+	sub := keywords{m: m, path: appendPath(k.path, key), c: k.c}
+	res := make(map[string]*schema, len(m))
+	for name := range m {
+		res[name] = sub.schema(name)
+	}
+	if sub.err != nil && k.err == nil {
+		k.err = sub.err
+	}
+	return res
+}
+
+func sortedKeys(m map[string]*schema) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func errorf(path string, msg string) error {
+	return &Error{Path: path, Msg: msg}
+}
@@ -0,0 +1,127 @@
+package schema
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/segmentio/objconv"
+)
+
+// node is the representation of values being validated, it retains the
+// position of values in their input.
+type node struct {
+	offset int64       // offset of the value in the input, -1 if unknown
+	value  interface{} // value of scalars
+	kind   kind
+	elems  []*node // elements of arrays, values of objects
+	keys   []string
+}
+
+type kind int
+
+const (
+	scalar kind = iota
+	array
+	object
+)
+
+// DecodeValue satisfies the objconv.ValueDecoder interface, the parser is
+// used directly to record the offsets of values.
+func (n *node) DecodeValue(d objconv.Decoder) error {
+	t, err := d.Parser.ParseType()
+	if err != nil {
+		return err
+	}
+
+	n.offset = -1
+	if p, ok := d.Parser.(objconv.OffsetParser); ok {
+		n.offset = p.Offset()
+	}
+
+	switch t {
+	case objconv.Array:
+		n.kind = array
+		return d.DecodeArray(func(d objconv.Decoder) error {
+			e := &node{}
+			n.elems = append(n.elems, e)
+			return d.Decode(e)
+		})
+
+	case objconv.Map:
+		n.kind = object
+		return d.DecodeMap(func(kd objconv.Decoder, vd objconv.Decoder) error {
+			var k interface{}
+			if err := kd.Decode(&k); err != nil {
+				return err
+			}
+			e := &node{}
+			n.keys = append(n.keys, objconv.KeyString(k))
+			n.elems = append(n.elems, e)
+			return vd.Decode(e)
+		})
+	}
+
+	return d.Decode(&n.value)
+}
+
+// newNode builds the node tree of v, which has no known position.
+func newNode(v interface{}) *node {
+	n := &node{offset: -1}
+
+	switch x := v.(type) {
+	case []byte:
+		n.value = x
+		return n
+	case []interface{}:
+		n.kind = array
+		for _, e := range x {
+			n.elems = append(n.elems, newNode(e))
+		}
+		return n
+	}
+
+	switch r := reflect.ValueOf(v); r.Kind() {
+	case reflect.Slice, reflect.Array:
+		n.kind = array
+		for i := 0; i != r.Len(); i++ {
+			n.elems = append(n.elems, newNode(r.Index(i).Interface()))
+		}
+
+	case reflect.Map:
+		keys := r.MapKeys()
+		sort.Slice(keys, func(i int, j int) bool {
+			return objconv.KeyString(keys[i].Interface()) < objconv.KeyString(keys[j].Interface())
+		})
+		n.kind = object
+		for _, k := range keys {
+			n.keys = append(n.keys, objconv.KeyString(k.Interface()))
+			n.elems = append(n.elems, newNode(r.MapIndex(k).Interface()))
+		}
+
+	default:
+		n.value = normalize(v)
+	}
+
+	return n
+}
+
+// interfaceValue returns the generic representation of the value of n.
+func (n *node) interfaceValue() interface{} {
+	switch n.kind {
+	case array:
+		a := make([]interface{}, len(n.elems))
+		for i, e := range n.elems {
+			a[i] = e.interfaceValue()
+		}
+		return a
+
+	case object:
+		m := make(map[string]interface{}, len(n.elems))
+		for i, e := range n.elems {
+			m[n.keys[i]] = e.interfaceValue()
+		}
+		return m
+	}
+
+	return normalize(n.value)
+}
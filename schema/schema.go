@@ -0,0 +1,123 @@
+// Package schema implements the validation of values against JSON Schema
+// documents, independently of the format they are decoded from.
+//
+// Values are validated while being decoded from an objconv.Parser, so YAML,
+// msgpack or CBOR inputs can be checked with the same schemas as JSON
+// documents. Violations report the JSON Pointer of the values that failed
+// validation, and their offset in the input when the parser implements the
+// objconv.OffsetParser interface.
+//
+// Schemas are compiled from any generic value, including values decoded from
+// formats other than JSON. The package supports the keywords of drafts 4 to
+// 2020-12 applying to values, with the following limitations:
+//
+//   - references must be local JSON Pointers ("#/$defs/name"),
+//   - unevaluatedItems and unevaluatedProperties are ignored,
+//   - patterns use the syntax of the regexp package.
+//
+// Types that JSON doesn't have are mapped to the closest JSON Schema type:
+// byte slices, times and durations are strings, like in their JSON
+// representation.
+package schema
+
+import (
+	"fmt"
+
+	"github.com/segmentio/objconv"
+)
+
+// Schema is a compiled JSON Schema, it is safe to use concurrently.
+type Schema struct {
+	root *schema
+}
+
+// Compile compiles the JSON Schema represented by v, which is usually a value
+// decoded by objconv.
+func Compile(v interface{}) (*Schema, error) {
+	c := &compiler{
+		doc:   newNode(v).interfaceValue(),
+		cache: make(map[string]*schema),
+	}
+
+	root, err := c.compile(c.doc, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Schema{root: root}, nil
+}
+
+// Load decodes the next value from p and compiles it as a schema.
+func Load(p objconv.Parser) (*Schema, error) {
+	var v interface{}
+
+	if err := objconv.NewDecoder(p).Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return Compile(v)
+}
+
+// Validate validates v against the schema and returns the list of violations,
+// which is empty if v is valid. Violations of values passed to Validate have
+// no offsets.
+func (s *Schema) Validate(v interface{}) []Violation {
+	return s.validate(newNode(v))
+}
+
+// ValidateParser decodes the next value from p and validates it against the
+// schema. The returned error is not nil if the value could not be decoded.
+func (s *Schema) ValidateParser(p objconv.Parser) ([]Violation, error) {
+	n := &node{}
+
+	if err := objconv.NewDecoder(p).Decode(n); err != nil {
+		return nil, err
+	}
+
+	return s.validate(n), nil
+}
+
+func (s *Schema) validate(n *node) []Violation {
+	v := &validation{}
+	s.root.validate(v, n, "")
+	return v.violations
+}
+
+// Violation represents a value which failed validation.
+type Violation struct {
+	// Path is the JSON Pointer (RFC 6901) of the value, the empty string
+	// represents the root value.
+	Path string
+
+	// Offset is the offset of the value in the input, or -1 if unknown.
+	Offset int64
+
+	// Keyword is the schema keyword which failed.
+	Keyword string
+
+	// Message describes the violation.
+	Message string
+}
+
+func (v Violation) String() string {
+	path := v.Path
+	if path == "" {
+		path = "(root)"
+	}
+	return fmt.Sprintf("%s: %s", path, v.Message)
+}
+
+// Error is returned when compiling an invalid schema.
+type Error struct {
+	Path string // JSON Pointer of the invalid keyword in the schema
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("objconv/schema: %s (at %q)", e.Msg, e.Path)
+}
+
+// appendPath returns the JSON Pointer of key within path.
+func appendPath(path string, key string) string {
+	return path + "/" + objconv.EscapePointer(key)
+}
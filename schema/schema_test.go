@@ -0,0 +1,199 @@
+package schema
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/objconv/json"
+	"github.com/segmentio/objconv/msgpack"
+)
+
+func compileJSON(t *testing.T, s string) *Schema {
+	schema, err := Load(json.NewParser(strings.NewReader(s)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+// violations returns the paths and keywords of violations.
+func violations(list []Violation) []string {
+	res := []string{}
+	for _, v := range list {
+		res = append(res, v.Path+" "+v.Keyword)
+	}
+	return res
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		schema     string
+		value      string
+		violations []string
+	}{
+		{`true`, `{"a":1}`, []string{}},
+		{`false`, `1`, []string{" false"}},
+		{`{"type":"integer"}`, `1`, []string{}},
+		{`{"type":"integer"}`, `1.0`, []string{}},
+		{`{"type":"integer"}`, `1.5`, []string{" type"}},
+		{`{"type":"number"}`, `1`, []string{}},
+		{`{"type":["string","null"]}`, `null`, []string{}},
+		{`{"type":["string","null"]}`, `[]`, []string{" type"}},
+		{`{"enum":[1,"a",{"b":[true]}]}`, `{"b":[true]}`, []string{}},
+		{`{"enum":[1,"a"]}`, `1.0`, []string{}},
+		{`{"enum":[1,"a"]}`, `"b"`, []string{" enum"}},
+		{`{"const":"a"}`, `"b"`, []string{" const"}},
+		{`{"minimum":1,"maximum":3}`, `0`, []string{" minimum"}},
+		{`{"minimum":1,"maximum":3}`, `3`, []string{}},
+		{`{"exclusiveMinimum":1,"exclusiveMaximum":3}`, `3`, []string{" exclusiveMaximum"}},
+		{`{"minimum":1,"exclusiveMinimum":true}`, `1`, []string{" exclusiveMinimum"}},
+		{`{"multipleOf":3}`, `9`, []string{}},
+		{`{"multipleOf":0.1}`, `0.3`, []string{}},
+		{`{"multipleOf":3}`, `10`, []string{" multipleOf"}},
+		{`{"minLength":2,"maxLength":3}`, `"é"`, []string{" minLength"}},
+		{`{"maxLength":3}`, `"abcd"`, []string{" maxLength"}},
+		{`{"pattern":"^a+$"}`, `"aab"`, []string{" pattern"}},
+		{`{"format":"date-time"}`, `"2024-01-02T03:04:05Z"`, []string{}},
+		{`{"format":"date"}`, `"2024-13-02"`, []string{" format"}},
+		{`{"format":"email"}`, `"bob@example.com"`, []string{}},
+		{`{"format":"ipv4"}`, `"::1"`, []string{" format"}},
+		{`{"format":"unknown"}`, `"anything"`, []string{}},
+		{`{"items":{"type":"string"}}`, `["a",1,"b",2]`, []string{"/1 type", "/3 type"}},
+		{`{"prefixItems":[{"type":"string"}],"items":false}`, `["a",1]`, []string{"/1 false"}},
+		{`{"items":[{"type":"string"}],"additionalItems":{"type":"integer"}}`, `["a",1,"b"]`, []string{"/2 type"}},
+		{`{"minItems":1,"maxItems":2}`, `[]`, []string{" minItems"}},
+		{`{"uniqueItems":true}`, `[1,{"a":1},1.0]`, []string{" uniqueItems"}},
+		{`{"contains":{"const":1}}`, `[2,3]`, []string{" contains"}},
+		{`{"contains":{"const":1},"maxContains":1}`, `[1,1]`, []string{" maxContains"}},
+		{
+			`{"properties":{"a":{"type":"string"}},"required":["a","b"],"additionalProperties":false}`,
+			`{"a":1,"c/d":2}`,
+			[]string{" required", "/a type", "/c~1d additionalProperties"},
+		},
+		{`{"patternProperties":{"^x-":{"type":"integer"}},"additionalProperties":{"type":"string"}}`, `{"x-a":1,"b":2}`, []string{"/b type"}},
+		{`{"propertyNames":{"maxLength":2}}`, `{"abc":1}`, []string{"/abc propertyNames"}},
+		{`{"minProperties":1}`, `{}`, []string{" minProperties"}},
+		{`{"dependencies":{"a":["b"],"c":{"required":["d"]}}}`, `{"a":1,"c":2}`, []string{" dependentRequired", " required"}},
+		{`{"allOf":[{"type":"integer"},{"minimum":2}]}`, `1`, []string{" minimum"}},
+		{`{"anyOf":[{"type":"string"},{"minimum":2}]}`, `1`, []string{" anyOf"}},
+		{`{"oneOf":[{"type":"integer"},{"minimum":2}]}`, `3`, []string{" oneOf"}},
+		{`{"not":{"type":"integer"}}`, `3`, []string{" not"}},
+		{`{"if":{"minimum":10},"then":{"multipleOf":10},"else":{"maximum":5}}`, `15`, []string{" multipleOf"}},
+		{`{"if":{"minimum":10},"then":{"multipleOf":10},"else":{"maximum":5}}`, `7`, []string{" maximum"}},
+		{
+			`{"$defs":{"node":{"type":"object","properties":{"next":{"$ref":"#/$defs/node"},"v":{"type":"integer"}}}},"$ref":"#/$defs/node"}`,
+			`{"v":1,"next":{"v":2,"next":{"v":"3"}}}`,
+			[]string{"/next/next/v type"},
+		},
+		{`{"definitions":{"a b":{"type":"string"}},"items":{"$ref":"#/definitions/a%20b"}}`, `["a",1]`, []string{"/1 type"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.schema+" "+test.value, func(t *testing.T) {
+			s := compileJSON(t, test.schema)
+
+			list, err := s.ValidateParser(json.NewParser(strings.NewReader(test.value)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if v := violations(list); !reflect.DeepEqual(v, test.violations) {
+				t.Errorf("\n<<< %q\n>>> %q", test.violations, v)
+			}
+		})
+	}
+}
+
+func TestValidateOffsets(t *testing.T) {
+	s := compileJSON(t, `{"properties":{"users":{"items":{"properties":{"age":{"minimum":0}}}}}}`)
+	src := `{"users": [{"age": 1}, {"age": -1}]}`
+
+	list, err := s.ValidateParser(json.NewParser(strings.NewReader(src)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list) != 1 {
+		t.Fatalf("expected one violation but got %v", list)
+	}
+
+	if v := list[0]; v.Path != "/users/1/age" || v.Offset != int64(strings.Index(src, "-1")) {
+		t.Errorf("bad violation: %+v", v)
+	}
+}
+
+func TestValidateBinaryTypes(t *testing.T) {
+	s := compileJSON(t, `{
+		"properties": {
+			"key": {"type": "string", "maxLength": 2},
+			"created": {"type": "string", "format": "date-time", "const": "2024-01-02T03:04:05Z"},
+			"2": {"type": "boolean"}
+		}
+	}`)
+
+	date := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	b, err := msgpack.Marshal(map[interface{}]interface{}{
+		"key":     []byte("abc"),
+		"created": date,
+		int64(2):  "true",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := s.ValidateParser(msgpack.NewParser(bytes.NewReader(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := violations(list); !reflect.DeepEqual(v, []string{"/2 type", "/key maxLength"}) && !reflect.DeepEqual(v, []string{"/key maxLength", "/2 type"}) {
+		t.Errorf("bad violations: %q", v)
+	}
+
+	// The msgpack parser reports the offsets of the values.
+	for _, v := range list {
+		if v.Offset <= 0 || v.Offset >= int64(len(b)) {
+			t.Errorf("unexpected offset: %+v", v)
+		}
+	}
+
+	// Go values are validated as well.
+	list = s.Validate(map[string]interface{}{"key": "ab", "created": date})
+	if len(list) != 0 {
+		t.Errorf("unexpected violations: %v", list)
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	tests := []struct {
+		schema string
+		path   string
+	}{
+		{`1`, ``},
+		{`{"minimum":"1"}`, `/minimum`},
+		{`{"minLength":-1}`, `/minLength`},
+		{`{"pattern":"("}`, `/pattern`},
+		{`{"properties":{"a":{"type":1}}}`, `/properties/a/type`},
+		{`{"items":[true,1]}`, `/items/1`},
+		{`{"$ref":"other.json#/a"}`, `/$ref`},
+		{`{"$ref":"#/missing"}`, `/$ref`},
+		{`{"multipleOf":0}`, `/multipleOf`},
+	}
+
+	for _, test := range tests {
+		_, err := Load(json.NewParser(strings.NewReader(test.schema)))
+
+		e, ok := err.(*Error)
+		if !ok {
+			t.Errorf("%s: expected a schema error but got %v", test.schema, err)
+			continue
+		}
+
+		if e.Path != test.path {
+			t.Errorf("%s: expected an error at %q: %s", test.schema, test.path, e)
+		}
+	}
+}
@@ -0,0 +1,418 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// validation accumulates the violations found while validating a value.
+type validation struct {
+	violations []Violation
+}
+
+func (v *validation) report(n *node, path string, keyword string, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{
+		Path:    path,
+		Offset:  n.offset,
+		Keyword: keyword,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// valid returns true if n is valid against s, violations are discarded.
+func (s *schema) valid(n *node, path string) bool {
+	v := &validation{}
+	s.validate(v, n, path)
+	return len(v.violations) == 0
+}
+
+func (s *schema) validate(v *validation, n *node, path string) {
+	switch {
+	case s.always:
+		return
+	case s.never:
+		v.report(n, path, "false", "no value is allowed")
+		return
+	}
+
+	if s.ref != nil {
+		s.ref.validate(v, n, path)
+	}
+
+	if len(s.types) != 0 && !s.validType(n) {
+		v.report(n, path, "type", "expected %s but found %s", typeList(s.types), typeOf(n))
+		return
+	}
+
+	if s.hasEnum || s.hasConst {
+		value := n.interfaceValue()
+
+		if s.hasConst && !equal(value, s.constant) {
+			v.report(n, path, "const", "value must be equal to the constant %s", describe(s.constant))
+		}
+
+		if s.hasEnum && !s.inEnum(value) {
+			v.report(n, path, "enum", "value must be one of %s", describe(s.enum))
+		}
+	}
+
+	switch n.kind {
+	case array:
+		s.validateArray(v, n, path)
+	case object:
+		s.validateObject(v, n, path)
+	default:
+		if isNumber(n.value) {
+			s.validateNumber(v, n, path)
+		} else if str, ok := stringOf(n.value); ok {
+			s.validateString(v, n, path, str)
+		}
+	}
+
+	for _, sub := range s.allOf {
+		sub.validate(v, n, path)
+	}
+
+	if len(s.anyOf) != 0 {
+		match := false
+		for _, sub := range s.anyOf {
+			if match = sub.valid(n, path); match {
+				break
+			}
+		}
+		if !match {
+			v.report(n, path, "anyOf", "value must match at least one schema of anyOf")
+		}
+	}
+
+	if len(s.oneOf) != 0 {
+		count := 0
+		for _, sub := range s.oneOf {
+			if sub.valid(n, path) {
+				count++
+			}
+		}
+		if count != 1 {
+			v.report(n, path, "oneOf", "value must match exactly one schema of oneOf but matched %d", count)
+		}
+	}
+
+	if s.not != nil && s.not.valid(n, path) {
+		v.report(n, path, "not", "value must not match the schema of not")
+	}
+
+	if s.cond != nil {
+		if s.cond.valid(n, path) {
+			if s.then != nil {
+				s.then.validate(v, n, path)
+			}
+		} else if s.els != nil {
+			s.els.validate(v, n, path)
+		}
+	}
+}
+
+func (s *schema) validType(n *node) bool {
+	t := typeOf(n)
+	for _, typ := range s.types {
+		if typ == t || (typ == "number" && t == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *schema) inEnum(value interface{}) bool {
+	for _, e := range s.enum {
+		if equal(value, e) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *schema) validateNumber(v *validation, n *node, path string) {
+	x := n.value
+
+	if s.minimum != nil {
+		if c, _ := compareNumbers(x, s.minimum); c < 0 {
+			v.report(n, path, "minimum", "%v is less than the minimum of %v", x, s.minimum)
+		}
+	}
+
+	if s.exclusiveMinimum != nil {
+		if c, _ := compareNumbers(x, s.exclusiveMinimum); c <= 0 {
+			v.report(n, path, "exclusiveMinimum", "%v must be greater than %v", x, s.exclusiveMinimum)
+		}
+	}
+
+	if s.maximum != nil {
+		if c, _ := compareNumbers(x, s.maximum); c > 0 {
+			v.report(n, path, "maximum", "%v is greater than the maximum of %v", x, s.maximum)
+		}
+	}
+
+	if s.exclusiveMaximum != nil {
+		if c, _ := compareNumbers(x, s.exclusiveMaximum); c >= 0 {
+			v.report(n, path, "exclusiveMaximum", "%v must be less than %v", x, s.exclusiveMaximum)
+		}
+	}
+
+	if s.multipleOf != nil && !isMultiple(x, s.multipleOf) {
+		v.report(n, path, "multipleOf", "%v is not a multiple of %v", x, s.multipleOf)
+	}
+}
+
+func isMultiple(x interface{}, m interface{}) bool {
+	if i, ok := x.(int64); ok {
+		if j, ok := m.(int64); ok {
+			return i%j == 0
+		}
+	}
+	if i, ok := x.(uint64); ok {
+		if j, ok := m.(int64); ok {
+			return i%uint64(j) == 0
+		}
+	}
+	q := toFloat(x) / toFloat(m)
+	return math.Abs(q-math.Round(q)) < 1e-9
+}
+
+func (s *schema) validateString(v *validation, n *node, path string, str string) {
+	if s.minLength >= 0 || s.maxLength >= 0 {
+		length := utf8.RuneCountInString(str)
+
+		if s.minLength >= 0 && length < s.minLength {
+			v.report(n, path, "minLength", "length of %d is less than the minimum of %d", length, s.minLength)
+		}
+
+		if s.maxLength >= 0 && length > s.maxLength {
+			v.report(n, path, "maxLength", "length of %d is greater than the maximum of %d", length, s.maxLength)
+		}
+	}
+
+	if s.pattern != nil && !s.pattern.MatchString(str) {
+		v.report(n, path, "pattern", "%q does not match the pattern %q", str, s.pattern.String())
+	}
+
+	if s.format != "" {
+		if check, ok := formats[s.format]; ok && !check(n.value, str) {
+			v.report(n, path, "format", "%q is not a valid %s", str, s.format)
+		}
+	}
+}
+
+func (s *schema) validateArray(v *validation, n *node, path string) {
+	count := len(n.elems)
+
+	if s.minItems >= 0 && count < s.minItems {
+		v.report(n, path, "minItems", "array has %d items, less than the minimum of %d", count, s.minItems)
+	}
+
+	if s.maxItems >= 0 && count > s.maxItems {
+		v.report(n, path, "maxItems", "array has %d items, more than the maximum of %d", count, s.maxItems)
+	}
+
+	for i, e := range n.elems {
+		elemPath := appendPath(path, strconv.Itoa(i))
+
+		if i < len(s.prefixItems) {
+			s.prefixItems[i].validate(v, e, elemPath)
+		} else if s.items != nil {
+			s.items.validate(v, e, elemPath)
+		}
+	}
+
+	if s.uniqueItems {
+		values := make([]interface{}, count)
+		for i, e := range n.elems {
+			values[i] = e.interfaceValue()
+		}
+	unique:
+		for i := range values {
+			for j := i + 1; j < count; j++ {
+				if equal(values[i], values[j]) {
+					v.report(n, path, "uniqueItems", "items at index %d and %d are equal", i, j)
+					break unique
+				}
+			}
+		}
+	}
+
+	if s.contains != nil {
+		matches := 0
+		for i, e := range n.elems {
+			if s.contains.valid(e, appendPath(path, strconv.Itoa(i))) {
+				matches++
+			}
+		}
+
+		minContains := s.minContains
+		if minContains < 0 {
+			minContains = 1
+		}
+
+		switch {
+		case matches < minContains:
+			v.report(n, path, "contains", "array has %d items matching the schema of contains, less than the minimum of %d", matches, minContains)
+		case s.maxContains >= 0 && matches > s.maxContains:
+			v.report(n, path, "maxContains", "array has %d items matching the schema of contains, more than the maximum of %d", matches, s.maxContains)
+		}
+	}
+}
+
+func (s *schema) validateObject(v *validation, n *node, path string) {
+	count := len(n.elems)
+
+	if s.minProperties >= 0 && count < s.minProperties {
+		v.report(n, path, "minProperties", "object has %d properties, less than the minimum of %d", count, s.minProperties)
+	}
+
+	if s.maxProperties >= 0 && count > s.maxProperties {
+		v.report(n, path, "maxProperties", "object has %d properties, more than the maximum of %d", count, s.maxProperties)
+	}
+
+	for _, name := range s.required {
+		if !n.has(name) {
+			v.report(n, path, "required", "missing required property %q", name)
+		}
+	}
+
+	for i, name := range n.keys {
+		e := n.elems[i]
+		propPath := appendPath(path, name)
+		matched := false
+
+		if sub, ok := s.properties[name]; ok {
+			sub.validate(v, e, propPath)
+			matched = true
+		}
+
+		for _, p := range s.patternProperties {
+			if p.pattern.MatchString(name) {
+				p.schema.validate(v, e, propPath)
+				matched = true
+			}
+		}
+
+		if !matched && s.additionalProperties != nil {
+			if s.additionalProperties.never {
+				v.report(e, propPath, "additionalProperties", "property %q is not allowed", name)
+			} else {
+				s.additionalProperties.validate(v, e, propPath)
+			}
+		}
+
+		if s.propertyNames != nil && !s.propertyNames.valid(&node{offset: e.offset, value: name}, propPath) {
+			v.report(e, propPath, "propertyNames", "property name %q is invalid", name)
+		}
+
+		for _, dep := range s.dependentRequired[name] {
+			if !n.has(dep) {
+				v.report(n, path, "dependentRequired", "property %q is required by %q", dep, name)
+			}
+		}
+
+		if sub := s.dependentSchemas[name]; sub != nil {
+			sub.validate(v, n, path)
+		}
+	}
+}
+
+// has returns true if the object n has the property name.
+func (n *node) has(name string) bool {
+	for _, k := range n.keys {
+		if k == name {
+			return true
+		}
+	}
+	return false
+}
+
+func typeList(types []string) string {
+	if len(types) == 1 {
+		return types[0]
+	}
+	return fmt.Sprintf("one of %v", types)
+}
+
+// describe returns a short representation of a schema value for messages.
+func describe(v interface{}) string {
+	s := fmt.Sprintf("%v", v)
+	if str, ok := v.(string); ok {
+		s = strconv.Quote(str)
+	}
+	if len(s) > 64 {
+		s = s[:61] + "..."
+	}
+	return s
+}
+
+// formats maps the names of formats to the functions validating them, both
+// the value and its string representation are passed to the functions.
+var formats = map[string]func(v interface{}, s string) bool{
+	"date-time": func(v interface{}, s string) bool {
+		if _, ok := v.(time.Time); ok {
+			return true
+		}
+		_, err := time.Parse(time.RFC3339Nano, s)
+		return err == nil
+	},
+	"date": func(_ interface{}, s string) bool {
+		_, err := time.Parse("2006-01-02", s)
+		return err == nil
+	},
+	"time": func(_ interface{}, s string) bool {
+		_, err := time.Parse("15:04:05.999999999Z07:00", s)
+		return err == nil
+	},
+	"duration": func(v interface{}, s string) bool {
+		if _, ok := v.(time.Duration); ok {
+			return true
+		}
+		return isoDuration.MatchString(s) && s != "P" && !strings.HasSuffix(s, "T")
+	},
+	"email": func(_ interface{}, s string) bool {
+		a, err := mail.ParseAddress(s)
+		return err == nil && a.Address == s
+	},
+	"hostname": func(_ interface{}, s string) bool {
+		return hostname.MatchString(s) && len(s) <= 253
+	},
+	"ipv4": func(_ interface{}, s string) bool {
+		ip := net.ParseIP(s)
+		return ip != nil && ip.To4() != nil && !strings.Contains(s, ":")
+	},
+	"ipv6": func(_ interface{}, s string) bool {
+		return net.ParseIP(s) != nil && strings.Contains(s, ":")
+	},
+	"uri": func(_ interface{}, s string) bool {
+		u, err := url.Parse(s)
+		return err == nil && u.IsAbs()
+	},
+	"uri-reference": func(_ interface{}, s string) bool {
+		_, err := url.Parse(s)
+		return err == nil
+	},
+	"uuid": func(_ interface{}, s string) bool {
+		return uuid.MatchString(s)
+	},
+	"regex": func(_ interface{}, s string) bool {
+		_, err := regexp.Compile(s)
+		return err == nil
+	},
+}
+
+var (
+	hostname    = regexp.MustCompile(`^(?i)[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*$`)
+	uuid        = regexp.MustCompile(`^(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	isoDuration = regexp.MustCompile(`^P(\d+W|(\d+Y)?(\d+M)?(\d+D)?(T(\d+H)?(\d+M)?(\d+(\.\d+)?S)?)?)$`)
+)
@@ -0,0 +1,206 @@
+package schema
+
+import (
+	"math"
+	"reflect"
+	"time"
+)
+
+// normalize converts numbers to int64, uint64 or float64, and errors to
+// strings.
+func normalize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case nil, bool, int64, uint64, float64, string, []byte, time.Time, time.Duration:
+		return v
+	case error:
+		return x.Error()
+	}
+
+	switch r := reflect.ValueOf(v); r.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return r.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return r.Uint()
+	case reflect.Float32, reflect.Float64:
+		return r.Float()
+	case reflect.String:
+		return r.String()
+	case reflect.Bool:
+		return r.Bool()
+	}
+
+	return v
+}
+
+// typeOf returns the JSON Schema type of n. Binary formats have types that
+// JSON doesn't support, byte slices, times and durations are strings like in
+// their JSON representation.
+func typeOf(n *node) string {
+	switch n.kind {
+	case array:
+		return "array"
+	case object:
+		return "object"
+	}
+
+	switch x := n.value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case int64, uint64:
+		return "integer"
+	case float64:
+		if x == math.Trunc(x) && !math.IsInf(x, 0) {
+			return "integer"
+		}
+		return "number"
+	}
+
+	return "string"
+}
+
+func isNumber(v interface{}) bool {
+	switch v.(type) {
+	case int64, uint64, float64:
+		return true
+	}
+	return false
+}
+
+// stringOf returns the string representation of values of the string type.
+func stringOf(v interface{}) (string, bool) {
+	switch x := v.(type) {
+	case string:
+		return x, true
+	case []byte:
+		return string(x), true
+	case time.Time:
+		return x.Format(time.RFC3339Nano), true
+	case time.Duration:
+		return x.String(), true
+	}
+	return "", false
+}
+
+// equal compares generic values, numbers are equal regardless of their type
+// and strings are compared with the string representation of values of the
+// string type.
+func equal(a interface{}, b interface{}) bool {
+	switch x := a.(type) {
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			if w, ok := y[k]; !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if isNumber(a) && isNumber(b) {
+		c, ok := compareNumbers(a, b)
+		return ok && c == 0
+	}
+
+	if s1, ok := stringOf(a); ok {
+		if s2, ok := stringOf(b); ok {
+			if t1, ok := a.(time.Time); ok {
+				if t2, err := time.Parse(time.RFC3339Nano, s2); err == nil {
+					return t1.Equal(t2)
+				}
+			}
+			if t2, ok := b.(time.Time); ok {
+				if t1, err := time.Parse(time.RFC3339Nano, s1); err == nil {
+					return t1.Equal(t2)
+				}
+			}
+			return s1 == s2
+		}
+		return false
+	}
+
+	return a == b
+}
+
+// compareNumbers returns the order of the numbers a and b, ok is false if one
+// of them is NaN.
+func compareNumbers(a interface{}, b interface{}) (c int, ok bool) {
+	switch x := a.(type) {
+	case int64:
+		switch y := b.(type) {
+		case int64:
+			return compareInts(x, y), true
+		case uint64:
+			if x < 0 || y > math.MaxInt64 {
+				return -1, true
+			}
+			return compareInts(x, int64(y)), true
+		}
+
+	case uint64:
+		switch y := b.(type) {
+		case uint64:
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		case int64:
+			c, ok = compareNumbers(b, a)
+			return -c, ok
+		}
+	}
+
+	f1, f2 := toFloat(a), toFloat(b)
+
+	switch {
+	case f1 < f2:
+		return -1, true
+	case f1 > f2:
+		return 1, true
+	case f1 == f2:
+		return 0, true
+	}
+
+	return 0, false
+}
+
+func compareInts(a int64, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func toFloat(v interface{}) float64 {
+	switch x := v.(type) {
+	case int64:
+		return float64(x)
+	case uint64:
+		return float64(x)
+	case float64:
+		return x
+	}
+	return math.NaN()
+}
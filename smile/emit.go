@@ -0,0 +1,316 @@
+package smile
+
+import (
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/segmentio/objconv/objutil"
+)
+
+// Emitter implements a Smile emitter that satisfies the objconv.Emitter
+// interface.
+//
+// The emitter writes the Smile header before the first value and enables
+// shared keys and shared string values, repeated keys and short strings are
+// written as back-references to their first occurrence. Byte slices are
+// written with the 7-bit encoding, unsigned integers that overflow the range
+// of int64 are written as big integers, and times, durations and errors are
+// written as strings.
+type Emitter struct {
+	w io.Writer
+	b []byte // output buffer
+
+	// This stack tracks the state of the arrays and objects being emitted,
+	// elements are true for objects where the next value is a key.
+	stack []bool
+
+	// Tables of keys and string values that were written and may be
+	// referenced by the next ones.
+	names  sharedTable
+	values sharedTable
+
+	// Set to true after the header was written.
+	header bool
+}
+
+// bufferSize is the size of the output buffer after which the emitter writes
+// even if it is in the middle of a value.
+const bufferSize = 16384
+
+func NewEmitter(w io.Writer) *Emitter {
+	return &Emitter{w: w}
+}
+
+func (e *Emitter) Reset(w io.Writer) {
+	e.w = w
+	e.b = e.b[:0]
+	e.stack = e.stack[:0]
+	e.names.reset()
+	e.values.reset()
+	e.header = false
+}
+
+func (e *Emitter) EmitNil() (err error) {
+	if e.isKey() {
+		return errKey
+	}
+	e.begin()
+	e.b = append(e.b, Null)
+	return e.end()
+}
+
+func (e *Emitter) EmitBool(v bool) (err error) {
+	if e.isKey() {
+		return e.emitKey(strconv.FormatBool(v))
+	}
+	e.begin()
+	if v {
+		e.b = append(e.b, True)
+	} else {
+		e.b = append(e.b, False)
+	}
+	return e.end()
+}
+
+func (e *Emitter) EmitInt(v int64, _ int) (err error) {
+	if e.isKey() {
+		return e.emitKey(strconv.FormatInt(v, 10))
+	}
+	e.begin()
+	switch {
+	case v >= -16 && v <= 15:
+		e.b = append(e.b, SmallInt|byte(zigzag(v)))
+	case v >= objutil.Int32Min && v <= objutil.Int32Max:
+		e.b = appendVInt(append(e.b, Int32), zigzag(v))
+	default:
+		e.b = appendVInt(append(e.b, Int64), zigzag(v))
+	}
+	return e.end()
+}
+
+func (e *Emitter) EmitUint(v uint64, _ int) (err error) {
+	if v <= objutil.Int64Max {
+		return e.EmitInt(int64(v), 64)
+	}
+	if e.isKey() {
+		return e.emitKey(strconv.FormatUint(v, 10))
+	}
+	e.begin()
+
+	// Big integers are encoded in two's complement, a leading zero byte is
+	// needed to keep the value positive.
+	b := [9]byte{0, byte(v >> 56), byte(v >> 48), byte(v >> 40), byte(v >> 32), byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+	e.b = appendVInt(append(e.b, BigInteger), uint64(len(b)))
+	e.b = appendBinary(e.b, b[:])
+	return e.end()
+}
+
+func (e *Emitter) EmitFloat(v float64, bitSize int) (err error) {
+	if e.isKey() {
+		return e.emitKey(strconv.FormatFloat(v, 'g', -1, bitSize))
+	}
+	e.begin()
+	if bitSize == 32 {
+		e.b = append7Bit(append(e.b, Float32), uint64(math.Float32bits(float32(v))), 32)
+	} else {
+		e.b = append7Bit(append(e.b, Float64), math.Float64bits(v), 64)
+	}
+	return e.end()
+}
+
+func (e *Emitter) EmitString(v string) (err error) {
+	if e.isKey() {
+		return e.emitKey(v)
+	}
+	e.begin()
+	e.b = e.appendString(e.b, v)
+	return e.end()
+}
+
+func (e *Emitter) EmitBytes(v []byte) (err error) {
+	if e.isKey() {
+		return errKey
+	}
+	e.begin()
+	e.b = appendVInt(append(e.b, Binary7Bit), uint64(len(v)))
+	e.b = appendBinary(e.b, v)
+	return e.end()
+}
+
+func (e *Emitter) EmitTime(v time.Time) error {
+	return e.EmitString(v.Format(time.RFC3339Nano))
+}
+
+func (e *Emitter) EmitDuration(v time.Duration) error {
+	return e.EmitString(v.String())
+}
+
+func (e *Emitter) EmitError(v error) error {
+	return e.EmitString(v.Error())
+}
+
+func (e *Emitter) EmitArrayBegin(_ int) (err error) {
+	if e.isKey() {
+		return errKey
+	}
+	e.begin()
+	e.b = append(e.b, ArrayBegin)
+	e.stack = append(e.stack, false)
+	return
+}
+
+func (e *Emitter) EmitArrayEnd() (err error) {
+	e.stack = e.stack[:len(e.stack)-1]
+	e.b = append(e.b, ArrayEnd)
+	return e.end()
+}
+
+func (e *Emitter) EmitArrayNext() (err error) {
+	return
+}
+
+func (e *Emitter) EmitMapBegin(_ int) (err error) {
+	if e.isKey() {
+		return errKey
+	}
+	e.begin()
+	e.b = append(e.b, ObjectBegin)
+	e.stack = append(e.stack, true)
+	return
+}
+
+func (e *Emitter) EmitMapEnd() (err error) {
+	e.stack = e.stack[:len(e.stack)-1]
+	e.b = append(e.b, ObjectEnd)
+	return e.end()
+}
+
+func (e *Emitter) EmitMapValue() (err error) {
+	e.stack[len(e.stack)-1] = false
+	return
+}
+
+func (e *Emitter) EmitMapNext() (err error) {
+	e.stack[len(e.stack)-1] = true
+	return
+}
+
+// isKey returns true if the next value emitted is the key of an object.
+func (e *Emitter) isKey() bool {
+	n := len(e.stack)
+	return n != 0 && e.stack[n-1]
+}
+
+func (e *Emitter) emitKey(k string) error {
+	e.b = e.appendKey(e.b, k)
+	return e.end()
+}
+
+// begin must be called before writing a value, it writes the header if no
+// values were written yet.
+func (e *Emitter) begin() {
+	if !e.header {
+		e.b = append(e.b, header0, header1, header2, sharedNames|sharedValues)
+		e.header = true
+	}
+}
+
+// end must be called after writing a value, it flushes the output buffer to
+// the writer when a top-level value or element of a top-level array (like in
+// streams) was completed.
+func (e *Emitter) end() (err error) {
+	if len(e.stack) <= 1 || len(e.b) >= bufferSize {
+		if len(e.b) != 0 {
+			_, err = e.w.Write(e.b)
+			e.b = e.b[:0]
+		}
+	}
+	return
+}
+
+func (e *Emitter) appendKey(b []byte, k string) []byte {
+	if len(k) == 0 {
+		return append(b, KeyEmpty)
+	}
+
+	if i, ok := e.names.lookup(k); ok {
+		if i < 64 {
+			return append(b, KeyShortShared+byte(i))
+		}
+		return append(b, KeyLongShared|byte(i>>8), byte(i))
+	}
+
+	k = validString(k)
+	e.names.add(k)
+
+	switch n := len(k); {
+	case n <= maxShortKeyASCII && isASCII(k):
+		b = append(b, KeyShortASCII+byte(n-1))
+	case n <= maxShortKeyUnicode && !isASCII(k):
+		b = append(b, KeyShortUnicode+byte(n-2))
+	default:
+		return append(append(append(b, KeyLongUnicode), k...), EndOfString)
+	}
+
+	return append(b, k...)
+}
+
+func (e *Emitter) appendString(b []byte, s string) []byte {
+	if len(s) == 0 {
+		return append(b, EmptyString)
+	}
+
+	if i, ok := e.values.lookup(s); ok {
+		if i < 31 {
+			return append(b, byte(i+1))
+		}
+		return append(b, LongShared|byte(i>>8), byte(i))
+	}
+
+	s = validString(s)
+	ascii := isASCII(s)
+
+	switch n := len(s); {
+	case ascii && n <= maxTinyASCII:
+		b = append(b, TinyASCII+byte(n-1))
+	case ascii && n <= maxShortASCII:
+		b = append(b, ShortASCII+byte(n-maxTinyASCII-1))
+	case !ascii && n <= maxTinyUnicode:
+		b = append(b, TinyUnicode+byte(n-2))
+	case !ascii && n <= maxShortUnicode:
+		b = append(b, ShortUnicode+byte(n-maxTinyUnicode-1))
+	case ascii:
+		return append(append(append(b, LongASCII), s...), EndOfString)
+	default:
+		return append(append(append(b, LongUnicode), s...), EndOfString)
+	}
+
+	// Only short strings are added to the table of shared values.
+	e.values.add(s)
+	return append(b, s...)
+}
+
+func isASCII(s string) bool {
+	for i := 0; i != len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// validString replaces invalid UTF-8 sequences in s, which guarantees that the
+// string never contains the end-of-string marker.
+func validString(s string) string {
+	if !utf8.ValidString(s) {
+		s = strings.ToValidUTF8(s, "\uFFFD")
+	}
+	return s
+}
+
+var errKey = errors.New("objconv/smile: object keys must be strings or scalar values")
@@ -0,0 +1,32 @@
+package smile
+
+import (
+	"io"
+
+	"github.com/segmentio/objconv"
+)
+
+// Codec for the Smile format.
+var Codec = objconv.Codec{
+	NewEmitter: func(w io.Writer) objconv.Emitter { return NewEmitter(w) },
+	NewParser:  func(r io.Reader) objconv.Parser { return NewParser(r) },
+	Extensions: []string{".sml", ".smile"},
+	Sniff:      sniff,
+}
+
+func init() {
+	for _, name := range [...]string{
+		"application/x-jackson-smile",
+		"smile",
+	} {
+		objconv.Register(name, Codec)
+	}
+}
+
+// sniff recognizes the header of Smile documents.
+func sniff(b []byte) float64 {
+	if len(b) >= 3 && b[0] == header0 && b[1] == header1 && b[2] == header2 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,662 @@
+package smile
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/objconv"
+	"github.com/segmentio/objconv/objutil"
+)
+
+// Parser implements a Smile parser that satisfies the objconv.Parser
+// interface.
+//
+// The header of documents is optional, when it is missing the parser assumes
+// that only shared keys are enabled. Headers and end-of-content markers that
+// appear between top-level values start a new document. Big integers are
+// parsed as integers when they fit in 64 bits, and big decimals as floats.
+type Parser struct {
+	r io.Reader // reader to load bytes from
+	i int       // offset of the first unread byte in b
+	j int       // offset + 1 of the last unread byte in b
+	n int64     // number of bytes consumed before b[0]
+	s []byte    // string buffer
+	d []byte    // binary buffer
+	b [240]byte // read buffer
+
+	// This stack tracks the state of the arrays and objects being parsed,
+	// elements are true for objects where the next value is a key.
+	stack []bool
+
+	// Tables of keys and string values that were parsed and may be
+	// referenced by the next ones.
+	names  sharedTable
+	values sharedTable
+	flags  byte
+
+	// Big integers have to be read to know their type, the value is retained
+	// here by ParseType until it is consumed.
+	h   interface{}
+	hok bool
+}
+
+func NewParser(r io.Reader) *Parser {
+	return &Parser{r: r, flags: sharedNames}
+}
+
+func (p *Parser) Reset(r io.Reader) {
+	p.r = r
+	p.i = 0
+	p.j = 0
+	p.n = 0
+	p.stack = p.stack[:0]
+	p.names.reset()
+	p.values.reset()
+	p.flags = sharedNames
+	p.h, p.hok = nil, false
+}
+
+func (p *Parser) Buffered() io.Reader {
+	return bytes.NewReader(p.b[p.i:p.j])
+}
+
+// Offset returns the number of bytes of the input consumed by p.
+func (p *Parser) Offset() int64 {
+	return p.n + int64(p.i)
+}
+
+func (p *Parser) ParseType() (typ objconv.Type, err error) {
+	if p.hok {
+		return typeOf(p.h), nil
+	}
+
+	if p.isKey() {
+		return objconv.String, nil
+	}
+
+	c, err := p.token()
+	if err != nil {
+		return
+	}
+
+	switch {
+	case c == Null:
+		typ = objconv.Nil
+
+	case c == True, c == False:
+		typ = objconv.Bool
+
+	case c == Int32, c == Int64, c >= SmallInt && c < LongASCII:
+		typ = objconv.Int
+
+	case c == Float32, c == Float64, c == BigDecimal:
+		typ = objconv.Float
+
+	case c > 0 && c <= EmptyString, c >= TinyASCII && c < SmallInt, c == LongASCII, c == LongUnicode, c >= LongShared && c <= LongShared|0x3:
+		typ = objconv.String
+
+	case c == Binary7Bit, c == RawBinary:
+		typ = objconv.Bytes
+
+	case c == ArrayBegin:
+		typ = objconv.Array
+
+	case c == ObjectBegin:
+		typ = objconv.Map
+
+	case c == BigInteger:
+		p.i++
+		if p.h, err = p.readBigInteger(); err != nil {
+			return
+		}
+		p.hok = true
+		typ = typeOf(p.h)
+
+	default:
+		err = fmt.Errorf("objconv/smile: unexpected token 0x%02X", c)
+	}
+
+	return
+}
+
+func (p *Parser) ParseNil() (err error) {
+	p.i++
+	return
+}
+
+func (p *Parser) ParseBool() (v bool, err error) {
+	v = p.b[p.i] == True
+	p.i++
+	return
+}
+
+func (p *Parser) ParseInt() (v int64, err error) {
+	if p.hok {
+		v = p.takeBigInteger().(int64)
+		return
+	}
+
+	c := p.b[p.i]
+	p.i++
+
+	if c >= SmallInt {
+		v = unzigzag(uint64(c & 0x1F))
+		return
+	}
+
+	var u uint64
+	if u, err = p.readVInt(); err != nil {
+		return
+	}
+
+	v = unzigzag(u)
+	return
+}
+
+func (p *Parser) ParseUint() (v uint64, err error) {
+	v = p.takeBigInteger().(uint64)
+	return
+}
+
+func (p *Parser) ParseFloat() (v float64, err error) {
+	c := p.b[p.i]
+	p.i++
+
+	switch c {
+	case Float32:
+		var u uint64
+		if u, err = p.read7Bit(5); err == nil {
+			v = float64(math.Float32frombits(uint32(u)))
+		}
+
+	case Float64:
+		var u uint64
+		if u, err = p.read7Bit(10); err == nil {
+			v = math.Float64frombits(u)
+		}
+
+	default:
+		var u uint64
+		var x *big.Int
+
+		if u, err = p.readVInt(); err != nil {
+			return
+		}
+		if x, err = p.readBigInt(); err != nil {
+			return
+		}
+
+		s := x.String() + "e" + strconv.FormatInt(-unzigzag(u), 10)
+		v, err = strconv.ParseFloat(s, 64)
+	}
+
+	return
+}
+
+func (p *Parser) ParseString() (v []byte, err error) {
+	if p.isKey() {
+		return p.parseKey()
+	}
+
+	c := p.b[p.i]
+	p.i++
+
+	switch {
+	case c == EmptyString:
+		v = p.s[:0]
+
+	case c < EmptyString:
+		v, err = p.reference(&p.values, sharedValues, int(c)-1)
+
+	case c >= LongShared:
+		var b []byte
+		if b, err = p.read(1); err == nil {
+			v, err = p.reference(&p.values, sharedValues, int(c&0x3)<<8|int(b[0]))
+		}
+
+	case c == LongASCII, c == LongUnicode:
+		v, err = p.readLong()
+
+	default:
+		var n int
+
+		switch {
+		case c < ShortASCII:
+			n = int(c-TinyASCII) + 1
+		case c < TinyUnicode:
+			n = int(c-ShortASCII) + maxTinyASCII + 1
+		case c < ShortUnicode:
+			n = int(c-TinyUnicode) + 2
+		default:
+			n = int(c-ShortUnicode) + maxTinyUnicode + 1
+		}
+
+		if v, err = p.read(n); err == nil && (p.flags&sharedValues) != 0 {
+			p.values.add(string(v))
+		}
+	}
+
+	return
+}
+
+func (p *Parser) ParseBytes() (v []byte, err error) {
+	c := p.b[p.i]
+	p.i++
+
+	var n int
+	var b []byte
+
+	if n, err = p.readLength(); err != nil {
+		return
+	}
+
+	if c == RawBinary {
+		return p.read(n)
+	}
+
+	if b, err = p.read(binaryLength(n)); err != nil {
+		return
+	}
+
+	p.d = decodeBinary(p.d[:0], b, n)
+	v = p.d
+	return
+}
+
+func (p *Parser) ParseTime() (v time.Time, err error) {
+	panic("objconv/smile: ParseTime should never be called because Smile has no time type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseDuration() (v time.Duration, err error) {
+	panic("objconv/smile: ParseDuration should never be called because Smile has no duration type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseError() (v error, err error) {
+	panic("objconv/smile: ParseError should never be called because Smile has no error type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseArrayBegin() (n int, err error) {
+	p.i++
+	p.stack = append(p.stack, false)
+	return -1, nil
+}
+
+func (p *Parser) ParseArrayEnd(n int) (err error) {
+	return p.parseContainerEnd(ArrayEnd)
+}
+
+func (p *Parser) ParseArrayNext(n int) (err error) {
+	return p.parseContainerNext(ArrayEnd)
+}
+
+func (p *Parser) ParseMapBegin() (n int, err error) {
+	p.i++
+	p.stack = append(p.stack, true)
+	return -1, nil
+}
+
+func (p *Parser) ParseMapEnd(n int) (err error) {
+	return p.parseContainerEnd(ObjectEnd)
+}
+
+func (p *Parser) ParseMapValue(n int) (err error) {
+	p.stack[len(p.stack)-1] = false
+	return
+}
+
+func (p *Parser) ParseMapNext(n int) (err error) {
+	p.stack[len(p.stack)-1] = true
+	return p.parseContainerNext(ObjectEnd)
+}
+
+func (p *Parser) parseContainerEnd(end byte) (err error) {
+	p.stack = p.stack[:len(p.stack)-1]
+
+	var b []byte
+
+	if b, err = p.peek(1); err != nil {
+		return
+	}
+	if b[0] != end {
+		return fmt.Errorf("objconv/smile: expected token 0x%02X but found 0x%02X", end, b[0])
+	}
+
+	p.i++
+	return
+}
+
+func (p *Parser) parseContainerNext(end byte) (err error) {
+	var b []byte
+
+	if b, err = p.peek(1); err != nil {
+		return
+	}
+	if b[0] == end {
+		err = objconv.End
+	}
+
+	return
+}
+
+func (p *Parser) parseKey() (v []byte, err error) {
+	var b []byte
+
+	if b, err = p.read(1); err != nil {
+		return
+	}
+
+	switch c := b[0]; {
+	case c == KeyEmpty:
+		v = p.s[:0]
+
+	case c >= KeyLongShared && c < KeyLongUnicode:
+		if b, err = p.read(1); err == nil {
+			v, err = p.reference(&p.names, sharedNames, int(c&0x3)<<8|int(b[0]))
+		}
+
+	case c == KeyLongUnicode:
+		if v, err = p.readLong(); err == nil && (p.flags&sharedNames) != 0 {
+			p.names.add(string(v))
+		}
+
+	case c >= KeyShortShared && c < KeyShortASCII:
+		v, err = p.reference(&p.names, sharedNames, int(c-KeyShortShared))
+
+	case c >= KeyShortASCII && c < ArrayBegin:
+		n := int(c-KeyShortASCII) + 1
+		if c >= KeyShortUnicode {
+			n = int(c-KeyShortUnicode) + 2
+		}
+		if v, err = p.read(n); err == nil && (p.flags&sharedNames) != 0 {
+			p.names.add(string(v))
+		}
+
+	default:
+		err = fmt.Errorf("objconv/smile: unexpected token 0x%02X for an object key", c)
+	}
+
+	return
+}
+
+// token returns the first byte of the next value without consuming it, at the
+// top level it skips the headers and end-of-content markers.
+func (p *Parser) token() (c byte, err error) {
+	for {
+		var b []byte
+
+		if b, err = p.peek(1); err != nil {
+			return
+		}
+
+		if c = b[0]; len(p.stack) != 0 {
+			return
+		}
+
+		switch c {
+		case EndOfContent:
+			p.i++
+
+		case header0:
+			if err = p.readHeader(); err != nil {
+				return
+			}
+
+		default:
+			return
+		}
+	}
+}
+
+func (p *Parser) readHeader() (err error) {
+	var b []byte
+
+	if b, err = p.peek(4); err != nil {
+		return
+	}
+	if b[1] != header1 || b[2] != header2 {
+		return fmt.Errorf("objconv/smile: invalid header %q", b[:3])
+	}
+	if (b[3] & versionMask) != 0 {
+		return fmt.Errorf("objconv/smile: unsupported version %d", b[3]>>4)
+	}
+
+	p.flags = b[3]
+	p.names.reset()
+	p.values.reset()
+	p.i += 4
+	return
+}
+
+// reference returns the shared string at index i of table t, the feature flag
+// f must be enabled in the header.
+func (p *Parser) reference(t *sharedTable, f byte, i int) (v []byte, err error) {
+	if (p.flags&f) == 0 || i >= len(t.list) {
+		err = fmt.Errorf("objconv/smile: invalid reference to shared string %d", i)
+		return
+	}
+	p.s = append(p.s[:0], t.list[i]...)
+	v = p.s
+	return
+}
+
+// readLong reads a string terminated by the end-of-string marker.
+func (p *Parser) readLong() (v []byte, err error) {
+	v = p.s[:0]
+
+	for {
+		if _, err = p.peek(1); err != nil {
+			return
+		}
+
+		b := p.b[p.i:p.j]
+
+		if k := bytes.IndexByte(b, EndOfString); k >= 0 {
+			v = append(v, b[:k]...)
+			p.i += k + 1
+			break
+		}
+
+		v = append(v, b...)
+		p.i = p.j
+	}
+
+	p.s = v
+	return
+}
+
+func (p *Parser) readVInt() (v uint64, err error) {
+	var b []byte
+
+	for i := 0; ; i++ {
+		if b, err = p.read(1); err != nil {
+			return
+		}
+
+		c := b[0]
+
+		if (c & 0x80) != 0 {
+			if v > (math.MaxUint64 >> 6) {
+				break
+			}
+			v = v<<6 | uint64(c&0x3F)
+			return
+		}
+
+		if v > (math.MaxUint64 >> 7) {
+			break
+		}
+		v = v<<7 | uint64(c)
+	}
+
+	err = errVIntOverflow
+	return
+}
+
+func (p *Parser) readLength() (n int, err error) {
+	var v uint64
+
+	if v, err = p.readVInt(); err != nil {
+		return
+	}
+
+	if v > uint64(objutil.IntMax) {
+		err = fmt.Errorf("objconv/smile: invalid length %d", v)
+		return
+	}
+
+	n = int(v)
+	return
+}
+
+// read7Bit reads n bytes holding 7 bits each of a number.
+func (p *Parser) read7Bit(n int) (v uint64, err error) {
+	var b []byte
+
+	if b, err = p.read(n); err != nil {
+		return
+	}
+
+	for _, c := range b {
+		v = v<<7 | uint64(c&0x7F)
+	}
+
+	return
+}
+
+// readBigInt reads the length and 7-bit encoded two's complement
+// representation of a big integer.
+func (p *Parser) readBigInt() (x *big.Int, err error) {
+	var n int
+	var b []byte
+
+	if n, err = p.readLength(); err != nil {
+		return
+	}
+	if b, err = p.read(binaryLength(n)); err != nil {
+		return
+	}
+
+	p.d = decodeBinary(p.d[:0], b, n)
+	x = new(big.Int).SetBytes(p.d)
+
+	if n != 0 && (p.d[0]&0x80) != 0 {
+		x.Sub(x, new(big.Int).Lsh(big.NewInt(1), uint(8*n)))
+	}
+
+	return
+}
+
+// readBigInteger reads a big integer as an int64 or uint64 value.
+func (p *Parser) readBigInteger() (v interface{}, err error) {
+	var x *big.Int
+
+	if x, err = p.readBigInt(); err != nil {
+		return
+	}
+
+	switch {
+	case x.IsInt64():
+		v = x.Int64()
+	case x.IsUint64():
+		v = x.Uint64()
+	default:
+		err = fmt.Errorf("objconv/smile: big integer %s overflows 64 bits", x)
+	}
+
+	return
+}
+
+func (p *Parser) takeBigInteger() (v interface{}) {
+	v, p.h, p.hok = p.h, nil, false
+	return
+}
+
+// isKey returns true if the next value is the key of an object.
+func (p *Parser) isKey() bool {
+	n := len(p.stack)
+	return n != 0 && p.stack[n-1]
+}
+
+func (p *Parser) read(n int) (b []byte, err error) {
+	if n <= (p.j - p.i) { // check if the string is already buffered
+		b = p.b[p.i : p.i+n]
+		p.i += n
+		return
+	}
+
+	if n <= len(p.b) { // check if the string can be loaded in the read buffer
+		if b, err = p.peek(n); err != nil {
+			return
+		}
+		p.i += n
+		return
+	}
+
+	if cap(p.s) < n {
+		p.s = make([]byte, n, align(n, 1024))
+	} else {
+		p.s = p.s[:n]
+	}
+
+	copy(p.s, p.b[p.i:p.j])
+	p.n += int64(p.i + len(p.s))
+	n = p.j - p.i
+	p.i = 0
+	p.j = 0
+
+	if _, err = io.ReadFull(p.r, p.s[n:]); err != nil {
+		return
+	}
+
+	b = p.s
+	return
+}
+
+func (p *Parser) peek(n int) (b []byte, err error) {
+	for (p.i + n) > p.j {
+		if err = p.fill(); err != nil {
+			return
+		}
+	}
+	b = p.b[p.i : p.i+n]
+	return
+}
+
+func (p *Parser) fill() (err error) {
+	n := p.j - p.i
+	copy(p.b[:], p.b[p.i:p.j])
+	p.n += int64(p.i)
+	p.i = 0
+	p.j = n
+
+	if n, err = p.r.Read(p.b[n:]); n > 0 {
+		err = nil
+		p.j += n
+	} else if err != nil {
+		return
+	} else {
+		err = io.ErrNoProgress
+		return
+	}
+
+	return
+}
+
+func align(n int, a int) int {
+	if (n % a) == 0 {
+		return n
+	}
+	return ((n / a) + 1) * a
+}
+
+func typeOf(v interface{}) objconv.Type {
+	if _, ok := v.(uint64); ok {
+		return objconv.Uint
+	}
+	return objconv.Int
+}
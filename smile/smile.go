@@ -0,0 +1,189 @@
+package smile
+
+import "errors"
+
+// The header of Smile documents is made of these three bytes followed by a
+// byte holding the version of the format and flags of the features enabled in
+// the document.
+const (
+	header0 = ':'
+	header1 = ')'
+	header2 = '\n'
+
+	versionMask  = 0xF0
+	sharedNames  = 0x01
+	sharedValues = 0x02
+	rawBinary    = 0x04
+)
+
+// Limits of the format, references to shared strings are 10 bits long and
+// only short string values may be shared.
+const (
+	maxShared          = 1024
+	maxTinyASCII       = 32
+	maxShortASCII      = 64
+	maxTinyUnicode     = 33
+	maxShortUnicode    = 65
+	maxShortKeyASCII   = 64
+	maxShortKeyUnicode = 57
+)
+
+// Tokens of values.
+const (
+	EmptyString  = 0x20
+	Null         = 0x21
+	False        = 0x22
+	True         = 0x23
+	Int32        = 0x24
+	Int64        = 0x25
+	BigInteger   = 0x26
+	Float32      = 0x28
+	Float64      = 0x29
+	BigDecimal   = 0x2A
+	TinyASCII    = 0x40 // 0x40-0x5F, length 1-32
+	ShortASCII   = 0x60 // 0x60-0x7F, length 33-64
+	TinyUnicode  = 0x80 // 0x80-0x9F, length 2-33
+	ShortUnicode = 0xA0 // 0xA0-0xBF, length 34-65
+	SmallInt     = 0xC0 // 0xC0-0xDF, zigzag encoded -16 to 15
+	LongASCII    = 0xE0
+	LongUnicode  = 0xE4
+	Binary7Bit   = 0xE8
+	LongShared   = 0xEC // 0xEC-0xEF, shared value references 31-1023
+	ArrayBegin   = 0xF8
+	ArrayEnd     = 0xF9
+	ObjectBegin  = 0xFA
+	ObjectEnd    = 0xFB
+	EndOfString  = 0xFC
+	RawBinary    = 0xFD
+	EndOfContent = 0xFF
+)
+
+// Tokens of object keys.
+const (
+	KeyEmpty        = 0x20
+	KeyLongShared   = 0x30 // 0x30-0x33, shared key references 64-1023
+	KeyLongUnicode  = 0x34
+	KeyShortShared  = 0x40 // 0x40-0x7F, shared key references 0-63
+	KeyShortASCII   = 0x80 // 0x80-0xBF, length 1-64
+	KeyShortUnicode = 0xC0 // 0xC0-0xF7, length 2-57
+)
+
+func appendVInt(b []byte, v uint64) []byte {
+	var a [11]byte
+	i := len(a) - 1
+	a[i] = 0x80 | byte(v&0x3F)
+
+	for v >>= 6; v != 0; v >>= 7 {
+		i--
+		a[i] = byte(v & 0x7F)
+	}
+
+	return append(b, a[i:]...)
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+func unzigzag(u uint64) int64 {
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+// append7Bit appends the n lowest bits of v in groups of 7 bits, most
+// significant first.
+func append7Bit(b []byte, v uint64, n int) []byte {
+	for i := (n+6)/7 - 1; i >= 0; i-- {
+		b = append(b, byte(v>>(7*uint(i)))&0x7F)
+	}
+	return b
+}
+
+// appendBinary appends the 7-bit encoding of data, every 7 bytes are encoded
+// as 8 bytes and the bits of the last incomplete group are right-aligned in
+// its last byte.
+func appendBinary(b []byte, data []byte) []byte {
+	var acc uint64
+	var bits uint
+
+	for _, c := range data {
+		acc = acc<<8 | uint64(c)
+		bits += 8
+
+		for bits >= 7 {
+			bits -= 7
+			b = append(b, byte(acc>>bits)&0x7F)
+		}
+	}
+
+	if bits != 0 {
+		b = append(b, byte(acc)&(1<<bits-1))
+	}
+
+	return b
+}
+
+// binaryLength returns the number of bytes needed to encode n bytes of data
+// with the 7-bit encoding.
+func binaryLength(n int) int {
+	return n/7*8 + (n%7*8+6)/7
+}
+
+// decodeBinary decodes the 7-bit encoding of n bytes held in b and appends
+// them to dst.
+func decodeBinary(dst []byte, b []byte, n int) []byte {
+	var acc uint64
+	var bits uint
+	var left = uint(8 * n)
+
+	for _, c := range b {
+		// The last byte only holds the bits that remain to be decoded.
+		k := min(7, left)
+		acc = acc<<k | uint64(c&0x7F)
+		bits += k
+		left -= k
+
+		for bits >= 8 {
+			bits -= 8
+			dst = append(dst, byte(acc>>bits))
+		}
+	}
+
+	return dst
+}
+
+// sharedTable is the table of shared keys or values, which may be referenced
+// by their index once they were added.
+type sharedTable struct {
+	list  []string
+	index map[string]int
+}
+
+func (t *sharedTable) reset() {
+	t.list = t.list[:0]
+	t.index = nil
+}
+
+// add adds s to the table, which is cleared when it's full. Strings whose
+// index would end with 0xFE or 0xFF are never referenced.
+func (t *sharedTable) add(s string) {
+	if len(t.list) == maxShared {
+		t.reset()
+	}
+
+	if t.index == nil {
+		t.index = make(map[string]int)
+	}
+
+	if i := len(t.list); i&0xFF < 0xFE {
+		t.index[s] = i
+	}
+
+	t.list = append(t.list, s)
+}
+
+func (t *sharedTable) lookup(s string) (int, bool) {
+	i, ok := t.index[s]
+	return i, ok
+}
+
+var errVIntOverflow = errors.New("objconv/smile: variable length integer overflows 64 bits")
@@ -0,0 +1,169 @@
+package smile
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/segmentio/objconv/objtests"
+)
+
+const header = ":)\n\x03"
+
+func TestCodec(t *testing.T) {
+	objtests.TestCodec(t, Codec)
+}
+
+func BenchmarkCodec(b *testing.B) {
+	objtests.BenchmarkCodec(b, Codec)
+}
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		v interface{}
+		b string
+	}{
+		{nil, "\x21"},
+		{true, "\x23"},
+		{false, "\x22"},
+		{1, "\xc2"},
+		{-1, "\xc1"},
+		{-16, "\xdf"},
+		{16, "\x24\xa0"},
+		{int64(1) << 31, "\x25\x20\x00\x00\x00\x80"},
+		{uint64(math.MaxUint64), "\x26\x89\x00\x3f\x7f\x7f\x7f\x7f\x7f\x7f\x7f\x7f\x03"},
+		{float32(0.5), "\x28\x03\x78\x00\x00\x00"},
+		{0.5, "\x29\x00\x3f\x70\x00\x00\x00\x00\x00\x00\x00"},
+		{"", "\x20"},
+		{"a", "\x40a"},
+		{"é", "\x80é"},
+		{strings.Repeat("x", 40), "\x67" + strings.Repeat("x", 40)},
+		{strings.Repeat("x", 70), "\xe0" + strings.Repeat("x", 70) + "\xfc"},
+		{[]byte("hi"), "\xe8\x82\x34\x1a\x01"},
+		{[]int{1, 2}, "\xf8\xc2\xc4\xf9"},
+		{[]string{"ab", "ab"}, "\xf8\x41ab\x01\xf9"},
+		{map[string]int{"a": 1}, "\xfa\x80a\xc2\xfb"},
+		{map[string]int{"": 1}, "\xfa\x20\xc2\xfb"},
+		{[]map[string]int{{"a": 1}, {"a": 2}}, "\xf8\xfa\x80a\xc2\xfb\xfa\x40\xc4\xfb\xf9"},
+		{map[string]string{"a": "a"}, "\xfa\x80a\x40a\xfb"},
+	}
+
+	for _, test := range tests {
+		b, err := Marshal(test.v)
+		if err != nil {
+			t.Errorf("%#v: %s", test.v, err)
+			continue
+		}
+		if string(b) != header+test.b {
+			t.Errorf("%#v: %q != %q", test.v, header+test.b, b)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	tests := []struct {
+		b string
+		v interface{}
+	}{
+		{header + "\x21", nil},
+		{"\x40a", "a"},
+		{header + "\x21\xff", nil},
+		{header + "\x26\x89\x00\x3f\x7f\x7f\x7f\x7f\x7f\x7f\x7f\x7f\x03", uint64(math.MaxUint64)},
+		{header + "\x26\x81\x7f\x01", int64(-1)},
+		{header + "\x2a\x84\x82\x02\x32\x01", 12.25},
+		{":)\n\x04\xfd\x82hi", []byte("hi")},
+		{header + "\xe4é\xfc", "é"},
+		{header + "\xf8\x41ab\xec\x00\xf9", []interface{}{"ab", "ab"}},
+		{header + "\xfa\x34long\xfc\x21\xfb", map[interface{}]interface{}{"long": nil}},
+		{"\xf8\xfa\x80a\xc2\xfb\xfa\x30\x00\xc4\xfb\xf9", []interface{}{
+			map[interface{}]interface{}{"a": int64(1)},
+			map[interface{}]interface{}{"a": int64(2)},
+		}},
+	}
+
+	for _, test := range tests {
+		var v interface{}
+
+		if err := Unmarshal([]byte(test.b), &v); err != nil {
+			t.Errorf("%q: %s", test.b, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(v, test.v) {
+			t.Errorf("%q: %#v != %#v", test.b, test.v, v)
+		}
+	}
+}
+
+func TestUnmarshalError(t *testing.T) {
+	tests := []string{
+		"",
+		"\x00",
+		":)\n\x13\x21",
+		":)\n\x00\xf8\x41ab\x01\xf9",
+		header + "\x01",
+		"\xfa\x40\x21\xfb",
+		"\xfa\x21\x21\xfb",
+		"\x25\x7f\x7f\x7f\x7f\x7f\x7f\x7f\x7f\x7f\x7f",
+		"\x26\x8a\x01\x7f\x7f\x7f\x7f\x7f\x7f\x7f\x7f\x7f\x7f\x03",
+	}
+
+	for _, test := range tests {
+		var v interface{}
+
+		if err := Unmarshal([]byte(test), &v); err == nil {
+			t.Errorf("%q: expected an error but got %#v", test, v)
+		}
+	}
+}
+
+func TestSharedStrings(t *testing.T) {
+	// More than 1024 distinct keys and values exercise the resets of the
+	// tables of shared strings, and the indexes that may not be referenced.
+	in := make([]map[string]string, 3000)
+
+	for i := range in {
+		s := strconv.Itoa(i % 1500)
+		in[i] = map[string]string{"k" + s: "v" + s}
+	}
+
+	b, err := Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out []map[string]string
+
+	if err := Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(in, out) {
+		t.Error("the decoded value doesn't match the encoded one")
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	for n := 0; n != 20; n++ {
+		in := bytes.Repeat([]byte{0xA5}, n)
+
+		b, err := Marshal(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var out []byte
+
+		if err := Unmarshal(b, &out); err != nil {
+			t.Error(n, err)
+			continue
+		}
+
+		if !bytes.Equal(in, out) {
+			t.Errorf("%d: %x != %x", n, in, out)
+		}
+	}
+}
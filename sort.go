@@ -2,6 +2,7 @@ package objconv
 
 import (
 	"bytes"
+	"fmt"
 	"reflect"
 	"sort"
 )
@@ -38,6 +39,14 @@ func (s sortBytesValues) Less(i int, j int) bool {
 	return bytes.Compare(s[i].Bytes(), s[j].Bytes()) < 0
 }
 
+type sortInterfaceValues []reflect.Value
+
+func (s sortInterfaceValues) Len() int          { return len(s) }
+func (s sortInterfaceValues) Swap(i int, j int) { s[i], s[j] = s[j], s[i] }
+func (s sortInterfaceValues) Less(i int, j int) bool {
+	return fmt.Sprint(s[i].Interface()) < fmt.Sprint(s[j].Interface())
+}
+
 func sortValues(typ reflect.Type, v []reflect.Value) {
 	switch typ.Kind() {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
@@ -56,6 +65,11 @@ func sortValues(typ reflect.Type, v []reflect.Value) {
 		if typ.Elem().Kind() == reflect.Uint8 {
 			sort.Sort(sortBytesValues(v))
 		}
+
+	case reflect.Interface:
+		// Keys of different types are ordered by their string representation,
+		// which is stable across runs.
+		sort.Sort(sortInterfaceValues(v))
 	}
 
 	// For all other types we give up on trying to sort the values,
@@ -16,6 +16,11 @@ type structField struct {
 	// The name of the field in the structure.
 	name string
 
+	// The name of the field prefixed with objutil.AttrPrefix when the field is
+	// tagged as an attribute, used by emitters and parsers of formats which
+	// have attributes.
+	attr string
+
 	// Omitempty is set to true when the field should be omitted if it has an
 	// empty value.
 	omitempty bool
@@ -66,6 +71,10 @@ func makeStructField(f reflect.StructField, c map[reflect.Type]*structType) stru
 		s.name = t.Name
 	}
 
+	if t.Attr && s.name != "-" {
+		s.attr = objutil.AttrPrefix + s.name
+	}
+
 	return s
 }
 
@@ -79,6 +88,7 @@ func (f *structField) omit(v reflect.Value) bool {
 type structType struct {
 	fields       []structField           // the serializable fields of the struct
 	fieldsByName map[string]*structField // cache of fields by name
+	attrsByName  map[string]*structField // cache of attribute fields by prefixed name
 }
 
 // newStructType takes a Go type as argument and extract information to make a
@@ -111,6 +121,13 @@ func newStructType(t reflect.Type, c map[reflect.Type]*structType) *structType {
 
 		s.fields = append(s.fields, sf)
 		s.fieldsByName[sf.name] = &s.fields[len(s.fields)-1]
+
+		if sf.attr != "" {
+			if s.attrsByName == nil {
+				s.attrsByName = make(map[string]*structField)
+			}
+			s.attrsByName[sf.attr] = &s.fields[len(s.fields)-1]
+		}
 	}
 
 	return s
@@ -10,6 +10,9 @@ func TestMakeStructField(t *testing.T) {
 	type a struct{ a int }
 	type B struct{ A }
 	type b struct{ a }
+	type C struct {
+		ID int `objconv:"id,attr"`
+	}
 
 	tests := []struct {
 		s reflect.StructField
@@ -46,6 +49,15 @@ func TestMakeStructField(t *testing.T) {
 				name:  "a",
 			},
 		},
+
+		{
+			s: reflect.TypeOf(C{}).Field(0),
+			f: structField{
+				index: []int{0},
+				name:  "id",
+				attr:  "@id",
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -0,0 +1,213 @@
+package toml
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Emitter implements a TOML emitter that satisfies the objconv.Emitter
+// interface.
+//
+// TOML documents are tables, so only maps can be emitted at the top level.
+// Nested maps are written as [table] sections and arrays of maps as
+// [[array]] sections, unless they appear within arrays of mixed values where
+// they are written as inline tables. Nil values have no representation in
+// TOML, the keys of maps that hold nil values are omitted.
+type Emitter struct {
+	w io.Writer
+	b []byte
+	// The stack is used to keep track of the container being built by the
+	// emitter, which may be an arrayEmitter or tableEmitter.
+	stack []emitter
+}
+
+func NewEmitter(w io.Writer) *Emitter {
+	return &Emitter{w: w}
+}
+
+func (e *Emitter) Reset(w io.Writer) {
+	e.w = w
+	e.stack = e.stack[:0]
+}
+
+func (e *Emitter) EmitNil() error {
+	return e.emit(nil)
+}
+
+func (e *Emitter) EmitBool(v bool) error {
+	return e.emit(v)
+}
+
+func (e *Emitter) EmitInt(v int64, _ int) error {
+	return e.emit(v)
+}
+
+func (e *Emitter) EmitUint(v uint64, _ int) error {
+	if v > 1<<63-1 {
+		return errors.New("objconv/toml: " + strconv.FormatUint(v, 10) + " overflows the range of TOML integers")
+	}
+	return e.emit(int64(v))
+}
+
+func (e *Emitter) EmitFloat(v float64, bitSize int) error {
+	if bitSize == 32 {
+		return e.emit(float32(v))
+	}
+	return e.emit(v)
+}
+
+func (e *Emitter) EmitString(v string) error {
+	return e.emit(v)
+}
+
+func (e *Emitter) EmitBytes(v []byte) error {
+	return e.emit(base64.StdEncoding.EncodeToString(v))
+}
+
+func (e *Emitter) EmitTime(v time.Time) error {
+	return e.emit(v)
+}
+
+func (e *Emitter) EmitDuration(v time.Duration) error {
+	return e.emit(v.String())
+}
+
+func (e *Emitter) EmitError(v error) error {
+	return e.emit(v.Error())
+}
+
+func (e *Emitter) EmitArrayBegin(_ int) (err error) {
+	e.push(&arrayEmitter{self: []interface{}{}})
+	return
+}
+
+func (e *Emitter) EmitArrayEnd() (err error) {
+	return e.emit(e.pop().value())
+}
+
+func (e *Emitter) EmitArrayNext() (err error) {
+	return
+}
+
+func (e *Emitter) EmitMapBegin(_ int) (err error) {
+	e.push(&tableEmitter{self: newTable()})
+	return
+}
+
+func (e *Emitter) EmitMapEnd() (err error) {
+	return e.emit(e.pop().value())
+}
+
+func (e *Emitter) EmitMapValue() (err error) {
+	return
+}
+
+func (e *Emitter) EmitMapNext() (err error) {
+	return
+}
+
+func (e *Emitter) TextEmitter() bool {
+	return true
+}
+
+func (e *Emitter) emit(v interface{}) (err error) {
+	if n := len(e.stack); n != 0 {
+		return e.stack[n-1].emit(v)
+	}
+
+	t, ok := v.(*table)
+
+	if !ok {
+		return errors.New("objconv/toml: only maps can be encoded at the top level of TOML documents")
+	}
+
+	if e.b, err = appendTable(e.b[:0], t, nil); err != nil {
+		return
+	}
+
+	_, err = e.w.Write(e.b)
+	return
+}
+
+func (e *Emitter) push(v emitter) {
+	e.stack = append(e.stack, v)
+}
+
+func (e *Emitter) pop() emitter {
+	i := len(e.stack) - 1
+	v := e.stack[i]
+	e.stack = e.stack[:i]
+	return v
+}
+
+type emitter interface {
+	emit(interface{}) error
+	value() interface{}
+}
+
+type arrayEmitter struct {
+	self []interface{}
+}
+
+func (e *arrayEmitter) emit(v interface{}) error {
+	if v == nil {
+		return errors.New("objconv/toml: arrays cannot contain nil values because TOML has no null type")
+	}
+	e.self = append(e.self, v)
+	return nil
+}
+
+func (e *arrayEmitter) value() interface{} {
+	return e.self
+}
+
+type tableEmitter struct {
+	self *table
+	key  string
+	val  bool
+}
+
+func (e *tableEmitter) emit(v interface{}) (err error) {
+	if e.val {
+		e.val = false
+
+		if _, exists := e.self.get(e.key); exists {
+			return errors.New("objconv/toml: duplicate key " + strconv.Quote(e.key))
+		}
+
+		if v != nil {
+			e.self.set(e.key, v)
+		}
+		return
+	}
+
+	e.val = true
+	e.key, err = keyString(v)
+	return
+}
+
+func (e *tableEmitter) value() interface{} {
+	return e.self
+}
+
+func keyString(v interface{}) (string, error) {
+	switch k := v.(type) {
+	case string:
+		return k, nil
+	case bool:
+		return strconv.FormatBool(k), nil
+	case int64:
+		return strconv.FormatInt(k, 10), nil
+	case float32:
+		return strconv.FormatFloat(float64(k), 'g', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(k, 'g', -1, 64), nil
+	case time.Time:
+		return k.Format(time.RFC3339Nano), nil
+	default:
+		return "", errors.New("objconv/toml: map keys must be strings or scalar values")
+	}
+}
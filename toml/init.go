@@ -0,0 +1,72 @@
+package toml
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/segmentio/objconv"
+)
+
+// Codec for the TOML format.
+var Codec = objconv.Codec{
+	NewEmitter: func(w io.Writer) objconv.Emitter { return NewEmitter(w) },
+	NewParser:  func(r io.Reader) objconv.Parser { return NewParser(r) },
+	Extensions: []string{".toml"},
+	Sniff:      sniff,
+}
+
+func init() {
+	for _, name := range [...]string{
+		"application/toml",
+		"toml",
+	} {
+		objconv.Register(name, Codec)
+	}
+}
+
+// sniff recognizes TOML documents starting with a table header or a key/value
+// pair.
+func sniff(b []byte) float64 {
+	line := firstLine(b)
+
+	if len(line) == 0 {
+		return 0
+	}
+
+	if line[0] == '[' {
+		if line[len(line)-1] == ']' && len(line) > 2 && isKeyByte(line[1]) {
+			return 0.6
+		}
+		return 0
+	}
+
+	if i := bytes.IndexByte(line, '='); i > 0 {
+		if k := bytes.TrimSpace(line[:i]); len(k) != 0 && bytes.IndexFunc(k, func(r rune) bool { return r > 0x7F || !isKeyByte(byte(r)) && r != '.' }) < 0 {
+			return 0.5
+		}
+	}
+
+	return 0
+}
+
+// firstLine returns the first line of b which isn't blank or a comment.
+func firstLine(b []byte) []byte {
+	for len(b) != 0 {
+		var line []byte
+
+		if i := bytes.IndexByte(b, '\n'); i >= 0 {
+			line, b = b[:i], b[i+1:]
+		} else {
+			line, b = b, nil
+		}
+
+		if line = bytes.TrimSpace(line); len(line) != 0 && line[0] != '#' {
+			return line
+		}
+	}
+	return nil
+}
+
+func isKeyByte(c byte) bool {
+	return c == '_' || c == '-' || c == '"' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
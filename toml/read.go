@@ -0,0 +1,897 @@
+package toml
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// reader implements the parsing of TOML documents into tables.
+type reader struct {
+	b    []byte
+	i    int
+	line int
+}
+
+func readDocument(b []byte) (root *table, err error) {
+	r := &reader{b: b, line: 1}
+	root = newTable()
+	cur := root
+
+	r.skipBOM()
+
+	for {
+		r.skipBlank()
+
+		if r.eof() {
+			break
+		}
+
+		var keys []string
+
+		if r.peek() == '[' {
+			array := r.hasPrefix("[[")
+
+			if array {
+				r.i += 2
+			} else {
+				r.i++
+			}
+
+			if keys, err = r.readKey(); err != nil {
+				return
+			}
+
+			if array {
+				if !r.hasPrefix("]]") {
+					return nil, r.errorf("expected ']]' at the end of an array of tables header")
+				}
+				r.i += 2
+				cur, err = r.defineTableArray(root, keys)
+			} else {
+				if !r.hasPrefix("]") {
+					return nil, r.errorf("expected ']' at the end of a table header")
+				}
+				r.i++
+				cur, err = r.defineTable(root, keys)
+			}
+		} else {
+			err = r.readKeyValue(cur)
+		}
+
+		if err != nil {
+			return
+		}
+
+		if err = r.readEndOfLine(); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func (r *reader) defineTable(root *table, keys []string) (t *table, err error) {
+	if t, err = r.walk(root, keys[:len(keys)-1], false); err != nil {
+		return
+	}
+
+	k := keys[len(keys)-1]
+	v, exists := t.get(k)
+
+	if !exists {
+		sub := newTable()
+		sub.defined = true
+		t.set(k, sub)
+		return sub, nil
+	}
+
+	if sub, ok := v.(*table); ok && !sub.defined && !sub.dotted && !sub.inline {
+		sub.defined = true
+		return sub, nil
+	}
+
+	return nil, r.errorf("table %s is defined more than once", formatKey(keys))
+}
+
+func (r *reader) defineTableArray(root *table, keys []string) (t *table, err error) {
+	if t, err = r.walk(root, keys[:len(keys)-1], false); err != nil {
+		return
+	}
+
+	k := keys[len(keys)-1]
+	v, exists := t.get(k)
+	sub := newTable()
+	sub.defined = true
+
+	if !exists {
+		t.set(k, &tableArray{tables: []*table{sub}})
+		return sub, nil
+	}
+
+	if a, ok := v.(*tableArray); ok {
+		a.tables = append(a.tables, sub)
+		return sub, nil
+	}
+
+	return nil, r.errorf("%s cannot be redefined as an array of tables", formatKey(keys))
+}
+
+// walk returns the table reached by following keys from t, creating implicit
+// tables along the way. When dotted is true the tables are created by the
+// dotted key of a key/value pair.
+func (r *reader) walk(t *table, keys []string, dotted bool) (*table, error) {
+	for i, k := range keys {
+		v, exists := t.get(k)
+
+		if !exists {
+			sub := newTable()
+			sub.dotted = dotted
+			t.set(k, sub)
+			t = sub
+			continue
+		}
+
+		switch x := v.(type) {
+		case *table:
+			if x.inline || (dotted && x.defined) {
+				return nil, r.errorf("table %s cannot be extended", formatKey(keys[:i+1]))
+			}
+			t = x
+
+		case *tableArray:
+			if dotted {
+				return nil, r.errorf("array of tables %s cannot be extended by a dotted key", formatKey(keys[:i+1]))
+			}
+			t = x.last()
+
+		default:
+			return nil, r.errorf("key %s is already defined and is not a table", formatKey(keys[:i+1]))
+		}
+	}
+
+	return t, nil
+}
+
+func (r *reader) readKeyValue(t *table) (err error) {
+	var keys []string
+	var v interface{}
+
+	if keys, err = r.readKey(); err != nil {
+		return
+	}
+
+	if r.eof() || r.peek() != '=' {
+		return r.errorf("expected '=' after key %s", formatKey(keys))
+	}
+
+	r.i++
+	r.skipSpaces()
+
+	if v, err = r.readValue(); err != nil {
+		return
+	}
+
+	if t, err = r.walk(t, keys[:len(keys)-1], true); err != nil {
+		return
+	}
+
+	k := keys[len(keys)-1]
+
+	if _, exists := t.get(k); exists {
+		return r.errorf("key %s is defined more than once", formatKey(keys))
+	}
+
+	t.set(k, v)
+	return
+}
+
+func (r *reader) readKey() (keys []string, err error) {
+	for {
+		var k string
+		r.skipSpaces()
+
+		if r.eof() {
+			return nil, r.errorf("unexpected end of document while reading a key")
+		}
+
+		switch c := r.peek(); {
+		case c == '"':
+			k, err = r.readBasicString()
+		case c == '\'':
+			k, err = r.readLiteralString()
+		case isBareKeyChar(c):
+			j := r.i
+			for r.i < len(r.b) && isBareKeyChar(r.b[r.i]) {
+				r.i++
+			}
+			k = string(r.b[j:r.i])
+		default:
+			err = r.errorf("invalid character %q in key", c)
+		}
+
+		if err != nil {
+			return
+		}
+
+		keys = append(keys, k)
+		r.skipSpaces()
+
+		if r.eof() || r.peek() != '.' {
+			return
+		}
+
+		r.i++
+	}
+}
+
+func (r *reader) readValue() (v interface{}, err error) {
+	if r.eof() {
+		return nil, r.errorf("unexpected end of document while reading a value")
+	}
+
+	switch c := r.peek(); {
+	case c == '"':
+		if r.hasPrefix(`"""`) {
+			return r.readMultiLineBasicString()
+		}
+		return r.readBasicString()
+
+	case c == '\'':
+		if r.hasPrefix(`'''`) {
+			return r.readMultiLineLiteralString()
+		}
+		return r.readLiteralString()
+
+	case c == '[':
+		return r.readArray()
+
+	case c == '{':
+		return r.readInlineTable()
+
+	case r.hasPrefix("true") && r.isDelimiter(r.i+4):
+		r.i += 4
+		return true, nil
+
+	case r.hasPrefix("false") && r.isDelimiter(r.i+5):
+		r.i += 5
+		return false, nil
+
+	case isDigit(c) || c == '+' || c == '-' || c == 'i' || c == 'n':
+		return r.readNumberOrDateTime()
+
+	default:
+		return nil, r.errorf("invalid character %q at the beginning of a value", c)
+	}
+}
+
+func (r *reader) readArray() (v interface{}, err error) {
+	a := []interface{}{}
+	r.i++ // '['
+
+	for {
+		r.skipBlank()
+
+		if r.eof() {
+			return nil, r.errorf("unterminated array")
+		}
+
+		if r.peek() == ']' {
+			r.i++
+			return a, nil
+		}
+
+		var x interface{}
+
+		if x, err = r.readValue(); err != nil {
+			return
+		}
+
+		a = append(a, x)
+		r.skipBlank()
+
+		if r.eof() {
+			return nil, r.errorf("unterminated array")
+		}
+
+		switch r.peek() {
+		case ',':
+			r.i++
+		case ']':
+			r.i++
+			return a, nil
+		default:
+			return nil, r.errorf("expected ',' or ']' after array element")
+		}
+	}
+}
+
+func (r *reader) readInlineTable() (v interface{}, err error) {
+	t := newTable()
+	r.i++ // '{'
+	r.skipSpaces()
+
+	if !r.eof() && r.peek() == '}' {
+		r.i++
+		t.inline = true
+		return t, nil
+	}
+
+	for {
+		if err = r.readKeyValue(t); err != nil {
+			return
+		}
+
+		r.skipSpaces()
+
+		if r.eof() {
+			return nil, r.errorf("unterminated inline table")
+		}
+
+		switch r.peek() {
+		case ',':
+			r.i++
+		case '}':
+			r.i++
+			freeze(t)
+			return t, nil
+		default:
+			return nil, r.errorf("expected ',' or '}' after inline table element")
+		}
+	}
+}
+
+// freeze marks t and the tables it contains as inline, which prevents them
+// from being extended later in the document.
+func freeze(t *table) {
+	t.inline = true
+	for _, v := range t.values {
+		if sub, ok := v.(*table); ok {
+			freeze(sub)
+		}
+	}
+}
+
+func (r *reader) readBasicString() (s string, err error) {
+	var b []byte
+	r.i++ // '"'
+
+	for {
+		if r.eof() {
+			return "", r.errorf("unterminated string")
+		}
+
+		switch c := r.peek(); {
+		case c == '"':
+			r.i++
+			return string(b), nil
+
+		case c == '\\':
+			if b, err = r.readEscape(b); err != nil {
+				return
+			}
+
+		case c == '\n' || c == '\r':
+			return "", r.errorf("newlines are not allowed in basic strings")
+
+		case isControl(c):
+			return "", r.errorf("control character %q must be escaped in strings", c)
+
+		default:
+			b = append(b, c)
+			r.i++
+		}
+	}
+}
+
+func (r *reader) readMultiLineBasicString() (s string, err error) {
+	var b []byte
+	r.i += 3
+	r.skipNewline()
+
+	for {
+		if r.eof() {
+			return "", r.errorf("unterminated multi-line string")
+		}
+
+		switch c := r.peek(); {
+		case c == '"':
+			if n := r.countQuotes('"'); n >= 3 {
+				b = append(b, strings.Repeat(`"`, n-3)...)
+				r.i += n
+				return string(b), nil
+			}
+			b = append(b, c)
+			r.i++
+
+		case c == '\\':
+			if j := r.lineEndingBackslash(); j >= 0 {
+				r.i = j
+				r.skipBlankNoComments()
+				continue
+			}
+			if b, err = r.readEscape(b); err != nil {
+				return
+			}
+
+		case c == '\n':
+			b = append(b, c)
+			r.i++
+			r.line++
+
+		case c == '\r' && r.hasPrefix("\r\n"):
+			b = append(b, '\n')
+			r.i += 2
+			r.line++
+
+		case isControl(c):
+			return "", r.errorf("control character %q must be escaped in strings", c)
+
+		default:
+			b = append(b, c)
+			r.i++
+		}
+	}
+}
+
+func (r *reader) readLiteralString() (s string, err error) {
+	r.i++ // '\''
+	j := r.i
+
+	for {
+		if r.eof() {
+			return "", r.errorf("unterminated literal string")
+		}
+
+		switch c := r.peek(); {
+		case c == '\'':
+			s = string(r.b[j:r.i])
+			r.i++
+			return
+
+		case c == '\n' || c == '\r':
+			return "", r.errorf("newlines are not allowed in literal strings")
+
+		case isControl(c):
+			return "", r.errorf("control character %q is not allowed in literal strings", c)
+		}
+
+		r.i++
+	}
+}
+
+func (r *reader) readMultiLineLiteralString() (s string, err error) {
+	var b []byte
+	r.i += 3
+	r.skipNewline()
+
+	for {
+		if r.eof() {
+			return "", r.errorf("unterminated multi-line literal string")
+		}
+
+		switch c := r.peek(); {
+		case c == '\'':
+			if n := r.countQuotes('\''); n >= 3 {
+				b = append(b, strings.Repeat("'", n-3)...)
+				r.i += n
+				return string(b), nil
+			}
+			b = append(b, c)
+			r.i++
+
+		case c == '\n':
+			b = append(b, c)
+			r.i++
+			r.line++
+
+		case c == '\r' && r.hasPrefix("\r\n"):
+			b = append(b, '\n')
+			r.i += 2
+			r.line++
+
+		case isControl(c):
+			return "", r.errorf("control character %q is not allowed in literal strings", c)
+
+		default:
+			b = append(b, c)
+			r.i++
+		}
+	}
+}
+
+// countQuotes returns the number of consecutive quotes at the current
+// position, up to 5 (a closing delimiter preceded by two quotes).
+func (r *reader) countQuotes(q byte) int {
+	n := 0
+	for n != 5 && r.i+n < len(r.b) && r.b[r.i+n] == q {
+		n++
+	}
+	return n
+}
+
+// lineEndingBackslash returns the position after the newline following a line
+// ending backslash at the current position, or -1 if the backslash is an
+// escape sequence.
+func (r *reader) lineEndingBackslash() int {
+	j := r.i + 1
+
+	for j < len(r.b) && (r.b[j] == ' ' || r.b[j] == '\t') {
+		j++
+	}
+
+	switch {
+	case j < len(r.b) && r.b[j] == '\n':
+		r.line++
+		return j + 1
+	case j+1 < len(r.b) && r.b[j] == '\r' && r.b[j+1] == '\n':
+		r.line++
+		return j + 2
+	default:
+		return -1
+	}
+}
+
+func (r *reader) readEscape(b []byte) ([]byte, error) {
+	if r.i+1 >= len(r.b) {
+		return b, r.errorf("unterminated escape sequence")
+	}
+
+	c := r.b[r.i+1]
+	r.i += 2
+
+	switch c {
+	case 'b':
+		return append(b, '\b'), nil
+	case 't':
+		return append(b, '\t'), nil
+	case 'n':
+		return append(b, '\n'), nil
+	case 'f':
+		return append(b, '\f'), nil
+	case 'r':
+		return append(b, '\r'), nil
+	case 'e':
+		return append(b, 0x1B), nil
+	case '"':
+		return append(b, '"'), nil
+	case '\\':
+		return append(b, '\\'), nil
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+
+		if r.i+n > len(r.b) {
+			return b, r.errorf("truncated unicode escape sequence")
+		}
+
+		s := string(r.b[r.i : r.i+n])
+
+		for i := 0; i != n; i++ {
+			if !isHexDigit(s[i]) {
+				return b, r.errorf("invalid unicode escape sequence \\%c%s", c, s)
+			}
+		}
+
+		x, _ := strconv.ParseUint(s, 16, 32)
+		u := rune(x)
+
+		if !utf8.ValidRune(u) {
+			return b, r.errorf("invalid unicode code point \\%c%s", c, s)
+		}
+
+		r.i += n
+		return append(b, string(u)...), nil
+
+	default:
+		return b, r.errorf("invalid escape sequence \\%c", c)
+	}
+}
+
+func (r *reader) readNumberOrDateTime() (v interface{}, err error) {
+	j := r.i
+
+	for !r.isDelimiter(r.i) {
+		r.i++
+	}
+
+	// Datetimes may use a space instead of the 'T' separator, in which case
+	// the time part needs to be added to the token.
+	if r.i-j == 10 && isDate(r.b[j:r.i]) && r.i+3 < len(r.b) && r.b[r.i] == ' ' &&
+		isDigit(r.b[r.i+1]) && isDigit(r.b[r.i+2]) && r.b[r.i+3] == ':' {
+		r.i++
+		for !r.isDelimiter(r.i) {
+			r.i++
+		}
+	}
+
+	s := string(r.b[j:r.i])
+
+	switch {
+	case len(s) >= 10 && isDate([]byte(s[:10])):
+		return r.parseDateTime(s)
+	case len(s) >= 5 && isDigit(s[0]) && isDigit(s[1]) && s[2] == ':':
+		return r.parseTime(s)
+	default:
+		return r.parseNumber(s)
+	}
+}
+
+func (r *reader) parseDateTime(s string) (v interface{}, err error) {
+	if len(s) == 10 {
+		if v, err = time.ParseInLocation("2006-01-02", s, time.UTC); err != nil {
+			err = r.errorf("invalid date %q", s)
+		}
+		return
+	}
+
+	switch s[10] {
+	case 'T', 't', ' ':
+	default:
+		return nil, r.errorf("invalid datetime %q", s)
+	}
+
+	b := []byte(s)
+	b[10] = 'T'
+
+	if c := b[len(b)-1]; c == 'z' {
+		b[len(b)-1] = 'Z'
+	}
+
+	s = string(b)
+
+	if hasOffset(s[11:]) {
+		v, err = time.Parse("2006-01-02T15:04:05Z07:00", s)
+	} else {
+		v, err = time.ParseInLocation("2006-01-02T15:04:05", s, time.UTC)
+	}
+
+	if err != nil {
+		err = r.errorf("invalid datetime %q", s)
+	}
+	return
+}
+
+func (r *reader) parseTime(s string) (v interface{}, err error) {
+	if v, err = time.ParseInLocation("15:04:05", s, time.UTC); err != nil {
+		err = r.errorf("invalid time %q", s)
+	}
+	return
+}
+
+func (r *reader) parseNumber(s string) (v interface{}, err error) {
+	switch s {
+	case "inf", "+inf":
+		return math.Inf(+1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan", "+nan", "-nan":
+		return math.NaN(), nil
+	}
+
+	if len(s) > 2 && s[0] == '0' {
+		base := 0
+
+		switch s[1] {
+		case 'x':
+			base = 16
+		case 'o':
+			base = 8
+		case 'b':
+			base = 2
+		}
+
+		if base != 0 {
+			digits, ok := removeUnderscores(s[2:])
+			if !ok {
+				return nil, r.errorf("invalid integer %q", s)
+			}
+
+			u, perr := strconv.ParseUint(digits, base, 64)
+			if perr != nil || u > math.MaxInt64 {
+				return nil, r.errorf("invalid integer %q", s)
+			}
+			return int64(u), nil
+		}
+	}
+
+	digits, ok := removeUnderscores(s)
+	if !ok {
+		return nil, r.errorf("invalid number %q", s)
+	}
+
+	// Leading zeros are not allowed in integers or in the integer part of
+	// floats.
+	unsigned := strings.TrimLeft(digits, "+-")
+	if len(unsigned) > 1 && unsigned[0] == '0' && isDigit(unsigned[1]) {
+		return nil, r.errorf("leading zeros are not allowed in number %q", s)
+	}
+
+	if strings.ContainsAny(digits, ".eE") {
+		if !isValidFloat(unsigned) {
+			return nil, r.errorf("invalid float %q", s)
+		}
+
+		f, perr := strconv.ParseFloat(digits, 64)
+		if perr != nil {
+			return nil, r.errorf("invalid float %q", s)
+		}
+		return f, nil
+	}
+
+	i, perr := strconv.ParseInt(digits, 10, 64)
+	if perr != nil {
+		return nil, r.errorf("invalid integer %q", s)
+	}
+	return i, nil
+}
+
+// isValidFloat checks that the dots of an unsigned float are surrounded by
+// digits, which strconv.ParseFloat doesn't enforce.
+func isValidFloat(s string) bool {
+	for i := 0; i != len(s); i++ {
+		if s[i] == '.' && (i == 0 || i == len(s)-1 || !isDigit(s[i-1]) || !isDigit(s[i+1])) {
+			return false
+		}
+	}
+	return len(s) != 0 && isDigit(s[0])
+}
+
+// removeUnderscores returns s without its underscores, and whether each of
+// them was surrounded by digits.
+func removeUnderscores(s string) (string, bool) {
+	if strings.IndexByte(s, '_') < 0 {
+		return s, true
+	}
+
+	b := make([]byte, 0, len(s))
+
+	for i := 0; i != len(s); i++ {
+		if s[i] == '_' {
+			if i == 0 || i == len(s)-1 || !isHexDigit(s[i-1]) || !isHexDigit(s[i+1]) {
+				return "", false
+			}
+			continue
+		}
+		b = append(b, s[i])
+	}
+
+	return string(b), true
+}
+
+func isDate(b []byte) bool {
+	return len(b) == 10 &&
+		isDigit(b[0]) && isDigit(b[1]) && isDigit(b[2]) && isDigit(b[3]) && b[4] == '-' &&
+		isDigit(b[5]) && isDigit(b[6]) && b[7] == '-' &&
+		isDigit(b[8]) && isDigit(b[9])
+}
+
+func hasOffset(s string) bool {
+	return strings.HasSuffix(s, "Z") || strings.ContainsAny(s, "+-")
+}
+
+func isControl(c byte) bool {
+	return (c < 0x20 && c != '\t') || c == 0x7F
+}
+
+func (r *reader) skipBOM() {
+	if r.hasPrefix("\xEF\xBB\xBF") {
+		r.i += 3
+	}
+}
+
+func (r *reader) readEndOfLine() error {
+	r.skipSpaces()
+	r.skipComment()
+
+	switch {
+	case r.eof():
+	case r.peek() == '\n':
+		r.i++
+		r.line++
+	case r.hasPrefix("\r\n"):
+		r.i += 2
+		r.line++
+	default:
+		return r.errorf("unexpected character %q after value", r.peek())
+	}
+
+	return nil
+}
+
+// skipBlank skips spaces, newlines and comments.
+func (r *reader) skipBlank() {
+	for {
+		r.skipBlankNoComments()
+
+		if r.eof() || r.peek() != '#' {
+			return
+		}
+
+		r.skipComment()
+	}
+}
+
+func (r *reader) skipBlankNoComments() {
+	for !r.eof() {
+		switch r.peek() {
+		case ' ', '\t', '\r':
+		case '\n':
+			r.line++
+		default:
+			return
+		}
+		r.i++
+	}
+}
+
+func (r *reader) skipSpaces() {
+	for !r.eof() && (r.peek() == ' ' || r.peek() == '\t') {
+		r.i++
+	}
+}
+
+func (r *reader) skipComment() {
+	if r.eof() || r.peek() != '#' {
+		return
+	}
+	for !r.eof() && r.peek() != '\n' {
+		r.i++
+	}
+}
+
+func (r *reader) skipNewline() {
+	switch {
+	case r.hasPrefix("\n"):
+		r.i++
+		r.line++
+	case r.hasPrefix("\r\n"):
+		r.i += 2
+		r.line++
+	}
+}
+
+// isDelimiter returns true if the byte at index i terminates a value.
+func (r *reader) isDelimiter(i int) bool {
+	if i >= len(r.b) {
+		return true
+	}
+	switch r.b[i] {
+	case ' ', '\t', '\r', '\n', ',', ']', '}', '#':
+		return true
+	}
+	return false
+}
+
+func (r *reader) eof() bool {
+	return r.i >= len(r.b)
+}
+
+func (r *reader) peek() byte {
+	return r.b[r.i]
+}
+
+func (r *reader) hasPrefix(s string) bool {
+	return len(r.b)-r.i >= len(s) && string(r.b[r.i:r.i+len(s)]) == s
+}
+
+func (r *reader) errorf(msg string, args ...interface{}) error {
+	return errors.New("objconv/toml: line " + strconv.Itoa(r.line) + ": " + fmt.Sprintf(msg, args...))
+}
+
+func formatKey(keys []string) string {
+	b := make([]byte, 0, 32)
+	for i, k := range keys {
+		if i != 0 {
+			b = append(b, '.')
+		}
+		b = appendKey(b, k)
+	}
+	return string(b)
+}
@@ -0,0 +1,64 @@
+package toml
+
+// table is the in-memory representation of TOML tables, it retains the order
+// in which keys were defined so documents can be decoded and encoded
+// deterministically.
+type table struct {
+	keys   []string
+	values map[string]interface{}
+
+	defined bool // defined by a [table] header
+	dotted  bool // created by a dotted key of a key/value pair
+	inline  bool // inline tables cannot be extended after being defined
+}
+
+func newTable() *table {
+	return &table{values: make(map[string]interface{})}
+}
+
+func (t *table) get(k string) (v interface{}, ok bool) {
+	v, ok = t.values[k]
+	return
+}
+
+func (t *table) set(k string, v interface{}) {
+	if _, exists := t.values[k]; !exists {
+		t.keys = append(t.keys, k)
+	}
+	t.values[k] = v
+}
+
+// tableArray is the in-memory representation of arrays of tables, it is kept
+// distinct from static arrays because only arrays of tables can be extended by
+// [[array]] headers.
+type tableArray struct {
+	tables []*table
+}
+
+func (a *tableArray) last() *table {
+	return a.tables[len(a.tables)-1]
+}
+
+func isBareKey(k string) bool {
+	if len(k) == 0 {
+		return false
+	}
+	for i := 0; i != len(k); i++ {
+		if !isBareKeyChar(k[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isBareKeyChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '-'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
@@ -0,0 +1,278 @@
+package toml
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testDocument = `# This is a TOML document
+
+title = "TOML Example"
+
+[owner]
+name = "Tom Preston-Werner"
+dob = 1979-05-27T07:32:00-08:00
+
+[database]
+enabled = true
+ports = [ 8000, 8001, 8002 ]
+data = [ ["delta", "phi"], [3.14] ]
+temp_targets = { cpu = 79.5, case = 72.0 }
+
+[servers]
+
+[servers.alpha]
+ip = "10.0.0.1"
+role = "frontend"
+
+[servers.beta]
+ip = "10.0.0.2"
+role = "backend"
+
+[[products]]
+name = "Hammer"
+sku = 738594937
+
+[[products]]  # empty table within the array
+
+[[products]]
+name = "Nail"
+sku = 284758393
+color = "gray"
+`
+
+func TestUnmarshal(t *testing.T) {
+	var v map[string]interface{}
+
+	if err := Unmarshal([]byte(testDocument), &v); err != nil {
+		t.Fatal(err)
+	}
+
+	dob := v["owner"].(map[interface{}]interface{})["dob"].(time.Time)
+
+	if !dob.Equal(time.Date(1979, 5, 27, 15, 32, 0, 0, time.UTC)) {
+		t.Error("bad datetime:", dob)
+	}
+
+	delete(v["owner"].(map[interface{}]interface{}), "dob")
+
+	if !reflect.DeepEqual(v, map[string]interface{}{
+		"title": "TOML Example",
+		"owner": map[interface{}]interface{}{
+			"name": "Tom Preston-Werner",
+		},
+		"database": map[interface{}]interface{}{
+			"enabled": true,
+			"ports":   []interface{}{int64(8000), int64(8001), int64(8002)},
+			"data": []interface{}{
+				[]interface{}{"delta", "phi"},
+				[]interface{}{3.14},
+			},
+			"temp_targets": map[interface{}]interface{}{
+				"cpu":  79.5,
+				"case": 72.0,
+			},
+		},
+		"servers": map[interface{}]interface{}{
+			"alpha": map[interface{}]interface{}{"ip": "10.0.0.1", "role": "frontend"},
+			"beta":  map[interface{}]interface{}{"ip": "10.0.0.2", "role": "backend"},
+		},
+		"products": []interface{}{
+			map[interface{}]interface{}{"name": "Hammer", "sku": int64(738594937)},
+			map[interface{}]interface{}{},
+			map[interface{}]interface{}{"name": "Nail", "sku": int64(284758393), "color": "gray"},
+		},
+	}) {
+		t.Errorf("%#v", v)
+	}
+}
+
+func TestUnmarshalValues(t *testing.T) {
+	tests := []struct {
+		s string
+		v interface{}
+	}{
+		{`"tab\there \u00E9\U0001F600 \"q\" \\"`, "tab\there é😀 \"q\" \\"},
+		{`'C:\Users\nodejs'`, `C:\Users\nodejs`},
+		{"\"\"\"\nRoses\r\nViolets\"\"\"", "Roses\nViolets"},
+		{"\"\"\"\nThe quick \\\n\n   brown fox.\"\"\"", "The quick brown fox."},
+		{`"""Here are two quotes: "". Five: """""`, `Here are two quotes: "". Five: ""`},
+		{"'''\nfirst\n  second'''", "first\n  second"},
+		{`''''That,' she said.''''`, `'That,' she said.'`},
+		{"+99", int64(99)},
+		{"-17", int64(-17)},
+		{"1_000_000", int64(1000000)},
+		{"0xDEAD_beef", int64(0xdeadbeef)},
+		{"0o755", int64(0755)},
+		{"0b1101", int64(13)},
+		{"6.626e-34", 6.626e-34},
+		{"-0.01", -0.01},
+		{"5e+22", 5e+22},
+		{"224_617.445_991", 224617.445991},
+		{"inf", math.Inf(1)},
+		{"-inf", math.Inf(-1)},
+		{"1979-05-27T07:32:00Z", time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC)},
+		{"1979-05-27 07:32:00.999999z", time.Date(1979, 5, 27, 7, 32, 0, 999999000, time.UTC)},
+		{"1979-05-27T07:32:00", time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC)},
+		{"1979-05-27", time.Date(1979, 5, 27, 0, 0, 0, 0, time.UTC)},
+		{"00:32:00.5", time.Date(0, 1, 1, 0, 32, 0, 5e8, time.UTC)},
+		{"[ 1, # comment\n 2,\n ]", []interface{}{int64(1), int64(2)}},
+		{"[ { x = 1 }, 'a' ]", []interface{}{map[interface{}]interface{}{"x": int64(1)}, "a"}},
+		{"{ a.b = 1, c = [] }", map[interface{}]interface{}{
+			"a": map[interface{}]interface{}{"b": int64(1)},
+			"c": []interface{}{},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.s, func(t *testing.T) {
+			var v struct{ V interface{} }
+
+			if err := Unmarshal([]byte("V = "+test.s+"\n"), &v); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(v.V, test.v) {
+				t.Errorf("%#v", v.V)
+			}
+		})
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []string{
+		"a = 1\na = 2",
+		"[a]\n[a]",
+		"a.b = 1\n[a]",
+		"[a]\nb = 1\n[a.b]",
+		"a = { b = 1 }\n[a.c]",
+		"a = [1]\n[[a]]",
+		"a = 01",
+		"a = 1__0",
+		"a = .5",
+		"a = 1.",
+		"a = \"unterminated",
+		"a = \"\\q\"",
+		"a = 1 b = 2",
+		"a = { b = 1, }",
+		"a =",
+		"= 1",
+		"a = 1979-05-27T25:00:00Z",
+	}
+
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			var v interface{}
+
+			if err := Unmarshal([]byte(test), &v); err == nil {
+				t.Errorf("%#v", v)
+			} else if !strings.HasPrefix(err.Error(), "objconv/toml: line ") {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+type testConfig struct {
+	Name     string            `objconv:"name"`
+	Version  float32           `objconv:"version"`
+	Debug    bool              `objconv:"debug,omitempty"`
+	Created  time.Time         `objconv:"created"`
+	Timeout  time.Duration     `objconv:"timeout"`
+	Tags     []string          `objconv:"tags"`
+	Labels   map[string]string `objconv:"labels"`
+	Server   testServer        `objconv:"server"`
+	Backends []testServer      `objconv:"backends"`
+	Matrix   []interface{}     `objconv:"matrix"`
+}
+
+type testServer struct {
+	Host string         `objconv:"host"`
+	Port int            `objconv:"port"`
+	TLS  *testTLSConfig `objconv:"tls"`
+}
+
+type testTLSConfig struct {
+	Cert string `objconv:"cert"`
+}
+
+func TestMarshal(t *testing.T) {
+	config := testConfig{
+		Name:    "my service",
+		Version: 1,
+		Created: time.Date(2017, 1, 2, 3, 4, 5, 6, time.UTC),
+		Timeout: 1500 * time.Millisecond,
+		Tags:    []string{"a", "b"},
+		Labels:  map[string]string{"team.name": "core"},
+		Server:  testServer{Host: "localhost", Port: 8080, TLS: &testTLSConfig{Cert: "cert.pem"}},
+		Backends: []testServer{
+			{Host: "10.0.0.1", Port: 80},
+			{Host: "10.0.0.2", Port: 80},
+		},
+		Matrix: []interface{}{1, "x", map[string]int{"y": 2}},
+	}
+
+	b, err := Marshal(config)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expect = `name = "my service"
+version = 1.0
+created = 2017-01-02T03:04:05.000000006Z
+timeout = "1.5s"
+tags = ["a", "b"]
+matrix = [1, "x", { y = 2 }]
+
+[labels]
+"team.name" = "core"
+
+[server]
+host = "localhost"
+port = 8080
+
+[server.tls]
+cert = "cert.pem"
+
+[[backends]]
+host = "10.0.0.1"
+port = 80
+
+[[backends]]
+host = "10.0.0.2"
+port = 80
+`
+
+	if s := string(b); s != expect {
+		t.Error(s)
+	}
+
+	var config2 testConfig
+
+	if err := Unmarshal(b, &config2); err != nil {
+		t.Fatal(err)
+	}
+
+	config.Matrix = []interface{}{int64(1), "x", map[interface{}]interface{}{"y": int64(2)}}
+
+	if !reflect.DeepEqual(config, config2) {
+		t.Errorf("%#v", config2)
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	for _, v := range []interface{}{
+		42,
+		[]int{1, 2, 3},
+		map[string]interface{}{"a": []interface{}{nil}},
+		map[string]uint64{"a": math.MaxUint64},
+	} {
+		if _, err := Marshal(v); err == nil {
+			t.Errorf("encoding %#v should have failed", v)
+		}
+	}
+}
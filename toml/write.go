@@ -0,0 +1,238 @@
+package toml
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// appendTable appends the TOML representation of t to b, path is the list of
+// keys leading to t from the root of the document.
+//
+// Values of t are written first as key/value pairs, then nested tables and
+// arrays of tables are written as sections.
+func appendTable(b []byte, t *table, path []string) (_ []byte, err error) {
+	for _, k := range t.keys {
+		v := t.values[k]
+
+		if isSection(v) {
+			continue
+		}
+
+		b = appendKey(b, k)
+		b = append(b, " = "...)
+
+		if b, err = appendValue(b, v); err != nil {
+			return
+		}
+
+		b = append(b, '\n')
+	}
+
+	for _, k := range t.keys {
+		v := t.values[k]
+
+		if !isSection(v) {
+			continue
+		}
+
+		p := append(path[:len(path):len(path)], k)
+
+		switch x := v.(type) {
+		case *table:
+			// Tables holding only sections don't need their own header, it is
+			// implied by the headers of the nested sections.
+			if !hasOnlySections(x) {
+				b = appendHeader(b, "[", p, "]")
+			}
+			if b, err = appendTable(b, x, p); err != nil {
+				return
+			}
+
+		case []interface{}:
+			for _, elem := range x {
+				b = appendHeader(b, "[[", p, "]]")
+				if b, err = appendTable(b, elem.(*table), p); err != nil {
+					return
+				}
+			}
+		}
+	}
+
+	return b, nil
+}
+
+// isSection returns true if v is written as a [table] section or as a list
+// of [[array]] sections.
+func isSection(v interface{}) bool {
+	switch x := v.(type) {
+	case *table:
+		return true
+
+	case []interface{}:
+		if len(x) == 0 {
+			return false
+		}
+		for _, elem := range x {
+			if _, ok := elem.(*table); !ok {
+				return false
+			}
+		}
+		return true
+
+	default:
+		return false
+	}
+}
+
+func hasOnlySections(t *table) bool {
+	if len(t.keys) == 0 {
+		return false
+	}
+	for _, v := range t.values {
+		if !isSection(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func appendHeader(b []byte, open string, path []string, close string) []byte {
+	if len(b) != 0 {
+		b = append(b, '\n')
+	}
+	b = append(b, open...)
+	for i, k := range path {
+		if i != 0 {
+			b = append(b, '.')
+		}
+		b = appendKey(b, k)
+	}
+	b = append(b, close...)
+	return append(b, '\n')
+}
+
+func appendKey(b []byte, k string) []byte {
+	if isBareKey(k) {
+		return append(b, k...)
+	}
+	return appendString(b, k)
+}
+
+// appendValue appends the inline representation of v to b.
+func appendValue(b []byte, v interface{}) (_ []byte, err error) {
+	switch x := v.(type) {
+	case bool:
+		return strconv.AppendBool(b, x), nil
+
+	case int64:
+		return strconv.AppendInt(b, x, 10), nil
+
+	case float32:
+		return appendFloat(b, float64(x), 32), nil
+
+	case float64:
+		return appendFloat(b, x, 64), nil
+
+	case string:
+		return appendString(b, x), nil
+
+	case time.Time:
+		return x.AppendFormat(b, time.RFC3339Nano), nil
+
+	case []interface{}:
+		b = append(b, '[')
+		for i, elem := range x {
+			if i != 0 {
+				b = append(b, ", "...)
+			}
+			if b, err = appendValue(b, elem); err != nil {
+				return
+			}
+		}
+		return append(b, ']'), nil
+
+	case *table:
+		if len(x.keys) == 0 {
+			return append(b, "{}"...), nil
+		}
+		b = append(b, "{ "...)
+		for i, k := range x.keys {
+			if i != 0 {
+				b = append(b, ", "...)
+			}
+			b = appendKey(b, k)
+			b = append(b, " = "...)
+			if b, err = appendValue(b, x.values[k]); err != nil {
+				return
+			}
+		}
+		return append(b, " }"...), nil
+
+	default:
+		return b, fmt.Errorf("objconv/toml: cannot encode value of type %T", v)
+	}
+}
+
+func appendFloat(b []byte, f float64, bitSize int) []byte {
+	switch {
+	case math.IsNaN(f):
+		return append(b, "nan"...)
+	case math.IsInf(f, +1):
+		return append(b, "inf"...)
+	case math.IsInf(f, -1):
+		return append(b, "-inf"...)
+	}
+
+	i := len(b)
+	b = strconv.AppendFloat(b, f, 'g', -1, bitSize)
+
+	// TOML floats must have a fractional part or an exponent.
+	for _, c := range b[i:] {
+		if c == '.' || c == 'e' {
+			return b
+		}
+	}
+
+	return append(b, ".0"...)
+}
+
+func appendString(b []byte, s string) []byte {
+	b = append(b, '"')
+
+	for i := 0; i < len(s); {
+		r, n := utf8.DecodeRuneInString(s[i:])
+
+		switch {
+		case r == '"':
+			b = append(b, '\\', '"')
+		case r == '\\':
+			b = append(b, '\\', '\\')
+		case r == '\b':
+			b = append(b, '\\', 'b')
+		case r == '\t':
+			b = append(b, '\\', 't')
+		case r == '\n':
+			b = append(b, '\\', 'n')
+		case r == '\f':
+			b = append(b, '\\', 'f')
+		case r == '\r':
+			b = append(b, '\\', 'r')
+		case r < 0x20 || r == 0x7F || (r == utf8.RuneError && n == 1):
+			// Invalid UTF-8 bytes are escaped as code points, TOML documents
+			// must be valid UTF-8.
+			b = append(b, `\u00`...)
+			b = append(b, hex[s[i]>>4], hex[s[i]&0xF])
+		default:
+			b = append(b, s[i:i+n]...)
+		}
+
+		i += n
+	}
+
+	return append(b, '"')
+}
+
+const hex = "0123456789ABCDEF"
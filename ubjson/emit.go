@@ -0,0 +1,416 @@
+package ubjson
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/objconv/objutil"
+)
+
+// Emitter implements a UBJSON emitter that satisfies the objconv.Emitter
+// interface.
+//
+// Arrays and objects of known length are written with a count prefix. Arrays
+// where all elements are integers, or all elements are floats of the same
+// size, are written as optimized containers where the type marker appears only
+// once. Integer arrays never use the uint8 type in optimized containers, which
+// is reserved for byte slices.
+//
+// Unsigned integers that overflow the range of int64 are written as high
+// precision numbers, and times, durations and errors are written as strings.
+type Emitter struct {
+	w io.Writer
+	b []byte // output buffer
+
+	// This stack tracks the state of the arrays and objects being emitted.
+	stack []frame
+
+	// Elements of the array at the top of the stack are collected in these
+	// slices while they may be written as an optimized container. Only one
+	// array can be in this state at a time since any nested container makes
+	// its parent a regular array.
+	ints   []int64
+	floats []float64
+}
+
+type frame struct {
+	n     int  // declared number of elements, negative if unknown
+	i     int  // number of elements written
+	obj   bool // true for objects, false for arrays
+	key   bool // true if the next value of an object is a key
+	typed bool // true if elements are collected for an optimized container
+	kind  byte // Int64, Float32 or Float64 when elements were collected
+}
+
+// bufferSize is the size of the output buffer after which the emitter writes
+// even if it is in the middle of a value.
+const bufferSize = 16384
+
+func NewEmitter(w io.Writer) *Emitter {
+	return &Emitter{w: w}
+}
+
+func (e *Emitter) Reset(w io.Writer) {
+	e.w = w
+	e.b = e.b[:0]
+	e.stack = e.stack[:0]
+	e.ints = e.ints[:0]
+	e.floats = e.floats[:0]
+}
+
+func (e *Emitter) EmitNil() (err error) {
+	if e.isKey() {
+		return errKey
+	}
+	if err = e.begin(); err != nil {
+		return
+	}
+	e.b = append(e.b, Null)
+	return e.end()
+}
+
+func (e *Emitter) EmitBool(v bool) (err error) {
+	if e.isKey() {
+		return e.emitKey(strconv.FormatBool(v))
+	}
+	if err = e.begin(); err != nil {
+		return
+	}
+	if v {
+		e.b = append(e.b, True)
+	} else {
+		e.b = append(e.b, False)
+	}
+	return e.end()
+}
+
+func (e *Emitter) EmitInt(v int64, _ int) (err error) {
+	if e.isKey() {
+		return e.emitKey(strconv.FormatInt(v, 10))
+	}
+	if e.collect(Int64) {
+		e.ints = append(e.ints, v)
+		return
+	}
+	if err = e.begin(); err != nil {
+		return
+	}
+	e.b = appendIntValue(e.b, v)
+	return e.end()
+}
+
+func (e *Emitter) EmitUint(v uint64, _ int) (err error) {
+	if v <= objutil.Int64Max {
+		return e.EmitInt(int64(v), 64)
+	}
+	s := strconv.FormatUint(v, 10)
+	if e.isKey() {
+		return e.emitKey(s)
+	}
+	if err = e.begin(); err != nil {
+		return
+	}
+	e.b = append(e.b, HighPrecision)
+	e.b = appendString(e.b, s)
+	return e.end()
+}
+
+func (e *Emitter) EmitFloat(v float64, bitSize int) (err error) {
+	if e.isKey() {
+		return e.emitKey(strconv.FormatFloat(v, 'g', -1, bitSize))
+	}
+
+	kind := byte(Float64)
+	if bitSize == 32 {
+		kind = Float32
+	}
+
+	if e.collect(kind) {
+		e.floats = append(e.floats, v)
+		return
+	}
+	if err = e.begin(); err != nil {
+		return
+	}
+	e.b = append(e.b, kind)
+	e.b = appendFloat(e.b, kind, v)
+	return e.end()
+}
+
+func (e *Emitter) EmitString(v string) (err error) {
+	if e.isKey() {
+		return e.emitKey(v)
+	}
+	if err = e.begin(); err != nil {
+		return
+	}
+	e.b = append(e.b, String)
+	e.b = appendString(e.b, v)
+	return e.end()
+}
+
+func (e *Emitter) EmitBytes(v []byte) (err error) {
+	if e.isKey() {
+		return errKey
+	}
+	if err = e.begin(); err != nil {
+		return
+	}
+	e.b = append(e.b, ArrayBegin, ContainerType, Uint8, ContainerCount)
+	e.b = appendIntValue(e.b, int64(len(v)))
+	e.b = append(e.b, v...)
+	return e.end()
+}
+
+func (e *Emitter) EmitTime(v time.Time) error {
+	return e.EmitString(v.Format(time.RFC3339Nano))
+}
+
+func (e *Emitter) EmitDuration(v time.Duration) error {
+	return e.EmitString(v.String())
+}
+
+func (e *Emitter) EmitError(v error) error {
+	return e.EmitString(v.Error())
+}
+
+func (e *Emitter) EmitArrayBegin(n int) (err error) {
+	if e.isKey() {
+		return errKey
+	}
+	if err = e.begin(); err != nil {
+		return
+	}
+
+	// The header of arrays of known length is written when the first element
+	// that cannot be part of an optimized container is emitted, or when the
+	// array ends.
+	if n > 0 {
+		e.stack = append(e.stack, frame{n: n, typed: true})
+		return
+	}
+
+	e.stack = append(e.stack, frame{n: n})
+	e.b = append(e.b, ArrayBegin)
+
+	if n == 0 {
+		e.b = append(e.b, ContainerCount, Int8, 0)
+	}
+
+	return
+}
+
+func (e *Emitter) EmitArrayEnd() (err error) {
+	f := e.pop()
+
+	if f.typed {
+		if f.kind != 0 && f.i == f.n {
+			e.appendTyped(f)
+		} else {
+			e.flushTyped(&f)
+		}
+	}
+
+	if f.n < 0 {
+		e.b = append(e.b, ArrayEnd)
+	} else if f.i != f.n {
+		return fmt.Errorf("objconv/ubjson: array length mismatch, expected %d but %d elements were emitted", f.n, f.i)
+	}
+
+	return e.end()
+}
+
+func (e *Emitter) EmitArrayNext() (err error) {
+	return
+}
+
+func (e *Emitter) EmitMapBegin(n int) (err error) {
+	if e.isKey() {
+		return errKey
+	}
+	if err = e.begin(); err != nil {
+		return
+	}
+
+	e.stack = append(e.stack, frame{n: n, obj: true, key: true})
+	e.b = append(e.b, ObjectBegin)
+
+	if n >= 0 {
+		e.b = append(e.b, ContainerCount)
+		e.b = appendIntValue(e.b, int64(n))
+	}
+
+	return
+}
+
+func (e *Emitter) EmitMapEnd() (err error) {
+	if f := e.pop(); f.n < 0 {
+		e.b = append(e.b, ObjectEnd)
+	}
+	return e.end()
+}
+
+func (e *Emitter) EmitMapValue() (err error) {
+	e.stack[len(e.stack)-1].key = false
+	return
+}
+
+func (e *Emitter) EmitMapNext() (err error) {
+	e.stack[len(e.stack)-1].key = true
+	return
+}
+
+func (e *Emitter) pop() frame {
+	i := len(e.stack) - 1
+	f := e.stack[i]
+	e.stack = e.stack[:i]
+	return f
+}
+
+func (e *Emitter) top() *frame {
+	if n := len(e.stack); n != 0 {
+		return &e.stack[n-1]
+	}
+	return nil
+}
+
+// isKey returns true if the next value emitted is the key of an object.
+func (e *Emitter) isKey() bool {
+	f := e.top()
+	return f != nil && f.obj && f.key
+}
+
+func (e *Emitter) emitKey(k string) error {
+	e.b = appendString(e.b, k)
+	return e.end()
+}
+
+// collect attempts to add an element of the given kind to the optimized
+// container at the top of the stack, the caller is expected to record the
+// value when the method returns true.
+func (e *Emitter) collect(kind byte) bool {
+	f := e.top()
+
+	if f == nil || !f.typed || (f.kind != 0 && f.kind != kind) || f.i == f.n {
+		return false
+	}
+
+	f.kind = kind
+	f.i++
+	return true
+}
+
+// begin must be called before writing a value, it converts the array at the
+// top of the stack to a regular array if it was collecting elements.
+func (e *Emitter) begin() error {
+	f := e.top()
+
+	if f == nil || f.obj {
+		return nil
+	}
+
+	if f.typed {
+		e.flushTyped(f)
+	}
+
+	if f.n >= 0 && f.i == f.n {
+		return fmt.Errorf("objconv/ubjson: too many elements emitted in an array of length %d", f.n)
+	}
+
+	f.i++
+	return nil
+}
+
+// end must be called after writing a value, it flushes the output buffer to
+// the writer when a top-level value or element of a top-level array (like in
+// streams) was completed.
+func (e *Emitter) end() (err error) {
+	if len(e.stack) <= 1 || len(e.b) >= bufferSize {
+		if len(e.b) != 0 {
+			_, err = e.w.Write(e.b)
+			e.b = e.b[:0]
+		}
+	}
+	return
+}
+
+// flushTyped writes f as a regular array, including the elements that were
+// collected.
+func (e *Emitter) flushTyped(f *frame) {
+	e.b = append(e.b, ArrayBegin, ContainerCount)
+	e.b = appendIntValue(e.b, int64(f.n))
+
+	switch f.kind {
+	case Int64:
+		for _, v := range e.ints {
+			e.b = appendIntValue(e.b, v)
+		}
+	case Float32, Float64:
+		for _, v := range e.floats {
+			e.b = append(e.b, f.kind)
+			e.b = appendFloat(e.b, f.kind, v)
+		}
+	}
+
+	f.typed = false
+	e.ints = e.ints[:0]
+	e.floats = e.floats[:0]
+}
+
+// appendTyped writes f as an optimized container of the elements that were
+// collected.
+func (e *Emitter) appendTyped(f frame) {
+	m := f.kind
+
+	if m == Int64 {
+		m = Int8
+		for _, v := range e.ints {
+			x := intMarker(v)
+			if x == Uint8 {
+				x = Int16
+			}
+			if intSize(x) > intSize(m) {
+				m = x
+			}
+		}
+	}
+
+	e.b = append(e.b, ArrayBegin, ContainerType, m, ContainerCount)
+	e.b = appendIntValue(e.b, int64(f.n))
+
+	switch f.kind {
+	case Int64:
+		for _, v := range e.ints {
+			e.b = appendInt(e.b, m, v)
+		}
+	default:
+		for _, v := range e.floats {
+			e.b = appendFloat(e.b, m, v)
+		}
+	}
+
+	e.ints = e.ints[:0]
+	e.floats = e.floats[:0]
+}
+
+func appendIntValue(b []byte, v int64) []byte {
+	m := intMarker(v)
+	return appendInt(append(b, m), m, v)
+}
+
+func appendFloat(b []byte, m byte, v float64) []byte {
+	if m == Float32 {
+		return appendInt(b, Int32, int64(math.Float32bits(float32(v))))
+	}
+	return appendInt(b, Int64, int64(math.Float64bits(v)))
+}
+
+func appendString(b []byte, s string) []byte {
+	return append(appendIntValue(b, int64(len(s))), s...)
+}
+
+var errKey = errors.New("objconv/ubjson: object keys must be strings or scalar values")
@@ -0,0 +1,46 @@
+package ubjson
+
+import (
+	"io"
+	"strings"
+
+	"github.com/segmentio/objconv"
+)
+
+// Codec for the UBJSON format.
+var Codec = objconv.Codec{
+	NewEmitter: func(w io.Writer) objconv.Emitter { return NewEmitter(w) },
+	NewParser:  func(r io.Reader) objconv.Parser { return NewParser(r) },
+	Extensions: []string{".ubj", ".ubjson"},
+	Sniff:      sniff,
+}
+
+func init() {
+	for _, name := range [...]string{
+		"application/ubjson",
+		"ubjson",
+	} {
+		objconv.Register(name, Codec)
+	}
+}
+
+// sniff recognizes UBJSON containers, which are distinguished from JSON by
+// the markers that follow their opening brackets.
+func sniff(b []byte) float64 {
+	if len(b) < 2 {
+		return 0
+	}
+
+	switch b[0] {
+	case ObjectBegin:
+		if strings.IndexByte("iUIlL$#}", b[1]) >= 0 {
+			return 0.6
+		}
+	case ArrayBegin:
+		if strings.IndexByte("ZNTFiUIlLdDHCS$#]", b[1]) >= 0 {
+			return 0.4
+		}
+	}
+
+	return 0
+}
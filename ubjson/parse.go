@@ -0,0 +1,549 @@
+package ubjson
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/objconv"
+	"github.com/segmentio/objconv/objutil"
+)
+
+// Parser implements a UBJSON parser that satisfies the objconv.Parser
+// interface.
+//
+// Optimized arrays of uint8 values are parsed as byte slices. High precision
+// numbers are parsed as integers or floats when they can be represented by
+// one of these types, and as strings otherwise.
+type Parser struct {
+	r io.Reader // reader to load bytes from
+	i int       // offset of the first unread byte in b
+	j int       // offset + 1 of the last unread byte in b
+	n int64     // number of bytes consumed before b[0]
+	s []byte    // string buffer
+	b [240]byte // read buffer
+
+	// This stack tracks the state of the arrays and objects being parsed.
+	stack []container
+
+	// High precision numbers have to be read to know their type, the value is
+	// retained here by ParseType until it is consumed.
+	h   interface{}
+	hok bool
+}
+
+type container struct {
+	typ byte // type of the elements of optimized containers, zero otherwise
+	n   int  // number of elements, negative if unknown
+	obj bool // true for objects, false for arrays
+	key bool // true if the next value of an object is a key
+}
+
+func NewParser(r io.Reader) *Parser {
+	return &Parser{r: r}
+}
+
+func (p *Parser) Reset(r io.Reader) {
+	p.r = r
+	p.i = 0
+	p.j = 0
+	p.n = 0
+	p.stack = p.stack[:0]
+	p.h, p.hok = nil, false
+}
+
+func (p *Parser) Buffered() io.Reader {
+	return bytes.NewReader(p.b[p.i:p.j])
+}
+
+// Offset returns the number of bytes of the input consumed by p.
+func (p *Parser) Offset() int64 {
+	return p.n + int64(p.i)
+}
+
+func (p *Parser) ParseType() (typ objconv.Type, err error) {
+	if p.hok {
+		return typeOf(p.h), nil
+	}
+
+	if p.isKey() {
+		return objconv.String, nil
+	}
+
+	m, off, err := p.marker()
+	if err != nil {
+		return
+	}
+
+	switch m {
+	case Null:
+		typ = objconv.Nil
+
+	case True, False:
+		typ = objconv.Bool
+
+	case Int8, Uint8, Int16, Int32, Int64:
+		typ = objconv.Int
+
+	case Float32, Float64:
+		typ = objconv.Float
+
+	case Char, String:
+		typ = objconv.String
+
+	case HighPrecision:
+		var s []byte
+		p.i += off
+
+		if s, err = p.readString(); err != nil {
+			return
+		}
+		if p.h, err = parseHighPrecision(string(s)); err != nil {
+			return
+		}
+
+		p.hok = true
+		typ = typeOf(p.h)
+
+	case ArrayBegin:
+		var b []byte
+		typ = objconv.Array
+
+		if b, err = p.peek(off + 1); err != nil || b[off] != ContainerType {
+			return
+		}
+		if b, err = p.peek(off + 2); err != nil {
+			return
+		}
+		if b[off+1] == Uint8 {
+			typ = objconv.Bytes
+		}
+
+	case ObjectBegin:
+		typ = objconv.Map
+
+	default:
+		err = fmt.Errorf("objconv/ubjson: unexpected marker %q", m)
+	}
+
+	return
+}
+
+func (p *Parser) ParseNil() (err error) {
+	_, off, err := p.marker()
+	p.i += off
+	return
+}
+
+func (p *Parser) ParseBool() (v bool, err error) {
+	m, off, err := p.marker()
+	p.i += off
+	v = m == True
+	return
+}
+
+func (p *Parser) ParseInt() (v int64, err error) {
+	if p.hok {
+		v = p.takeHighPrecision().(int64)
+		return
+	}
+
+	m, off, err := p.marker()
+	if err != nil {
+		return
+	}
+	p.i += off
+
+	var b []byte
+	n := intSize(m)
+
+	if b, err = p.peek(n); err != nil {
+		return
+	}
+
+	v = getInt(m, b)
+	p.i += n
+	return
+}
+
+func (p *Parser) ParseUint() (v uint64, err error) {
+	v = p.takeHighPrecision().(uint64)
+	return
+}
+
+func (p *Parser) ParseFloat() (v float64, err error) {
+	if p.hok {
+		v = p.takeHighPrecision().(float64)
+		return
+	}
+
+	m, off, err := p.marker()
+	if err != nil {
+		return
+	}
+	p.i += off
+
+	var b []byte
+
+	if m == Float32 {
+		if b, err = p.peek(4); err == nil {
+			v = float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+			p.i += 4
+		}
+	} else {
+		if b, err = p.peek(8); err == nil {
+			v = math.Float64frombits(binary.BigEndian.Uint64(b))
+			p.i += 8
+		}
+	}
+
+	return
+}
+
+func (p *Parser) ParseString() (v []byte, err error) {
+	if p.isKey() {
+		return p.readString()
+	}
+
+	if p.hok {
+		v = append(p.s[:0], p.takeHighPrecision().(string)...)
+		return
+	}
+
+	m, off, err := p.marker()
+	if err != nil {
+		return
+	}
+	p.i += off
+
+	if m == Char {
+		return p.read(1)
+	}
+
+	return p.readString()
+}
+
+func (p *Parser) ParseBytes() (v []byte, err error) {
+	_, off, err := p.marker()
+	if err != nil {
+		return
+	}
+	p.i += off
+
+	var b []byte
+	var n int
+
+	if b, err = p.peek(3); err != nil {
+		return
+	}
+	if b[0] != ContainerType || b[1] != Uint8 || b[2] != ContainerCount {
+		err = fmt.Errorf("objconv/ubjson: invalid header of optimized uint8 array")
+		return
+	}
+	p.i += 3
+
+	if n, err = p.readLength(); err != nil {
+		return
+	}
+
+	return p.read(n)
+}
+
+func (p *Parser) ParseTime() (v time.Time, err error) {
+	panic("objconv/ubjson: ParseTime should never be called because UBJSON has no time type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseDuration() (v time.Duration, err error) {
+	panic("objconv/ubjson: ParseDuration should never be called because UBJSON has no duration type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseError() (v error, err error) {
+	panic("objconv/ubjson: ParseError should never be called because UBJSON has no error type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseArrayBegin() (n int, err error) {
+	return p.parseContainerBegin(false)
+}
+
+func (p *Parser) ParseArrayEnd(n int) (err error) {
+	return p.parseContainerEnd(ArrayEnd)
+}
+
+func (p *Parser) ParseArrayNext(n int) (err error) {
+	return p.parseContainerNext(ArrayEnd)
+}
+
+func (p *Parser) ParseMapBegin() (n int, err error) {
+	return p.parseContainerBegin(true)
+}
+
+func (p *Parser) ParseMapEnd(n int) (err error) {
+	return p.parseContainerEnd(ObjectEnd)
+}
+
+func (p *Parser) ParseMapValue(n int) (err error) {
+	p.stack[len(p.stack)-1].key = false
+	return
+}
+
+func (p *Parser) ParseMapNext(n int) (err error) {
+	p.stack[len(p.stack)-1].key = true
+	return p.parseContainerNext(ObjectEnd)
+}
+
+func (p *Parser) parseContainerBegin(obj bool) (n int, err error) {
+	_, off, err := p.marker()
+	if err != nil {
+		return
+	}
+	p.i += off
+
+	var b []byte
+	var typ byte
+	n = -1
+
+	if b, err = p.peek(1); err != nil {
+		return
+	}
+
+	if b[0] == ContainerType {
+		if b, err = p.peek(3); err != nil {
+			return
+		}
+		if b[2] != ContainerCount {
+			err = fmt.Errorf("objconv/ubjson: optimized containers must have a count")
+			return
+		}
+		typ = b[1]
+		p.i += 2
+		b = b[2:]
+	}
+
+	if b[0] == ContainerCount {
+		p.i++
+		if n, err = p.readLength(); err != nil {
+			return
+		}
+	}
+
+	p.stack = append(p.stack, container{typ: typ, n: n, obj: obj, key: obj})
+	return
+}
+
+func (p *Parser) parseContainerEnd(end byte) (err error) {
+	i := len(p.stack) - 1
+	c := p.stack[i]
+	p.stack = p.stack[:i]
+
+	if c.n < 0 {
+		var b []byte
+
+		if b, err = p.peekNoOp(); err != nil {
+			return
+		}
+		if b[0] != end {
+			return fmt.Errorf("objconv/ubjson: expected %q but found %q", end, b[0])
+		}
+
+		p.i++
+	}
+
+	return
+}
+
+func (p *Parser) parseContainerNext(end byte) (err error) {
+	if p.stack[len(p.stack)-1].n < 0 {
+		var b []byte
+
+		if b, err = p.peekNoOp(); err != nil {
+			return
+		}
+		if b[0] == end {
+			err = objconv.End
+		}
+	}
+	return
+}
+
+// isKey returns true if the next value is the key of an object.
+func (p *Parser) isKey() bool {
+	n := len(p.stack)
+	return n != 0 && p.stack[n-1].obj && p.stack[n-1].key
+}
+
+// marker returns the marker of the next value and the number of bytes it
+// occupies in the input, which is zero for elements of optimized containers.
+func (p *Parser) marker() (m byte, off int, err error) {
+	if n := len(p.stack); n != 0 {
+		if c := p.stack[n-1]; c.typ != 0 && !c.key {
+			return c.typ, 0, nil
+		}
+	}
+
+	var b []byte
+
+	if b, err = p.peekNoOp(); err != nil {
+		return
+	}
+
+	return b[0], 1, nil
+}
+
+// peekNoOp skips no-op markers and returns the next byte of the input.
+func (p *Parser) peekNoOp() (b []byte, err error) {
+	for {
+		if b, err = p.peek(1); err != nil || b[0] != NoOp {
+			return
+		}
+		p.i++
+	}
+}
+
+func (p *Parser) readLength() (n int, err error) {
+	var b []byte
+
+	if b, err = p.peek(1); err != nil {
+		return
+	}
+
+	m := b[0]
+
+	if !isInt(m) {
+		err = fmt.Errorf("objconv/ubjson: expected an integer length but found the %q marker", m)
+		return
+	}
+
+	if b, err = p.peek(1 + intSize(m)); err != nil {
+		return
+	}
+
+	v := getInt(m, b[1:])
+	p.i += len(b)
+
+	if v < 0 || v > int64(objutil.IntMax) {
+		err = fmt.Errorf("objconv/ubjson: invalid length %d", v)
+		return
+	}
+
+	n = int(v)
+	return
+}
+
+func (p *Parser) readString() (v []byte, err error) {
+	var n int
+	if n, err = p.readLength(); err != nil {
+		return
+	}
+	return p.read(n)
+}
+
+func (p *Parser) takeHighPrecision() (v interface{}) {
+	v, p.h, p.hok = p.h, nil, false
+	return
+}
+
+func (p *Parser) read(n int) (b []byte, err error) {
+	if n <= (p.j - p.i) { // check if the string is already buffered
+		b = p.b[p.i : p.i+n]
+		p.i += n
+		return
+	}
+
+	if n <= len(p.b) { // check if the string can be loaded in the read buffer
+		if b, err = p.peek(n); err != nil {
+			return
+		}
+		p.i += n
+		return
+	}
+
+	if cap(p.s) < n {
+		p.s = make([]byte, n, align(n, 1024))
+	} else {
+		p.s = p.s[:n]
+	}
+
+	copy(p.s, p.b[p.i:p.j])
+	p.n += int64(p.i + len(p.s))
+	n = p.j - p.i
+	p.i = 0
+	p.j = 0
+
+	if _, err = io.ReadFull(p.r, p.s[n:]); err != nil {
+		return
+	}
+
+	b = p.s
+	return
+}
+
+func (p *Parser) peek(n int) (b []byte, err error) {
+	for (p.i + n) > p.j {
+		if err = p.fill(); err != nil {
+			return
+		}
+	}
+	b = p.b[p.i : p.i+n]
+	return
+}
+
+func (p *Parser) fill() (err error) {
+	n := p.j - p.i
+	copy(p.b[:], p.b[p.i:p.j])
+	p.n += int64(p.i)
+	p.i = 0
+	p.j = n
+
+	if n, err = p.r.Read(p.b[n:]); n > 0 {
+		err = nil
+		p.j += n
+	} else if err != nil {
+		return
+	} else {
+		err = io.ErrNoProgress
+		return
+	}
+
+	return
+}
+
+// parseHighPrecision converts the high precision number s to an int64,
+// uint64 or float64 value, or returns it unchanged if none of these types can
+// represent it.
+func parseHighPrecision(s string) (interface{}, error) {
+	integer, ok := objutil.IsNumber(s)
+
+	if !ok {
+		return nil, fmt.Errorf("objconv/ubjson: invalid high precision number %q", s)
+	}
+
+	if integer {
+		if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return v, nil
+		}
+		if v, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return v, nil
+		}
+	} else if v, err := strconv.ParseFloat(s, 64); err == nil {
+		return v, nil
+	}
+
+	return s, nil
+}
+
+func typeOf(v interface{}) objconv.Type {
+	switch v.(type) {
+	case int64:
+		return objconv.Int
+	case uint64:
+		return objconv.Uint
+	case float64:
+		return objconv.Float
+	default:
+		return objconv.String
+	}
+}
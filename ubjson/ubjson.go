@@ -0,0 +1,116 @@
+package ubjson
+
+import (
+	"encoding/binary"
+
+	"github.com/segmentio/objconv/objutil"
+)
+
+// Markers of the UBJSON format, see http://ubjson.org/type-reference/
+const (
+	Null  = 'Z'
+	NoOp  = 'N'
+	True  = 'T'
+	False = 'F'
+
+	Int8  = 'i'
+	Uint8 = 'U'
+	Int16 = 'I'
+	Int32 = 'l'
+	Int64 = 'L'
+
+	Float32 = 'd'
+	Float64 = 'D'
+
+	HighPrecision = 'H'
+
+	Char   = 'C'
+	String = 'S'
+
+	ArrayBegin  = '['
+	ArrayEnd    = ']'
+	ObjectBegin = '{'
+	ObjectEnd   = '}'
+
+	// ContainerType and ContainerCount introduce the type and count of
+	// optimized containers.
+	ContainerType  = '$'
+	ContainerCount = '#'
+)
+
+// intMarker returns the marker of the smallest integer type that can hold v.
+func intMarker(v int64) byte {
+	switch {
+	case v >= objutil.Int8Min && v <= objutil.Int8Max:
+		return Int8
+	case v >= 0 && v <= objutil.Uint8Max:
+		return Uint8
+	case v >= objutil.Int16Min && v <= objutil.Int16Max:
+		return Int16
+	case v >= objutil.Int32Min && v <= objutil.Int32Max:
+		return Int32
+	default:
+		return Int64
+	}
+}
+
+// intSize returns the size of the payload of integers of type m.
+func intSize(m byte) int {
+	switch m {
+	case Int8, Uint8:
+		return 1
+	case Int16:
+		return 2
+	case Int32:
+		return 4
+	default:
+		return 8
+	}
+}
+
+func appendInt(b []byte, m byte, v int64) []byte {
+	switch m {
+	case Int8, Uint8:
+		return append(b, byte(v))
+	case Int16:
+		return append(b, byte(v>>8), byte(v))
+	case Int32:
+		var a [4]byte
+		binary.BigEndian.PutUint32(a[:], uint32(v))
+		return append(b, a[:]...)
+	default:
+		var a [8]byte
+		binary.BigEndian.PutUint64(a[:], uint64(v))
+		return append(b, a[:]...)
+	}
+}
+
+func getInt(m byte, b []byte) int64 {
+	switch m {
+	case Int8:
+		return int64(int8(b[0]))
+	case Uint8:
+		return int64(b[0])
+	case Int16:
+		return int64(int16(binary.BigEndian.Uint16(b)))
+	case Int32:
+		return int64(int32(binary.BigEndian.Uint32(b)))
+	default:
+		return int64(binary.BigEndian.Uint64(b))
+	}
+}
+
+func isInt(m byte) bool {
+	switch m {
+	case Int8, Uint8, Int16, Int32, Int64:
+		return true
+	}
+	return false
+}
+
+func align(n int, a int) int {
+	if (n % a) == 0 {
+		return n
+	}
+	return ((n / a) + 1) * a
+}
@@ -0,0 +1,114 @@
+package ubjson
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/segmentio/objconv/objtests"
+)
+
+func TestCodec(t *testing.T) {
+	objtests.TestCodec(t, Codec)
+}
+
+func BenchmarkCodec(b *testing.B) {
+	objtests.BenchmarkCodec(b, Codec)
+}
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		v interface{}
+		b []byte
+	}{
+		{nil, []byte("Z")},
+		{true, []byte("T")},
+		{-1, []byte("i\xff")},
+		{200, []byte("U\xc8")},
+		{uint64(math.MaxUint64), []byte("Hi\x1418446744073709551615")},
+		{"hi", []byte("Si\x02hi")},
+		{[]byte("hi"), []byte("[$U#i\x02hi")},
+		{[]int{}, []byte("[#i\x00")},
+		{[]int32{1, 2, 200}, []byte("[$I#i\x03\x00\x01\x00\x02\x00\xc8")},
+		{[]int8{-1, 1}, []byte("[$i#i\x02\xff\x01")},
+		{[]float64{0.5}, []byte("[$D#i\x01\x3f\xe0\x00\x00\x00\x00\x00\x00")},
+		{[]float32{0.5}, []byte("[$d#i\x01\x3f\x00\x00\x00")},
+		{[]interface{}{1, "a"}, []byte("[#i\x02i\x01Si\x01a")},
+		{[]interface{}{1, 0.5}, []byte("[#i\x02i\x01D\x3f\xe0\x00\x00\x00\x00\x00\x00")},
+		{[][]int{{1}}, []byte("[#i\x01[$i#i\x01\x01")},
+		{struct{ A int }{1}, []byte("{#i\x01i\x01Ai\x01")},
+	}
+
+	for _, test := range tests {
+		b, err := Marshal(test.v)
+		if err != nil {
+			t.Errorf("%#v: %s", test.v, err)
+			continue
+		}
+		if !bytes.Equal(b, test.b) {
+			t.Errorf("%#v: %q != %q", test.v, test.b, b)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	tests := []struct {
+		b []byte
+		v interface{}
+	}{
+		{[]byte("NZ"), nil},
+		{[]byte("Ca"), "a"},
+		{[]byte("HU\x0512.25"), 12.25},
+		{[]byte("HU\x0512345"), int64(12345)},
+		{[]byte("HU\x1418446744073709551615"), uint64(math.MaxUint64)},
+		{[]byte("HU\x191234567890123456789012345"), "1234567890123456789012345"},
+		{[]byte("[i\x01NSU\x01a]"), []interface{}{int64(1), "a"}},
+		{[]byte("[$T#i\x02"), []interface{}{true, true}},
+		{[]byte("[$[#i\x02#i\x01i\x01]"), []interface{}{[]interface{}{int64(1)}, []interface{}{}}},
+		{[]byte("{U\x01ai\x01U\x01b[]}"), map[interface{}]interface{}{"a": int64(1), "b": []interface{}{}}},
+		{[]byte("{$i#i\x02U\x01a\x01U\x01b\x02"), map[interface{}]interface{}{"a": int64(1), "b": int64(2)}},
+	}
+
+	for _, test := range tests {
+		var v interface{}
+
+		if err := Unmarshal(test.b, &v); err != nil {
+			t.Errorf("%q: %s", test.b, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(v, test.v) {
+			t.Errorf("%q: %#v != %#v", test.b, test.v, v)
+		}
+	}
+}
+
+func TestOptimizedRoundTrip(t *testing.T) {
+	in := struct {
+		I []int32
+		F []float64
+	}{
+		I: []int32{math.MinInt32, -1, 0, 1, math.MaxInt32},
+		F: []float64{-1.5, 0, math.Pi},
+	}
+	out := in
+	out.I, out.F = nil, nil
+
+	b, err := Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Contains(b, []byte("[$l#i\x05")) || !bytes.Contains(b, []byte("[$D#i\x03")) {
+		t.Errorf("arrays were not written as optimized containers: %q", b)
+	}
+
+	if err := Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("%#v != %#v", in, out)
+	}
+}
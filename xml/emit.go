@@ -0,0 +1,234 @@
+package xml
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Emitter implements an XML emitter that satisfies the objconv.Emitter
+// interface.
+//
+// Values are written as the root element of XML documents, following the
+// conventions described on Parser in reverse: keys of maps prefixed with
+// AttrPrefix are written as attributes, the value of TextKey is written as
+// text, other keys are written as child elements, and arrays are written as
+// repeated elements. Arrays emitted at the top level are written as item
+// elements of the root.
+//
+// Arrays cannot be directly nested within other arrays because XML has no
+// representation for them.
+type Emitter struct {
+	// Root is the name of the root element of documents, it defaults to
+	// DefaultRoot when empty.
+	Root string
+
+	w io.Writer
+	b []byte
+	// The stack is used to keep track of the container being built by the
+	// emitter, which may be an arrayEmitter or nodeEmitter.
+	stack []emitter
+}
+
+func NewEmitter(w io.Writer) *Emitter {
+	return &Emitter{w: w}
+}
+
+func (e *Emitter) Reset(w io.Writer) {
+	e.w = w
+	e.stack = e.stack[:0]
+}
+
+func (e *Emitter) EmitNil() error {
+	return e.emit(nil)
+}
+
+func (e *Emitter) EmitBool(v bool) error {
+	return e.emit(v)
+}
+
+func (e *Emitter) EmitInt(v int64, _ int) error {
+	return e.emit(v)
+}
+
+func (e *Emitter) EmitUint(v uint64, _ int) error {
+	return e.emit(v)
+}
+
+func (e *Emitter) EmitFloat(v float64, bitSize int) error {
+	if bitSize == 32 {
+		return e.emit(float32(v))
+	}
+	return e.emit(v)
+}
+
+func (e *Emitter) EmitString(v string) error {
+	return e.emit(v)
+}
+
+func (e *Emitter) EmitBytes(v []byte) error {
+	return e.emit(base64.StdEncoding.EncodeToString(v))
+}
+
+func (e *Emitter) EmitTime(v time.Time) error {
+	return e.emit(v.Format(time.RFC3339Nano))
+}
+
+func (e *Emitter) EmitDuration(v time.Duration) error {
+	return e.emit(v.String())
+}
+
+func (e *Emitter) EmitError(v error) error {
+	return e.emit(v.Error())
+}
+
+func (e *Emitter) EmitArrayBegin(_ int) (err error) {
+	e.push(&arrayEmitter{self: []interface{}{}})
+	return
+}
+
+func (e *Emitter) EmitArrayEnd() (err error) {
+	return e.emit(e.pop().value())
+}
+
+func (e *Emitter) EmitArrayNext() (err error) {
+	return
+}
+
+func (e *Emitter) EmitMapBegin(_ int) (err error) {
+	e.push(&nodeEmitter{self: newNode()})
+	return
+}
+
+func (e *Emitter) EmitMapEnd() (err error) {
+	return e.emit(e.pop().value())
+}
+
+func (e *Emitter) EmitMapValue() (err error) {
+	return
+}
+
+func (e *Emitter) EmitMapNext() (err error) {
+	return
+}
+
+func (e *Emitter) TextEmitter() bool {
+	return true
+}
+
+// AttrEmitter returns true, struct fields tagged with `attr` are written as
+// attributes.
+func (e *Emitter) AttrEmitter() bool {
+	return true
+}
+
+func (e *Emitter) emit(v interface{}) (err error) {
+	if n := len(e.stack); n != 0 {
+		return e.stack[n-1].emit(v)
+	}
+
+	root := e.Root
+	if len(root) == 0 {
+		root = DefaultRoot
+	}
+
+	if a, ok := v.([]interface{}); ok {
+		n := newNode()
+		if len(a) != 0 {
+			n.set(ItemName, a)
+		}
+		v = n
+	}
+
+	e.b = append(e.b[:0], header...)
+
+	if e.b, err = appendElement(e.b, root, v); err != nil {
+		return
+	}
+
+	e.b = append(e.b, '\n')
+	_, err = e.w.Write(e.b)
+	return
+}
+
+func (e *Emitter) push(v emitter) {
+	e.stack = append(e.stack, v)
+}
+
+func (e *Emitter) pop() emitter {
+	i := len(e.stack) - 1
+	v := e.stack[i]
+	e.stack = e.stack[:i]
+	return v
+}
+
+const header = `<?xml version="1.0" encoding="UTF-8"?>` + "\n"
+
+type emitter interface {
+	emit(interface{}) error
+	value() interface{}
+}
+
+type arrayEmitter struct {
+	self []interface{}
+}
+
+func (e *arrayEmitter) emit(v interface{}) error {
+	if _, ok := v.([]interface{}); ok {
+		return errors.New("objconv/xml: arrays cannot be nested within arrays because XML has no representation for them")
+	}
+	e.self = append(e.self, v)
+	return nil
+}
+
+func (e *arrayEmitter) value() interface{} {
+	return e.self
+}
+
+type nodeEmitter struct {
+	self *node
+	key  string
+	val  bool
+}
+
+func (e *nodeEmitter) emit(v interface{}) (err error) {
+	if e.val {
+		e.val = false
+
+		if _, exists := e.self.get(e.key); exists {
+			return errors.New("objconv/xml: duplicate key " + strconv.Quote(e.key))
+		}
+
+		e.self.set(e.key, v)
+		return
+	}
+
+	e.val = true
+	e.key, err = keyString(v)
+	return
+}
+
+func (e *nodeEmitter) value() interface{} {
+	return e.self
+}
+
+func keyString(v interface{}) (string, error) {
+	switch k := v.(type) {
+	case string:
+		return k, nil
+	case bool:
+		return strconv.FormatBool(k), nil
+	case int64:
+		return strconv.FormatInt(k, 10), nil
+	case uint64:
+		return strconv.FormatUint(k, 10), nil
+	case float32:
+		return strconv.FormatFloat(float64(k), 'g', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(k, 'g', -1, 64), nil
+	default:
+		return "", errors.New("objconv/xml: map keys must be strings or scalar values")
+	}
+}
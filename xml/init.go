@@ -0,0 +1,40 @@
+package xml
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/segmentio/objconv"
+)
+
+// Codec for the XML format.
+var Codec = objconv.Codec{
+	NewEmitter: func(w io.Writer) objconv.Emitter { return NewEmitter(w) },
+	NewParser:  func(r io.Reader) objconv.Parser { return NewParser(r) },
+	Extensions: []string{".xml"},
+	Sniff:      sniff,
+}
+
+func init() {
+	for _, name := range [...]string{
+		"application/xml",
+		"text/xml",
+		"xml",
+	} {
+		objconv.Register(name, Codec)
+	}
+}
+
+// sniff recognizes XML declarations and documents starting with an element.
+func sniff(b []byte) float64 {
+	b = bytes.TrimLeft(bytes.TrimPrefix(b, []byte("\xEF\xBB\xBF")), " \t\r\n")
+
+	switch {
+	case bytes.HasPrefix(b, []byte("<?xml")):
+		return 1
+	case len(b) > 1 && b[0] == '<' && (b[1] == '!' || b[1] == '_' || (b[1]|0x20) >= 'a' && (b[1]|0x20) <= 'z'):
+		return 0.6
+	}
+
+	return 0
+}
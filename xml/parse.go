@@ -0,0 +1,328 @@
+package xml
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/segmentio/objconv"
+)
+
+// Parser implements an XML parser that satisfies the objconv.Parser
+// interface.
+//
+// The parser loads the whole document in memory before producing its values,
+// the root element represents the decoded value and its name is ignored.
+// Elements are converted to values following these rules:
+//
+//   - elements that have no attributes and no child elements are simple
+//     values, inferred from their text (nil, bool, number or string)
+//   - other elements are maps where attributes are keys prefixed with
+//     AttrPrefix, child elements are keyed by their name, and the text is
+//     set under TextKey
+//   - repeated child elements are arrays
+//   - root elements containing only item elements are arrays
+//
+// Since arrays of a single element are indistinguishable from the element,
+// the parser lets decoders accept single values where arrays are expected.
+type Parser struct {
+	r io.Reader // reader to load bytes from
+	s []byte    // string buffer
+	// This stack is used to iterate over the arrays and maps of the loaded
+	// document.
+	stack []parser
+}
+
+func NewParser(r io.Reader) *Parser {
+	return &Parser{r: r}
+}
+
+func (p *Parser) Reset(r io.Reader) {
+	p.r = r
+	p.s = nil
+	p.stack = nil
+}
+
+func (p *Parser) Buffered() io.Reader {
+	return bytes.NewReader(nil)
+}
+
+func (p *Parser) ParseType() (typ objconv.Type, err error) {
+	if p.stack == nil {
+		var b []byte
+		var v interface{}
+
+		if b, err = ioutil.ReadAll(p.r); err != nil {
+			return
+		}
+		if v, err = readDocument(b); err != nil {
+			return
+		}
+		p.push(newParser(v))
+	}
+
+	switch v := p.value(); v.(type) {
+	case nil:
+		typ = objconv.Nil
+
+	case bool:
+		typ = objconv.Bool
+
+	case int64:
+		typ = objconv.Int
+
+	case uint64:
+		typ = objconv.Uint
+
+	case float64:
+		typ = objconv.Float
+
+	case string:
+		typ = objconv.String
+
+	case *node:
+		typ = objconv.Map
+
+	case []interface{}:
+		typ = objconv.Array
+
+	case eof:
+		err = io.EOF
+
+	default:
+		err = fmt.Errorf("objconv/xml: unsupported value of type %T", v)
+	}
+
+	return
+}
+
+func (p *Parser) ParseNil() (err error) {
+	p.pop()
+	return
+}
+
+func (p *Parser) ParseBool() (v bool, err error) {
+	v = p.pop().value().(bool)
+	return
+}
+
+func (p *Parser) ParseInt() (v int64, err error) {
+	v = p.pop().value().(int64)
+	return
+}
+
+func (p *Parser) ParseUint() (v uint64, err error) {
+	v = p.pop().value().(uint64)
+	return
+}
+
+func (p *Parser) ParseFloat() (v float64, err error) {
+	v = p.pop().value().(float64)
+	return
+}
+
+func (p *Parser) ParseString() (v []byte, err error) {
+	s := p.pop().value().(string)
+	n := len(s)
+
+	if cap(p.s) < n {
+		p.s = make([]byte, 0, ((n/1024)+1)*1024)
+	}
+
+	v = p.s[:n]
+	copy(v, s)
+	return
+}
+
+func (p *Parser) ParseBytes() (v []byte, err error) {
+	panic("objconv/xml: ParseBytes should never be called because XML has no bytes type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseTime() (v time.Time, err error) {
+	panic("objconv/xml: ParseTime should never be called because XML has no time type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseDuration() (v time.Duration, err error) {
+	panic("objconv/xml: ParseDuration should never be called because XML has no duration type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseError() (v error, err error) {
+	panic("objconv/xml: ParseError should never be called because XML has no error type, this is likely a bug in the decoder code")
+}
+
+func (p *Parser) ParseArrayBegin() (n int, err error) {
+	if n = p.top().len(); n != 0 {
+		p.push(newParser(p.top().next()))
+	}
+	return
+}
+
+func (p *Parser) ParseArrayEnd(n int) (err error) {
+	p.pop()
+	return
+}
+
+func (p *Parser) ParseArrayNext(n int) (err error) {
+	p.push(newParser(p.top().next()))
+	return
+}
+
+func (p *Parser) ParseMapBegin() (n int, err error) {
+	if n = p.top().len(); n != 0 {
+		p.push(newParser(p.top().next()))
+	}
+	return
+}
+
+func (p *Parser) ParseMapEnd(n int) (err error) {
+	p.pop()
+	return
+}
+
+func (p *Parser) ParseMapValue(n int) (err error) {
+	p.push(newParser(p.top().next()))
+	return
+}
+
+func (p *Parser) ParseMapNext(n int) (err error) {
+	p.push(newParser(p.top().next()))
+	return
+}
+
+func (p *Parser) TextParser() bool {
+	return true
+}
+
+// AttrParser returns true, attributes are decoded into the struct fields
+// tagged with `attr`.
+func (p *Parser) AttrParser() bool {
+	return true
+}
+
+// ImplicitArrayParser returns true, elements that appear once cannot be
+// distinguished from arrays of a single element.
+func (p *Parser) ImplicitArrayParser() bool {
+	return true
+}
+
+func (p *Parser) DecodeBytes(b []byte) (v []byte, err error) {
+	var n int
+	if n, err = base64.StdEncoding.Decode(b, b); err != nil {
+		return
+	}
+	v = b[:n]
+	return
+}
+
+func (p *Parser) push(v parser) {
+	p.stack = append(p.stack, v)
+}
+
+func (p *Parser) pop() parser {
+	i := len(p.stack) - 1
+	v := p.stack[i]
+	p.stack = p.stack[:i]
+	return v
+}
+
+func (p *Parser) top() parser {
+	return p.stack[len(p.stack)-1]
+}
+
+func (p *Parser) value() interface{} {
+	n := len(p.stack)
+	if n == 0 {
+		return eof{}
+	}
+	return p.stack[n-1].value()
+}
+
+type parser interface {
+	value() interface{}
+	next() interface{}
+	len() int
+}
+
+type valueParser struct {
+	self interface{}
+}
+
+func (p *valueParser) value() interface{} {
+	return p.self
+}
+
+func (p *valueParser) next() interface{} {
+	panic("objconv/xml: invalid call of next method on simple value parser")
+}
+
+func (p *valueParser) len() int {
+	panic("objconv/xml: invalid call of len method on simple value parser")
+}
+
+type arrayParser struct {
+	self []interface{}
+	off  int
+}
+
+func (p *arrayParser) value() interface{} {
+	return p.self
+}
+
+func (p *arrayParser) next() interface{} {
+	v := p.self[p.off]
+	p.off++
+	return v
+}
+
+func (p *arrayParser) len() int {
+	return len(p.self)
+}
+
+type nodeParser struct {
+	self *node
+	off  int
+	val  bool
+}
+
+func (p *nodeParser) value() interface{} {
+	return p.self
+}
+
+func (p *nodeParser) next() (v interface{}) {
+	k := p.self.keys[p.off]
+
+	if p.val {
+		v = p.self.values[k]
+		p.val = false
+		p.off++
+	} else {
+		v = k
+		p.val = true
+	}
+
+	return
+}
+
+func (p *nodeParser) len() int {
+	return len(p.self.keys)
+}
+
+func newParser(v interface{}) parser {
+	switch x := v.(type) {
+	case *node:
+		return &nodeParser{self: x}
+
+	case []interface{}:
+		return &arrayParser{self: x}
+
+	default:
+		return &valueParser{self: x}
+	}
+}
+
+// eof values are returned by the top method to indicate that all values have
+// already been consumed.
+type eof struct{}
@@ -0,0 +1,110 @@
+package xml
+
+import (
+	"bytes"
+	stdxml "encoding/xml"
+	"io"
+	"strings"
+
+	"github.com/segmentio/objconv/objutil"
+)
+
+// readDocument parses the XML document in b and returns the value of its root
+// element, io.EOF is returned if b contains no elements.
+func readDocument(b []byte) (v interface{}, err error) {
+	d := stdxml.NewDecoder(bytes.NewReader(b))
+
+	for {
+		var t stdxml.Token
+
+		if t, err = d.Token(); err != nil {
+			return
+		}
+
+		if start, ok := t.(stdxml.StartElement); ok {
+			if v, err = readElement(d, start); err != nil {
+				return
+			}
+			return rootValue(v), nil
+		}
+	}
+}
+
+// readElement reads the content of the element opened by start, and returns
+// its value.
+func readElement(d *stdxml.Decoder, start stdxml.StartElement) (interface{}, error) {
+	n := newNode()
+	b := []byte{}
+
+	for _, a := range start.Attr {
+		if a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns") {
+			continue // namespace declarations are not values
+		}
+		n.set(AttrPrefix+a.Name.Local, objutil.InferValue(a.Value))
+	}
+
+	for {
+		t, err := d.Token()
+
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		switch x := t.(type) {
+		case stdxml.StartElement:
+			v, err := readElement(d, x)
+			if err != nil {
+				return nil, err
+			}
+			n.add(x.Name.Local, v)
+
+		case stdxml.CharData:
+			b = append(b, x...)
+
+		case stdxml.EndElement:
+			return elementValue(n, string(b)), nil
+		}
+	}
+}
+
+// elementValue returns the value of an element made of the attributes and
+// child elements in n, and the text s.
+//
+// Elements that have no attributes and no child elements are simple values,
+// they are nil when their text is only made of white spaces. Otherwise the
+// text of the element is set under TextKey after trimming white spaces.
+func elementValue(n *node, s string) interface{} {
+	if len(n.keys) == 0 {
+		if len(strings.TrimSpace(s)) == 0 {
+			return nil
+		}
+		return objutil.InferValue(s)
+	}
+
+	if s = strings.TrimSpace(s); len(s) != 0 {
+		n.set(TextKey, objutil.InferValue(s))
+	}
+
+	return n
+}
+
+// rootValue returns the value of the document which has v as root element.
+//
+// Root elements that contain only item elements represent arrays.
+func rootValue(v interface{}) interface{} {
+	n, ok := v.(*node)
+
+	if !ok || len(n.keys) != 1 || n.keys[0] != ItemName {
+		return v
+	}
+
+	switch x := n.values[ItemName].(type) {
+	case []interface{}:
+		return x
+	default:
+		return []interface{}{x}
+	}
+}
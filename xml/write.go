@@ -0,0 +1,185 @@
+package xml
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+// appendElement appends the XML representation of v as an element named name
+// to b.
+func appendElement(b []byte, name string, v interface{}) (_ []byte, err error) {
+	if !isName(name) {
+		return b, fmt.Errorf("objconv/xml: %q is not a valid XML element name", name)
+	}
+
+	b = append(b, '<')
+	b = append(b, name...)
+
+	switch x := v.(type) {
+	case nil:
+		return append(b, "/>"...), nil
+
+	case *node:
+		return appendNode(b, name, x)
+
+	case []interface{}:
+		return b, errors.New("objconv/xml: arrays cannot be nested within arrays because XML has no representation for them")
+	}
+
+	b = append(b, '>')
+
+	if b, err = appendText(b, v, false); err != nil {
+		return
+	}
+
+	return appendEndTag(b, name), nil
+}
+
+func appendNode(b []byte, name string, n *node) (_ []byte, err error) {
+	content := false
+
+	for _, k := range n.keys {
+		v := n.values[k]
+
+		if !isAttr(k) {
+			content = content || !isEmptyContent(k, v)
+			continue
+		}
+
+		if v == nil {
+			continue
+		}
+
+		attr := k[len(AttrPrefix):]
+
+		if !isName(attr) {
+			return b, fmt.Errorf("objconv/xml: %q is not a valid XML attribute name", attr)
+		}
+
+		b = append(b, ' ')
+		b = append(b, attr...)
+		b = append(b, `="`...)
+
+		if b, err = appendText(b, v, true); err != nil {
+			return
+		}
+
+		b = append(b, '"')
+	}
+
+	if !content {
+		return append(b, "/>"...), nil
+	}
+
+	b = append(b, '>')
+
+	if v, ok := n.values[TextKey]; ok && v != nil {
+		if b, err = appendText(b, v, false); err != nil {
+			return
+		}
+	}
+
+	for _, k := range n.keys {
+		if isAttr(k) || k == TextKey {
+			continue
+		}
+
+		switch x := n.values[k].(type) {
+		case []interface{}:
+			for _, elem := range x {
+				if b, err = appendElement(b, k, elem); err != nil {
+					return
+				}
+			}
+
+		default:
+			if b, err = appendElement(b, k, x); err != nil {
+				return
+			}
+		}
+	}
+
+	return appendEndTag(b, name), nil
+}
+
+// isEmptyContent returns true if the value v of the key k of a node produces
+// no content within the element.
+func isEmptyContent(k string, v interface{}) bool {
+	if k == TextKey {
+		return v == nil
+	}
+	a, ok := v.([]interface{})
+	return ok && len(a) == 0
+}
+
+func appendEndTag(b []byte, name string) []byte {
+	b = append(b, "</"...)
+	b = append(b, name...)
+	return append(b, '>')
+}
+
+// appendText appends the escaped text representation of the simple value v
+// to b, attr is true if the text is the value of an attribute.
+func appendText(b []byte, v interface{}, attr bool) ([]byte, error) {
+	switch x := v.(type) {
+	case bool:
+		return strconv.AppendBool(b, x), nil
+	case int64:
+		return strconv.AppendInt(b, x, 10), nil
+	case uint64:
+		return strconv.AppendUint(b, x, 10), nil
+	case float32:
+		return strconv.AppendFloat(b, float64(x), 'g', -1, 32), nil
+	case float64:
+		return strconv.AppendFloat(b, x, 'g', -1, 64), nil
+	case string:
+		return appendEscaped(b, x, attr), nil
+	default:
+		return b, fmt.Errorf("objconv/xml: cannot encode value of type %T as text", v)
+	}
+}
+
+func appendEscaped(b []byte, s string, attr bool) []byte {
+	for i := 0; i < len(s); {
+		r, n := utf8.DecodeRuneInString(s[i:])
+
+		switch {
+		case r == '&':
+			b = append(b, "&amp;"...)
+		case r == '<':
+			b = append(b, "&lt;"...)
+		case r == '>':
+			b = append(b, "&gt;"...)
+		case r == '"' && attr:
+			b = append(b, "&quot;"...)
+		case r == '\t' && attr:
+			b = append(b, "&#x9;"...)
+		case r == '\n' && attr:
+			b = append(b, "&#xA;"...)
+		case r == '\r':
+			b = append(b, "&#xD;"...)
+		case !isChar(r) || (r == utf8.RuneError && n == 1):
+			// Characters that cannot appear in XML documents are replaced
+			// like the standard encoding/xml package does.
+			b = append(b, "\uFFFD"...)
+		default:
+			b = append(b, s[i:i+n]...)
+		}
+
+		i += n
+	}
+	return b
+}
+
+// isChar returns true if r is in the Char production of the XML
+// specification.
+func isChar(r rune) bool {
+	return r == 0x09 ||
+		r == 0x0A ||
+		r == 0x0D ||
+		r >= 0x20 && r <= 0xD7FF ||
+		r >= 0xE000 && r <= 0xFFFD ||
+		r >= 0x10000 && r <= 0x10FFFF
+}
@@ -0,0 +1,95 @@
+package xml
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/segmentio/objconv/objutil"
+)
+
+const (
+	// AttrPrefix is the prefix of map keys representing attributes of XML
+	// elements. Struct fields can be marked as attributes with the `attr`
+	// option of the objconv tag.
+	AttrPrefix = objutil.AttrPrefix
+
+	// TextKey is the map key representing the text of XML elements that also
+	// have attributes or child elements.
+	TextKey = "#text"
+
+	// DefaultRoot is the name of the root element written by emitters that
+	// don't have one configured.
+	DefaultRoot = "root"
+
+	// ItemName is the name of the elements representing the values of arrays
+	// written at the top level of a document.
+	ItemName = "item"
+)
+
+// node is the in-memory representation of XML elements that have attributes
+// or child elements, it retains the order in which keys were defined.
+type node struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newNode() *node {
+	return &node{values: make(map[string]interface{})}
+}
+
+func (n *node) get(k string) (v interface{}, ok bool) {
+	v, ok = n.values[k]
+	return
+}
+
+func (n *node) set(k string, v interface{}) {
+	if _, exists := n.values[k]; !exists {
+		n.keys = append(n.keys, k)
+	}
+	n.values[k] = v
+}
+
+// add sets v as the value of k, or appends it to the list of values of k if
+// the key was already set, which is how repeated elements are represented.
+func (n *node) add(k string, v interface{}) {
+	x, exists := n.values[k]
+
+	if !exists {
+		n.set(k, v)
+		return
+	}
+
+	if list, ok := x.([]interface{}); ok {
+		n.values[k] = append(list, v)
+	} else {
+		n.values[k] = []interface{}{x, v}
+	}
+}
+
+// isAttr returns true if k is the key of an attribute.
+func isAttr(k string) bool {
+	return strings.HasPrefix(k, AttrPrefix)
+}
+
+// isName returns true if s is a valid XML name (without namespace prefix).
+func isName(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+
+	for i, r := range s {
+		if r == utf8.RuneError {
+			return false
+		}
+		if unicode.IsLetter(r) || r == '_' {
+			continue
+		}
+		if i != 0 && (unicode.IsDigit(r) || r == '-' || r == '.' || unicode.Is(unicode.Mn, r)) {
+			continue
+		}
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,197 @@
+package xml
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/segmentio/objconv"
+)
+
+type book struct {
+	ID      int      `objconv:"id,attr"`
+	Lang    string   `objconv:"lang,attr,omitempty"`
+	Title   string   `objconv:"title"`
+	Authors []string `objconv:"author"`
+	Price   float64  `objconv:"price"`
+}
+
+type library struct {
+	Name  string `objconv:"name,attr"`
+	Books []book `objconv:"book"`
+}
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		v interface{}
+		s string
+	}{
+		{nil, `<root/>`},
+		{true, `<root>true</root>`},
+		{42, `<root>42</root>`},
+		{"a < b & c", `<root>a &lt; b &amp; c</root>`},
+		{[]int{1, 2}, `<root><item>1</item><item>2</item></root>`},
+		{[]int{}, `<root/>`},
+		{
+			book{ID: 1, Title: "Go", Authors: []string{"A", "B"}, Price: 9.5},
+			`<root id="1"><title>Go</title><author>A</author><author>B</author><price>9.5</price></root>`,
+		},
+		{
+			struct {
+				Key  string `objconv:"key,attr"`
+				Text string `objconv:"#text"`
+			}{`"x"`, "hello"},
+			`<root key="&quot;x&quot;">hello</root>`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.s, func(t *testing.T) {
+			b, err := Marshal(test.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := string(b); s != header+test.s+"\n" {
+				t.Errorf("bad output:\n%s", s)
+			}
+		})
+	}
+}
+
+func TestMarshalError(t *testing.T) {
+	tests := []interface{}{
+		[][]int{{1}},
+		map[string]int{"not a name": 1},
+		map[string]interface{}{"@id": []int{1}},
+	}
+
+	for _, test := range tests {
+		if _, err := Marshal(test); err == nil {
+			t.Errorf("%#v: expected an error", test)
+		}
+	}
+}
+
+func TestEmitterRoot(t *testing.T) {
+	b := &strings.Builder{}
+	e := NewEmitter(b)
+	e.Root = "library"
+
+	if err := objconv.NewEncoder(e).Encode(library{Name: "city"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := b.String(); s != header+`<library name="city"/>`+"\n" {
+		t.Error("bad output:", s)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	const doc = `<?xml version="1.0"?>
+<!-- list of books -->
+<library xmlns="http://example.com/library" name="city">
+  <book id="1" lang="en">
+    <title>Go</title>
+    <author>A</author>
+    <author>B</author>
+    <price>9.5</price>
+  </book>
+  <book id="2">
+    <title>XML</title>
+    <author>C</author>
+    <price>12</price>
+  </book>
+</library>
+`
+
+	var lib library
+
+	if err := Unmarshal([]byte(doc), &lib); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(lib, library{
+		Name: "city",
+		Books: []book{
+			{ID: 1, Lang: "en", Title: "Go", Authors: []string{"A", "B"}, Price: 9.5},
+			{ID: 2, Title: "XML", Authors: []string{"C"}, Price: 12},
+		},
+	}) {
+		t.Errorf("bad value: %#v", lib)
+	}
+}
+
+func TestUnmarshalInterface(t *testing.T) {
+	tests := []struct {
+		s string
+		v interface{}
+	}{
+		{`<a/>`, nil},
+		{`<a>  </a>`, nil},
+		{`<a>-1</a>`, int64(-1)},
+		{`<a>hello world</a>`, "hello world"},
+		{`<a><item>1</item><item>x</item></a>`, []interface{}{int64(1), "x"}},
+		{`<a><item>1</item></a>`, []interface{}{int64(1)}},
+		{
+			`<a id="1"> text <b>true</b><c/><b>false</b></a>`,
+			map[interface{}]interface{}{
+				"@id":   int64(1),
+				"#text": "text",
+				"b":     []interface{}{true, false},
+				"c":     nil,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.s, func(t *testing.T) {
+			var v interface{}
+
+			if err := Unmarshal([]byte(test.s), &v); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(v, test.v) {
+				t.Errorf("bad value: %#v", v)
+			}
+		})
+	}
+}
+
+func TestUnmarshalError(t *testing.T) {
+	for _, s := range []string{
+		``,
+		`<a>`,
+		`<a></b>`,
+	} {
+		var v interface{}
+
+		if err := Unmarshal([]byte(s), &v); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	lib := library{
+		Name: "city",
+		Books: []book{
+			{ID: 1, Lang: "fr", Title: "Été", Authors: []string{"A"}, Price: 1.25},
+		},
+	}
+
+	b, err := Marshal(lib)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var res library
+
+	if err := Unmarshal(b, &res); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(lib, res) {
+		t.Errorf("%#v != %#v", lib, res)
+	}
+}
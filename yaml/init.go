@@ -1,6 +1,7 @@
 package yaml
 
 import (
+	"bytes"
 	"io"
 
 	"github.com/segmentio/objconv"
@@ -10,6 +11,8 @@ import (
 var Codec = objconv.Codec{
 	NewEmitter: func(w io.Writer) objconv.Emitter { return NewEmitter(w) },
 	NewParser:  func(r io.Reader) objconv.Parser { return NewParser(r) },
+	Extensions: []string{".yaml", ".yml"},
+	Sniff:      sniff,
 }
 
 func init() {
@@ -21,3 +24,49 @@ func init() {
 		objconv.Register(name, Codec)
 	}
 }
+
+// sniff recognizes YAML directives and document markers, and to a lesser
+// degree documents starting with a mapping or a sequence.
+func sniff(b []byte) float64 {
+	switch {
+	case bytes.HasPrefix(b, []byte("%YAML")):
+		return 1
+	case bytes.HasPrefix(b, []byte("---")):
+		return 0.8
+	}
+
+	line := firstLine(b)
+
+	switch {
+	case bytes.HasPrefix(line, []byte("- ")):
+		return 0.4
+	case len(line) != 0 && isKeyByte(line[0]):
+		if i := bytes.IndexByte(line, ':'); i > 0 && (i == len(line)-1 || line[i+1] == ' ') {
+			return 0.5
+		}
+	}
+
+	return 0
+}
+
+// firstLine returns the first line of b which isn't blank or a comment.
+func firstLine(b []byte) []byte {
+	for len(b) != 0 {
+		var line []byte
+
+		if i := bytes.IndexByte(b, '\n'); i >= 0 {
+			line, b = b[:i], b[i+1:]
+		} else {
+			line, b = b, nil
+		}
+
+		if line = bytes.TrimSpace(line); len(line) != 0 && line[0] != '#' {
+			return line
+		}
+	}
+	return nil
+}
+
+func isKeyByte(c byte) bool {
+	return c == '_' || c == '-' || c == '"' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}